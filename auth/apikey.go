@@ -0,0 +1,82 @@
+package auth
+
+import (
+	"crypto/sha256"
+	"dataflow/models"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+)
+
+type APIKey struct {
+	ID       string   `json:"id"`
+	Hash     string   `json:"hash"`
+	Subject  string   `json:"subject"`
+	Roles    []string `json:"roles,omitempty"`
+	Stores   []string `json:"stores,omitempty"`
+	Disabled bool     `json:"disabled,omitempty"`
+}
+
+type apiKeysFile struct {
+	Keys []APIKey `json:"keys"`
+}
+
+// KeyStore authenticates static API keys. Only SHA-256 hashes of the keys are
+// kept, so the key file never contains usable secrets.
+type KeyStore struct {
+	keys map[string]APIKey
+}
+
+func HashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+func NewKeyStore(keys []APIKey) (*KeyStore, error) {
+	store := &KeyStore{keys: make(map[string]APIKey, len(keys))}
+	for _, key := range keys {
+		hash := strings.ToLower(key.Hash)
+		if decoded, err := hex.DecodeString(hash); err != nil || len(decoded) != sha256.Size {
+			return nil, fmt.Errorf("api key %q: hash must be a hex encoded SHA-256 digest", key.ID)
+		}
+		if key.Subject == "" {
+			return nil, fmt.Errorf("api key %q: subject is required", key.ID)
+		}
+		if _, ok := store.keys[hash]; ok {
+			return nil, fmt.Errorf("api key %q: duplicate hash", key.ID)
+		}
+		store.keys[hash] = key
+	}
+	return store, nil
+}
+
+// LoadAPIKeys reads a JSON file of the form {"keys": [APIKey, ...]}.
+func LoadAPIKeys(path string) (*KeyStore, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't read api keys: %w", err)
+	}
+	var file apiKeysFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("couldn't parse api keys: %w", err)
+	}
+	return NewKeyStore(file.Keys)
+}
+
+func (s *KeyStore) Authenticate(key string) (*models.Principal, error) {
+	apiKey, ok := s.keys[HashAPIKey(key)]
+	if !ok {
+		return nil, ErrInvalidCredentials
+	}
+	if apiKey.Disabled {
+		return nil, ErrDisabledCredentials
+	}
+	return &models.Principal{
+		Subject: apiKey.Subject,
+		Method:  MethodAPIKey,
+		Roles:   apiKey.Roles,
+		Stores:  apiKey.Stores,
+	}, nil
+}
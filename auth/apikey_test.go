@@ -0,0 +1,44 @@
+package auth
+
+import (
+	"dataflow/models"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestKeyStore_Authenticate(t *testing.T) {
+	store, err := NewKeyStore([]APIKey{
+		{ID: "ingest", Hash: HashAPIKey("secret-1"), Subject: "pos-integration", Roles: []string{"integration"}},
+		{ID: "old", Hash: HashAPIKey("secret-2"), Subject: "retired", Disabled: true},
+	})
+	assert.NoError(t, err)
+
+	principal, err := store.Authenticate("secret-1")
+	assert.NoError(t, err)
+	assert.Equal(t, &models.Principal{Subject: "pos-integration", Method: MethodAPIKey, Roles: []string{"integration"}}, principal)
+
+	_, err = store.Authenticate("secret-2")
+	assert.ErrorIs(t, err, ErrDisabledCredentials)
+
+	_, err = store.Authenticate("unknown")
+	assert.ErrorIs(t, err, ErrInvalidCredentials)
+}
+
+func TestNewKeyStore_InvalidHash(t *testing.T) {
+	_, err := NewKeyStore([]APIKey{{ID: "plain", Hash: "secret-1", Subject: "x"}})
+	assert.Error(t, err)
+}
+
+func TestLoadAPIKeys(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "keys.json")
+	data := `{"keys": [{"id": "ingest", "hash": "` + HashAPIKey("secret-1") + `", "subject": "pos-integration"}]}`
+	assert.NoError(t, os.WriteFile(path, []byte(data), 0600))
+
+	store, err := LoadAPIKeys(path)
+	assert.NoError(t, err)
+	principal, err := store.Authenticate("secret-1")
+	assert.NoError(t, err)
+	assert.Equal(t, "pos-integration", principal.Subject)
+}
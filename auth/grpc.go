@@ -0,0 +1,88 @@
+package auth
+
+import (
+	"context"
+	"dataflow/models"
+	"errors"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"strings"
+)
+
+// UnaryServerInterceptor is Middleware for unary gRPC calls. Credentials are
+// read from the "x-api-key" and "authorization" metadata. Unauthenticated
+// calls fail with Unauthenticated, and calls with disabled credentials with
+// PermissionDenied.
+func (a *Authenticator) UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return unaryInterceptor(a.authenticateMetadata)
+}
+
+// StreamServerInterceptor is UnaryServerInterceptor for streaming calls.
+func (a *Authenticator) StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return streamInterceptor(a.authenticateMetadata)
+}
+
+// AnonymousUnaryServerInterceptor is Anonymous for unary gRPC calls.
+func AnonymousUnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return unaryInterceptor(anonymous)
+}
+
+// AnonymousStreamServerInterceptor is Anonymous for streaming gRPC calls.
+func AnonymousStreamServerInterceptor() grpc.StreamServerInterceptor {
+	return streamInterceptor(anonymous)
+}
+
+func (a *Authenticator) authenticateMetadata(ctx context.Context) (*models.Principal, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	principal, err := a.authenticate(first(md, strings.ToLower(APIKeyHeader)), first(md, "authorization"))
+	if err != nil {
+		if errors.Is(err, ErrDisabledCredentials) {
+			return nil, status.Error(codes.PermissionDenied, err.Error())
+		}
+		return nil, status.Error(codes.Unauthenticated, err.Error())
+	}
+	return principal, nil
+}
+
+func anonymous(context.Context) (*models.Principal, error) {
+	return AnonymousPrincipal, nil
+}
+
+func first(md metadata.MD, key string) string {
+	if values := md.Get(key); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+func unaryInterceptor(authenticate func(context.Context) (*models.Principal, error)) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		principal, err := authenticate(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return handler(WithPrincipal(ctx, principal), req)
+	}
+}
+
+func streamInterceptor(authenticate func(context.Context) (*models.Principal, error)) grpc.StreamServerInterceptor {
+	return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		principal, err := authenticate(stream.Context())
+		if err != nil {
+			return err
+		}
+		return handler(srv, &serverStream{ServerStream: stream, ctx: WithPrincipal(stream.Context(), principal)})
+	}
+}
+
+// serverStream replaces the context of a stream.
+type serverStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *serverStream) Context() context.Context {
+	return s.ctx
+}
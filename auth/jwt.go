@@ -0,0 +1,247 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha256"
+	"dataflow/models"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"os"
+	"strings"
+	"time"
+)
+
+const (
+	AlgHS256 = "HS256"
+	AlgRS256 = "RS256"
+)
+
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid,omitempty"`
+	Alg string `json:"alg,omitempty"`
+	Use string `json:"use,omitempty"`
+	K   string `json:"k,omitempty"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+}
+
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+type JWTOptions struct {
+	Issuer   string
+	Audience string
+	// Leeway is the clock skew tolerated when checking exp and nbf.
+	Leeway time.Duration
+}
+
+type verificationKey struct {
+	alg    string
+	secret []byte
+	public *rsa.PublicKey
+}
+
+// JWTVerifier validates HS256 and RS256 bearer tokens against a local JWKS.
+// The algorithm is bound to the key type, so an RSA public key can never be
+// used as an HMAC secret.
+type JWTVerifier struct {
+	keys map[string]verificationKey
+	opts JWTOptions
+	now  func() time.Time
+}
+
+type claims struct {
+	Subject   string   `json:"sub"`
+	Issuer    string   `json:"iss"`
+	Audience  audience `json:"aud"`
+	ExpiresAt *int64   `json:"exp"`
+	NotBefore *int64   `json:"nbf"`
+	Roles     []string `json:"roles"`
+	Stores    []string `json:"stores"`
+}
+
+// audience accepts both the string and the array form of the aud claim.
+type audience []string
+
+func (a *audience) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*a = audience{single}
+		return nil
+	}
+	var many []string
+	if err := json.Unmarshal(data, &many); err != nil {
+		return err
+	}
+	*a = many
+	return nil
+}
+
+func LoadJWKS(path string) (*JWKS, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't read jwks: %w", err)
+	}
+	var jwks JWKS
+	if err := json.Unmarshal(data, &jwks); err != nil {
+		return nil, fmt.Errorf("couldn't parse jwks: %w", err)
+	}
+	return &jwks, nil
+}
+
+func NewJWTVerifier(jwks *JWKS, opts JWTOptions) (*JWTVerifier, error) {
+	verifier := &JWTVerifier{keys: make(map[string]verificationKey), opts: opts, now: time.Now}
+	for _, jwk := range jwks.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		var key verificationKey
+		switch jwk.Kty {
+		case "oct":
+			secret, err := base64.RawURLEncoding.DecodeString(jwk.K)
+			if err != nil || len(secret) == 0 {
+				return nil, fmt.Errorf("jwk %q: invalid symmetric key", jwk.Kid)
+			}
+			key = verificationKey{alg: AlgHS256, secret: secret}
+		case "RSA":
+			public, err := rsaPublicKey(jwk)
+			if err != nil {
+				return nil, fmt.Errorf("jwk %q: %w", jwk.Kid, err)
+			}
+			key = verificationKey{alg: AlgRS256, public: public}
+		default:
+			return nil, fmt.Errorf("jwk %q: unsupported key type %q", jwk.Kid, jwk.Kty)
+		}
+		if jwk.Alg != "" && jwk.Alg != key.alg {
+			return nil, fmt.Errorf("jwk %q: unsupported algorithm %q", jwk.Kid, jwk.Alg)
+		}
+		if _, ok := verifier.keys[jwk.Kid]; ok {
+			return nil, fmt.Errorf("jwk %q: duplicate key id", jwk.Kid)
+		}
+		verifier.keys[jwk.Kid] = key
+	}
+	if len(verifier.keys) == 0 {
+		return nil, fmt.Errorf("jwks contains no signing keys")
+	}
+	return verifier, nil
+}
+
+func (v *JWTVerifier) Verify(token string) (*models.Principal, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("%w: malformed token", ErrInvalidCredentials)
+	}
+
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, fmt.Errorf("%w: malformed header", ErrInvalidCredentials)
+	}
+	key, ok := v.keys[header.Kid]
+	if !ok {
+		return nil, fmt.Errorf("%w: unknown key", ErrInvalidCredentials)
+	}
+	if header.Alg != key.alg {
+		return nil, fmt.Errorf("%w: unexpected algorithm %q", ErrInvalidCredentials, header.Alg)
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("%w: malformed signature", ErrInvalidCredentials)
+	}
+	signed := []byte(parts[0] + "." + parts[1])
+	if !key.verify(signed, signature) {
+		return nil, fmt.Errorf("%w: invalid signature", ErrInvalidCredentials)
+	}
+
+	var c claims
+	if err := decodeSegment(parts[1], &c); err != nil {
+		return nil, fmt.Errorf("%w: malformed claims", ErrInvalidCredentials)
+	}
+	if err := v.validate(&c); err != nil {
+		return nil, err
+	}
+	return &models.Principal{
+		Subject: c.Subject,
+		Method:  MethodJWT,
+		Roles:   c.Roles,
+		Stores:  c.Stores,
+	}, nil
+}
+
+func (v *JWTVerifier) validate(c *claims) error {
+	now := v.now()
+	if c.Subject == "" {
+		return fmt.Errorf("%w: missing subject", ErrInvalidCredentials)
+	}
+	if c.ExpiresAt == nil {
+		return fmt.Errorf("%w: missing expiry", ErrInvalidCredentials)
+	}
+	if !now.Before(time.Unix(*c.ExpiresAt, 0).Add(v.opts.Leeway)) {
+		return fmt.Errorf("%w: token expired", ErrInvalidCredentials)
+	}
+	if c.NotBefore != nil && now.Add(v.opts.Leeway).Before(time.Unix(*c.NotBefore, 0)) {
+		return fmt.Errorf("%w: token not yet valid", ErrInvalidCredentials)
+	}
+	if v.opts.Issuer != "" && c.Issuer != v.opts.Issuer {
+		return fmt.Errorf("%w: unexpected issuer", ErrInvalidCredentials)
+	}
+	if v.opts.Audience != "" {
+		for _, aud := range c.Audience {
+			if aud == v.opts.Audience {
+				return nil
+			}
+		}
+		return fmt.Errorf("%w: unexpected audience", ErrInvalidCredentials)
+	}
+	return nil
+}
+
+func (k verificationKey) verify(signed []byte, signature []byte) bool {
+	digest := sha256.Sum256(signed)
+	switch k.alg {
+	case AlgHS256:
+		mac := hmac.New(sha256.New, k.secret)
+		mac.Write(signed)
+		return hmac.Equal(mac.Sum(nil), signature)
+	case AlgRS256:
+		return rsa.VerifyPKCS1v15(k.public, crypto.SHA256, digest[:], signature) == nil
+	}
+	return false
+}
+
+func rsaPublicKey(jwk JWK) (*rsa.PublicKey, error) {
+	n, err := base64.RawURLEncoding.DecodeString(jwk.N)
+	if err != nil || len(n) == 0 {
+		return nil, fmt.Errorf("invalid RSA modulus")
+	}
+	e, err := base64.RawURLEncoding.DecodeString(jwk.E)
+	if err != nil || len(e) == 0 || len(e) > 4 {
+		return nil, fmt.Errorf("invalid RSA exponent")
+	}
+	exponent := 0
+	for _, b := range e {
+		exponent = exponent<<8 | int(b)
+	}
+	public := &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: exponent}
+	if public.N.BitLen() < 2048 {
+		return nil, fmt.Errorf("RSA keys must be at least 2048 bits")
+	}
+	return public, nil
+}
+
+func decodeSegment(segment string, v interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
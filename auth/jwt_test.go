@@ -0,0 +1,135 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"dataflow/models"
+	"encoding/base64"
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"math/big"
+	"testing"
+	"time"
+)
+
+var testHMACSecret = []byte("0123456789abcdef0123456789abcdef")
+
+func signToken(t *testing.T, alg string, kid string, claims map[string]interface{}, key interface{}) string {
+	header, _ := json.Marshal(map[string]string{"alg": alg, "kid": kid, "typ": "JWT"})
+	payload, _ := json.Marshal(claims)
+	signed := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+
+	var signature []byte
+	switch k := key.(type) {
+	case []byte:
+		mac := hmac.New(sha256.New, k)
+		mac.Write([]byte(signed))
+		signature = mac.Sum(nil)
+	case *rsa.PrivateKey:
+		digest := sha256.Sum256([]byte(signed))
+		var err error
+		signature, err = rsa.SignPKCS1v15(rand.Reader, k, crypto.SHA256, digest[:])
+		assert.NoError(t, err)
+	}
+	return signed + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+func testJWKS(public *rsa.PublicKey) *JWKS {
+	return &JWKS{Keys: []JWK{
+		{Kty: "oct", Kid: "hs", Alg: AlgHS256, K: base64.RawURLEncoding.EncodeToString(testHMACSecret)},
+		{
+			Kty: "RSA",
+			Kid: "rs",
+			Alg: AlgRS256,
+			N:   base64.RawURLEncoding.EncodeToString(public.N.Bytes()),
+			E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(public.E)).Bytes()),
+		},
+	}}
+}
+
+func validClaims() map[string]interface{} {
+	return map[string]interface{}{
+		"sub":    "manager-1",
+		"iss":    "https://idp.example.com",
+		"aud":    "dataflow",
+		"exp":    time.Now().Add(time.Hour).Unix(),
+		"roles":  []string{"store_manager"},
+		"stores": []string{"6789"},
+	}
+}
+
+func newTestVerifier(t *testing.T) (*JWTVerifier, *rsa.PrivateKey) {
+	private, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+	verifier, err := NewJWTVerifier(testJWKS(&private.PublicKey), JWTOptions{Issuer: "https://idp.example.com", Audience: "dataflow"})
+	assert.NoError(t, err)
+	return verifier, private
+}
+
+func TestJWTVerifier_Verify_HS256(t *testing.T) {
+	verifier, _ := newTestVerifier(t)
+
+	principal, err := verifier.Verify(signToken(t, AlgHS256, "hs", validClaims(), testHMACSecret))
+	assert.NoError(t, err)
+	assert.Equal(t, &models.Principal{Subject: "manager-1", Method: MethodJWT, Roles: []string{"store_manager"}, Stores: []string{"6789"}}, principal)
+}
+
+func TestJWTVerifier_Verify_RS256(t *testing.T) {
+	verifier, private := newTestVerifier(t)
+
+	claims := validClaims()
+	claims["aud"] = []string{"other", "dataflow"}
+	principal, err := verifier.Verify(signToken(t, AlgRS256, "rs", claims, private))
+	assert.NoError(t, err)
+	assert.Equal(t, "manager-1", principal.Subject)
+}
+
+func TestJWTVerifier_Verify_Rejected(t *testing.T) {
+	verifier, private := newTestVerifier(t)
+	other, _ := rsa.GenerateKey(rand.Reader, 2048)
+
+	expired := validClaims()
+	expired["exp"] = time.Now().Add(-time.Hour).Unix()
+	notYetValid := validClaims()
+	notYetValid["nbf"] = time.Now().Add(time.Hour).Unix()
+	wrongIssuer := validClaims()
+	wrongIssuer["iss"] = "https://evil.example.com"
+	wrongAudience := validClaims()
+	wrongAudience["aud"] = "billing"
+	noExpiry := validClaims()
+	delete(noExpiry, "exp")
+
+	tokens := map[string]string{
+		"expired":         signToken(t, AlgHS256, "hs", expired, testHMACSecret),
+		"not yet valid":   signToken(t, AlgHS256, "hs", notYetValid, testHMACSecret),
+		"wrong issuer":    signToken(t, AlgHS256, "hs", wrongIssuer, testHMACSecret),
+		"wrong audience":  signToken(t, AlgHS256, "hs", wrongAudience, testHMACSecret),
+		"no expiry":       signToken(t, AlgHS256, "hs", noExpiry, testHMACSecret),
+		"wrong secret":    signToken(t, AlgHS256, "hs", validClaims(), []byte("another secret")),
+		"wrong rsa key":   signToken(t, AlgRS256, "rs", validClaims(), other),
+		"alg confusion":   signToken(t, AlgHS256, "rs", validClaims(), private.PublicKey.N.Bytes()),
+		"unknown key":     signToken(t, AlgHS256, "nope", validClaims(), testHMACSecret),
+		"alg none":        signToken(t, "none", "hs", validClaims(), nil),
+		"malformed token": "not-a-token",
+	}
+	for name, token := range tokens {
+		_, err := verifier.Verify(token)
+		assert.ErrorIs(t, err, ErrInvalidCredentials, name)
+	}
+}
+
+func TestNewJWTVerifier_RejectsWeakRSAKeys(t *testing.T) {
+	private, err := rsa.GenerateKey(rand.Reader, 1024)
+	assert.NoError(t, err)
+
+	_, err = NewJWTVerifier(&JWKS{Keys: []JWK{{
+		Kty: "RSA",
+		Kid: "weak",
+		N:   base64.RawURLEncoding.EncodeToString(private.N.Bytes()),
+		E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(private.E)).Bytes()),
+	}}}, JWTOptions{})
+	assert.Error(t, err)
+}
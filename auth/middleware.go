@@ -0,0 +1,97 @@
+package auth
+
+import (
+	"dataflow/models"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"strings"
+)
+
+const APIKeyHeader = "X-API-Key"
+
+const principalContextKey = "principal"
+
+var (
+	ErrMissingCredentials  = errors.New("missing credentials")
+	ErrInvalidCredentials  = errors.New("invalid credentials")
+	ErrDisabledCredentials = errors.New("credentials are disabled")
+)
+
+// Authenticator accepts an API key in the X-API-Key header or a JWT in the
+// Authorization header. Either source may be nil.
+type Authenticator struct {
+	keys *KeyStore
+	jwt  *JWTVerifier
+}
+
+func NewAuthenticator(keys *KeyStore, jwt *JWTVerifier) *Authenticator {
+	return &Authenticator{keys: keys, jwt: jwt}
+}
+
+func (a *Authenticator) Authenticate(r *http.Request) (*models.Principal, error) {
+	return a.authenticate(r.Header.Get(APIKeyHeader), r.Header.Get("Authorization"))
+}
+
+// authenticate checks the values of the API key and Authorization headers,
+// or of the equivalent gRPC metadata.
+func (a *Authenticator) authenticate(key string, authorization string) (*models.Principal, error) {
+	if key != "" {
+		if a.keys == nil {
+			return nil, ErrInvalidCredentials
+		}
+		return a.keys.Authenticate(key)
+	}
+	if authorization != "" {
+		scheme, token, ok := strings.Cut(authorization, " ")
+		if !ok || !strings.EqualFold(scheme, "Bearer") || a.jwt == nil {
+			return nil, ErrInvalidCredentials
+		}
+		return a.jwt.Verify(strings.TrimSpace(token))
+	}
+	return nil, ErrMissingCredentials
+}
+
+// Middleware rejects unauthenticated requests with 401, and requests with
+// disabled credentials with 403. The principal is stored in the request
+// context for the service layer.
+func (a *Authenticator) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		principal, err := a.Authenticate(c.Request)
+		if err != nil {
+			status := http.StatusUnauthorized
+			if errors.Is(err, ErrDisabledCredentials) {
+				status = http.StatusForbidden
+			} else {
+				c.Header("WWW-Authenticate", `Bearer realm="dataflow"`)
+			}
+			c.AbortWithStatusJSON(status, gin.H{"error": err.Error(), "status": status})
+			return
+		}
+		c.Set(principalContextKey, principal)
+		c.Request = c.Request.WithContext(WithPrincipal(c.Request.Context(), principal))
+		c.Next()
+	}
+}
+
+// Anonymous attaches AnonymousPrincipal to every request. It replaces
+// Middleware when authentication is disabled.
+func Anonymous() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Set(principalContextKey, AnonymousPrincipal)
+		c.Request = c.Request.WithContext(WithPrincipal(c.Request.Context(), AnonymousPrincipal))
+		c.Next()
+	}
+}
+
+// Require rejects requests whose principal lacks the permission with 403.
+func Require(permission string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		principal, _ := PrincipalFromContext(c.Request.Context())
+		if !HasPermission(principal, permission) {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "missing permission " + permission, "status": http.StatusForbidden})
+			return
+		}
+		c.Next()
+	}
+}
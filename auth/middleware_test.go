@@ -0,0 +1,87 @@
+package auth
+
+import (
+	"dataflow/models"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func setupRouter(t *testing.T) (*gin.Engine, *models.Principal) {
+	keys, err := NewKeyStore([]APIKey{
+		{ID: "ingest", Hash: HashAPIKey("secret-1"), Subject: "pos-integration"},
+		{ID: "old", Hash: HashAPIKey("secret-2"), Subject: "retired", Disabled: true},
+	})
+	assert.NoError(t, err)
+	verifier, _ := newTestVerifier(t)
+
+	var seen models.Principal
+	router := gin.New()
+	router.Use(NewAuthenticator(keys, verifier).Middleware())
+	router.GET("/data", func(c *gin.Context) {
+		principal, _ := PrincipalFromContext(c.Request.Context())
+		seen = *principal
+		c.Status(http.StatusOK)
+	})
+	return router, &seen
+}
+
+func TestMiddleware_APIKey(t *testing.T) {
+	router, seen := setupRouter(t)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/data", nil)
+	req.Header.Set(APIKeyHeader, "secret-1")
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "pos-integration", seen.Subject)
+}
+
+func TestMiddleware_BearerToken(t *testing.T) {
+	router, seen := setupRouter(t)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/data", nil)
+	req.Header.Set("Authorization", "Bearer "+signToken(t, AlgHS256, "hs", validClaims(), testHMACSecret))
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "manager-1", seen.Subject)
+	assert.Equal(t, MethodJWT, seen.Method)
+}
+
+func TestMiddleware_Unauthorized(t *testing.T) {
+	router, _ := setupRouter(t)
+
+	requests := map[string]func(*http.Request){
+		"no credentials": func(*http.Request) {},
+		"wrong key":      func(r *http.Request) { r.Header.Set(APIKeyHeader, "guess") },
+		"bad token":      func(r *http.Request) { r.Header.Set("Authorization", "Bearer abc.def.ghi") },
+		"basic auth":     func(r *http.Request) { r.SetBasicAuth("admin", "admin") },
+	}
+	for name, prepare := range requests {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/data", nil)
+		prepare(req)
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusUnauthorized, w.Code, name)
+		assert.Contains(t, w.Body.String(), `"status":401`, name)
+		assert.NotEmpty(t, w.Header().Get("WWW-Authenticate"), name)
+	}
+}
+
+func TestMiddleware_Forbidden(t *testing.T) {
+	router, _ := setupRouter(t)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/data", nil)
+	req.Header.Set(APIKeyHeader, "secret-2")
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Contains(t, w.Body.String(), `"status":403`)
+}
@@ -0,0 +1,32 @@
+package auth
+
+import (
+	"context"
+	"dataflow/models"
+)
+
+const (
+	MethodAPIKey    = "api_key"
+	MethodJWT       = "jwt"
+	MethodSystem    = "system"
+	MethodAnonymous = "anonymous"
+)
+
+// SystemPrincipal is used by background tasks such as alert evaluation that
+// act on behalf of the service itself.
+var SystemPrincipal = &models.Principal{Subject: "system", Method: MethodSystem, Roles: []string{RoleAdmin}}
+
+// AnonymousPrincipal is attached to every request when authentication is
+// disabled.
+var AnonymousPrincipal = &models.Principal{Subject: "anonymous", Method: MethodAnonymous, Roles: []string{RoleAdmin}}
+
+type principalKey struct{}
+
+func WithPrincipal(ctx context.Context, principal *models.Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, principal)
+}
+
+func PrincipalFromContext(ctx context.Context) (*models.Principal, bool) {
+	principal, ok := ctx.Value(principalKey{}).(*models.Principal)
+	return principal, ok && principal != nil
+}
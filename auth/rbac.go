@@ -0,0 +1,60 @@
+package auth
+
+import (
+	"dataflow/models"
+	"slices"
+)
+
+const (
+	PermissionReadSales  = "read:sales"
+	PermissionWriteSales = "write:sales"
+	PermissionCalculate  = "calculate"
+	PermissionAdmin      = "admin"
+)
+
+const (
+	RoleStoreManager = "store_manager"
+	RoleAnalyst      = "analyst"
+	RoleIntegration  = "integration"
+	RoleAdmin        = "admin"
+)
+
+var RolePermissions = map[string][]string{
+	RoleStoreManager: {PermissionReadSales, PermissionCalculate},
+	RoleAnalyst:      {PermissionReadSales, PermissionCalculate},
+	RoleIntegration:  {PermissionWriteSales},
+	RoleAdmin:        {PermissionReadSales, PermissionWriteSales, PermissionCalculate, PermissionAdmin},
+}
+
+// HasPermission reports whether any of the principal's roles grants the
+// permission. The admin permission implies every other one.
+func HasPermission(principal *models.Principal, permission string) bool {
+	if principal == nil {
+		return false
+	}
+	for _, role := range principal.Roles {
+		permissions := RolePermissions[role]
+		if slices.Contains(permissions, permission) || slices.Contains(permissions, PermissionAdmin) {
+			return true
+		}
+	}
+	return false
+}
+
+// CanAccessStore reports whether the principal's store scope includes the
+// store. Principals without a scope may access every store, except store
+// managers, who only ever see the stores they are scoped to.
+func CanAccessStore(principal *models.Principal, storeId string) bool {
+	if principal == nil {
+		return false
+	}
+	if len(principal.Stores) > 0 {
+		return slices.Contains(principal.Stores, storeId)
+	}
+	return !slices.Contains(principal.Roles, RoleStoreManager) || HasPermission(principal, PermissionAdmin)
+}
+
+// Unscoped reports whether the principal may access every store.
+func Unscoped(principal *models.Principal) bool {
+	return principal != nil && len(principal.Stores) == 0 && CanAccessStore(principal, "")
+}
@@ -0,0 +1,62 @@
+package auth
+
+import (
+	"dataflow/models"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHasPermission(t *testing.T) {
+	manager := &models.Principal{Roles: []string{RoleStoreManager}}
+	integration := &models.Principal{Roles: []string{RoleIntegration}}
+	admin := &models.Principal{Roles: []string{RoleAdmin}}
+
+	assert.True(t, HasPermission(manager, PermissionReadSales))
+	assert.True(t, HasPermission(manager, PermissionCalculate))
+	assert.False(t, HasPermission(manager, PermissionWriteSales))
+	assert.True(t, HasPermission(integration, PermissionWriteSales))
+	assert.False(t, HasPermission(integration, PermissionReadSales))
+	assert.True(t, HasPermission(admin, PermissionWriteSales))
+	assert.False(t, HasPermission(&models.Principal{Roles: []string{"unknown"}}, PermissionReadSales))
+	assert.False(t, HasPermission(nil, PermissionReadSales))
+}
+
+func TestCanAccessStore(t *testing.T) {
+	scoped := &models.Principal{Roles: []string{RoleStoreManager}, Stores: []string{"6789"}}
+	unscopedManager := &models.Principal{Roles: []string{RoleStoreManager}}
+	analyst := &models.Principal{Roles: []string{RoleAnalyst}}
+	scopedAnalyst := &models.Principal{Roles: []string{RoleAnalyst}, Stores: []string{"9876"}}
+
+	assert.True(t, CanAccessStore(scoped, "6789"))
+	assert.False(t, CanAccessStore(scoped, "9876"))
+	assert.False(t, CanAccessStore(unscopedManager, "6789"))
+	assert.True(t, CanAccessStore(analyst, "6789"))
+	assert.False(t, CanAccessStore(scopedAnalyst, "6789"))
+	assert.False(t, CanAccessStore(nil, "6789"))
+
+	assert.True(t, Unscoped(analyst))
+	assert.False(t, Unscoped(scoped))
+	assert.False(t, Unscoped(unscopedManager))
+}
+
+func TestRequire(t *testing.T) {
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		principal := &models.Principal{Subject: c.GetHeader("X-Subject"), Roles: []string{c.GetHeader("X-Role")}}
+		c.Request = c.Request.WithContext(WithPrincipal(c.Request.Context(), principal))
+	})
+	router.GET("/alerts", Require(PermissionAdmin), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	for role, expected := range map[string]int{RoleAdmin: http.StatusOK, RoleAnalyst: http.StatusForbidden} {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/alerts", nil)
+		req.Header.Set("X-Role", role)
+		router.ServeHTTP(w, req)
+		assert.Equal(t, expected, w.Code, role)
+	}
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+	"dataflow/handlers"
+	"dataflow/services"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// runCalc prints the response POST /calculate or GET /anomalies would give
+// for the sales in a file.
+func runCalc(args []string, stdin io.Reader, stdout io.Writer, stderr io.Writer) error {
+	flags, file := newFlagSet("calc", stderr)
+	stores := flags.String("stores", services.AllStores, "comma separated store IDs, or all")
+	operations := flags.String("operations", services.MetricTotalSales, "comma separated operations: total_sales, units_sold, sale_count, average_sale")
+	from := flags.String("from", "", "start of the range, RFC 3339 (a day for -anomalies)")
+	to := flags.String("to", "", "end of the range, RFC 3339 (a day for -anomalies)")
+	anomalies := flags.Bool("anomalies", false, "detect anomalous days instead of calculating")
+	store := flags.String("store", "", "store to detect anomalies for, all stores if empty")
+	configFile := configFlag(flags)
+	if err := parseFlags(flags, file, args); err != nil {
+		return err
+	}
+
+	cfg, err := loadConfig(*configFile, stderr)
+	if err != nil {
+		return err
+	}
+	opened, err := openFileServices(*file, cfg)
+	if err != nil {
+		return err
+	}
+	var result interface{}
+	if *anomalies {
+		fromDay, err := parseCalcDay(*from)
+		if err != nil {
+			return usageError{fmt.Errorf("invalid -from: %w", err)}
+		}
+		toDay, err := parseCalcDay(*to)
+		if err != nil {
+			return usageError{fmt.Errorf("invalid -to: %w", err)}
+		}
+		result, err = services.NewAnomalyService(opened.data, opened.catalog, services.DefaultAnomalyOptions).DetectAnomalies(context.Background(), fromDay, toDay, *store)
+		if err != nil {
+			return err
+		}
+	} else {
+		request, _ := json.Marshal(handlers.CalculateRequest{
+			StoreIds:   splitList(*stores),
+			Operations: splitList(*operations),
+			StartDate:  *from,
+			EndDate:    *to,
+		})
+		data, _, err := opened.handler().CalculateJob(context.Background(), request, func(int) {})
+		if err != nil {
+			return err
+		}
+		result = json.RawMessage(data)
+	}
+
+	encoder := json.NewEncoder(stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(result)
+}
+
+func parseCalcDay(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.DateOnly, value); err == nil {
+		return t, nil
+	}
+	return time.Parse(time.RFC3339, value)
+}
+
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
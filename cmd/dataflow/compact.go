@@ -0,0 +1,25 @@
+package main
+
+import (
+	"dataflow/repo"
+	"fmt"
+	"io"
+)
+
+func runCompact(args []string, stdin io.Reader, stdout io.Writer, stderr io.Writer) error {
+	flags, file := newFlagSet("compact", stderr)
+	if err := parseFlags(flags, file, args); err != nil {
+		return err
+	}
+	scan, err := repo.CompactSalesFile(*file)
+	if err != nil {
+		return err
+	}
+	torn := 0
+	if scan.TornTail {
+		torn = 1
+	}
+	fmt.Fprintf(stdout, "kept %d sales, dropped %d duplicate, %d corrupt and %d incomplete lines\n",
+		len(scan.Sales), len(scan.Duplicates), len(scan.Corrupt), torn)
+	return nil
+}
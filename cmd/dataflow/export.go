@@ -0,0 +1,93 @@
+package main
+
+import (
+	"context"
+	"dataflow/config"
+	"dataflow/handlers"
+	"dataflow/repo"
+	"dataflow/server"
+	"dataflow/services"
+	"encoding/json"
+	"io"
+	"os"
+)
+
+// runExport produces the same output as an export job of the API.
+func runExport(args []string, stdin io.Reader, stdout io.Writer, stderr io.Writer) error {
+	flags, file := newFlagSet("export", stderr)
+	var request handlers.ExportRequest
+	flags.StringVar(&request.Format, "format", handlers.ExportFormatJSON, "output format: json or csv")
+	flags.StringVar(&request.StoreId, "store", "", "only export this store")
+	flags.StringVar(&request.StartDate, "from", "", "only export sales at or after this RFC 3339 time")
+	flags.StringVar(&request.EndDate, "to", "", "only export sales before this RFC 3339 time, or at it too with -range closed")
+	flags.StringVar(&request.Range, "range", repo.RangeHalfOpen, "half_open or closed: whether sales at the -to time are excluded or included")
+	output := flags.String("o", "-", "output file")
+	configFile := configFlag(flags)
+	if err := parseFlags(flags, file, args); err != nil {
+		return err
+	}
+
+	cfg, err := loadConfig(*configFile, stderr)
+	if err != nil {
+		return err
+	}
+	opened, err := openFileServices(*file, cfg)
+	if err != nil {
+		return err
+	}
+	body, _ := json.Marshal(request)
+	data, _, err := opened.handler().ExportJob(context.Background(), body, func(int) {})
+	if err != nil {
+		return err
+	}
+	if *output == "-" {
+		_, err = stdout.Write(data)
+		return err
+	}
+	return os.WriteFile(*output, data, 0o600)
+}
+
+// fileServices serve a sales file through the same services as the API,
+// without authorization.
+type fileServices struct {
+	data     services.DataService
+	catalog  services.CatalogService
+	calendar *services.Calendar
+}
+
+// openFileServices loads the sales of a file into memory, along with the
+// catalog kept next to it and the fiscal calendar of cfg. The files aren't
+// kept open, so this is safe next to a running server.
+func openFileServices(path string, cfg *config.Config) (*fileServices, error) {
+	scan, err := repo.ScanSalesFile(path)
+	if err != nil {
+		return nil, err
+	}
+	repository := repo.NewInMemoryRepository()
+	for _, sale := range scan.Sales {
+		repository.Restore(sale)
+	}
+	repositoryConfig := cfg.Repository
+	repositoryConfig.File = path
+	stores, products, err := server.NewCatalogRepositories(repositoryConfig)
+	if err != nil {
+		return nil, err
+	}
+	catalog := services.NewCatalogService(stores, products, repository)
+	calendar, err := server.NewCalendar(cfg.Fiscal)
+	if err != nil {
+		return nil, err
+	}
+	// Nothing is added to a file opened this way, so the audit log stays
+	// empty.
+	auditLog := services.NewAuditLog(repo.NewInMemoryAuditRepository())
+	return &fileServices{
+		data:     server.NewDataService(services.NewDataService(repository), catalog, calendar, auditLog, cfg.Catalog),
+		catalog:  catalog,
+		calendar: calendar,
+	}, nil
+}
+
+func (fs *fileServices) handler() *handlers.DataHandler {
+	return handlers.NewDataHandler(fs.data, fs.catalog, fs.calendar)
+}
@@ -0,0 +1,264 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"dataflow/models"
+	"dataflow/repo"
+	"dataflow/server"
+	"dataflow/services"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	importFormatCSV    = "csv"
+	importFormatNDJSON = "ndjson"
+	importFormatJSON   = "json"
+)
+
+// csvColumns are the columns an import CSV must have, in any order. An id
+// column is allowed and ignored, like the ID of a sale posted to /data.
+var csvColumns = []string{"product_id", "store_id", "quantity_sold", "sale_price", "sale_date"}
+
+// runImport reads every input and runs every sale through the catalog checks
+// and audit log of the server before writing anything, so that a bad row
+// doesn't leave a partial import behind. The sales are then appended with a
+// single sync.
+func runImport(args []string, stdin io.Reader, stdout io.Writer, stderr io.Writer) error {
+	flags, file := newFlagSet("import", stderr)
+	format := flags.String("format", "", "input format: csv, ndjson or json (default from the file extension, ndjson for stdin)")
+	configFile := configFlag(flags)
+	if err := parseFlags(flags, file, args); err != nil {
+		return err
+	}
+	inputs := flags.Args()
+	if len(inputs) == 0 {
+		inputs = []string{"-"}
+	}
+	cfg, err := loadConfig(*configFile, stderr)
+	if err != nil {
+		return err
+	}
+
+	var sales []*models.Sale
+	for _, input := range inputs {
+		inputFormat := *format
+		if inputFormat == "" {
+			inputFormat = formatFromExtension(input)
+		}
+		parsed, err := readSalesInput(input, inputFormat, stdin)
+		if err != nil {
+			return err
+		}
+		sales = append(sales, parsed...)
+	}
+
+	repository, err := repo.NewFileRepository(*file)
+	if err != nil {
+		return err
+	}
+	defer repository.Close()
+	repositoryConfig := cfg.Repository
+	repositoryConfig.File = *file
+	stores, products, err := server.NewCatalogRepositories(repositoryConfig)
+	if err != nil {
+		return err
+	}
+	catalog := services.NewCatalogService(stores, products, repository)
+	calendar, err := server.NewCalendar(cfg.Fiscal)
+	if err != nil {
+		return err
+	}
+	auditRepository, err := server.NewAuditRepository(cfg.Audit)
+	if err != nil {
+		return fmt.Errorf("couldn't open audit log: %w", err)
+	}
+	if closer, ok := auditRepository.(io.Closer); ok {
+		defer closer.Close()
+	}
+	auditLog := services.NewAuditLog(auditRepository)
+
+	ctx := context.Background()
+	staged := &stagingRepository{Repository: repository}
+	service := server.NewDataService(services.NewDataService(staged), catalog, calendar, auditLog, cfg.Catalog)
+	for i, sale := range sales {
+		if err := service.AddSale(ctx, sale); err != nil {
+			abandonImport(ctx, auditLog, staged.sales)
+			return fmt.Errorf("sale %d of %d: %w", i+1, len(sales), err)
+		}
+	}
+	if err := repository.AddSales(ctx, staged.sales); err != nil {
+		abandonImport(ctx, auditLog, staged.sales)
+		return err
+	}
+	if err := repository.Close(); err != nil {
+		return err
+	}
+	fmt.Fprintf(stdout, "imported %d sales into %s\n", len(sales), *file)
+	return nil
+}
+
+// stagingRepository collects the sales that passed the services of an
+// import, so that they can be written at once.
+type stagingRepository struct {
+	repo.Repository
+	sales []*models.Sale
+}
+
+func (sr *stagingRepository) AddSale(ctx context.Context, sale *models.Sale) error {
+	sr.sales = append(sr.sales, sale)
+	return nil
+}
+
+// abandonImport follows the audit entries of sales that were staged but won't
+// be written with failure entries, as the auditing service does for a sale
+// that couldn't be stored.
+func abandonImport(ctx context.Context, auditLog *services.AuditLog, sales []*models.Sale) {
+	for _, sale := range sales {
+		if _, err := auditLog.Record(ctx, models.AuditActionAddSaleFailed, sale.StoreId, sale, nil); err != nil {
+			slog.ErrorContext(ctx, "couldn't record failed sale in the audit log", slog.String("store_id", sale.StoreId), slog.Any("error", err))
+		}
+	}
+}
+
+func formatFromExtension(path string) string {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".csv":
+		return importFormatCSV
+	case ".json":
+		return importFormatJSON
+	default:
+		return importFormatNDJSON
+	}
+}
+
+func readSalesInput(path string, format string, stdin io.Reader) ([]*models.Sale, error) {
+	reader := stdin
+	if path != "-" {
+		file, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		defer file.Close()
+		reader = file
+	}
+
+	var sales []*models.Sale
+	var err error
+	switch format {
+	case importFormatCSV:
+		sales, err = readCSVSales(reader)
+	case importFormatNDJSON:
+		sales, err = readNDJSONSales(reader)
+	case importFormatJSON:
+		err = json.NewDecoder(reader).Decode(&sales)
+		if err == nil {
+			for i, sale := range sales {
+				if err = validateSale(sale); err != nil {
+					err = fmt.Errorf("sale %d: %w", i+1, err)
+					break
+				}
+			}
+		}
+	default:
+		return nil, usageError{fmt.Errorf("unsupported format %q", format)}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return sales, nil
+}
+
+func readNDJSONSales(reader io.Reader) ([]*models.Sale, error) {
+	var sales []*models.Sale
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if strings.TrimSpace(scanner.Text()) == "" {
+			continue
+		}
+		var sale models.Sale
+		if err := json.Unmarshal(scanner.Bytes(), &sale); err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		if err := validateSale(&sale); err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		sales = append(sales, &sale)
+	}
+	return sales, scanner.Err()
+}
+
+func readCSVSales(reader io.Reader) ([]*models.Sale, error) {
+	records := csv.NewReader(reader)
+	header, err := records.Read()
+	if err != nil {
+		return nil, fmt.Errorf("couldn't read header: %w", err)
+	}
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		columns[strings.TrimSpace(name)] = i
+	}
+	for _, name := range csvColumns {
+		if _, ok := columns[name]; !ok {
+			return nil, fmt.Errorf("missing column %q", name)
+		}
+	}
+
+	var sales []*models.Sale
+	for line := 2; ; line++ {
+		record, err := records.Read()
+		if errors.Is(err, io.EOF) {
+			return sales, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		sale, err := parseCSVSale(record, columns)
+		if err == nil {
+			err = validateSale(sale)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		sales = append(sales, sale)
+	}
+}
+
+func parseCSVSale(record []string, columns map[string]int) (*models.Sale, error) {
+	sale := &models.Sale{
+		ProductId: record[columns["product_id"]],
+		StoreId:   record[columns["store_id"]],
+	}
+	var err error
+	if sale.QuantitySold, err = strconv.Atoi(record[columns["quantity_sold"]]); err != nil {
+		return nil, fmt.Errorf("invalid quantity_sold %q", record[columns["quantity_sold"]])
+	}
+	if sale.SalePrice, err = strconv.ParseFloat(record[columns["sale_price"]], 64); err != nil {
+		return nil, fmt.Errorf("invalid sale_price %q", record[columns["sale_price"]])
+	}
+	if sale.SaleDate, err = time.Parse(time.RFC3339, record[columns["sale_date"]]); err != nil {
+		return nil, fmt.Errorf("invalid sale_date %q", record[columns["sale_date"]])
+	}
+	return sale, nil
+}
+
+func validateSale(sale *models.Sale) error {
+	if sale.StoreId == "" {
+		return errors.New("store_id is required")
+	}
+	if sale.SaleDate.IsZero() {
+		return errors.New("sale_date is required")
+	}
+	return nil
+}
@@ -0,0 +1,139 @@
+// Command dataflow runs the API server and works with sales files offline,
+// using the repo and services packages directly instead of going over HTTP.
+//
+//	dataflow serve [flags]
+//	dataflow import -file sales.ndjson [-config file] [-format csv|ndjson|json] [input ...]
+//	dataflow export -file sales.ndjson [-config file] [-format json|csv] [-store id] [-from time] [-to time] [-o output]
+//	dataflow calc -file sales.ndjson [-config file] [-stores ids] [-operations names] [-from time] [-to time]
+//	dataflow calc -file sales.ndjson [-config file] -anomalies [-store id] [-from day] [-to day]
+//	dataflow verify -file sales.ndjson [-audit audit.ndjson]
+//	dataflow compact -file sales.ndjson
+//
+// -file defaults to $DATAFLOW_REPOSITORY_FILE. -config names the server
+// configuration, whose catalog validation, fiscal calendar and audit log the
+// commands share with the server; it defaults to $DATAFLOW_CONFIG. Store time
+// zones come from the catalog next to the sales file. The offline commands
+// must not write to a file that a running server has open.
+package main
+
+import (
+	"dataflow/config"
+	"dataflow/server"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+)
+
+type command struct {
+	name    string
+	summary string
+	run     func(args []string, stdin io.Reader, stdout io.Writer, stderr io.Writer) error
+}
+
+var commands = []command{
+	{"import", "add sales from CSV, NDJSON or JSON files to a sales file", runImport},
+	{"export", "write the sales of a sales file as JSON or CSV", runExport},
+	{"calc", "run a calculation or anomaly detection against a sales file", runCalc},
+	{"verify", "check a sales file and optionally an audit log for damage", runVerify},
+	{"compact", "rewrite a sales file without duplicate or damaged lines", runCompact},
+}
+
+// usageError makes the command exit with status 2.
+type usageError struct {
+	error
+}
+
+// errFailedCheck is returned by verify when it found damage; the details have
+// already been printed.
+var errFailedCheck = errors.New("verification failed")
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+func run(args []string, stdin io.Reader, stdout io.Writer, stderr io.Writer) int {
+	if len(args) == 0 {
+		usage(stderr)
+		return 2
+	}
+	if args[0] == "serve" {
+		return server.Main(args[1:])
+	}
+	for _, command := range commands {
+		if command.name != args[0] {
+			continue
+		}
+		err := command.run(args[1:], stdin, stdout, stderr)
+		var usageErr usageError
+		switch {
+		case err == nil, errors.Is(err, flag.ErrHelp):
+			return 0
+		case errors.Is(err, errFailedCheck):
+			return 1
+		case errors.As(err, &usageErr):
+			fmt.Fprintf(stderr, "dataflow %s: %v\n", command.name, err)
+			return 2
+		default:
+			fmt.Fprintf(stderr, "dataflow %s: %v\n", command.name, err)
+			return 1
+		}
+	}
+	usage(stderr)
+	return 2
+}
+
+func usage(w io.Writer) {
+	fmt.Fprintln(w, "usage: dataflow <command> [flags]")
+	fmt.Fprintln(w)
+	fmt.Fprintf(w, "  %-8s %s\n", "serve", "run the API server")
+	for _, command := range commands {
+		fmt.Fprintf(w, "  %-8s %s\n", command.name, command.summary)
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, `Run "dataflow <command> -h" for the flags of a command.`)
+}
+
+// newFlagSet adds the -file flag every offline command needs.
+func newFlagSet(name string, output io.Writer) (*flag.FlagSet, *string) {
+	flags := flag.NewFlagSet("dataflow "+name, flag.ContinueOnError)
+	flags.SetOutput(output)
+	file := flags.String("file", os.Getenv("DATAFLOW_REPOSITORY_FILE"), "sales file ($DATAFLOW_REPOSITORY_FILE)")
+	return flags, file
+}
+
+// configFlag adds the -config flag of the commands that check sales against
+// the catalog, use the fiscal calendar or write to the audit log.
+func configFlag(flags *flag.FlagSet) *string {
+	return flags.String("config", "", "server configuration file for the catalog validation, fiscal calendar and audit log (default $"+config.EnvConfigFile+")")
+}
+
+// loadConfig loads the server configuration from path, or the file named by
+// $DATAFLOW_CONFIG when path is empty, and the environment.
+func loadConfig(path string, stderr io.Writer) (*config.Config, error) {
+	// The offline commands serve no requests, so they need no credentials.
+	args := []string{"-auth-disabled"}
+	if path != "" {
+		args = append(args, "-config", path)
+	}
+	cfg, _, err := config.Load(args, os.Getenv, stderr)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't load configuration: %w", err)
+	}
+	return cfg, nil
+}
+
+// parseFlags parses args and requires a sales file.
+func parseFlags(flags *flag.FlagSet, file *string, args []string) error {
+	if err := flags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return err
+		}
+		return usageError{err}
+	}
+	if *file == "" {
+		return usageError{errors.New("-file is required")}
+	}
+	return nil
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func runCommand(t *testing.T, stdin string, args ...string) (int, string, string) {
+	var stdout, stderr bytes.Buffer
+	code := run(args, strings.NewReader(stdin), &stdout, &stderr)
+	return code, stdout.String(), stderr.String()
+}
+
+func TestImportExportCalc(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "sales.ndjson")
+	input := filepath.Join(dir, "input.csv")
+	assert.NoError(t, os.WriteFile(input, []byte(
+		"store_id,product_id,quantity_sold,sale_price,sale_date\n"+
+			"6789,12345,2,10.5,2024-06-15T12:00:00Z\n"+
+			"9876,54321,1,4,2024-06-16T12:00:00Z\n"), 0o600))
+
+	code, stdout, stderr := runCommand(t, "", "import", "-file", file, input)
+	assert.Equal(t, 0, code, stderr)
+	assert.Contains(t, stdout, "imported 2 sales")
+
+	code, stdout, stderr = runCommand(t, `{"store_id":"6789","product_id":"1","quantity_sold":1,"sale_price":1,"sale_date":"2024-06-17T12:00:00Z"}`+"\n",
+		"import", "-file", file)
+	assert.Equal(t, 0, code, stderr)
+	assert.Contains(t, stdout, "imported 1 sales")
+
+	code, stdout, _ = runCommand(t, "", "export", "-file", file, "-format", "csv", "-store", "6789")
+	assert.Equal(t, 0, code)
+	assert.Equal(t, 3, strings.Count(stdout, "\n"))
+
+	code, stdout, stderr = runCommand(t, "", "calc", "-file", file, "-operations", "total_sales,sale_count")
+	assert.Equal(t, 0, code, stderr)
+	var response struct {
+		Results map[string]map[string]struct {
+			Value json.Number `json:"value"`
+		} `json:"results"`
+	}
+	assert.NoError(t, json.Unmarshal([]byte(stdout), &response))
+	assert.Equal(t, json.Number("22"), response.Results["6789"]["total_sales"].Value)
+	assert.Equal(t, json.Number("2"), response.Results["6789"]["sale_count"].Value)
+	assert.Equal(t, json.Number("4"), response.Results["9876"]["total_sales"].Value)
+
+	code, stdout, _ = runCommand(t, "", "verify", "-file", file)
+	assert.Equal(t, 0, code)
+	assert.Contains(t, stdout, "3 sales")
+}
+
+func TestCalc_StoreTimeZone(t *testing.T) {
+	t.Setenv("DATAFLOW_CONFIG", "")
+	dir := t.TempDir()
+	file := filepath.Join(dir, "sales.ndjson")
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "sales.stores.json"), []byte(`[{"id":"6789","name":"Shibuya","timezone":"Asia/Tokyo","active":true}]`), 0o600))
+	// 21:00 on June 15th and 01:00 on June 16th in Tokyo.
+	assert.NoError(t, os.WriteFile(file, []byte(
+		`{"id":"a","store_id":"6789","quantity_sold":1,"sale_price":5,"sale_date":"2024-06-15T12:00:00Z"}`+"\n"+
+			`{"id":"b","store_id":"6789","quantity_sold":1,"sale_price":7,"sale_date":"2024-06-15T16:00:00Z"}`+"\n"), 0o600))
+
+	code, stdout, stderr := runCommand(t, "", "calc", "-file", file, "-stores", "6789", "-from", "2024-06-15", "-to", "2024-06-15")
+	assert.Equal(t, 0, code, stderr)
+	var response struct {
+		Results map[string]map[string]struct {
+			Value json.Number `json:"value"`
+		} `json:"results"`
+	}
+	assert.NoError(t, json.Unmarshal([]byte(stdout), &response))
+	assert.Equal(t, json.Number("5"), response.Results["6789"]["total_sales"].Value)
+
+	code, stdout, stderr = runCommand(t, "", "export", "-file", file, "-format", "csv", "-store", "6789", "-from", "2024-06-16", "-to", "2024-06-16")
+	assert.Equal(t, 0, code, stderr)
+	assert.Equal(t, "id,product_id,store_id,quantity_sold,sale_price,sale_date\nb,,6789,1,7,2024-06-15T16:00:00Z\n", stdout)
+}
+
+func TestImport_InvalidRowWritesNothing(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "sales.ndjson")
+
+	code, _, stderr := runCommand(t, "{\"store_id\":\"6789\",\"sale_date\":\"2024-06-15T12:00:00Z\"}\n{\"product_id\":\"1\"}\n",
+		"import", "-file", file, "-format", "ndjson")
+
+	assert.Equal(t, 1, code)
+	assert.Contains(t, stderr, "line 2: store_id is required")
+	_, err := os.Stat(file)
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestImport_CatalogAndAudit(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "sales.ndjson")
+	audit := filepath.Join(dir, "audit.ndjson")
+	configFile := filepath.Join(dir, "dataflow.yaml")
+	assert.NoError(t, os.WriteFile(configFile, []byte("catalog:\n  validation: strict\naudit:\n  file: "+audit+"\n"), 0o600))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "sales.stores.json"), []byte(`[{"id":"6789","name":"Paris","active":true}]`), 0o600))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "sales.products.json"), []byte(`[{"id":"12345","name":"Umbrella","active":true}]`), 0o600))
+	known := `{"store_id":"6789","product_id":"12345","quantity_sold":1,"sale_price":1,"sale_date":"2024-06-17T12:00:00Z"}` + "\n"
+
+	code, _, stderr := runCommand(t, known+`{"store_id":"9876","product_id":"12345","sale_date":"2024-06-17T12:00:00Z"}`+"\n",
+		"import", "-file", file, "-config", configFile)
+	assert.Equal(t, 1, code)
+	assert.Contains(t, stderr, `sale 2 of 2: unknown reference: store "9876" isn't in the catalog`)
+	code, stdout, _ := runCommand(t, "", "verify", "-file", file, "-audit", audit)
+	assert.Equal(t, 0, code)
+	assert.Contains(t, stdout, "0 sales")
+	assert.Contains(t, stdout, "4 entries")
+
+	code, stdout, stderr = runCommand(t, known+known, "import", "-file", file, "-config", configFile)
+	assert.Equal(t, 0, code, stderr)
+	assert.Contains(t, stdout, "imported 2 sales")
+	code, stdout, _ = runCommand(t, "", "verify", "-file", file, "-audit", audit)
+	assert.Equal(t, 0, code)
+	assert.Contains(t, stdout, "2 sales")
+	assert.Contains(t, stdout, "6 entries")
+}
+
+func TestVerifyAndCompact(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "sales.ndjson")
+	assert.NoError(t, os.WriteFile(file, []byte("{\"id\":\"a\",\"store_id\":\"6789\"}\ngarbage\n{\"id\":\"a\",\"store_id\":\"6789\"}\n"), 0o600))
+
+	code, stdout, _ := runCommand(t, "", "verify", "-file", file)
+	assert.Equal(t, 1, code)
+	assert.Contains(t, stdout, "corrupt lines: [2]")
+	assert.Contains(t, stdout, "duplicate sale IDs on lines: [3]")
+
+	code, stdout, _ = runCommand(t, "", "compact", "-file", file)
+	assert.Equal(t, 0, code)
+	assert.Contains(t, stdout, "kept 1 sales, dropped 1 duplicate, 1 corrupt")
+
+	code, _, _ = runCommand(t, "", "verify", "-file", file)
+	assert.Equal(t, 0, code)
+}
+
+func TestUsage(t *testing.T) {
+	t.Setenv("DATAFLOW_REPOSITORY_FILE", "")
+	code, _, stderr := runCommand(t, "", "frobnicate")
+	assert.Equal(t, 2, code)
+	assert.Contains(t, stderr, "usage: dataflow <command>")
+
+	code, _, stderr = runCommand(t, "", "export")
+	assert.Equal(t, 2, code)
+	assert.Contains(t, stderr, "-file is required")
+}
@@ -0,0 +1,305 @@
+// Package config loads the server configuration from defaults, a YAML or TOML
+// file, DATAFLOW_* environment variables and command-line flags, in that
+// order of precedence, and validates it before the server starts.
+package config
+
+import (
+	"dataflow/logging"
+	"dataflow/ratelimit"
+	"dataflow/tracing"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+)
+
+const (
+	RepositoryMemory = "memory"
+	RepositoryFile   = "file"
+)
+
+const (
+	CatalogLenient = "lenient"
+	CatalogStrict  = "strict"
+)
+
+const (
+	FiscalGregorian = "gregorian"
+	Fiscal445       = "4-4-5"
+	Fiscal454       = "4-5-4"
+	Fiscal544       = "5-4-4"
+)
+
+const (
+	FiscalYearNamedByStart = "start"
+	FiscalYearNamedByEnd   = "end"
+)
+
+type Config struct {
+	Server     ServerConfig     `yaml:"server" toml:"server"`
+	Repository RepositoryConfig `yaml:"repository" toml:"repository"`
+	Log        LogConfig        `yaml:"log" toml:"log"`
+	Tracing    TracingConfig    `yaml:"tracing" toml:"tracing"`
+	Auth       AuthConfig       `yaml:"auth" toml:"auth"`
+	RateLimit  RateLimitConfig  `yaml:"rate_limit" toml:"rate_limit"`
+	Audit      AuditConfig      `yaml:"audit" toml:"audit"`
+	Alerts     AlertsConfig     `yaml:"alerts" toml:"alerts"`
+	Jobs       JobsConfig       `yaml:"jobs" toml:"jobs"`
+	GraphQL    GraphQLConfig    `yaml:"graphql" toml:"graphql"`
+	Catalog    CatalogConfig    `yaml:"catalog" toml:"catalog"`
+	Fiscal     FiscalConfig     `yaml:"fiscal" toml:"fiscal"`
+}
+
+type ServerConfig struct {
+	Addr string `yaml:"addr" toml:"addr" env:"DATAFLOW_ADDR" flag:"addr" usage:"listen address"`
+	// GRPCAddr is the listen address of the gRPC API, which is disabled when
+	// it is empty.
+	GRPCAddr          string   `yaml:"grpc_addr" toml:"grpc_addr" env:"DATAFLOW_GRPC_ADDR" flag:"grpc-addr" usage:"gRPC listen address, empty to disable the gRPC API"`
+	ReadHeaderTimeout Duration `yaml:"read_header_timeout" toml:"read_header_timeout" env:"DATAFLOW_READ_HEADER_TIMEOUT" flag:"read-header-timeout" usage:"time allowed to read request headers"`
+	ReadTimeout       Duration `yaml:"read_timeout" toml:"read_timeout" env:"DATAFLOW_READ_TIMEOUT" flag:"read-timeout" usage:"time allowed to read a whole request"`
+	// WriteTimeout is zero by default because it would cut off the sales
+	// stream and the live aggregates.
+	WriteTimeout Duration `yaml:"write_timeout" toml:"write_timeout" env:"DATAFLOW_WRITE_TIMEOUT" flag:"write-timeout" usage:"time allowed to write a response, 0 for none"`
+	IdleTimeout  Duration `yaml:"idle_timeout" toml:"idle_timeout" env:"DATAFLOW_IDLE_TIMEOUT" flag:"idle-timeout" usage:"keep-alive timeout"`
+	// ShutdownTimeout bounds how long in-flight requests and jobs may take to
+	// finish after SIGTERM before connections are closed.
+	ShutdownTimeout Duration `yaml:"shutdown_timeout" toml:"shutdown_timeout" env:"DATAFLOW_SHUTDOWN_TIMEOUT" flag:"shutdown-timeout" usage:"time allowed to drain requests on shutdown"`
+	// ValidateResponses checks responses against the OpenAPI document too,
+	// which buffers them; requests are always checked.
+	ValidateResponses bool `yaml:"validate_responses" toml:"validate_responses" env:"DATAFLOW_VALIDATE_RESPONSES" flag:"validate-responses" usage:"answer 500 instead of sending responses that don't match the OpenAPI document"`
+}
+
+type RepositoryConfig struct {
+	Backend string `yaml:"backend" toml:"backend" env:"DATAFLOW_REPOSITORY" flag:"repository" usage:"sales repository backend: memory or file"`
+	// File is the newline-delimited JSON file of the file backend.
+	File string `yaml:"file" toml:"file" env:"DATAFLOW_REPOSITORY_FILE" flag:"repository-file" usage:"sales file of the file backend"`
+}
+
+type LogConfig struct {
+	Level  string `yaml:"level" toml:"level" env:"DATAFLOW_LOG_LEVEL" flag:"log-level" usage:"debug, info, warn or error"`
+	Format string `yaml:"format" toml:"format" env:"DATAFLOW_LOG_FORMAT" flag:"log-format" usage:"json or text"`
+}
+
+type TracingConfig struct {
+	Exporter string `yaml:"exporter" toml:"exporter" env:"DATAFLOW_TRACES_EXPORTER" flag:"traces-exporter" usage:"none, otlp, stdout or file"`
+	Endpoint string `yaml:"endpoint" toml:"endpoint" env:"DATAFLOW_TRACES_ENDPOINT" flag:"traces-endpoint" usage:"OTLP/HTTP endpoint (host:port)"`
+	// Headers are sent with every OTLP export and typically carry an API
+	// token, so they are redacted when the configuration is printed.
+	Headers map[string]string `yaml:"headers" toml:"headers" env:"DATAFLOW_TRACES_HEADERS" flag:"traces-headers" usage:"OTLP headers as key=value,..." secret:"true"`
+	File    string            `yaml:"file" toml:"file" env:"DATAFLOW_TRACES_FILE" flag:"traces-file" usage:"file the file exporter appends spans to"`
+}
+
+type AuthConfig struct {
+	Disabled    bool   `yaml:"disabled" toml:"disabled" env:"DATAFLOW_AUTH_DISABLED" flag:"auth-disabled" usage:"run without authentication"`
+	APIKeysFile string `yaml:"api_keys_file" toml:"api_keys_file" env:"DATAFLOW_API_KEYS_FILE" flag:"api-keys-file" usage:"JSON file of hashed API keys"`
+	JWKSFile    string `yaml:"jwks_file" toml:"jwks_file" env:"DATAFLOW_JWKS_FILE" flag:"jwks-file" usage:"JWKS used to verify bearer tokens"`
+	JWTIssuer   string `yaml:"jwt_issuer" toml:"jwt_issuer" env:"DATAFLOW_JWT_ISSUER" flag:"jwt-issuer" usage:"expected iss claim"`
+	JWTAudience string `yaml:"jwt_audience" toml:"jwt_audience" env:"DATAFLOW_JWT_AUDIENCE" flag:"jwt-audience" usage:"expected aud claim"`
+}
+
+type RateLimitConfig struct {
+	Ingest     string `yaml:"ingest" toml:"ingest" env:"DATAFLOW_RATE_LIMIT_INGEST" flag:"rate-limit-ingest" usage:"POST /data limit per client, e.g. 10/s,20"`
+	Query      string `yaml:"query" toml:"query" env:"DATAFLOW_RATE_LIMIT_QUERY" flag:"rate-limit-query" usage:"limit per client for other routes, e.g. 50/s,100"`
+	DailyQuota int64  `yaml:"daily_quota" toml:"daily_quota" env:"DATAFLOW_DAILY_QUOTA" flag:"daily-quota" usage:"requests per client and UTC day, 0 for none"`
+}
+
+type AuditConfig struct {
+	File string `yaml:"file" toml:"file" env:"DATAFLOW_AUDIT_LOG_FILE" flag:"audit-log-file" usage:"append the audit log to this file instead of keeping it in memory"`
+}
+
+type AlertsConfig struct {
+	Enabled            bool     `yaml:"enabled" toml:"enabled" env:"DATAFLOW_ALERTS_ENABLED" flag:"alerts-enabled" usage:"evaluate alert rules"`
+	EvaluationInterval Duration `yaml:"evaluation_interval" toml:"evaluation_interval" env:"DATAFLOW_ALERTS_INTERVAL" flag:"alerts-interval" usage:"time between alert evaluations"`
+}
+
+type JobsConfig struct {
+	Workers   int      `yaml:"workers" toml:"workers" env:"DATAFLOW_JOB_WORKERS" flag:"job-workers" usage:"number of concurrent background jobs"`
+	QueueSize int      `yaml:"queue_size" toml:"queue_size" env:"DATAFLOW_JOB_QUEUE_SIZE" flag:"job-queue-size" usage:"jobs waiting for a worker before submissions are rejected"`
+	TTL       Duration `yaml:"ttl" toml:"ttl" env:"DATAFLOW_JOB_TTL" flag:"job-ttl" usage:"how long finished jobs are kept"`
+}
+
+type GraphQLConfig struct {
+	MaxDepth      int `yaml:"max_depth" toml:"max_depth" env:"DATAFLOW_GRAPHQL_MAX_DEPTH" flag:"graphql-max-depth" usage:"deepest selection nesting a GraphQL query may have, 0 for no limit"`
+	MaxComplexity int `yaml:"max_complexity" toml:"max_complexity" env:"DATAFLOW_GRAPHQL_MAX_COMPLEXITY" flag:"graphql-max-complexity" usage:"most fields a GraphQL query may resolve, 0 for no limit"`
+}
+
+type CatalogConfig struct {
+	// Validation is strict to reject sales whose store or product isn't in
+	// the catalog, or lenient to add them with a warning.
+	Validation string `yaml:"validation" toml:"validation" env:"DATAFLOW_CATALOG_VALIDATION" flag:"catalog-validation" usage:"lenient or strict checking of sale store and product IDs against the catalog"`
+}
+
+type FiscalConfig struct {
+	Calendar   string `yaml:"calendar" toml:"calendar" env:"DATAFLOW_FISCAL_CALENDAR" flag:"fiscal-calendar" usage:"fiscal calendar: gregorian, 4-4-5, 4-5-4 or 5-4-4"`
+	StartMonth int    `yaml:"start_month" toml:"start_month" env:"DATAFLOW_FISCAL_START_MONTH" flag:"fiscal-start-month" usage:"month fiscal years start in, 1 to 12"`
+	// WeekStart is the day retail calendar weeks start on. Their years start
+	// on the WeekStart nearest to the first of StartMonth.
+	WeekStart Weekday `yaml:"week_start" toml:"week_start" env:"DATAFLOW_FISCAL_WEEK_START" flag:"fiscal-week-start" usage:"day retail calendar weeks start on, such as sunday"`
+	// YearNamedBy is start or end: fiscal years are named after the calendar
+	// year they start or end in.
+	YearNamedBy string `yaml:"year_named_by" toml:"year_named_by" env:"DATAFLOW_FISCAL_YEAR_NAMED_BY" flag:"fiscal-year-named-by" usage:"start or end, the calendar year fiscal years are named after"`
+}
+
+// Default returns the configuration used for everything that isn't set
+// explicitly.
+func Default() Config {
+	return Config{
+		Server: ServerConfig{
+			Addr:              ":8080",
+			GRPCAddr:          ":9090",
+			ReadHeaderTimeout: Duration(10 * time.Second),
+			ReadTimeout:       Duration(30 * time.Second),
+			IdleTimeout:       Duration(2 * time.Minute),
+			ShutdownTimeout:   Duration(30 * time.Second),
+		},
+		Repository: RepositoryConfig{Backend: RepositoryMemory},
+		Log:        LogConfig{Level: "info", Format: logging.FormatJSON},
+		Tracing:    TracingConfig{Exporter: tracing.ExporterNone},
+		RateLimit:  RateLimitConfig{Ingest: "10/s,20", Query: "50/s,100"},
+		Alerts:     AlertsConfig{Enabled: true, EvaluationInterval: Duration(time.Minute)},
+		Jobs:       JobsConfig{Workers: 4, QueueSize: 100, TTL: Duration(time.Hour)},
+		GraphQL:    GraphQLConfig{MaxDepth: 10, MaxComplexity: 50000},
+		Catalog:    CatalogConfig{Validation: CatalogLenient},
+		Fiscal:     FiscalConfig{Calendar: FiscalGregorian, StartMonth: 1, WeekStart: Weekday(time.Sunday), YearNamedBy: FiscalYearNamedByStart},
+	}
+}
+
+// Validate reports every invalid value at once.
+func (c *Config) Validate() error {
+	var errs []error
+	invalid := func(format string, args ...interface{}) {
+		errs = append(errs, fmt.Errorf(format, args...))
+	}
+
+	if c.Server.Addr == "" {
+		invalid("server.addr is required")
+	}
+	if c.Server.ReadHeaderTimeout < 0 || c.Server.ReadTimeout < 0 || c.Server.WriteTimeout < 0 || c.Server.IdleTimeout < 0 {
+		invalid("server timeouts must not be negative")
+	}
+	if c.Server.ShutdownTimeout <= 0 {
+		invalid("server.shutdown_timeout must be positive")
+	}
+	switch c.Repository.Backend {
+	case RepositoryMemory:
+	case RepositoryFile:
+		if c.Repository.File == "" {
+			invalid("repository.file is required by the file backend")
+		}
+	default:
+		invalid("repository.backend %q is not supported", c.Repository.Backend)
+	}
+
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(c.Log.Level)); err != nil {
+		invalid("log.level %q must be debug, info, warn or error", c.Log.Level)
+	}
+	switch strings.ToLower(c.Log.Format) {
+	case logging.FormatJSON, logging.FormatText:
+	default:
+		invalid("log.format %q must be json or text", c.Log.Format)
+	}
+
+	switch c.Tracing.Exporter {
+	case "", tracing.ExporterNone, tracing.ExporterOTLP, tracing.ExporterStdout:
+	case tracing.ExporterFile:
+		if c.Tracing.File == "" {
+			invalid("tracing.file is required by the file exporter")
+		}
+	default:
+		invalid("tracing.exporter %q must be none, otlp, stdout or file", c.Tracing.Exporter)
+	}
+
+	if !c.Auth.Disabled && c.Auth.APIKeysFile == "" && c.Auth.JWKSFile == "" {
+		invalid("auth.api_keys_file and/or auth.jwks_file is required unless auth.disabled is set")
+	}
+
+	if _, err := ratelimit.ParseLimit(c.RateLimit.Ingest); err != nil {
+		invalid("rate_limit.ingest: %v", err)
+	}
+	if _, err := ratelimit.ParseLimit(c.RateLimit.Query); err != nil {
+		invalid("rate_limit.query: %v", err)
+	}
+	if c.RateLimit.DailyQuota < 0 {
+		invalid("rate_limit.daily_quota must not be negative")
+	}
+
+	if c.Alerts.Enabled && c.Alerts.EvaluationInterval <= 0 {
+		invalid("alerts.evaluation_interval must be positive")
+	}
+	if c.Jobs.Workers < 1 {
+		invalid("jobs.workers must be at least 1")
+	}
+	if c.Jobs.QueueSize < 1 {
+		invalid("jobs.queue_size must be at least 1")
+	}
+	if c.Jobs.TTL <= 0 {
+		invalid("jobs.ttl must be positive")
+	}
+	if c.GraphQL.MaxDepth < 0 || c.GraphQL.MaxComplexity < 0 {
+		invalid("graphql limits must not be negative")
+	}
+	switch c.Catalog.Validation {
+	case CatalogLenient, CatalogStrict:
+	default:
+		invalid("catalog.validation %q must be lenient or strict", c.Catalog.Validation)
+	}
+	switch c.Fiscal.Calendar {
+	case FiscalGregorian, Fiscal445, Fiscal454, Fiscal544:
+	default:
+		invalid("fiscal.calendar %q must be gregorian, 4-4-5, 4-5-4 or 5-4-4", c.Fiscal.Calendar)
+	}
+	if c.Fiscal.StartMonth < 1 || c.Fiscal.StartMonth > 12 {
+		invalid("fiscal.start_month must be 1 to 12")
+	}
+	switch c.Fiscal.YearNamedBy {
+	case FiscalYearNamedByStart, FiscalYearNamedByEnd:
+	default:
+		invalid("fiscal.year_named_by %q must be start or end", c.Fiscal.YearNamedBy)
+	}
+	return errors.Join(errs...)
+}
+
+// Duration is a time.Duration written as "30s" or "5m" in files, environment
+// variables and flags.
+type Duration time.Duration
+
+func (d Duration) String() string {
+	return time.Duration(d).String()
+}
+
+func (d Duration) MarshalText() ([]byte, error) {
+	return []byte(d.String()), nil
+}
+
+func (d *Duration) UnmarshalText(text []byte) error {
+	parsed, err := time.ParseDuration(string(text))
+	if err != nil {
+		return fmt.Errorf("invalid duration %q", text)
+	}
+	*d = Duration(parsed)
+	return nil
+}
+
+// Weekday is a time.Weekday written as "sunday" or "monday" in files,
+// environment variables and flags.
+type Weekday time.Weekday
+
+func (d Weekday) String() string {
+	return strings.ToLower(time.Weekday(d).String())
+}
+
+func (d Weekday) MarshalText() ([]byte, error) {
+	return []byte(d.String()), nil
+}
+
+func (d *Weekday) UnmarshalText(text []byte) error {
+	for day := time.Sunday; day <= time.Saturday; day++ {
+		if strings.EqualFold(string(text), day.String()) {
+			*d = Weekday(day)
+			return nil
+		}
+	}
+	return fmt.Errorf("invalid weekday %q", text)
+}
@@ -0,0 +1,160 @@
+package config
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func env(values map[string]string) func(string) string {
+	return func(name string) string { return values[name] }
+}
+
+func writeFile(t *testing.T, name string, content string) string {
+	path := filepath.Join(t.TempDir(), name)
+	assert.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
+
+func TestLoad_Defaults(t *testing.T) {
+	config, options, err := Load([]string{"-auth-disabled"}, env(nil), &bytes.Buffer{})
+
+	assert.NoError(t, err)
+	assert.False(t, options.PrintConfig)
+	expected := Default()
+	expected.Auth.Disabled = true
+	assert.Equal(t, &expected, config)
+}
+
+func TestLoad_Precedence(t *testing.T) {
+	path := writeFile(t, "dataflow.yaml", `
+server:
+  addr: ":9000"
+  read_timeout: 5s
+log:
+  level: debug
+  format: text
+auth:
+  disabled: true
+fiscal:
+  calendar: 4-4-5
+  week_start: Monday
+`)
+	config, _, err := Load(
+		[]string{"-config", path, "-log-level", "error", "-fiscal-start-month", "2"},
+		env(map[string]string{"DATAFLOW_LOG_LEVEL": "warn", "DATAFLOW_LOG_FORMAT": "json", "DATAFLOW_JOB_WORKERS": "8"}),
+		&bytes.Buffer{},
+	)
+
+	assert.NoError(t, err)
+	assert.Equal(t, ":9000", config.Server.Addr)
+	assert.Equal(t, Duration(5*time.Second), config.Server.ReadTimeout)
+	assert.Equal(t, "error", config.Log.Level)
+	assert.Equal(t, "json", config.Log.Format)
+	assert.Equal(t, 8, config.Jobs.Workers)
+	assert.Equal(t, FiscalConfig{Calendar: Fiscal445, StartMonth: 2, WeekStart: Weekday(time.Monday), YearNamedBy: FiscalYearNamedByStart}, config.Fiscal)
+	assert.Equal(t, Default().Server.IdleTimeout, config.Server.IdleTimeout)
+}
+
+func TestLoad_TOMLFromEnvironment(t *testing.T) {
+	path := writeFile(t, "dataflow.toml", `
+[auth]
+api_keys_file = "keys.json"
+
+[rate_limit]
+ingest = "5/s,10"
+daily_quota = 1000
+
+[tracing]
+exporter = "otlp"
+headers = { authorization = "Bearer secret" }
+`)
+	config, _, err := Load(nil, env(map[string]string{EnvConfigFile: path}), &bytes.Buffer{})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "keys.json", config.Auth.APIKeysFile)
+	assert.Equal(t, "5/s,10", config.RateLimit.Ingest)
+	assert.Equal(t, int64(1000), config.RateLimit.DailyQuota)
+	assert.Equal(t, map[string]string{"authorization": "Bearer secret"}, config.Tracing.Headers)
+}
+
+func TestLoad_UnknownKey(t *testing.T) {
+	path := writeFile(t, "dataflow.yaml", "server:\n  adr: \":9000\"\n")
+
+	_, _, err := Load([]string{"-config", path}, env(nil), &bytes.Buffer{})
+
+	assert.ErrorContains(t, err, "adr")
+}
+
+func TestLoad_UnsupportedFileType(t *testing.T) {
+	path := writeFile(t, "dataflow.json", "{}")
+
+	_, _, err := Load([]string{"-config", path}, env(nil), &bytes.Buffer{})
+
+	assert.ErrorContains(t, err, ".yaml, .yml or .toml")
+}
+
+func TestLoad_InvalidValues(t *testing.T) {
+	_, _, err := Load([]string{"-auth-disabled", "-job-ttl", "soon"}, env(nil), &bytes.Buffer{})
+	assert.ErrorContains(t, err, "-job-ttl")
+
+	_, _, err = Load([]string{"-auth-disabled"}, env(map[string]string{"DATAFLOW_DAILY_QUOTA": "many"}), &bytes.Buffer{})
+	assert.ErrorContains(t, err, "DATAFLOW_DAILY_QUOTA")
+
+	_, _, err = Load([]string{"-auth-disabled", "-traces-headers", "no-separator"}, env(nil), &bytes.Buffer{})
+	assert.ErrorContains(t, err, "key=value")
+}
+
+func TestValidate(t *testing.T) {
+	config := Default()
+	config.Server.Addr = ""
+	config.Repository.Backend = "cassandra"
+	config.Log.Level = "loud"
+	config.Tracing.Exporter = "file"
+	config.RateLimit.Query = "fast"
+	config.Jobs.Workers = 0
+	config.GraphQL.MaxDepth = -1
+	config.Catalog.Validation = "picky"
+	config.Fiscal.Calendar = "lunar"
+	config.Fiscal.StartMonth = 13
+
+	err := config.Validate()
+
+	assert.ErrorContains(t, err, "server.addr")
+	assert.ErrorContains(t, err, "repository.backend")
+	assert.ErrorContains(t, err, "log.level")
+	assert.ErrorContains(t, err, "tracing.file")
+	assert.ErrorContains(t, err, "auth.api_keys_file")
+	assert.ErrorContains(t, err, "rate_limit.query")
+	assert.ErrorContains(t, err, "jobs.workers")
+	assert.ErrorContains(t, err, "graphql limits")
+	assert.ErrorContains(t, err, "catalog.validation")
+	assert.ErrorContains(t, err, "fiscal.calendar")
+	assert.ErrorContains(t, err, "fiscal.start_month")
+}
+
+func TestRedacted(t *testing.T) {
+	config := Default()
+	config.Tracing.Headers = map[string]string{"authorization": "Bearer secret"}
+
+	redactedConfig := config.Redacted()
+
+	assert.Equal(t, map[string]string{"authorization": redacted}, redactedConfig.Tracing.Headers)
+	assert.Equal(t, "Bearer secret", config.Tracing.Headers["authorization"])
+}
+
+func TestWriteYAML(t *testing.T) {
+	config := Default()
+	config.Tracing.Headers = map[string]string{"authorization": "Bearer secret"}
+	var buf bytes.Buffer
+
+	assert.NoError(t, config.WriteYAML(&buf))
+
+	assert.Contains(t, buf.String(), "addr: :8080")
+	assert.Contains(t, buf.String(), "read_timeout: 30s")
+	assert.Contains(t, buf.String(), "authorization: REDACTED")
+	assert.NotContains(t, buf.String(), "secret")
+}
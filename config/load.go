@@ -0,0 +1,251 @@
+package config
+
+import (
+	"bytes"
+	"encoding"
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/pelletier/go-toml/v2"
+	"gopkg.in/yaml.v3"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// EnvConfigFile names the configuration file when -config isn't given.
+const EnvConfigFile = "DATAFLOW_CONFIG"
+
+const redacted = "REDACTED"
+
+// Options are the flags that control the program rather than the server.
+type Options struct {
+	// PrintConfig asks for the effective configuration to be printed
+	// instead of starting the server.
+	PrintConfig bool
+}
+
+// Load builds the configuration from the defaults, the file named by -config
+// or DATAFLOW_CONFIG, the environment and args, each overriding the one
+// before, and validates the result. Usage and flag errors are written to
+// output.
+func Load(args []string, getenv func(string) string, output io.Writer) (*Config, Options, error) {
+	var options Options
+	flags := flag.NewFlagSet("dataflow", flag.ContinueOnError)
+	flags.SetOutput(output)
+	path := flags.String("config", "", "YAML or TOML configuration file (default $"+EnvConfigFile+")")
+	flags.BoolVar(&options.PrintConfig, "print-config", false, "print the effective configuration with secrets redacted and exit")
+	values := make(map[string]*flagValue)
+	for _, setting := range settings() {
+		values[setting.flag] = &flagValue{isBool: setting.kind == reflect.Bool}
+		flags.Var(values[setting.flag], setting.flag, setting.usage+" ($"+setting.env+")")
+	}
+	if err := flags.Parse(args); err != nil {
+		return nil, options, err
+	}
+	if *path == "" {
+		*path = getenv(EnvConfigFile)
+	}
+
+	config := Default()
+	if *path != "" {
+		if err := loadFile(*path, &config); err != nil {
+			return nil, options, err
+		}
+	}
+	fields := config.fields()
+	for _, setting := range settings() {
+		if value := getenv(setting.env); value != "" {
+			if err := setValue(fields[setting.flag], value); err != nil {
+				return nil, options, fmt.Errorf("%s: %w", setting.env, err)
+			}
+		}
+	}
+	var err error
+	flags.Visit(func(f *flag.Flag) {
+		if field, ok := fields[f.Name]; ok && err == nil {
+			if setErr := setValue(field, values[f.Name].value); setErr != nil {
+				err = fmt.Errorf("-%s: %w", f.Name, setErr)
+			}
+		}
+	})
+	if err != nil {
+		return nil, options, err
+	}
+	if err := config.Validate(); err != nil {
+		return nil, options, fmt.Errorf("invalid configuration: %w", err)
+	}
+	return &config, options, nil
+}
+
+// loadFile decodes a YAML (.yaml, .yml) or TOML (.toml) file into config.
+// Unknown keys are rejected so that a typo doesn't silently fall back to a
+// default.
+func loadFile(path string, config *Config) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("couldn't read config file: %w", err)
+	}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		decoder := yaml.NewDecoder(bytes.NewReader(data))
+		decoder.KnownFields(true)
+		if err := decoder.Decode(config); err != nil && !errors.Is(err, io.EOF) {
+			return fmt.Errorf("couldn't parse config file: %w", err)
+		}
+	case ".toml":
+		decoder := toml.NewDecoder(bytes.NewReader(data))
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(config); err != nil {
+			return fmt.Errorf("couldn't parse config file: %w", err)
+		}
+	default:
+		return fmt.Errorf("config file %q must end in .yaml, .yml or .toml", path)
+	}
+	return nil
+}
+
+// Redacted returns a copy of the configuration that is safe to print or log.
+func (c Config) Redacted() Config {
+	redactedConfig := c
+	fields := redactedConfig.fields()
+	for _, setting := range settings() {
+		if !setting.secret {
+			continue
+		}
+		field := fields[setting.flag]
+		switch field.Kind() {
+		case reflect.Map:
+			if field.Len() == 0 {
+				continue
+			}
+			masked := reflect.MakeMapWithSize(field.Type(), field.Len())
+			for _, key := range field.MapKeys() {
+				masked.SetMapIndex(key, reflect.ValueOf(redacted))
+			}
+			field.Set(masked)
+		case reflect.String:
+			if field.String() != "" {
+				field.SetString(redacted)
+			}
+		}
+	}
+	return redactedConfig
+}
+
+// WriteYAML writes the configuration with secrets redacted.
+func (c Config) WriteYAML(w io.Writer) error {
+	encoder := yaml.NewEncoder(w)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(c.Redacted()); err != nil {
+		return err
+	}
+	return encoder.Close()
+}
+
+// setting is a leaf field of Config that can be set from the environment and
+// the command line, described by its env, flag, usage and secret tags.
+type setting struct {
+	index  []int
+	env    string
+	flag   string
+	usage  string
+	secret bool
+	kind   reflect.Kind
+}
+
+// flagValue holds a flag's text until it is applied on top of the file and
+// the environment. Boolean settings can be given as a bare -flag.
+type flagValue struct {
+	value  string
+	isBool bool
+}
+
+func (v *flagValue) String() string {
+	if v == nil {
+		return ""
+	}
+	return v.value
+}
+
+func (v *flagValue) Set(value string) error {
+	v.value = value
+	return nil
+}
+
+func (v *flagValue) IsBoolFlag() bool {
+	return v.isBool
+}
+
+func settings() []setting {
+	var result []setting
+	configType := reflect.TypeOf(Config{})
+	for i := 0; i < configType.NumField(); i++ {
+		section := configType.Field(i)
+		for j := 0; j < section.Type.NumField(); j++ {
+			field := section.Type.Field(j)
+			if field.Tag.Get("flag") == "" {
+				continue
+			}
+			result = append(result, setting{
+				index:  []int{i, j},
+				env:    field.Tag.Get("env"),
+				flag:   field.Tag.Get("flag"),
+				usage:  field.Tag.Get("usage"),
+				secret: field.Tag.Get("secret") == "true",
+				kind:   field.Type.Kind(),
+			})
+		}
+	}
+	return result
+}
+
+// fields maps flag names to the settable fields of c.
+func (c *Config) fields() map[string]reflect.Value {
+	value := reflect.ValueOf(c).Elem()
+	fields := make(map[string]reflect.Value)
+	for _, setting := range settings() {
+		fields[setting.flag] = value.FieldByIndex(setting.index)
+	}
+	return fields
+}
+
+// setValue parses text into field according to the field's type. Maps are
+// written as "key=value,key=value".
+func setValue(field reflect.Value, text string) error {
+	if unmarshaler, ok := field.Addr().Interface().(encoding.TextUnmarshaler); ok {
+		return unmarshaler.UnmarshalText([]byte(text))
+	}
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(text)
+	case reflect.Bool:
+		parsed, err := strconv.ParseBool(text)
+		if err != nil {
+			return fmt.Errorf("invalid boolean %q", text)
+		}
+		field.SetBool(parsed)
+	case reflect.Int, reflect.Int64:
+		parsed, err := strconv.ParseInt(text, 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid integer %q", text)
+		}
+		field.SetInt(parsed)
+	case reflect.Map:
+		parsed := make(map[string]string)
+		for _, pair := range strings.Split(text, ",") {
+			key, value, ok := strings.Cut(pair, "=")
+			if !ok || strings.TrimSpace(key) == "" {
+				return fmt.Errorf("invalid key=value pair %q", pair)
+			}
+			parsed[strings.TrimSpace(key)] = strings.TrimSpace(value)
+		}
+		field.Set(reflect.ValueOf(parsed))
+	default:
+		return fmt.Errorf("unsupported setting type %s", field.Type())
+	}
+	return nil
+}
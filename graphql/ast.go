@@ -0,0 +1,143 @@
+package graphql
+
+// Document is a parsed GraphQL request document.
+type Document struct {
+	Operations []*Operation
+	Fragments  map[string]*Fragment
+}
+
+// Operation is a query, mutation or subscription. Only queries are executed.
+type Operation struct {
+	Type       string
+	Name       string
+	Variables  []*VariableDefinition
+	Directives []*Directive
+	Selections []Selection
+	Pos        Location
+}
+
+type VariableDefinition struct {
+	Name    string
+	Type    *TypeRef
+	Default *Value
+	Pos     Location
+}
+
+// Selection is a *Field, *FragmentSpread or *InlineFragment.
+type Selection interface {
+	position() Location
+}
+
+type Field struct {
+	Alias      string
+	Name       string
+	Arguments  []*Argument
+	Directives []*Directive
+	Selections []Selection
+	Pos        Location
+}
+
+// ResponseKey is the alias of the field, or its name when it has none.
+func (f *Field) ResponseKey() string {
+	if f.Alias != "" {
+		return f.Alias
+	}
+	return f.Name
+}
+
+type FragmentSpread struct {
+	Name       string
+	Directives []*Directive
+	Pos        Location
+}
+
+type InlineFragment struct {
+	TypeCondition string
+	Directives    []*Directive
+	Selections    []Selection
+	Pos           Location
+}
+
+type Fragment struct {
+	Name          string
+	TypeCondition string
+	Directives    []*Directive
+	Selections    []Selection
+	Pos           Location
+}
+
+func (f *Field) position() Location          { return f.Pos }
+func (f *FragmentSpread) position() Location { return f.Pos }
+func (f *InlineFragment) position() Location { return f.Pos }
+
+type Argument struct {
+	Name  string
+	Value *Value
+	Pos   Location
+}
+
+type Directive struct {
+	Name      string
+	Arguments []*Argument
+	Pos       Location
+}
+
+type ValueKind int
+
+const (
+	KindVariable ValueKind = iota
+	KindInt
+	KindFloat
+	KindString
+	KindBoolean
+	KindNull
+	KindEnum
+	KindList
+	KindObject
+)
+
+// Value is a literal or a variable. Raw holds the variable name, the text of
+// a scalar or enum value, or the decoded string.
+type Value struct {
+	Kind   ValueKind
+	Raw    string
+	List   []*Value
+	Fields []*ObjectField
+	Pos    Location
+}
+
+type ObjectField struct {
+	Name  string
+	Value *Value
+}
+
+// TypeRef is a named type, or a list of Elem when Name is empty.
+type TypeRef struct {
+	Name    string
+	Elem    *TypeRef
+	NonNull bool
+}
+
+func (t *TypeRef) String() string {
+	s := t.Name
+	if t.Elem != nil {
+		s = "[" + t.Elem.String() + "]"
+	}
+	if t.NonNull {
+		s += "!"
+	}
+	return s
+}
+
+// nullable returns t without its non-null marker.
+func (t *TypeRef) nullable() *TypeRef {
+	return &TypeRef{Name: t.Name, Elem: t.Elem}
+}
+
+// named returns the name of the type at the bottom of t's lists.
+func (t *TypeRef) named() string {
+	for t.Elem != nil {
+		t = t.Elem
+	}
+	return t.Name
+}
@@ -0,0 +1,337 @@
+package graphql
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+)
+
+// Request is a GraphQL request as sent in the body of a POST. Variables
+// should be decoded with json.Decoder.UseNumber so that integers keep their
+// precision.
+type Request struct {
+	Query         string                 `json:"query"`
+	OperationName string                 `json:"operationName,omitempty"`
+	Variables     map[string]interface{} `json:"variables,omitempty"`
+}
+
+// Response is the result of a request. Executed is false when the request
+// failed to parse or validate; Data is then left out of the JSON.
+type Response struct {
+	Data     interface{}
+	Errors   []*Error
+	Executed bool
+}
+
+func (r *Response) MarshalJSON() ([]byte, error) {
+	if !r.Executed {
+		return json.Marshal(struct {
+			Errors []*Error `json:"errors"`
+		}{r.Errors})
+	}
+	return json.Marshal(struct {
+		Data   interface{} `json:"data"`
+		Errors []*Error    `json:"errors,omitempty"`
+	}{r.Data, r.Errors})
+}
+
+// Execute validates the request against the schema and limits and runs it.
+//
+// Fields are resolved a level at a time: a field is resolved once for all
+// the objects of the level that select it, so lists of objects don't make
+// one resolver call per item.
+func (s *Schema) Execute(ctx context.Context, request Request, limits Limits) *Response {
+	op, errs := s.prepare(request, limits)
+	if errs != nil {
+		return &Response{Errors: errs}
+	}
+	e := &executor{schema: s, op: op}
+	data, propagate := e.selectionSet(ctx, s.query, []interface{}{nil}, [][]interface{}{nil}, op.Selections)
+	response := &Response{Errors: e.errors, Executed: true}
+	if !propagate[0] {
+		response.Data = data[0]
+	}
+	return response
+}
+
+type executor struct {
+	schema *Schema
+	op     *operation
+	errors []*Error
+}
+
+func (e *executor) fail(field *Field, path []interface{}, message string) {
+	e.errors = append(e.errors, &Error{Message: message, Locations: []Location{field.Pos}, Path: path})
+}
+
+// object is a result object; it marshals its fields in selection order.
+type object struct {
+	keys   []string
+	values map[string]interface{}
+}
+
+func (o *object) MarshalJSON() ([]byte, error) {
+	var b bytes.Buffer
+	b.WriteByte('{')
+	for i, key := range o.keys {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		name, err := json.Marshal(key)
+		if err != nil {
+			return nil, err
+		}
+		value, err := json.Marshal(o.values[key])
+		if err != nil {
+			return nil, err
+		}
+		b.Write(name)
+		b.WriteByte(':')
+		b.Write(value)
+	}
+	b.WriteByte('}')
+	return b.Bytes(), nil
+}
+
+// selectionSet resolves selections on every source, which are all of type
+// t, and returns a result object per source. propagate[i] is set when a
+// non-null field of source i is null; the object is then null too.
+func (e *executor) selectionSet(ctx context.Context, t *Object, sources []interface{}, paths [][]interface{}, selections []Selection) ([]interface{}, []bool) {
+	keys, fields := e.collect(t, selections, nil, map[string][]*Field{}, map[string]bool{})
+	results := make([]*object, len(sources))
+	for i := range results {
+		results[i] = &object{keys: keys, values: make(map[string]interface{}, len(keys))}
+	}
+	propagate := make([]bool, len(sources))
+	for _, key := range keys {
+		field := fields[key][0]
+		fieldPaths := make([][]interface{}, len(sources))
+		for i, path := range paths {
+			fieldPaths[i] = append(append([]interface{}{}, path...), key)
+		}
+		if field.Name == "__typename" {
+			for _, result := range results {
+				result.values[key] = t.Name
+			}
+			continue
+		}
+
+		definition := t.fields[field.Name]
+		values := make([]interface{}, len(sources))
+		failed := make([]bool, len(sources))
+		args, err := e.arguments(definition, field)
+		if err == nil {
+			var resolved []interface{}
+			resolved, err = definition.Resolve(ctx, sources, args)
+			if err == nil && len(resolved) != len(sources) {
+				panic("graphql: resolver for " + t.Name + "." + field.Name + " returned the wrong number of values")
+			}
+			copy(values, resolved)
+		}
+		for i := range values {
+			if err != nil {
+				e.fail(field, fieldPaths[i], err.Error())
+				failed[i] = true
+			} else if valueErr, ok := values[i].(error); ok {
+				e.fail(field, fieldPaths[i], valueErr.Error())
+				failed[i] = true
+			}
+		}
+
+		var merged []Selection
+		for _, f := range fields[key] {
+			merged = append(merged, f.Selections...)
+		}
+		completed, nulled := e.complete(ctx, definition.typ, field, merged, values, failed, fieldPaths)
+		for i, result := range results {
+			result.values[key] = completed[i]
+			propagate[i] = propagate[i] || nulled[i]
+		}
+	}
+
+	out := make([]interface{}, len(sources))
+	for i, result := range results {
+		if !propagate[i] {
+			out[i] = result
+		}
+	}
+	return out, propagate
+}
+
+// collect gathers the fields of selections that apply to t, in order, by
+// response key, following fragments and honouring @skip and @include.
+func (e *executor) collect(t *Object, selections []Selection, keys []string, fields map[string][]*Field, visited map[string]bool) ([]string, map[string][]*Field) {
+	for _, selection := range selections {
+		switch selection := selection.(type) {
+		case *Field:
+			if !e.included(selection.Directives) {
+				continue
+			}
+			key := selection.ResponseKey()
+			if _, ok := fields[key]; !ok {
+				keys = append(keys, key)
+			}
+			fields[key] = append(fields[key], selection)
+		case *InlineFragment:
+			if !e.included(selection.Directives) || selection.TypeCondition != "" && selection.TypeCondition != t.Name {
+				continue
+			}
+			keys, fields = e.collect(t, selection.Selections, keys, fields, visited)
+		case *FragmentSpread:
+			fragment := e.op.fragments[selection.Name]
+			if !e.included(selection.Directives) || visited[selection.Name] || fragment.TypeCondition != t.Name {
+				continue
+			}
+			visited[selection.Name] = true
+			keys, fields = e.collect(t, fragment.Selections, keys, fields, visited)
+		}
+	}
+	return keys, fields
+}
+
+func (e *executor) included(directives []*Directive) bool {
+	for _, directive := range directives {
+		condition, _ := e.schema.coerceLiteral(directive.Arguments[0].Value, &TypeRef{Name: "Boolean", NonNull: true}, e.op.variables)
+		if condition == (directive.Name == "skip") {
+			return false
+		}
+	}
+	return true
+}
+
+// arguments coerces the arguments of field. Arguments that are left out,
+// or given a variable without a value, take their default if they have one
+// and are otherwise missing from the map.
+func (e *executor) arguments(definition *FieldDef, field *Field) (map[string]interface{}, error) {
+	args := make(map[string]interface{}, len(definition.Args))
+	for _, arg := range definition.Args {
+		if arg.Default != "" {
+			args[arg.Name] = arg.defaultValue
+		}
+	}
+	for _, argument := range field.Arguments {
+		if argument.Value.Kind == KindVariable {
+			if _, ok := e.op.variables[argument.Value.Raw]; !ok {
+				continue
+			}
+		}
+		arg := definition.args[argument.Name]
+		value, err := e.schema.coerceLiteral(argument.Value, arg.typ, e.op.variables)
+		if err != nil {
+			return nil, err
+		}
+		args[arg.Name] = value
+	}
+	return args, nil
+}
+
+// complete converts resolved values to results of type t. nulled[i] is set
+// when the result is null but t is non-null, in which case the null
+// propagates to the parent.
+func (e *executor) complete(ctx context.Context, t *TypeRef, field *Field, selections []Selection, values []interface{}, failed []bool, paths [][]interface{}) ([]interface{}, []bool) {
+	if !t.NonNull {
+		completed, _ := e.completeNullable(ctx, t, field, selections, values, failed, paths)
+		return completed, make([]bool, len(values))
+	}
+	completed, nulled := e.completeNullable(ctx, t.nullable(), field, selections, values, failed, paths)
+	for i := range completed {
+		if completed[i] == nil {
+			if !nulled[i] {
+				e.fail(field, paths[i], "cannot return null for non-null field")
+			}
+			nulled[i] = true
+		}
+	}
+	return completed, nulled
+}
+
+// completeNullable completes values of the nullable type t. nulled[i] is
+// set when the result is null because of an error that was already
+// reported.
+func (e *executor) completeNullable(ctx context.Context, t *TypeRef, field *Field, selections []Selection, values []interface{}, failed []bool, paths [][]interface{}) ([]interface{}, []bool) {
+	completed := make([]interface{}, len(values))
+	nulled := make([]bool, len(values))
+	var present []int
+	for i, value := range values {
+		if failed[i] {
+			nulled[i] = true
+		} else if value != nil {
+			present = append(present, i)
+		}
+	}
+	if len(present) == 0 {
+		return completed, nulled
+	}
+
+	if t.Elem != nil {
+		var items []interface{}
+		var itemPaths [][]interface{}
+		var owners []int
+		for _, i := range present {
+			list, ok := values[i].([]interface{})
+			if !ok {
+				e.fail(field, paths[i], "expected a list")
+				nulled[i] = true
+				continue
+			}
+			for j, item := range list {
+				items = append(items, item)
+				itemPaths = append(itemPaths, append(append([]interface{}{}, paths[i]...), j))
+				owners = append(owners, i)
+			}
+			completed[i] = make([]interface{}, 0, len(list))
+		}
+		itemFailed := make([]bool, len(items))
+		for j, item := range items {
+			if err, ok := item.(error); ok {
+				e.fail(field, itemPaths[j], err.Error())
+				itemFailed[j] = true
+			}
+		}
+		itemResults, itemNulled := e.complete(ctx, t.Elem, field, selections, items, itemFailed, itemPaths)
+		for j, owner := range owners {
+			if itemNulled[j] {
+				nulled[owner] = true
+			}
+			if list, ok := completed[owner].([]interface{}); ok {
+				completed[owner] = append(list, itemResults[j])
+			}
+		}
+		for i := range completed {
+			if nulled[i] {
+				completed[i] = nil
+			}
+		}
+		return completed, nulled
+	}
+
+	switch named := e.schema.types[t.Name].(type) {
+	case *Scalar, *Enum:
+		for _, i := range present {
+			var serialized interface{}
+			var err error
+			if scalar, ok := named.(*Scalar); ok {
+				serialized, err = scalar.Serialize(values[i])
+			} else {
+				serialized, err = named.(*Enum).serialize(values[i])
+			}
+			if err != nil {
+				e.fail(field, paths[i], err.Error())
+				nulled[i] = true
+				continue
+			}
+			completed[i] = serialized
+		}
+	case *Object:
+		sources := make([]interface{}, len(present))
+		sourcePaths := make([][]interface{}, len(present))
+		for j, i := range present {
+			sources[j], sourcePaths[j] = values[i], paths[i]
+		}
+		results, propagate := e.selectionSet(ctx, named, sources, sourcePaths, selections)
+		for j, i := range present {
+			completed[i], nulled[i] = results[j], propagate[j]
+		}
+	}
+	return completed, nulled
+}
@@ -0,0 +1,255 @@
+package graphql
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+type testItem struct {
+	id       string
+	children []*testItem
+}
+
+// testSchema serves a tree of items. calls counts the resolver calls per
+// field.
+func testSchema(t *testing.T, calls map[string]int) *Schema {
+	items := []*testItem{
+		{id: "a", children: []*testItem{{id: "a1"}, {id: "a2"}}},
+		{id: "b", children: []*testItem{{id: "b1"}}},
+	}
+	each := func(name string, get func(item *testItem, args map[string]interface{}) interface{}) Resolver {
+		return func(ctx context.Context, sources []interface{}, args map[string]interface{}) ([]interface{}, error) {
+			calls[name]++
+			values := make([]interface{}, len(sources))
+			for i, source := range sources {
+				values[i] = get(source.(*testItem), args)
+			}
+			return values, nil
+		}
+	}
+	kind := &Enum{Name: "Kind", Values: []*EnumValue{{Name: "LEAF", Value: "leaf"}, {Name: "BRANCH", Value: "branch"}}}
+	item := &Object{
+		Name: "Item",
+		Fields: []*FieldDef{
+			{Name: "id", Type: "ID!", Resolve: each("id", func(item *testItem, args map[string]interface{}) interface{} { return item.id })},
+			{Name: "kind", Type: "Kind!", Resolve: each("kind", func(item *testItem, args map[string]interface{}) interface{} {
+				if len(item.children) == 0 {
+					return "leaf"
+				}
+				return "branch"
+			})},
+			{
+				Name: "children",
+				Type: "[Item!]!",
+				Args: []*ArgDef{{Name: "limit", Type: "Int!", Default: "10"}},
+				Resolve: each("children", func(item *testItem, args map[string]interface{}) interface{} {
+					children := make([]interface{}, 0, len(item.children))
+					for _, child := range item.children[:min(args["limit"].(int), len(item.children))] {
+						children = append(children, child)
+					}
+					return children
+				}),
+			},
+			{Name: "broken", Type: "String", Resolve: each("broken", func(item *testItem, args map[string]interface{}) interface{} {
+				return fmt.Errorf("%s is broken", item.id)
+			})},
+			{Name: "required", Type: "String!", Resolve: each("required", func(item *testItem, args map[string]interface{}) interface{} {
+				if item.id == "b" {
+					return nil
+				}
+				return "present"
+			})},
+		},
+	}
+	query := &Object{
+		Name: "Query",
+		Fields: []*FieldDef{
+			{
+				Name: "items",
+				Type: "[Item!]!",
+				Args: []*ArgDef{{Name: "limit", Type: "Int!", Default: "10"}, {Name: "kinds", Type: "[Kind!]"}},
+				Resolve: func(ctx context.Context, sources []interface{}, args map[string]interface{}) ([]interface{}, error) {
+					calls["items"]++
+					var values []interface{}
+					for _, item := range items[:min(args["limit"].(int), len(items))] {
+						values = append(values, item)
+					}
+					return []interface{}{values}, nil
+				},
+			},
+			{
+				Name: "item",
+				Type: "Item",
+				Args: []*ArgDef{{Name: "id", Type: "ID!"}},
+				Resolve: func(ctx context.Context, sources []interface{}, args map[string]interface{}) ([]interface{}, error) {
+					for _, item := range items {
+						if item.id == args["id"] {
+							return []interface{}{item}, nil
+						}
+					}
+					return []interface{}{nil}, nil
+				},
+			},
+			{
+				Name: "echo",
+				Type: "String!",
+				Args: []*ArgDef{{Name: "text", Type: "String!"}, {Name: "times", Type: "Int", Default: "1"}},
+				Resolve: func(ctx context.Context, sources []interface{}, args map[string]interface{}) ([]interface{}, error) {
+					return []interface{}{strings.Repeat(args["text"].(string), args["times"].(int))}, nil
+				},
+			},
+			{
+				Name: "fail",
+				Type: "String",
+				Resolve: func(ctx context.Context, sources []interface{}, args map[string]interface{}) ([]interface{}, error) {
+					return nil, errors.New("failed")
+				},
+			},
+		},
+	}
+	schema, err := NewSchema(query, item, kind)
+	assert.NoError(t, err)
+	return schema
+}
+
+func execute(t *testing.T, schema *Schema, request Request, limits Limits) (string, *Response) {
+	response := schema.Execute(context.Background(), request, limits)
+	body, err := json.Marshal(response)
+	assert.NoError(t, err)
+	return string(body), response
+}
+
+func TestExecute(t *testing.T) {
+	calls := map[string]int{}
+	schema := testSchema(t, calls)
+
+	body, response := execute(t, schema, Request{Query: `
+		query Tree {
+			items { id kind children { ...leaf } }
+			first: item(id: "b") { __typename id }
+			missing: item(id: "z") { id }
+		}
+		fragment leaf on Item { id kind }
+	`}, Limits{})
+
+	assert.True(t, response.Executed)
+	assert.Equal(t, `{"data":{"items":[`+
+		`{"id":"a","kind":"BRANCH","children":[{"id":"a1","kind":"LEAF"},{"id":"a2","kind":"LEAF"}]},`+
+		`{"id":"b","kind":"BRANCH","children":[{"id":"b1","kind":"LEAF"}]}],`+
+		`"first":{"__typename":"Item","id":"b"},"missing":null}}`, body)
+	// Each field is resolved once per level, whatever the number of items.
+	assert.Equal(t, 1, calls["children"])
+	assert.Equal(t, 3, calls["id"])
+	assert.Equal(t, 2, calls["kind"])
+}
+
+func TestExecute_VariablesAndDirectives(t *testing.T) {
+	schema := testSchema(t, map[string]int{})
+
+	body, _ := execute(t, schema, Request{
+		Query: `query ($text: String!, $times: Int = 2, $limit: Int!, $withKind: Boolean!) {
+			echo(text: $text, times: $times)
+			items(limit: $limit) { id kind @include(if: $withKind) ... @skip(if: true) { kind } }
+		}`,
+		Variables: map[string]interface{}{"text": "ab", "limit": json.Number("1"), "withKind": false},
+	}, Limits{})
+	assert.Equal(t, `{"data":{"echo":"abab","items":[{"id":"a"}]}}`, body)
+
+	_, response := execute(t, schema, Request{
+		Query:     `query ($limit: Int!) { items(limit: $limit) { id } }`,
+		Variables: map[string]interface{}{"limit": "one"},
+	}, Limits{})
+	assert.False(t, response.Executed)
+	assert.Contains(t, response.Errors[0].Message, "variable $limit")
+}
+
+func TestExecute_Errors(t *testing.T) {
+	schema := testSchema(t, map[string]int{})
+
+	body, response := execute(t, schema, Request{Query: `{ fail items(limit: 1) { broken } }`}, Limits{})
+	assert.True(t, response.Executed)
+	assert.Equal(t, `{"data":{"fail":null,"items":[{"broken":null}]},"errors":[`+
+		`{"message":"failed","locations":[{"line":1,"column":3}],"path":["fail"]},`+
+		`{"message":"a is broken","locations":[{"line":1,"column":26}],"path":["items",0,"broken"]}]}`, body)
+
+	// A null non-null field nulls its parent, up to the nearest nullable
+	// field: here the whole data.
+	body, _ = execute(t, schema, Request{Query: `{ items { required } }`}, Limits{})
+	assert.Equal(t, `{"data":null,"errors":[`+
+		`{"message":"cannot return null for non-null field","locations":[{"line":1,"column":11}],"path":["items",1,"required"]}]}`, body)
+
+	body, _ = execute(t, schema, Request{Query: `{ item(id: "b") { required } }`}, Limits{})
+	assert.Equal(t, `{"data":{"item":null},"errors":[`+
+		`{"message":"cannot return null for non-null field","locations":[{"line":1,"column":19}],"path":["item","required"]}]}`, body)
+}
+
+func TestExecute_InvalidQueries(t *testing.T) {
+	schema := testSchema(t, map[string]int{})
+
+	for query, message := range map[string]string{
+		`{ items { id }`:                                      "syntax error: unexpected end of document",
+		`{ items { name } }`:                                  `cannot query field "name" on type Item`,
+		`{ items }`:                                           `field "items" of type [Item!]! must have a selection of subfields`,
+		`{ echo(text: "a") { id } }`:                          `field "echo" of type String! can't have a selection of subfields`,
+		`{ echo }`:                                            `field Query.echo requires argument "text" of type String!`,
+		`{ echo(text: 1) }`:                                   `argument "text": String must be a string`,
+		`{ items(kinds: ["LEAF"]) { id } }`:                   `argument "kinds": Kind must be one of BRANCH, LEAF`,
+		`{ items(size: 1) { id } }`:                           `unknown argument "size" on field Query.items`,
+		`query ($s: String) { echo(text: $s) }`:               "variable $s of type String can't be used as String!",
+		`{ items(limit: $n) { id } }`:                         "variable $n is not defined",
+		`query ($n: Int) { items { id } }`:                    "variable $n is never used",
+		`{ items { ...missing } }`:                            "unknown fragment missing",
+		`{ items { ...a } } fragment a on Item { ...a }`:      "fragment a spreads itself",
+		`{ item(id: "a") { id id: kind } }`:                   `fields "id" conflict because they select different fields or arguments; use aliases`,
+		`{ items { id @defer } }`:                             "unknown directive @defer",
+		`mutation { items { id } }`:                           "mutation operations aren't supported",
+		`query A { items { id } } query B { echo(text: "") }`: "operationName is required when the document has several operations",
+	} {
+		_, response := execute(t, schema, Request{Query: query}, Limits{})
+		assert.False(t, response.Executed, query)
+		if assert.NotEmpty(t, response.Errors, query) {
+			assert.Equal(t, message, response.Errors[0].Message, query)
+		}
+	}
+
+	body, _ := execute(t, schema, Request{Query: `{ items { name } }`}, Limits{})
+	assert.Equal(t, `{"errors":[{"message":"cannot query field \"name\" on type Item","locations":[{"line":1,"column":11}]}]}`, body)
+}
+
+func TestExecute_Limits(t *testing.T) {
+	schema := testSchema(t, map[string]int{})
+
+	// items (1) + 5 items × (children (1) + 10 children × id (1)) = 56
+	query := `{ items(limit: 5) { children { id } } }`
+	_, response := execute(t, schema, Request{Query: query}, Limits{MaxComplexity: 56})
+	assert.True(t, response.Executed)
+	_, response = execute(t, schema, Request{Query: query}, Limits{MaxComplexity: 55})
+	assert.False(t, response.Executed)
+	assert.Equal(t, "query complexity 56 exceeds the limit of 55", response.Errors[0].Message)
+
+	// Limits given by variables are counted too.
+	_, response = execute(t, schema, Request{
+		Query:     `query ($n: Int!) { items(limit: $n) { id } }`,
+		Variables: map[string]interface{}{"n": json.Number("1000")},
+	}, Limits{MaxComplexity: 100})
+	assert.Equal(t, "query complexity 1001 exceeds the limit of 100", response.Errors[0].Message)
+
+	query = `{ items { children { children { id } } } }`
+	_, response = execute(t, schema, Request{Query: query}, Limits{MaxDepth: 4})
+	assert.True(t, response.Executed)
+	_, response = execute(t, schema, Request{Query: query}, Limits{MaxDepth: 3})
+	assert.Equal(t, "query depth 4 exceeds the limit of 3", response.Errors[0].Message)
+}
+
+func TestSchema_String(t *testing.T) {
+	schema := testSchema(t, map[string]int{})
+
+	sdl := schema.String()
+	assert.Contains(t, sdl, "type Query {\n  items(limit: Int! = 10, kinds: [Kind!]): [Item!]!\n")
+	assert.Contains(t, sdl, "enum Kind {\n  LEAF\n  BRANCH\n}\n")
+}
@@ -0,0 +1,236 @@
+package graphql
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenPunctuator
+	tokenName
+	tokenInt
+	tokenFloat
+	tokenString
+)
+
+type token struct {
+	kind  tokenKind
+	value string
+	pos   Location
+}
+
+// lexer splits a GraphQL document into tokens, skipping whitespace, commas
+// and comments.
+type lexer struct {
+	source string
+	offset int
+	line   int
+	column int
+}
+
+func newLexer(source string) *lexer {
+	return &lexer{source: source, line: 1, column: 1}
+}
+
+func (l *lexer) errorf(pos Location, format string, args ...interface{}) error {
+	return &Error{Message: "syntax error: " + fmt.Sprintf(format, args...), Locations: []Location{pos}}
+}
+
+func (l *lexer) advance(n int) {
+	for _, r := range l.source[l.offset : l.offset+n] {
+		if r == '\n' {
+			l.line++
+			l.column = 1
+		} else {
+			l.column++
+		}
+	}
+	l.offset += n
+}
+
+func (l *lexer) next() (token, error) {
+	l.skipIgnored()
+	pos := Location{Line: l.line, Column: l.column}
+	if l.offset >= len(l.source) {
+		return token{kind: tokenEOF, pos: pos}, nil
+	}
+	rest := l.source[l.offset:]
+	c := rest[0]
+	switch {
+	case strings.HasPrefix(rest, "..."):
+		l.advance(3)
+		return token{kind: tokenPunctuator, value: "...", pos: pos}, nil
+	case strings.ContainsRune("!$()/:=@[]{}|&", rune(c)):
+		l.advance(1)
+		return token{kind: tokenPunctuator, value: string(c), pos: pos}, nil
+	case c == '_' || isLetter(c):
+		n := 1
+		for n < len(rest) && (rest[n] == '_' || isLetter(rest[n]) || isDigit(rest[n])) {
+			n++
+		}
+		l.advance(n)
+		return token{kind: tokenName, value: rest[:n], pos: pos}, nil
+	case c == '-' || isDigit(c):
+		return l.number(pos)
+	case c == '"':
+		return l.string(pos)
+	}
+	r, _ := utf8.DecodeRuneInString(rest)
+	return token{}, l.errorf(pos, "unexpected character %q", r)
+}
+
+func (l *lexer) skipIgnored() {
+	for l.offset < len(l.source) {
+		switch c := l.source[l.offset]; {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == ',':
+			l.advance(1)
+		case c == '#':
+			n := strings.IndexAny(l.source[l.offset:], "\r\n")
+			if n < 0 {
+				n = len(l.source) - l.offset
+			}
+			l.advance(n)
+		case strings.HasPrefix(l.source[l.offset:], "\uFEFF"):
+			l.advance(len("\uFEFF"))
+		default:
+			return
+		}
+	}
+}
+
+func (l *lexer) number(pos Location) (token, error) {
+	rest := l.source[l.offset:]
+	n := 0
+	if rest[n] == '-' {
+		n++
+	}
+	digits := func() int {
+		start := n
+		for n < len(rest) && isDigit(rest[n]) {
+			n++
+		}
+		return n - start
+	}
+	if digits() == 0 {
+		return token{}, l.errorf(pos, "invalid number")
+	}
+	kind := tokenInt
+	if n < len(rest) && rest[n] == '.' {
+		n++
+		kind = tokenFloat
+		if digits() == 0 {
+			return token{}, l.errorf(pos, "invalid number")
+		}
+	}
+	if n < len(rest) && (rest[n] == 'e' || rest[n] == 'E') {
+		n++
+		kind = tokenFloat
+		if n < len(rest) && (rest[n] == '+' || rest[n] == '-') {
+			n++
+		}
+		if digits() == 0 {
+			return token{}, l.errorf(pos, "invalid number")
+		}
+	}
+	if n < len(rest) && (rest[n] == '_' || rest[n] == '.' || isLetter(rest[n])) {
+		return token{}, l.errorf(pos, "invalid number")
+	}
+	l.advance(n)
+	return token{kind: kind, value: rest[:n], pos: pos}, nil
+}
+
+func (l *lexer) string(pos Location) (token, error) {
+	rest := l.source[l.offset:]
+	if strings.HasPrefix(rest, `"""`) {
+		end := strings.Index(rest[3:], `"""`)
+		if end < 0 {
+			return token{}, l.errorf(pos, "unterminated string")
+		}
+		l.advance(end + 6)
+		return token{kind: tokenString, value: blockString(rest[3 : 3+end]), pos: pos}, nil
+	}
+	var value strings.Builder
+	for n := 1; n < len(rest); n++ {
+		switch c := rest[n]; c {
+		case '"':
+			l.advance(n + 1)
+			return token{kind: tokenString, value: value.String(), pos: pos}, nil
+		case '\n', '\r':
+			return token{}, l.errorf(pos, "unterminated string")
+		case '\\':
+			if n+1 >= len(rest) {
+				return token{}, l.errorf(pos, "unterminated string")
+			}
+			n++
+			switch e := rest[n]; e {
+			case '"', '\\', '/':
+				value.WriteByte(e)
+			case 'b':
+				value.WriteByte('\b')
+			case 'f':
+				value.WriteByte('\f')
+			case 'n':
+				value.WriteByte('\n')
+			case 'r':
+				value.WriteByte('\r')
+			case 't':
+				value.WriteByte('\t')
+			case 'u':
+				if n+4 >= len(rest) {
+					return token{}, l.errorf(pos, "invalid unicode escape")
+				}
+				code, err := strconv.ParseUint(rest[n+1:n+5], 16, 32)
+				if err != nil {
+					return token{}, l.errorf(pos, "invalid unicode escape")
+				}
+				value.WriteRune(rune(code))
+				n += 4
+			default:
+				return token{}, l.errorf(pos, "invalid escape \\%c", e)
+			}
+		default:
+			value.WriteByte(c)
+		}
+	}
+	return token{}, l.errorf(pos, "unterminated string")
+}
+
+// blockString removes the common indentation and the leading and trailing
+// blank lines of a """block string""".
+func blockString(raw string) string {
+	lines := strings.Split(strings.ReplaceAll(strings.ReplaceAll(raw, "\r\n", "\n"), `\"""`, `"""`), "\n")
+	indent := -1
+	for _, line := range lines[1:] {
+		trimmed := strings.TrimLeft(line, " \t")
+		if trimmed != "" && (indent < 0 || len(line)-len(trimmed) < indent) {
+			indent = len(line) - len(trimmed)
+		}
+	}
+	for i := 1; i < len(lines) && indent > 0; i++ {
+		if len(lines[i]) >= indent {
+			lines[i] = lines[i][indent:]
+		} else {
+			lines[i] = ""
+		}
+	}
+	for len(lines) > 0 && strings.TrimSpace(lines[0]) == "" {
+		lines = lines[1:]
+	}
+	for len(lines) > 0 && strings.TrimSpace(lines[len(lines)-1]) == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return strings.Join(lines, "\n")
+}
+
+func isLetter(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
@@ -0,0 +1,423 @@
+package graphql
+
+// Parse parses an executable GraphQL document: operations and fragments.
+func Parse(source string) (*Document, error) {
+	p := &parser{lexer: newLexer(source)}
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+	doc := &Document{Fragments: map[string]*Fragment{}}
+	for p.token.kind != tokenEOF {
+		switch {
+		case p.peek(tokenPunctuator, "{"):
+			selections, err := p.selectionSet()
+			if err != nil {
+				return nil, err
+			}
+			doc.Operations = append(doc.Operations, &Operation{Type: "query", Selections: selections, Pos: selections[0].position()})
+		case p.peek(tokenName, "fragment"):
+			fragment, err := p.fragment()
+			if err != nil {
+				return nil, err
+			}
+			if _, ok := doc.Fragments[fragment.Name]; ok {
+				return nil, &Error{Message: "there can be only one fragment named \"" + fragment.Name + "\"", Locations: []Location{fragment.Pos}}
+			}
+			doc.Fragments[fragment.Name] = fragment
+		case p.peek(tokenName, "query"), p.peek(tokenName, "mutation"), p.peek(tokenName, "subscription"):
+			operation, err := p.operation()
+			if err != nil {
+				return nil, err
+			}
+			doc.Operations = append(doc.Operations, operation)
+		default:
+			return nil, p.unexpected()
+		}
+	}
+	if len(doc.Operations) == 0 {
+		return nil, &Error{Message: "the document contains no operations"}
+	}
+	return doc, nil
+}
+
+type parser struct {
+	lexer *lexer
+	token token
+}
+
+func (p *parser) advance() error {
+	token, err := p.lexer.next()
+	if err != nil {
+		return err
+	}
+	p.token = token
+	return nil
+}
+
+func (p *parser) peek(kind tokenKind, value string) bool {
+	return p.token.kind == kind && p.token.value == value
+}
+
+func (p *parser) unexpected() error {
+	if p.token.kind == tokenEOF {
+		return p.lexer.errorf(p.token.pos, "unexpected end of document")
+	}
+	return p.lexer.errorf(p.token.pos, "unexpected %q", p.token.value)
+}
+
+// skip consumes the punctuator if it is next and reports whether it was.
+func (p *parser) skip(punctuator string) (bool, error) {
+	if !p.peek(tokenPunctuator, punctuator) {
+		return false, nil
+	}
+	return true, p.advance()
+}
+
+func (p *parser) expect(punctuator string) error {
+	if !p.peek(tokenPunctuator, punctuator) {
+		return p.unexpected()
+	}
+	return p.advance()
+}
+
+func (p *parser) name() (string, error) {
+	if p.token.kind != tokenName {
+		return "", p.unexpected()
+	}
+	name := p.token.value
+	return name, p.advance()
+}
+
+func (p *parser) operation() (*Operation, error) {
+	operation := &Operation{Type: p.token.value, Pos: p.token.pos}
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+	if p.token.kind == tokenName {
+		operation.Name = p.token.value
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+	}
+	if ok, err := p.skip("("); err != nil {
+		return nil, err
+	} else if ok {
+		for !p.peek(tokenPunctuator, ")") {
+			variable, err := p.variableDefinition()
+			if err != nil {
+				return nil, err
+			}
+			operation.Variables = append(operation.Variables, variable)
+		}
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+	}
+	var err error
+	if operation.Directives, err = p.directives(); err != nil {
+		return nil, err
+	}
+	if operation.Selections, err = p.selectionSet(); err != nil {
+		return nil, err
+	}
+	return operation, nil
+}
+
+func (p *parser) variableDefinition() (*VariableDefinition, error) {
+	variable := &VariableDefinition{Pos: p.token.pos}
+	if err := p.expect("$"); err != nil {
+		return nil, err
+	}
+	var err error
+	if variable.Name, err = p.name(); err != nil {
+		return nil, err
+	}
+	if err := p.expect(":"); err != nil {
+		return nil, err
+	}
+	if variable.Type, err = p.typeRef(); err != nil {
+		return nil, err
+	}
+	if ok, err := p.skip("="); err != nil {
+		return nil, err
+	} else if ok {
+		if variable.Default, err = p.value(true); err != nil {
+			return nil, err
+		}
+	}
+	return variable, nil
+}
+
+func (p *parser) typeRef() (*TypeRef, error) {
+	t := &TypeRef{}
+	if ok, err := p.skip("["); err != nil {
+		return nil, err
+	} else if ok {
+		if t.Elem, err = p.typeRef(); err != nil {
+			return nil, err
+		}
+		if err := p.expect("]"); err != nil {
+			return nil, err
+		}
+	} else if t.Name, err = p.name(); err != nil {
+		return nil, err
+	}
+	var err error
+	t.NonNull, err = p.skip("!")
+	return t, err
+}
+
+func (p *parser) fragment() (*Fragment, error) {
+	fragment := &Fragment{Pos: p.token.pos}
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+	var err error
+	if fragment.Name, err = p.name(); err != nil {
+		return nil, err
+	}
+	if fragment.Name == "on" {
+		return nil, p.lexer.errorf(fragment.Pos, "a fragment can't be named \"on\"")
+	}
+	if !p.peek(tokenName, "on") {
+		return nil, p.unexpected()
+	}
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+	if fragment.TypeCondition, err = p.name(); err != nil {
+		return nil, err
+	}
+	if fragment.Directives, err = p.directives(); err != nil {
+		return nil, err
+	}
+	if fragment.Selections, err = p.selectionSet(); err != nil {
+		return nil, err
+	}
+	return fragment, nil
+}
+
+func (p *parser) selectionSet() ([]Selection, error) {
+	if err := p.expect("{"); err != nil {
+		return nil, err
+	}
+	var selections []Selection
+	for {
+		if ok, err := p.skip("}"); err != nil {
+			return nil, err
+		} else if ok {
+			break
+		}
+		selection, err := p.selection()
+		if err != nil {
+			return nil, err
+		}
+		selections = append(selections, selection)
+	}
+	if len(selections) == 0 {
+		return nil, p.unexpected()
+	}
+	return selections, nil
+}
+
+func (p *parser) selection() (Selection, error) {
+	pos := p.token.pos
+	if ok, err := p.skip("..."); err != nil {
+		return nil, err
+	} else if ok {
+		return p.fragmentSelection(pos)
+	}
+
+	field := &Field{Pos: pos}
+	var err error
+	if field.Name, err = p.name(); err != nil {
+		return nil, err
+	}
+	if ok, err := p.skip(":"); err != nil {
+		return nil, err
+	} else if ok {
+		field.Alias = field.Name
+		if field.Name, err = p.name(); err != nil {
+			return nil, err
+		}
+	}
+	if field.Arguments, err = p.arguments(); err != nil {
+		return nil, err
+	}
+	if field.Directives, err = p.directives(); err != nil {
+		return nil, err
+	}
+	if p.peek(tokenPunctuator, "{") {
+		if field.Selections, err = p.selectionSet(); err != nil {
+			return nil, err
+		}
+	}
+	return field, nil
+}
+
+func (p *parser) fragmentSelection(pos Location) (Selection, error) {
+	if p.token.kind == tokenName && p.token.value != "on" {
+		spread := &FragmentSpread{Name: p.token.value, Pos: pos}
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		var err error
+		spread.Directives, err = p.directives()
+		return spread, err
+	}
+
+	fragment := &InlineFragment{Pos: pos}
+	var err error
+	if p.peek(tokenName, "on") {
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		if fragment.TypeCondition, err = p.name(); err != nil {
+			return nil, err
+		}
+	}
+	if fragment.Directives, err = p.directives(); err != nil {
+		return nil, err
+	}
+	if fragment.Selections, err = p.selectionSet(); err != nil {
+		return nil, err
+	}
+	return fragment, nil
+}
+
+func (p *parser) arguments() ([]*Argument, error) {
+	if ok, err := p.skip("("); err != nil || !ok {
+		return nil, err
+	}
+	var arguments []*Argument
+	for {
+		if ok, err := p.skip(")"); err != nil {
+			return nil, err
+		} else if ok {
+			break
+		}
+		argument := &Argument{Pos: p.token.pos}
+		var err error
+		if argument.Name, err = p.name(); err != nil {
+			return nil, err
+		}
+		if err := p.expect(":"); err != nil {
+			return nil, err
+		}
+		if argument.Value, err = p.value(false); err != nil {
+			return nil, err
+		}
+		arguments = append(arguments, argument)
+	}
+	if len(arguments) == 0 {
+		return nil, p.unexpected()
+	}
+	return arguments, nil
+}
+
+func (p *parser) directives() ([]*Directive, error) {
+	var directives []*Directive
+	for p.peek(tokenPunctuator, "@") {
+		directive := &Directive{Pos: p.token.pos}
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		var err error
+		if directive.Name, err = p.name(); err != nil {
+			return nil, err
+		}
+		if directive.Arguments, err = p.arguments(); err != nil {
+			return nil, err
+		}
+		directives = append(directives, directive)
+	}
+	return directives, nil
+}
+
+// value parses a value; constant values, such as variable defaults, can't
+// contain variables.
+func (p *parser) value(constant bool) (*Value, error) {
+	value := &Value{Raw: p.token.value, Pos: p.token.pos}
+	switch p.token.kind {
+	case tokenInt:
+		value.Kind = KindInt
+	case tokenFloat:
+		value.Kind = KindFloat
+	case tokenString:
+		value.Kind = KindString
+	case tokenName:
+		switch p.token.value {
+		case "true", "false":
+			value.Kind = KindBoolean
+		case "null":
+			value.Kind = KindNull
+		default:
+			value.Kind = KindEnum
+		}
+	case tokenPunctuator:
+		switch p.token.value {
+		case "$":
+			if constant {
+				return nil, p.unexpected()
+			}
+			if err := p.advance(); err != nil {
+				return nil, err
+			}
+			name, err := p.name()
+			return &Value{Kind: KindVariable, Raw: name, Pos: value.Pos}, err
+		case "[":
+			return p.listValue(value, constant)
+		case "{":
+			return p.objectValue(value, constant)
+		}
+		return nil, p.unexpected()
+	default:
+		return nil, p.unexpected()
+	}
+	return value, p.advance()
+}
+
+func (p *parser) listValue(value *Value, constant bool) (*Value, error) {
+	value.Kind, value.Raw = KindList, ""
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+	for {
+		if ok, err := p.skip("]"); err != nil {
+			return nil, err
+		} else if ok {
+			return value, nil
+		}
+		item, err := p.value(constant)
+		if err != nil {
+			return nil, err
+		}
+		value.List = append(value.List, item)
+	}
+}
+
+func (p *parser) objectValue(value *Value, constant bool) (*Value, error) {
+	value.Kind, value.Raw = KindObject, ""
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+	for {
+		if ok, err := p.skip("}"); err != nil {
+			return nil, err
+		} else if ok {
+			return value, nil
+		}
+		field := &ObjectField{}
+		var err error
+		if field.Name, err = p.name(); err != nil {
+			return nil, err
+		}
+		if err := p.expect(":"); err != nil {
+			return nil, err
+		}
+		if field.Value, err = p.value(constant); err != nil {
+			return nil, err
+		}
+		value.Fields = append(value.Fields, field)
+	}
+}
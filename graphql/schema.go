@@ -0,0 +1,403 @@
+// Package graphql is a small GraphQL engine: it parses and validates
+// query documents against a Schema, bounds their depth and complexity, and
+// executes them with batch resolvers. Mutations, subscriptions, interfaces,
+// unions, input objects and introspection aren't supported; Schema.String
+// renders the schema in SDL instead.
+package graphql
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+)
+
+// Resolver resolves a field for every parent object of a selection at once:
+// sources holds the parent values and the result must hold one value per
+// source, in the same order. Resolving a whole level in one call is what
+// lets a resolver batch its lookups instead of making one per parent.
+//
+// A result value can be an error, which fails the field for that source
+// only; a returned error fails it for every source. Values of list fields
+// are []interface{}.
+type Resolver func(ctx context.Context, sources []interface{}, args map[string]interface{}) ([]interface{}, error)
+
+// Type is a *Scalar, *Enum or *Object.
+type Type interface {
+	typeName() string
+	sdl() string
+}
+
+// Scalar is a leaf type. Serialize converts a resolved value to its JSON
+// representation; Parse converts an input value, a string, bool or
+// json.Number, to the value resolvers receive.
+type Scalar struct {
+	Name        string
+	Description string
+	Serialize   func(value interface{}) (interface{}, error)
+	Parse       func(value interface{}) (interface{}, error)
+}
+
+// Enum is a leaf type whose values are names; resolvers receive and return
+// the Value of the matching EnumValue.
+type Enum struct {
+	Name        string
+	Description string
+	Values      []*EnumValue
+}
+
+type EnumValue struct {
+	Name        string
+	Description string
+	Value       interface{}
+}
+
+// Object is a type with fields. Only Object types can be selected into.
+type Object struct {
+	Name        string
+	Description string
+	Fields      []*FieldDef
+
+	fields map[string]*FieldDef
+}
+
+// FieldDef defines a field of an Object. Type is written in SDL notation,
+// such as "[Sale!]!".
+type FieldDef struct {
+	Name        string
+	Description string
+	Type        string
+	Args        []*ArgDef
+	Resolve     Resolver
+
+	typ  *TypeRef
+	args map[string]*ArgDef
+}
+
+// ArgDef defines an argument of a field. Type is in SDL notation and
+// Default, when set, is a GraphQL literal such as "100".
+type ArgDef struct {
+	Name        string
+	Description string
+	Type        string
+	Default     string
+
+	typ          *TypeRef
+	defaultValue interface{}
+}
+
+func (s *Scalar) typeName() string { return s.Name }
+func (e *Enum) typeName() string   { return e.Name }
+func (o *Object) typeName() string { return o.Name }
+
+// Schema is a validated set of types with a Query root.
+type Schema struct {
+	query *Object
+	types map[string]Type
+	order []Type
+}
+
+// NewSchema validates the types reachable from query and builds a schema
+// from them. Every field needs a resolver; the built-in scalars Int, Float,
+// String, Boolean and ID are always available.
+func NewSchema(query *Object, types ...Type) (*Schema, error) {
+	s := &Schema{query: query, types: map[string]Type{}}
+	for _, scalar := range []*Scalar{Int, Float, String, Boolean, ID} {
+		s.types[scalar.Name] = scalar
+	}
+	for _, t := range append([]Type{query}, types...) {
+		if _, ok := s.types[t.typeName()]; ok {
+			return nil, fmt.Errorf("graphql: duplicate type %s", t.typeName())
+		}
+		s.types[t.typeName()] = t
+		s.order = append(s.order, t)
+	}
+	for _, t := range s.order {
+		object, ok := t.(*Object)
+		if !ok {
+			continue
+		}
+		if err := s.compile(object); err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+func (s *Schema) compile(object *Object) error {
+	object.fields = make(map[string]*FieldDef, len(object.Fields))
+	for _, field := range object.Fields {
+		name := object.Name + "." + field.Name
+		if _, ok := object.fields[field.Name]; ok || strings.HasPrefix(field.Name, "__") {
+			return fmt.Errorf("graphql: invalid or duplicate field %s", name)
+		}
+		if field.Resolve == nil {
+			return fmt.Errorf("graphql: field %s has no resolver", name)
+		}
+		var err error
+		if field.typ, err = s.parseType(field.Type); err != nil {
+			return fmt.Errorf("graphql: field %s: %w", name, err)
+		}
+		field.args = make(map[string]*ArgDef, len(field.Args))
+		for _, arg := range field.Args {
+			if arg.typ, err = s.parseType(arg.Type); err != nil {
+				return fmt.Errorf("graphql: argument %s(%s): %w", name, arg.Name, err)
+			}
+			if _, ok := s.types[arg.typ.named()].(*Object); ok {
+				return fmt.Errorf("graphql: argument %s(%s) isn't an input type", name, arg.Name)
+			}
+			if arg.Default != "" {
+				value, err := (&parser{lexer: newLexer(arg.Default)}).parseConstant()
+				if err == nil {
+					arg.defaultValue, err = s.coerceLiteral(value, arg.typ, nil)
+				}
+				if err != nil {
+					return fmt.Errorf("graphql: argument %s(%s) default: %v", name, arg.Name, err)
+				}
+			}
+			field.args[arg.Name] = arg
+		}
+		object.fields[field.Name] = field
+	}
+	return nil
+}
+
+func (s *Schema) parseType(source string) (*TypeRef, error) {
+	p := &parser{lexer: newLexer(source)}
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+	t, err := p.typeRef()
+	if err != nil {
+		return nil, err
+	}
+	if p.token.kind != tokenEOF {
+		return nil, p.unexpected()
+	}
+	if _, ok := s.types[t.named()]; !ok {
+		return nil, fmt.Errorf("unknown type %s", t.named())
+	}
+	return t, nil
+}
+
+// parseConstant parses a whole source as a single constant value.
+func (p *parser) parseConstant() (*Value, error) {
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+	value, err := p.value(true)
+	if err != nil {
+		return nil, err
+	}
+	if p.token.kind != tokenEOF {
+		return nil, p.unexpected()
+	}
+	return value, nil
+}
+
+// String renders the schema in the GraphQL schema definition language.
+func (s *Schema) String() string {
+	var b strings.Builder
+	for i, t := range s.order {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		b.WriteString(t.sdl())
+	}
+	return b.String()
+}
+
+func description(b *strings.Builder, indent, text string) {
+	if text == "" {
+		return
+	}
+	if !strings.Contains(text, "\n") && !strings.Contains(text, `"`) {
+		fmt.Fprintf(b, "%s\"%s\"\n", indent, text)
+		return
+	}
+	fmt.Fprintf(b, "%s\"\"\"\n", indent)
+	for _, line := range strings.Split(text, "\n") {
+		fmt.Fprintf(b, "%s%s\n", indent, strings.ReplaceAll(line, `"""`, `\"""`))
+	}
+	fmt.Fprintf(b, "%s\"\"\"\n", indent)
+}
+
+func (s *Scalar) sdl() string {
+	var b strings.Builder
+	description(&b, "", s.Description)
+	fmt.Fprintf(&b, "scalar %s\n", s.Name)
+	return b.String()
+}
+
+func (e *Enum) sdl() string {
+	var b strings.Builder
+	description(&b, "", e.Description)
+	fmt.Fprintf(&b, "enum %s {\n", e.Name)
+	for _, value := range e.Values {
+		description(&b, "  ", value.Description)
+		fmt.Fprintf(&b, "  %s\n", value.Name)
+	}
+	b.WriteString("}\n")
+	return b.String()
+}
+
+func (o *Object) sdl() string {
+	var b strings.Builder
+	description(&b, "", o.Description)
+	fmt.Fprintf(&b, "type %s {\n", o.Name)
+	for _, field := range o.Fields {
+		description(&b, "  ", field.Description)
+		fmt.Fprintf(&b, "  %s", field.Name)
+		if len(field.Args) > 0 {
+			args := make([]string, len(field.Args))
+			for i, arg := range field.Args {
+				args[i] = arg.Name + ": " + arg.Type
+				if arg.Default != "" {
+					args[i] += " = " + arg.Default
+				}
+			}
+			fmt.Fprintf(&b, "(%s)", strings.Join(args, ", "))
+		}
+		fmt.Fprintf(&b, ": %s\n", field.Type)
+	}
+	b.WriteString("}\n")
+	return b.String()
+}
+
+func (e *Enum) parse(value interface{}) (interface{}, error) {
+	name, ok := value.(string)
+	if ok {
+		for _, v := range e.Values {
+			if v.Name == name {
+				return v.Value, nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("%s must be one of %s", e.Name, strings.Join(e.names(), ", "))
+}
+
+func (e *Enum) serialize(value interface{}) (interface{}, error) {
+	for _, v := range e.Values {
+		if v.Value == value {
+			return v.Name, nil
+		}
+	}
+	return nil, fmt.Errorf("%v isn't a value of %s", value, e.Name)
+}
+
+func (e *Enum) names() []string {
+	names := make([]string, len(e.Values))
+	for i, v := range e.Values {
+		names[i] = v.Name
+	}
+	sort.Strings(names)
+	return names
+}
+
+// The built-in scalars. Int is 32-bit, as the specification requires.
+var (
+	Int = &Scalar{
+		Name: "Int",
+		Serialize: func(value interface{}) (interface{}, error) {
+			var n int64
+			switch v := value.(type) {
+			case int:
+				n = int64(v)
+			case int32:
+				n = int64(v)
+			case int64:
+				n = v
+			default:
+				return nil, fmt.Errorf("Int can't represent %T", value)
+			}
+			if n < math.MinInt32 || n > math.MaxInt32 {
+				return nil, fmt.Errorf("Int can't represent non 32-bit integer %d", n)
+			}
+			return n, nil
+		},
+		Parse: func(value interface{}) (interface{}, error) {
+			number, ok := value.(json.Number)
+			if !ok {
+				return nil, fmt.Errorf("Int must be an integer")
+			}
+			n, err := number.Int64()
+			if err != nil || n < math.MinInt32 || n > math.MaxInt32 {
+				return nil, fmt.Errorf("Int must be a 32-bit integer")
+			}
+			return int(n), nil
+		},
+	}
+	Float = &Scalar{
+		Name: "Float",
+		Serialize: func(value interface{}) (interface{}, error) {
+			switch v := value.(type) {
+			case float64:
+				return v, nil
+			case float32:
+				return float64(v), nil
+			case int:
+				return float64(v), nil
+			}
+			return nil, fmt.Errorf("Float can't represent %T", value)
+		},
+		Parse: func(value interface{}) (interface{}, error) {
+			number, ok := value.(json.Number)
+			if !ok {
+				return nil, fmt.Errorf("Float must be a number")
+			}
+			return number.Float64()
+		},
+	}
+	String = &Scalar{
+		Name: "String",
+		Serialize: func(value interface{}) (interface{}, error) {
+			if s, ok := value.(string); ok {
+				return s, nil
+			}
+			return nil, fmt.Errorf("String can't represent %T", value)
+		},
+		Parse: func(value interface{}) (interface{}, error) {
+			if s, ok := value.(string); ok {
+				return s, nil
+			}
+			return nil, fmt.Errorf("String must be a string")
+		},
+	}
+	Boolean = &Scalar{
+		Name: "Boolean",
+		Serialize: func(value interface{}) (interface{}, error) {
+			if b, ok := value.(bool); ok {
+				return b, nil
+			}
+			return nil, fmt.Errorf("Boolean can't represent %T", value)
+		},
+		Parse: func(value interface{}) (interface{}, error) {
+			if b, ok := value.(bool); ok {
+				return b, nil
+			}
+			return nil, fmt.Errorf("Boolean must be true or false")
+		},
+	}
+	ID = &Scalar{
+		Name: "ID",
+		Serialize: func(value interface{}) (interface{}, error) {
+			if s, ok := value.(string); ok {
+				return s, nil
+			}
+			return nil, fmt.Errorf("ID can't represent %T", value)
+		},
+		Parse: func(value interface{}) (interface{}, error) {
+			switch v := value.(type) {
+			case string:
+				return v, nil
+			case json.Number:
+				if _, err := v.Int64(); err == nil {
+					return v.String(), nil
+				}
+			}
+			return nil, fmt.Errorf("ID must be a string or an integer")
+		},
+	}
+)
@@ -0,0 +1,428 @@
+package graphql
+
+import (
+	"fmt"
+	"strings"
+)
+
+// DefaultListSize is the length complexity analysis assumes for list fields
+// without a limit argument.
+const DefaultListSize = 10
+
+// Limits bounds the cost of a query before it runs. Zero disables a limit.
+//
+// The depth of a query is the longest chain of nested selections. Its
+// complexity is the number of fields it could resolve: every field costs
+// 1, and the fields selected under a list field are counted once per item,
+// taking the field's limit argument, or DefaultListSize, as its length.
+type Limits struct {
+	MaxDepth      int
+	MaxComplexity int
+}
+
+// operation is a validated operation ready to execute.
+type operation struct {
+	*Operation
+	fragments map[string]*Fragment
+	variables map[string]interface{}
+}
+
+func (s *Schema) prepare(request Request, limits Limits) (*operation, []*Error) {
+	doc, err := Parse(request.Query)
+	if err != nil {
+		if e, ok := err.(*Error); ok {
+			return nil, []*Error{e}
+		}
+		return nil, []*Error{{Message: err.Error()}}
+	}
+	op, e := selectOperation(doc, request.OperationName)
+	if e != nil {
+		return nil, []*Error{e}
+	}
+	if op.Type != "query" {
+		return nil, []*Error{errorf(op.Pos, "%s operations aren't supported", op.Type)}
+	}
+
+	v := &validator{schema: s, doc: doc, definitions: map[string]*VariableDefinition{}, used: map[string]bool{}}
+	variables := v.variables(op, request.Variables)
+	if len(v.errors) > 0 {
+		return nil, v.errors
+	}
+	v.variableValues = variables
+	v.directives(op.Directives, false)
+	complexity, depth := v.selections(s.query, op.Selections)
+	v.mergeable(s.query, op.Selections)
+	for _, definition := range op.Variables {
+		if !v.used[definition.Name] {
+			v.errorf(definition.Pos, "variable $%s is never used", definition.Name)
+		}
+	}
+	for _, fragment := range doc.Fragments {
+		if !v.used["..."+fragment.Name] {
+			v.errorf(fragment.Pos, "fragment %s is never used", fragment.Name)
+		}
+	}
+	if len(v.errors) > 0 {
+		return nil, v.errors
+	}
+
+	if limits.MaxDepth > 0 && depth > limits.MaxDepth {
+		return nil, []*Error{errorf(op.Pos, "query depth %d exceeds the limit of %d", depth, limits.MaxDepth)}
+	}
+	if limits.MaxComplexity > 0 && complexity > limits.MaxComplexity {
+		return nil, []*Error{errorf(op.Pos, "query complexity %d exceeds the limit of %d", complexity, limits.MaxComplexity)}
+	}
+	return &operation{Operation: op, fragments: doc.Fragments, variables: variables}, nil
+}
+
+func selectOperation(doc *Document, name string) (*Operation, *Error) {
+	names := map[string]bool{}
+	var selected *Operation
+	for _, op := range doc.Operations {
+		if op.Name == "" && len(doc.Operations) > 1 {
+			return nil, errorf(op.Pos, "an anonymous operation must be the only operation in the document")
+		}
+		if names[op.Name] {
+			return nil, errorf(op.Pos, "there can be only one operation named %q", op.Name)
+		}
+		names[op.Name] = true
+		if name == "" || op.Name == name {
+			selected = op
+		}
+	}
+	switch {
+	case name == "" && len(doc.Operations) > 1:
+		return nil, &Error{Message: "operationName is required when the document has several operations"}
+	case selected == nil:
+		return nil, &Error{Message: fmt.Sprintf("unknown operation %q", name)}
+	}
+	return selected, nil
+}
+
+type validator struct {
+	schema         *Schema
+	doc            *Document
+	definitions    map[string]*VariableDefinition
+	variableValues map[string]interface{}
+	// used holds the names of used variables, and of used fragments
+	// prefixed with "...".
+	used      map[string]bool
+	spreading []string
+	errors    []*Error
+}
+
+func (v *validator) errorf(pos Location, format string, args ...interface{}) {
+	v.errors = append(v.errors, errorf(pos, format, args...))
+}
+
+// variables validates the variable definitions of op and coerces the
+// request's values, or the defaults, to their types. Variables without a
+// value or a default are left out.
+func (v *validator) variables(op *Operation, values map[string]interface{}) map[string]interface{} {
+	coerced := map[string]interface{}{}
+	for _, definition := range op.Variables {
+		if _, ok := v.definitions[definition.Name]; ok {
+			v.errorf(definition.Pos, "there can be only one variable named $%s", definition.Name)
+			continue
+		}
+		v.definitions[definition.Name] = definition
+		t, ok := v.schema.types[definition.Type.named()]
+		if !ok {
+			v.errorf(definition.Pos, "unknown type %s", definition.Type.named())
+			continue
+		}
+		if _, ok := t.(*Object); ok {
+			v.errorf(definition.Pos, "variable $%s can't be of output type %s", definition.Name, definition.Type)
+			continue
+		}
+
+		value, provided := values[definition.Name]
+		var err error
+		switch {
+		case provided:
+			value, err = v.schema.coerceInput(value, definition.Type)
+		case definition.Default != nil:
+			value, err = v.schema.coerceLiteral(definition.Default, definition.Type, nil)
+		case definition.Type.NonNull:
+			err = fmt.Errorf("a value of type %s is required", definition.Type)
+		default:
+			continue
+		}
+		if err != nil {
+			v.errorf(definition.Pos, "variable $%s: %v", definition.Name, err)
+			continue
+		}
+		coerced[definition.Name] = value
+	}
+	return coerced
+}
+
+// selections validates a selection set on object and returns its
+// complexity and depth.
+func (v *validator) selections(object *Object, selections []Selection) (complexity, depth int) {
+	for _, selection := range selections {
+		var c, d int
+		switch selection := selection.(type) {
+		case *Field:
+			c, d = v.field(object, selection)
+		case *InlineFragment:
+			v.directives(selection.Directives, true)
+			if selection.TypeCondition != "" && !v.typeCondition(object, selection.TypeCondition, selection.Pos) {
+				continue
+			}
+			c, d = v.selections(object, selection.Selections)
+		case *FragmentSpread:
+			c, d = v.spread(object, selection)
+		}
+		complexity += c
+		depth = max(depth, d)
+	}
+	return complexity, depth
+}
+
+func (v *validator) field(object *Object, field *Field) (complexity, depth int) {
+	v.directives(field.Directives, true)
+	if field.Name == "__typename" {
+		if len(field.Arguments) > 0 || len(field.Selections) > 0 {
+			v.errorf(field.Pos, "__typename has no arguments or fields")
+		}
+		return 0, 0
+	}
+	definition, ok := object.fields[field.Name]
+	if !ok {
+		v.errorf(field.Pos, "cannot query field %q on type %s", field.Name, object.Name)
+		return 0, 0
+	}
+	args := v.arguments(object, definition, field)
+
+	child, isObject := v.schema.types[definition.typ.named()].(*Object)
+	switch {
+	case isObject && len(field.Selections) == 0:
+		v.errorf(field.Pos, "field %q of type %s must have a selection of subfields", field.Name, definition.Type)
+		return 1, 1
+	case !isObject && len(field.Selections) > 0:
+		v.errorf(field.Pos, "field %q of type %s can't have a selection of subfields", field.Name, definition.Type)
+		return 1, 1
+	case !isObject:
+		return 1, 1
+	}
+
+	complexity, depth = v.selections(child, field.Selections)
+	v.mergeable(child, field.Selections)
+	if definition.typ.Elem != nil {
+		size := DefaultListSize
+		if limit, ok := args["limit"].(int); ok {
+			size = max(limit, 0)
+		}
+		complexity *= size
+	}
+	return 1 + complexity, 1 + depth
+}
+
+// arguments validates the arguments of field and returns their values, as
+// far as they are known before execution.
+func (v *validator) arguments(object *Object, definition *FieldDef, field *Field) map[string]interface{} {
+	values := map[string]interface{}{}
+	for _, arg := range definition.Args {
+		if arg.defaultValue != nil {
+			values[arg.Name] = arg.defaultValue
+		}
+	}
+	seen := map[string]bool{}
+	for _, argument := range field.Arguments {
+		arg, ok := definition.args[argument.Name]
+		if !ok {
+			v.errorf(argument.Pos, "unknown argument %q on field %s.%s", argument.Name, object.Name, field.Name)
+			continue
+		}
+		if seen[argument.Name] {
+			v.errorf(argument.Pos, "there can be only one argument named %q", argument.Name)
+			continue
+		}
+		seen[argument.Name] = true
+		if !v.value(argument.Value, arg.typ, arg.Default != "") {
+			continue
+		}
+		if argument.Value.Kind == KindVariable {
+			if _, ok := v.variableValues[argument.Value.Raw]; !ok {
+				continue
+			}
+		}
+		value, err := v.schema.coerceLiteral(argument.Value, arg.typ, v.variableValues)
+		if err != nil {
+			v.errorf(argument.Pos, "argument %q: %v", argument.Name, err)
+			continue
+		}
+		values[argument.Name] = value
+	}
+	for _, arg := range definition.Args {
+		if arg.typ.NonNull && arg.Default == "" && !seen[arg.Name] {
+			v.errorf(field.Pos, "field %s.%s requires argument %q of type %s", object.Name, field.Name, arg.Name, arg.Type)
+		}
+	}
+	return values
+}
+
+// value checks the variables used in value: they must be defined with a
+// type that fits where they're used.
+func (v *validator) value(value *Value, t *TypeRef, hasDefault bool) bool {
+	switch {
+	case value.Kind == KindVariable:
+		v.used[value.Raw] = true
+		definition, ok := v.definitions[value.Raw]
+		if !ok {
+			v.errorf(value.Pos, "variable $%s is not defined", value.Raw)
+			return false
+		}
+		if !fits(definition.Type, t, hasDefault || definition.Default != nil) {
+			v.errorf(value.Pos, "variable $%s of type %s can't be used as %s", value.Raw, definition.Type, t)
+			return false
+		}
+	case value.Kind == KindList && t.Elem != nil:
+		ok := true
+		for _, item := range value.List {
+			ok = v.value(item, t.Elem, false) && ok
+		}
+		return ok
+	case t.Elem != nil:
+		return v.value(value, t.Elem, false)
+	}
+	return true
+}
+
+// fits reports whether a variable of type variable can be used where the
+// type expected is; a nullable variable fits a non-null position when it,
+// or the position, has a default.
+func fits(variable, expected *TypeRef, hasDefault bool) bool {
+	if expected.NonNull && !variable.NonNull && !hasDefault {
+		return false
+	}
+	if expected.Elem != nil {
+		return variable.Elem != nil && fits(variable.Elem, expected.Elem, false)
+	}
+	return variable.Elem == nil && variable.Name == expected.Name
+}
+
+func (v *validator) spread(object *Object, spread *FragmentSpread) (complexity, depth int) {
+	v.directives(spread.Directives, true)
+	fragment, ok := v.doc.Fragments[spread.Name]
+	if !ok {
+		v.errorf(spread.Pos, "unknown fragment %s", spread.Name)
+		return 0, 0
+	}
+	v.used["..."+spread.Name] = true
+	for _, name := range v.spreading {
+		if name == spread.Name {
+			v.errorf(spread.Pos, "fragment %s spreads itself", spread.Name)
+			return 0, 0
+		}
+	}
+	if len(fragment.Directives) > 0 {
+		v.errorf(fragment.Directives[0].Pos, "directives aren't supported on fragment definitions")
+	}
+	if !v.typeCondition(object, fragment.TypeCondition, spread.Pos) {
+		return 0, 0
+	}
+	v.spreading = append(v.spreading, spread.Name)
+	defer func() { v.spreading = v.spreading[:len(v.spreading)-1] }()
+	return v.selections(object, fragment.Selections)
+}
+
+func (v *validator) typeCondition(object *Object, name string, pos Location) bool {
+	if _, ok := v.schema.types[name].(*Object); !ok {
+		v.errorf(pos, "unknown type %s", name)
+		return false
+	}
+	if name != object.Name {
+		v.errorf(pos, "a fragment on %s can't be spread within %s", name, object.Name)
+		return false
+	}
+	return true
+}
+
+// directives validates @include and @skip, the only directives supported,
+// which are only allowed on fields and fragment spreads.
+func (v *validator) directives(directives []*Directive, allowed bool) {
+	for _, directive := range directives {
+		if directive.Name != "include" && directive.Name != "skip" {
+			v.errorf(directive.Pos, "unknown directive @%s", directive.Name)
+			continue
+		}
+		if !allowed {
+			v.errorf(directive.Pos, "directive @%s isn't allowed here", directive.Name)
+			continue
+		}
+		if len(directive.Arguments) != 1 || directive.Arguments[0].Name != "if" {
+			v.errorf(directive.Pos, "directive @%s requires exactly one argument \"if\" of type Boolean!", directive.Name)
+			continue
+		}
+		value := directive.Arguments[0].Value
+		if v.value(value, &TypeRef{Name: "Boolean", NonNull: true}, false) && value.Kind != KindVariable && value.Kind != KindBoolean {
+			v.errorf(value.Pos, "directive @%s: expected Boolean!", directive.Name)
+		}
+	}
+}
+
+// mergeable checks that the fields selected under the same response key,
+// directly or through fragments, are the same field with the same
+// arguments, so that they can be merged into a single result.
+func (v *validator) mergeable(object *Object, selections []Selection) {
+	fields := map[string]*Field{}
+	var visit func(selections []Selection, spreading map[string]bool)
+	visit = func(selections []Selection, spreading map[string]bool) {
+		for _, selection := range selections {
+			switch selection := selection.(type) {
+			case *Field:
+				key := selection.ResponseKey()
+				other, ok := fields[key]
+				if !ok {
+					fields[key] = selection
+				} else if other.Name != selection.Name || printArguments(other.Arguments) != printArguments(selection.Arguments) {
+					v.errorf(selection.Pos, "fields %q conflict because they select different fields or arguments; use aliases", key)
+				}
+			case *InlineFragment:
+				if selection.TypeCondition == "" || selection.TypeCondition == object.Name {
+					visit(selection.Selections, spreading)
+				}
+			case *FragmentSpread:
+				fragment, ok := v.doc.Fragments[selection.Name]
+				if ok && !spreading[selection.Name] && fragment.TypeCondition == object.Name {
+					spreading[selection.Name] = true
+					visit(fragment.Selections, spreading)
+					delete(spreading, selection.Name)
+				}
+			}
+		}
+	}
+	visit(selections, map[string]bool{})
+}
+
+func printArguments(arguments []*Argument) string {
+	printed := make([]string, len(arguments))
+	for i, argument := range arguments {
+		printed[i] = argument.Name + ":" + printValue(argument.Value)
+	}
+	return strings.Join(printed, ",")
+}
+
+func printValue(value *Value) string {
+	switch value.Kind {
+	case KindVariable:
+		return "$" + value.Raw
+	case KindString:
+		return fmt.Sprintf("%q", value.Raw)
+	case KindList:
+		items := make([]string, len(value.List))
+		for i, item := range value.List {
+			items[i] = printValue(item)
+		}
+		return "[" + strings.Join(items, ",") + "]"
+	case KindObject:
+		fields := make([]string, len(value.Fields))
+		for i, field := range value.Fields {
+			fields[i] = field.Name + ":" + printValue(field.Value)
+		}
+		return "{" + strings.Join(fields, ",") + "}"
+	}
+	return value.Raw
+}
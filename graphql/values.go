@@ -0,0 +1,115 @@
+package graphql
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// enumLiteral is an unquoted name in the document. Only enums accept it, so
+// scalars can't be given FOO where they expect "FOO".
+type enumLiteral string
+
+// coerceLiteral converts a value written in the document to the input type
+// t. Variables are looked up in vars, which hold already coerced values.
+func (s *Schema) coerceLiteral(value *Value, t *TypeRef, vars map[string]interface{}) (interface{}, error) {
+	if value.Kind == KindVariable {
+		v := vars[value.Raw]
+		if v == nil && t.NonNull {
+			return nil, fmt.Errorf("expected %s, found null", t)
+		}
+		return v, nil
+	}
+	if value.Kind == KindNull {
+		if t.NonNull {
+			return nil, fmt.Errorf("expected %s, found null", t)
+		}
+		return nil, nil
+	}
+	if t.Elem != nil {
+		if value.Kind != KindList {
+			item, err := s.coerceLiteral(value, t.Elem, vars)
+			if err != nil {
+				return nil, err
+			}
+			return []interface{}{item}, nil
+		}
+		list := make([]interface{}, len(value.List))
+		for i, item := range value.List {
+			var err error
+			if list[i], err = s.coerceLiteral(item, t.Elem, vars); err != nil {
+				return nil, err
+			}
+		}
+		return list, nil
+	}
+
+	switch named := s.types[t.Name].(type) {
+	case *Enum:
+		if value.Kind != KindEnum {
+			return nil, fmt.Errorf("%s must be one of %s", named.Name, strings.Join(named.names(), ", "))
+		}
+		return named.parse(value.Raw)
+	case *Scalar:
+		var v interface{}
+		switch value.Kind {
+		case KindInt, KindFloat:
+			v = json.Number(value.Raw)
+		case KindString:
+			v = value.Raw
+		case KindBoolean:
+			v = value.Raw == "true"
+		case KindEnum:
+			v = enumLiteral(value.Raw)
+		default:
+			return nil, fmt.Errorf("expected %s", t)
+		}
+		return named.Parse(v)
+	}
+	return nil, fmt.Errorf("%s isn't an input type", t.Name)
+}
+
+// coerceInput converts a variable value decoded from JSON to the input type
+// t. Numbers are expected as json.Number, but float64 is accepted too.
+func (s *Schema) coerceInput(value interface{}, t *TypeRef) (interface{}, error) {
+	if value == nil {
+		if t.NonNull {
+			return nil, fmt.Errorf("expected %s, found null", t)
+		}
+		return nil, nil
+	}
+	if t.Elem != nil {
+		items, ok := value.([]interface{})
+		if !ok {
+			item, err := s.coerceInput(value, t.Elem)
+			if err != nil {
+				return nil, err
+			}
+			return []interface{}{item}, nil
+		}
+		list := make([]interface{}, len(items))
+		for i, item := range items {
+			var err error
+			if list[i], err = s.coerceInput(item, t.Elem); err != nil {
+				return nil, fmt.Errorf("item %d: %w", i, err)
+			}
+		}
+		return list, nil
+	}
+
+	if f, ok := value.(float64); ok {
+		value = json.Number(strconv.FormatFloat(f, 'f', -1, 64))
+	}
+	switch named := s.types[t.Name].(type) {
+	case *Enum:
+		return named.parse(value)
+	case *Scalar:
+		switch value.(type) {
+		case []interface{}, map[string]interface{}:
+			return nil, fmt.Errorf("expected %s", t)
+		}
+		return named.Parse(value)
+	}
+	return nil, fmt.Errorf("%s isn't an input type", t.Name)
+}
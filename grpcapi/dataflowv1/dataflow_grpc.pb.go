@@ -0,0 +1,259 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             (unknown)
+// source: dataflow/v1/dataflow.proto
+
+package dataflowv1
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	DataService_AddSale_FullMethodName   = "/dataflow.v1.DataService/AddSale"
+	DataService_GetSale_FullMethodName   = "/dataflow.v1.DataService/GetSale"
+	DataService_ListSales_FullMethodName = "/dataflow.v1.DataService/ListSales"
+	DataService_Calculate_FullMethodName = "/dataflow.v1.DataService/Calculate"
+)
+
+// DataServiceClient is the client API for DataService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// DataService mirrors the sales endpoints of the HTTP API. Callers
+// authenticate with an "x-api-key" or "authorization: Bearer <JWT>" metadata
+// entry and are subject to the same roles and store scopes.
+type DataServiceClient interface {
+	// AddSale stores a sale and returns it with its assigned ID.
+	AddSale(ctx context.Context, in *AddSaleRequest, opts ...grpc.CallOption) (*Sale, error)
+	// GetSale returns the sale with the given ID.
+	GetSale(ctx context.Context, in *GetSaleRequest, opts ...grpc.CallOption) (*Sale, error)
+	// ListSales streams every sale the caller may read, or the sales of one
+	// store in a date range.
+	ListSales(ctx context.Context, in *ListSalesRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[Sale], error)
+	// Calculate computes every operation for every store, like a batch
+	// POST /calculate.
+	Calculate(ctx context.Context, in *CalculateRequest, opts ...grpc.CallOption) (*CalculateResponse, error)
+}
+
+type dataServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewDataServiceClient(cc grpc.ClientConnInterface) DataServiceClient {
+	return &dataServiceClient{cc}
+}
+
+func (c *dataServiceClient) AddSale(ctx context.Context, in *AddSaleRequest, opts ...grpc.CallOption) (*Sale, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Sale)
+	err := c.cc.Invoke(ctx, DataService_AddSale_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *dataServiceClient) GetSale(ctx context.Context, in *GetSaleRequest, opts ...grpc.CallOption) (*Sale, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Sale)
+	err := c.cc.Invoke(ctx, DataService_GetSale_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *dataServiceClient) ListSales(ctx context.Context, in *ListSalesRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[Sale], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &DataService_ServiceDesc.Streams[0], DataService_ListSales_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[ListSalesRequest, Sale]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type DataService_ListSalesClient = grpc.ServerStreamingClient[Sale]
+
+func (c *dataServiceClient) Calculate(ctx context.Context, in *CalculateRequest, opts ...grpc.CallOption) (*CalculateResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(CalculateResponse)
+	err := c.cc.Invoke(ctx, DataService_Calculate_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// DataServiceServer is the server API for DataService service.
+// All implementations must embed UnimplementedDataServiceServer
+// for forward compatibility.
+//
+// DataService mirrors the sales endpoints of the HTTP API. Callers
+// authenticate with an "x-api-key" or "authorization: Bearer <JWT>" metadata
+// entry and are subject to the same roles and store scopes.
+type DataServiceServer interface {
+	// AddSale stores a sale and returns it with its assigned ID.
+	AddSale(context.Context, *AddSaleRequest) (*Sale, error)
+	// GetSale returns the sale with the given ID.
+	GetSale(context.Context, *GetSaleRequest) (*Sale, error)
+	// ListSales streams every sale the caller may read, or the sales of one
+	// store in a date range.
+	ListSales(*ListSalesRequest, grpc.ServerStreamingServer[Sale]) error
+	// Calculate computes every operation for every store, like a batch
+	// POST /calculate.
+	Calculate(context.Context, *CalculateRequest) (*CalculateResponse, error)
+	mustEmbedUnimplementedDataServiceServer()
+}
+
+// UnimplementedDataServiceServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedDataServiceServer struct{}
+
+func (UnimplementedDataServiceServer) AddSale(context.Context, *AddSaleRequest) (*Sale, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method AddSale not implemented")
+}
+func (UnimplementedDataServiceServer) GetSale(context.Context, *GetSaleRequest) (*Sale, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetSale not implemented")
+}
+func (UnimplementedDataServiceServer) ListSales(*ListSalesRequest, grpc.ServerStreamingServer[Sale]) error {
+	return status.Errorf(codes.Unimplemented, "method ListSales not implemented")
+}
+func (UnimplementedDataServiceServer) Calculate(context.Context, *CalculateRequest) (*CalculateResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Calculate not implemented")
+}
+func (UnimplementedDataServiceServer) mustEmbedUnimplementedDataServiceServer() {}
+func (UnimplementedDataServiceServer) testEmbeddedByValue()                     {}
+
+// UnsafeDataServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to DataServiceServer will
+// result in compilation errors.
+type UnsafeDataServiceServer interface {
+	mustEmbedUnimplementedDataServiceServer()
+}
+
+func RegisterDataServiceServer(s grpc.ServiceRegistrar, srv DataServiceServer) {
+	// If the following call pancis, it indicates UnimplementedDataServiceServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&DataService_ServiceDesc, srv)
+}
+
+func _DataService_AddSale_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AddSaleRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DataServiceServer).AddSale(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: DataService_AddSale_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DataServiceServer).AddSale(ctx, req.(*AddSaleRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _DataService_GetSale_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetSaleRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DataServiceServer).GetSale(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: DataService_GetSale_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DataServiceServer).GetSale(ctx, req.(*GetSaleRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _DataService_ListSales_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(ListSalesRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(DataServiceServer).ListSales(m, &grpc.GenericServerStream[ListSalesRequest, Sale]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type DataService_ListSalesServer = grpc.ServerStreamingServer[Sale]
+
+func _DataService_Calculate_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CalculateRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DataServiceServer).Calculate(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: DataService_Calculate_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DataServiceServer).Calculate(ctx, req.(*CalculateRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// DataService_ServiceDesc is the grpc.ServiceDesc for DataService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var DataService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "dataflow.v1.DataService",
+	HandlerType: (*DataServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "AddSale",
+			Handler:    _DataService_AddSale_Handler,
+		},
+		{
+			MethodName: "GetSale",
+			Handler:    _DataService_GetSale_Handler,
+		},
+		{
+			MethodName: "Calculate",
+			Handler:    _DataService_Calculate_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "ListSales",
+			Handler:       _DataService_ListSales_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "dataflow/v1/dataflow.proto",
+}
@@ -0,0 +1,270 @@
+// Package grpcapi serves services.DataService over gRPC. The service is
+// defined in proto/dataflow/v1/dataflow.proto; the generated code lives in
+// the dataflowv1 package.
+package grpcapi
+
+//go:generate protoc -I ../proto --go_out=.. --go_opt=module=dataflow --go-grpc_out=.. --go-grpc_opt=module=dataflow dataflow/v1/dataflow.proto
+
+import (
+	"context"
+	"dataflow/auth"
+	"dataflow/grpcapi/dataflowv1"
+	"dataflow/logging"
+	"dataflow/metrics"
+	"dataflow/models"
+	"dataflow/repo"
+	"dataflow/requestid"
+	"dataflow/services"
+	"errors"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"log/slog"
+	"strings"
+	"time"
+)
+
+// Options configures the interceptors of NewServer.
+type Options struct {
+	Logger  *slog.Logger
+	Metrics *metrics.Metrics
+	// Authenticator checks the credentials of every call. When it is nil,
+	// every call acts as auth.AnonymousPrincipal.
+	Authenticator *auth.Authenticator
+}
+
+// healthService is answered without credentials, like /healthz and /readyz,
+// so that it can be used as a Kubernetes gRPC probe.
+var healthService = "/" + healthpb.Health_ServiceDesc.ServiceName + "/"
+
+// NewServer returns a gRPC server for service with the same interceptors as
+// the HTTP API's middleware, in the same order: recovery, request IDs,
+// logging, metrics and authentication. The standard health service can be
+// registered on it and isn't authenticated.
+func NewServer(service services.DataService, options Options) *grpc.Server {
+	authUnary, authStream := auth.AnonymousUnaryServerInterceptor(), auth.AnonymousStreamServerInterceptor()
+	if options.Authenticator != nil {
+		authUnary, authStream = options.Authenticator.UnaryServerInterceptor(), options.Authenticator.StreamServerInterceptor()
+	}
+	authUnary, authStream = skipHealthUnary(authUnary), skipHealthStream(authStream)
+	server := grpc.NewServer(
+		grpc.ChainUnaryInterceptor(
+			logging.RecoveryUnaryServerInterceptor(options.Logger),
+			requestid.UnaryServerInterceptor(),
+			logging.UnaryServerInterceptor(options.Logger),
+			options.Metrics.UnaryServerInterceptor(),
+			authUnary,
+		),
+		grpc.ChainStreamInterceptor(
+			logging.RecoveryStreamServerInterceptor(options.Logger),
+			requestid.StreamServerInterceptor(),
+			logging.StreamServerInterceptor(options.Logger),
+			options.Metrics.StreamServerInterceptor(),
+			authStream,
+		),
+	)
+	dataflowv1.RegisterDataServiceServer(server, &dataServiceServer{service: service})
+	return server
+}
+
+func skipHealthUnary(interceptor grpc.UnaryServerInterceptor) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if strings.HasPrefix(info.FullMethod, healthService) {
+			return handler(ctx, req)
+		}
+		return interceptor(ctx, req, info, handler)
+	}
+}
+
+func skipHealthStream(interceptor grpc.StreamServerInterceptor) grpc.StreamServerInterceptor {
+	return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if strings.HasPrefix(info.FullMethod, healthService) {
+			return handler(srv, stream)
+		}
+		return interceptor(srv, stream, info, handler)
+	}
+}
+
+type dataServiceServer struct {
+	dataflowv1.UnimplementedDataServiceServer
+	service services.DataService
+}
+
+func (s *dataServiceServer) AddSale(ctx context.Context, request *dataflowv1.AddSaleRequest) (*dataflowv1.Sale, error) {
+	sale := request.GetSale()
+	switch {
+	case sale == nil:
+		return nil, status.Error(codes.InvalidArgument, "sale is required")
+	case sale.GetProductId() == "":
+		return nil, status.Error(codes.InvalidArgument, "sale.product_id is required")
+	case sale.GetStoreId() == "":
+		return nil, status.Error(codes.InvalidArgument, "sale.store_id is required")
+	case sale.GetSaleDate() == nil:
+		return nil, status.Error(codes.InvalidArgument, "sale.sale_date is required")
+	}
+	saleDate, err := toTime(sale.GetSaleDate(), "sale.sale_date")
+	if err != nil {
+		return nil, err
+	}
+	added := &models.Sale{
+		ProductId:    sale.GetProductId(),
+		StoreId:      sale.GetStoreId(),
+		QuantitySold: int(sale.GetQuantitySold()),
+		SalePrice:    sale.GetSalePrice(),
+		SaleDate:     saleDate,
+	}
+	if err := s.service.AddSale(ctx, added); err != nil {
+		return nil, toStatus(err)
+	}
+	return fromSale(added), nil
+}
+
+func (s *dataServiceServer) GetSale(ctx context.Context, request *dataflowv1.GetSaleRequest) (*dataflowv1.Sale, error) {
+	if request.GetId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "id is required")
+	}
+	sale, err := s.service.GetSale(ctx, request.GetId())
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return fromSale(sale), nil
+}
+
+func (s *dataServiceServer) ListSales(request *dataflowv1.ListSalesRequest, stream dataflowv1.DataService_ListSalesServer) error {
+	ctx := stream.Context()
+	startDate, err := toTime(request.GetStartDate(), "start_date")
+	if err != nil {
+		return err
+	}
+	endDate, err := toEnd(request.GetEndDate(), request.GetRange())
+	if err != nil {
+		return err
+	}
+
+	var sales []*models.Sale
+	if request.GetStoreId() == "" {
+		if !startDate.IsZero() || !endDate.IsZero() {
+			return status.Error(codes.InvalidArgument, "start_date and end_date require a store_id")
+		}
+		sales, err = s.service.GetAllSales(ctx)
+	} else {
+		sales, err = s.service.GetSalesInRange(ctx, startDate, endDate, request.GetStoreId())
+	}
+	if err != nil {
+		return toStatus(err)
+	}
+	for _, sale := range sales {
+		if err := stream.Send(fromSale(sale)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *dataServiceServer) Calculate(ctx context.Context, request *dataflowv1.CalculateRequest) (*dataflowv1.CalculateResponse, error) {
+	if len(request.GetStoreIds()) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "store_ids are required")
+	}
+	if len(request.GetOperations()) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "operations are required")
+	}
+	startDate, err := toTime(request.GetStartDate(), "start_date")
+	if err != nil {
+		return nil, err
+	}
+	endDate, err := toEnd(request.GetEndDate(), request.GetRange())
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := s.service.CalculateBatch(ctx, startDate, endDate, request.GetStoreIds(), request.GetOperations())
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	response := &dataflowv1.CalculateResponse{
+		StoreIds:   result.StoreIds,
+		Operations: result.Operations,
+		Results:    make(map[string]*dataflowv1.StoreResults, len(result.Cells)),
+	}
+	for storeId, cells := range result.Cells {
+		storeResults := &dataflowv1.StoreResults{Operations: make(map[string]*dataflowv1.CalculationCell, len(cells))}
+		for operation, cell := range cells {
+			storeResults.Operations[operation] = fromCell(cell)
+		}
+		response.Results[storeId] = storeResults
+	}
+	return response, nil
+}
+
+// toStatus maps service errors to the gRPC codes matching the HTTP API's
+// status codes.
+func toStatus(err error) error {
+	switch {
+	case errors.Is(err, services.ErrForbidden):
+		return status.Error(codes.PermissionDenied, err.Error())
+	case errors.Is(err, services.ErrWrongDate):
+		return status.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, repo.ErrSaleNotFound):
+		return status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, repo.ErrSaleAlreadyExists):
+		return status.Error(codes.AlreadyExists, err.Error())
+	case errors.Is(err, services.ErrUnknownReference):
+		return status.Error(codes.FailedPrecondition, err.Error())
+	case errors.Is(err, context.Canceled):
+		return status.Error(codes.Canceled, err.Error())
+	case errors.Is(err, context.DeadlineExceeded):
+		return status.Error(codes.DeadlineExceeded, err.Error())
+	default:
+		return status.Error(codes.Internal, err.Error())
+	}
+}
+
+// toTime converts an optional timestamp; nil is the zero time, which leaves
+// a range open.
+func toTime(timestamp *timestamppb.Timestamp, field string) (time.Time, error) {
+	if timestamp == nil {
+		return time.Time{}, nil
+	}
+	if err := timestamp.CheckValid(); err != nil {
+		return time.Time{}, status.Errorf(codes.InvalidArgument, "%s: %v", field, err)
+	}
+	return timestamp.AsTime(), nil
+}
+
+// toEnd converts the optional end_date of a range, moving it past the
+// timestamp when the range is closed as repo.EndOf does.
+func toEnd(timestamp *timestamppb.Timestamp, bounds dataflowv1.RangeBounds) (time.Time, error) {
+	end, err := toTime(timestamp, "end_date")
+	if err != nil {
+		return time.Time{}, err
+	}
+	switch bounds {
+	case dataflowv1.RangeBounds_RANGE_BOUNDS_UNSPECIFIED, dataflowv1.RangeBounds_RANGE_BOUNDS_HALF_OPEN:
+		return repo.EndOf(end, repo.RangeHalfOpen), nil
+	case dataflowv1.RangeBounds_RANGE_BOUNDS_CLOSED:
+		return repo.EndOf(end, repo.RangeClosed), nil
+	default:
+		return time.Time{}, status.Errorf(codes.InvalidArgument, "range: unknown bounds %d", bounds)
+	}
+}
+
+func fromSale(sale *models.Sale) *dataflowv1.Sale {
+	return &dataflowv1.Sale{
+		Id:           sale.ID,
+		ProductId:    sale.ProductId,
+		StoreId:      sale.StoreId,
+		QuantitySold: int64(sale.QuantitySold),
+		SalePrice:    sale.SalePrice,
+		SaleDate:     timestamppb.New(sale.SaleDate),
+	}
+}
+
+func fromCell(cell models.CalculationCell) *dataflowv1.CalculationCell {
+	if cell.Error != "" || cell.Value == nil {
+		return &dataflowv1.CalculationCell{Result: &dataflowv1.CalculationCell_Error{Error: cell.Error}}
+	}
+	// big.Float marshals to JSON with the same format.
+	return &dataflowv1.CalculationCell{Result: &dataflowv1.CalculationCell_Value{Value: cell.Value.Text('g', -1)}}
+}
@@ -0,0 +1,368 @@
+package grpcapi
+
+import (
+	"bytes"
+	"context"
+	"dataflow/auth"
+	"dataflow/grpcapi/dataflowv1"
+	"dataflow/logging"
+	"dataflow/metrics"
+	"dataflow/repo"
+	"dataflow/requestid"
+	"dataflow/services"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+const (
+	adminKey       = "admin-secret"
+	managerKey     = "manager-secret"
+	integrationKey = "integration-secret"
+	disabledKey    = "disabled-secret"
+)
+
+type testServer struct {
+	client  dataflowv1.DataServiceClient
+	health  healthpb.HealthClient
+	metrics *metrics.Metrics
+	logs    *syncBuffer
+}
+
+// syncBuffer collects log output written by the server's goroutines.
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func newKeyStore(t *testing.T) *auth.KeyStore {
+	keys, err := auth.NewKeyStore([]auth.APIKey{
+		{ID: "admin", Hash: auth.HashAPIKey(adminKey), Subject: "admin", Roles: []string{auth.RoleAdmin}},
+		{ID: "manager", Hash: auth.HashAPIKey(managerKey), Subject: "manager", Roles: []string{auth.RoleStoreManager}, Stores: []string{"6789"}},
+		{ID: "integration", Hash: auth.HashAPIKey(integrationKey), Subject: "pos", Roles: []string{auth.RoleIntegration}},
+		{ID: "old", Hash: auth.HashAPIKey(disabledKey), Subject: "retired", Disabled: true},
+	})
+	assert.NoError(t, err)
+	return keys
+}
+
+// setupServer serves the data service over an in-process bufconn listener.
+func setupServer(t *testing.T, authenticator *auth.Authenticator) *testServer {
+	logs := &syncBuffer{}
+	logger, err := logging.New(logs, logging.Config{Level: "info"})
+	assert.NoError(t, err)
+	m := metrics.New()
+	service := services.NewAuthorizingDataService(services.NewDataService(repo.NewInMemoryRepository()))
+	server := NewServer(service, Options{Logger: logger, Metrics: m, Authenticator: authenticator})
+	healthpb.RegisterHealthServer(server, health.NewServer())
+
+	listener := bufconn.Listen(1 << 20)
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufconn",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return listener.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	assert.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	return &testServer{client: dataflowv1.NewDataServiceClient(conn), health: healthpb.NewHealthClient(conn), metrics: m, logs: logs}
+}
+
+func withKey(key string) context.Context {
+	return metadata.AppendToOutgoingContext(context.Background(), "x-api-key", key)
+}
+
+func newSale(storeId string, quantity int64, price float64, date time.Time) *dataflowv1.AddSaleRequest {
+	return &dataflowv1.AddSaleRequest{Sale: &dataflowv1.Sale{
+		ProductId:    "12345",
+		StoreId:      storeId,
+		QuantitySold: quantity,
+		SalePrice:    price,
+		SaleDate:     timestamppb.New(date),
+	}}
+}
+
+// addSales adds sales to stores 6789 and 9876 and returns their IDs.
+func addSales(t *testing.T, client dataflowv1.DataServiceClient) []string {
+	var ids []string
+	for _, request := range []*dataflowv1.AddSaleRequest{
+		newSale("6789", 10, 19.99, time.Date(2024, 6, 15, 14, 30, 0, 0, time.UTC)),
+		newSale("6789", 2, 5, time.Date(2024, 6, 10, 9, 0, 0, 0, time.UTC)),
+		newSale("9876", 5, 9.99, time.Date(2024, 6, 16, 10, 0, 0, 0, time.UTC)),
+	} {
+		sale, err := client.AddSale(withKey(integrationKey), request)
+		assert.NoError(t, err)
+		ids = append(ids, sale.GetId())
+	}
+	return ids
+}
+
+func listSales(ctx context.Context, client dataflowv1.DataServiceClient, request *dataflowv1.ListSalesRequest) ([]*dataflowv1.Sale, error) {
+	stream, err := client.ListSales(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+	var sales []*dataflowv1.Sale
+	for {
+		sale, err := stream.Recv()
+		if err == io.EOF {
+			return sales, nil
+		}
+		if err != nil {
+			return sales, err
+		}
+		sales = append(sales, sale)
+	}
+}
+
+func TestAddSaleAndGetSale(t *testing.T) {
+	server := setupServer(t, auth.NewAuthenticator(newKeyStore(t), nil))
+	date := time.Date(2024, 6, 15, 14, 30, 0, 0, time.UTC)
+
+	added, err := server.client.AddSale(withKey(integrationKey), newSale("6789", 10, 19.99, date))
+	assert.NoError(t, err)
+	assert.NotEmpty(t, added.GetId())
+
+	sale, err := server.client.GetSale(withKey(managerKey), &dataflowv1.GetSaleRequest{Id: added.GetId()})
+	assert.NoError(t, err)
+	assert.Equal(t, added.GetId(), sale.GetId())
+	assert.Equal(t, "6789", sale.GetStoreId())
+	assert.Equal(t, int64(10), sale.GetQuantitySold())
+	assert.Equal(t, 19.99, sale.GetSalePrice())
+	assert.True(t, date.Equal(sale.GetSaleDate().AsTime()))
+}
+
+func TestAddSale_InvalidArgument(t *testing.T) {
+	server := setupServer(t, auth.NewAuthenticator(newKeyStore(t), nil))
+	date := time.Date(2024, 6, 15, 14, 30, 0, 0, time.UTC)
+
+	for message, request := range map[string]*dataflowv1.AddSaleRequest{
+		"sale is required":            {},
+		"sale.store_id is required":   newSale("", 1, 1, date),
+		"sale.sale_date is required":  {Sale: &dataflowv1.Sale{ProductId: "1", StoreId: "6789"}},
+		"sale.sale_date: proto:":      {Sale: &dataflowv1.Sale{ProductId: "1", StoreId: "6789", SaleDate: &timestamppb.Timestamp{Nanos: -1}}},
+		"sale.product_id is required": {Sale: &dataflowv1.Sale{StoreId: "6789", SaleDate: timestamppb.New(date)}},
+	} {
+		_, err := server.client.AddSale(withKey(adminKey), request)
+		assert.Equal(t, codes.InvalidArgument, status.Code(err), message)
+		assert.Contains(t, status.Convert(err).Message(), message)
+	}
+}
+
+func TestAuthentication(t *testing.T) {
+	server := setupServer(t, auth.NewAuthenticator(newKeyStore(t), nil))
+
+	_, err := server.client.GetSale(context.Background(), &dataflowv1.GetSaleRequest{Id: "1"})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	_, err = server.client.GetSale(withKey("wrong"), &dataflowv1.GetSaleRequest{Id: "1"})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "authorization", "Basic dXNlcg==")
+	_, err = server.client.GetSale(ctx, &dataflowv1.GetSaleRequest{Id: "1"})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	_, err = server.client.GetSale(withKey(disabledKey), &dataflowv1.GetSaleRequest{Id: "1"})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	_, err = listSales(context.Background(), server.client, &dataflowv1.ListSalesRequest{})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	response, err := server.health.Check(context.Background(), &healthpb.HealthCheckRequest{})
+	assert.NoError(t, err)
+	assert.Equal(t, healthpb.HealthCheckResponse_SERVING, response.GetStatus())
+}
+
+func TestAuthorization(t *testing.T) {
+	server := setupServer(t, auth.NewAuthenticator(newKeyStore(t), nil))
+	ids := addSales(t, server.client)
+
+	_, err := server.client.GetSale(withKey(managerKey), &dataflowv1.GetSaleRequest{Id: ids[2]})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	_, err = server.client.AddSale(withKey(managerKey), newSale("6789", 1, 1, time.Now()))
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	_, err = listSales(withKey(managerKey), server.client, &dataflowv1.ListSalesRequest{StoreId: "9876"})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	_, err = server.client.Calculate(withKey(integrationKey), &dataflowv1.CalculateRequest{StoreIds: []string{"all"}, Operations: []string{"total_sales"}})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+}
+
+func TestGetSale_NotFound(t *testing.T) {
+	server := setupServer(t, auth.NewAuthenticator(newKeyStore(t), nil))
+
+	_, err := server.client.GetSale(withKey(adminKey), &dataflowv1.GetSaleRequest{Id: "missing"})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	_, err = server.client.GetSale(withKey(adminKey), &dataflowv1.GetSaleRequest{})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestListSales(t *testing.T) {
+	server := setupServer(t, auth.NewAuthenticator(newKeyStore(t), nil))
+	ids := addSales(t, server.client)
+
+	sales, err := listSales(withKey(adminKey), server.client, &dataflowv1.ListSalesRequest{})
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, ids, saleIds(sales))
+
+	// Store managers only see their own stores.
+	sales, err = listSales(withKey(managerKey), server.client, &dataflowv1.ListSalesRequest{})
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, ids[:2], saleIds(sales))
+
+	sales, err = listSales(withKey(managerKey), server.client, &dataflowv1.ListSalesRequest{
+		StoreId:   "6789",
+		StartDate: timestamppb.New(time.Date(2024, 6, 12, 0, 0, 0, 0, time.UTC)),
+		EndDate:   timestamppb.New(time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC)),
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{ids[0]}, saleIds(sales))
+
+	_, err = listSales(withKey(adminKey), server.client, &dataflowv1.ListSalesRequest{
+		StartDate: timestamppb.New(time.Date(2024, 6, 12, 0, 0, 0, 0, time.UTC)),
+	})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	_, err = listSales(withKey(adminKey), server.client, &dataflowv1.ListSalesRequest{
+		StoreId:   "6789",
+		StartDate: timestamppb.New(time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC)),
+		EndDate:   timestamppb.New(time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)),
+	})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func saleIds(sales []*dataflowv1.Sale) []string {
+	ids := make([]string, 0, len(sales))
+	for _, sale := range sales {
+		ids = append(ids, sale.GetId())
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+func TestCalculate(t *testing.T) {
+	server := setupServer(t, auth.NewAuthenticator(newKeyStore(t), nil))
+	addSales(t, server.client)
+
+	response, err := server.client.Calculate(withKey(adminKey), &dataflowv1.CalculateRequest{
+		StoreIds:   []string{"all"},
+		Operations: []string{"total_sales", "units_sold", "median"},
+		EndDate:    timestamppb.New(time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC)),
+	})
+	assert.NoError(t, err)
+
+	assert.Equal(t, []string{"6789", "9876"}, response.GetStoreIds())
+	cells := response.GetResults()["6789"].GetOperations()
+	assert.Equal(t, "209.9", cells["total_sales"].GetValue())
+	assert.Equal(t, "12", cells["units_sold"].GetValue())
+	assert.NotEmpty(t, cells["median"].GetError())
+	assert.Equal(t, "49.95", response.GetResults()["9876"].GetOperations()["total_sales"].GetValue())
+
+	_, err = server.client.Calculate(withKey(adminKey), &dataflowv1.CalculateRequest{StoreIds: []string{"all"}})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	_, err = server.client.Calculate(withKey(adminKey), &dataflowv1.CalculateRequest{Operations: []string{"total_sales"}})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestRangeBounds(t *testing.T) {
+	server := setupServer(t, auth.NewAuthenticator(newKeyStore(t), nil))
+	ids := addSales(t, server.client)
+	end := timestamppb.New(time.Date(2024, 6, 15, 14, 30, 0, 0, time.UTC))
+
+	sales, err := listSales(withKey(adminKey), server.client, &dataflowv1.ListSalesRequest{StoreId: "6789", EndDate: end})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{ids[1]}, saleIds(sales))
+	sales, err = listSales(withKey(adminKey), server.client, &dataflowv1.ListSalesRequest{StoreId: "6789", EndDate: end, Range: dataflowv1.RangeBounds_RANGE_BOUNDS_CLOSED})
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, ids[:2], saleIds(sales))
+
+	for bounds, total := range map[dataflowv1.RangeBounds]string{
+		dataflowv1.RangeBounds_RANGE_BOUNDS_UNSPECIFIED: "10",
+		dataflowv1.RangeBounds_RANGE_BOUNDS_HALF_OPEN:   "10",
+		dataflowv1.RangeBounds_RANGE_BOUNDS_CLOSED:      "209.9",
+	} {
+		response, err := server.client.Calculate(withKey(adminKey), &dataflowv1.CalculateRequest{
+			StoreIds:   []string{"6789"},
+			Operations: []string{"total_sales"},
+			EndDate:    end,
+			Range:      bounds,
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, total, response.GetResults()["6789"].GetOperations()["total_sales"].GetValue(), bounds)
+	}
+
+	_, err = listSales(withKey(adminKey), server.client, &dataflowv1.ListSalesRequest{StoreId: "6789", EndDate: end, Range: 7})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestAnonymous(t *testing.T) {
+	server := setupServer(t, nil)
+
+	added, err := server.client.AddSale(context.Background(), newSale("6789", 1, 1, time.Now()))
+	assert.NoError(t, err)
+
+	sales, err := listSales(context.Background(), server.client, &dataflowv1.ListSalesRequest{})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{added.GetId()}, saleIds(sales))
+}
+
+func TestInterceptors(t *testing.T) {
+	server := setupServer(t, auth.NewAuthenticator(newKeyStore(t), nil))
+	addSales(t, server.client)
+
+	var header metadata.MD
+	ctx := metadata.AppendToOutgoingContext(withKey(adminKey), requestid.MetadataKey, "req-42")
+	_, err := server.client.GetSale(ctx, &dataflowv1.GetSaleRequest{Id: "missing"}, grpc.Header(&header))
+	assert.Equal(t, codes.NotFound, status.Code(err))
+	assert.Equal(t, []string{"req-42"}, header.Get(requestid.MetadataKey))
+
+	_, err = listSales(withKey(adminKey), server.client, &dataflowv1.ListSalesRequest{})
+	assert.NoError(t, err)
+
+	logs := server.logs.String()
+	assert.Contains(t, logs, `"msg":"rpc","method":"/dataflow.v1.DataService/GetSale","code":"NotFound"`)
+	assert.Contains(t, logs, `"request_id":"req-42"`)
+	assert.Contains(t, logs, `"level":"WARN"`)
+	assert.Contains(t, logs, `"method":"/dataflow.v1.DataService/ListSales","code":"OK"`)
+
+	w := httptest.NewRecorder()
+	server.metrics.Handler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	body := w.Body.String()
+	assert.Contains(t, body, `dataflow_grpc_requests_total{code="OK",method="/dataflow.v1.DataService/AddSale"} 3`)
+	assert.Contains(t, body, `dataflow_grpc_requests_total{code="NotFound",method="/dataflow.v1.DataService/GetSale"} 1`)
+	assert.Contains(t, body, `dataflow_grpc_requests_total{code="OK",method="/dataflow.v1.DataService/ListSales"} 1`)
+	assert.True(t, strings.Contains(body, "dataflow_grpc_request_duration_seconds_bucket"))
+}
@@ -0,0 +1,111 @@
+package handlers
+
+import (
+	"dataflow/models"
+	"dataflow/repo"
+	"dataflow/services"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
+
+type AlertHandler struct {
+	service services.AlertService
+}
+
+func NewAlertHandler(service services.AlertService) *AlertHandler {
+	return &AlertHandler{service: service}
+}
+
+func (h *AlertHandler) CreateRule(c *gin.Context) {
+	var rule models.AlertRule
+	if err := c.ShouldBindJSON(&rule); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "status": http.StatusBadRequest})
+		return
+	}
+	generated := rule.WebhookSecret == ""
+	if err := h.service.CreateRule(&rule); err != nil {
+		alertError(c, err)
+		return
+	}
+	if generated {
+		// The generated secret is only ever returned here.
+		c.JSON(http.StatusCreated, &rule)
+		return
+	}
+	c.JSON(http.StatusCreated, redactRule(&rule))
+}
+
+func (h *AlertHandler) GetRules(c *gin.Context) {
+	rules, err := h.service.GetAllRules()
+	if err != nil {
+		alertError(c, err)
+		return
+	}
+	redacted := make([]*models.AlertRule, 0, len(rules))
+	for _, rule := range rules {
+		redacted = append(redacted, redactRule(rule))
+	}
+	c.JSON(http.StatusOK, redacted)
+}
+
+func (h *AlertHandler) GetRule(c *gin.Context) {
+	rule, err := h.service.GetRule(c.Param("id"))
+	if err != nil {
+		alertError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, redactRule(rule))
+}
+
+func (h *AlertHandler) UpdateRule(c *gin.Context) {
+	var rule models.AlertRule
+	if err := c.ShouldBindJSON(&rule); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "status": http.StatusBadRequest})
+		return
+	}
+	rule.ID = c.Param("id")
+	if err := h.service.UpdateRule(&rule); err != nil {
+		alertError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, redactRule(&rule))
+}
+
+func (h *AlertHandler) DeleteRule(c *gin.Context) {
+	if err := h.service.DeleteRule(c.Param("id")); err != nil {
+		alertError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func (h *AlertHandler) GetDeliveries(c *gin.Context) {
+	if _, err := h.service.GetRule(c.Param("id")); err != nil {
+		alertError(c, err)
+		return
+	}
+	deliveries, err := h.service.GetDeliveries(c.Param("id"))
+	if err != nil {
+		alertError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, deliveries)
+}
+
+func alertError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrInvalidAlertRule):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "status": http.StatusBadRequest})
+	case errors.Is(err, repo.ErrAlertRuleNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error(), "status": http.StatusNotFound})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error(), "status": http.StatusInternalServerError})
+	}
+}
+
+func redactRule(rule *models.AlertRule) *models.AlertRule {
+	redacted := *rule
+	redacted.WebhookSecret = ""
+	return &redacted
+}
@@ -0,0 +1,175 @@
+package handlers
+
+import (
+	"bytes"
+	"dataflow/models"
+	"dataflow/repo"
+	"dataflow/services"
+	"encoding/json"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestAlertHandler_CreateRule(t *testing.T) {
+	mockService := &services.MockAlertService{}
+	handler := NewAlertHandler(mockService)
+
+	rule := models.AlertRule{
+		Name:          "low revenue",
+		Type:          models.AlertTypeThreshold,
+		StoreId:       "6789",
+		Comparator:    "<",
+		Threshold:     500,
+		WebhookURL:    "http://localhost:9000/hook",
+		WebhookSecret: "s3cret",
+	}
+
+	mockService.On("CreateRule", mock.AnythingOfType("*models.AlertRule")).Run(func(args mock.Arguments) {
+		args.Get(0).(*models.AlertRule).ID = "1"
+	}).Return(nil)
+
+	jsonData, _ := json.Marshal(rule)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request, _ = http.NewRequest("POST", "/alerts", bytes.NewBuffer(jsonData))
+	c.Request.Header.Set("Content-Type", "application/json")
+
+	handler.CreateRule(c)
+
+	assert.Equal(t, http.StatusCreated, w.Code)
+	var created models.AlertRule
+	err := json.Unmarshal(w.Body.Bytes(), &created)
+	assert.NoError(t, err)
+	assert.Equal(t, "1", created.ID)
+	assert.Empty(t, created.WebhookSecret)
+}
+
+func TestAlertHandler_CreateRule_GeneratedSecret(t *testing.T) {
+	mockService := &services.MockAlertService{}
+	handler := NewAlertHandler(mockService)
+
+	mockService.On("CreateRule", mock.AnythingOfType("*models.AlertRule")).Run(func(args mock.Arguments) {
+		rule := args.Get(0).(*models.AlertRule)
+		rule.ID = "1"
+		rule.WebhookSecret = "generated"
+	}).Return(nil)
+	mockService.On("GetRule", "1").Return(&models.AlertRule{ID: "1", WebhookSecret: "generated"}, nil)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request, _ = http.NewRequest("POST", "/alerts", bytes.NewBufferString(`{"name":"quiet","type":"no_sales","window":"2h","webhook_url":"http://localhost:9000/hook"}`))
+	c.Request.Header.Set("Content-Type", "application/json")
+
+	handler.CreateRule(c)
+
+	assert.Equal(t, http.StatusCreated, w.Code)
+	var created models.AlertRule
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &created))
+	assert.Equal(t, "generated", created.WebhookSecret)
+
+	// It isn't returned again.
+	w = httptest.NewRecorder()
+	c, _ = gin.CreateTestContext(w)
+	c.Params = gin.Params{{Key: "id", Value: "1"}}
+	c.Request, _ = http.NewRequest("GET", "/alerts/1", nil)
+
+	handler.GetRule(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.NotContains(t, w.Body.String(), "generated")
+}
+
+func TestAlertHandler_CreateRule_Invalid(t *testing.T) {
+	mockService := &services.MockAlertService{}
+	handler := NewAlertHandler(mockService)
+
+	mockService.On("CreateRule", mock.Anything).Return(fmt.Errorf("%w: name is required", services.ErrInvalidAlertRule))
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request, _ = http.NewRequest("POST", "/alerts", bytes.NewBufferString(`{"type":"threshold"}`))
+	c.Request.Header.Set("Content-Type", "application/json")
+
+	handler.CreateRule(c)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "name is required")
+}
+
+func TestAlertHandler_GetRule_NotFound(t *testing.T) {
+	mockService := &services.MockAlertService{}
+	handler := NewAlertHandler(mockService)
+
+	mockService.On("GetRule", "missing").Return(nil, repo.ErrAlertRuleNotFound)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request, _ = http.NewRequest("GET", "/alerts/missing", nil)
+	c.Params = gin.Params{{Key: "id", Value: "missing"}}
+
+	handler.GetRule(c)
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestAlertHandler_UpdateRule(t *testing.T) {
+	mockService := &services.MockAlertService{}
+	handler := NewAlertHandler(mockService)
+
+	mockService.On("UpdateRule", mock.MatchedBy(func(rule *models.AlertRule) bool {
+		return rule.ID == "1" && rule.Window == "3h"
+	})).Return(nil)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request, _ = http.NewRequest("PUT", "/alerts/1", bytes.NewBufferString(`{"name":"quiet","type":"no_sales","window":"3h","webhook_url":"http://localhost"}`))
+	c.Request.Header.Set("Content-Type", "application/json")
+	c.Params = gin.Params{{Key: "id", Value: "1"}}
+
+	handler.UpdateRule(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	mockService.AssertExpectations(t)
+}
+
+func TestAlertHandler_DeleteRule(t *testing.T) {
+	mockService := &services.MockAlertService{}
+	handler := NewAlertHandler(mockService)
+
+	mockService.On("DeleteRule", "1").Return(nil)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request, _ = http.NewRequest("DELETE", "/alerts/1", nil)
+	c.Params = gin.Params{{Key: "id", Value: "1"}}
+
+	handler.DeleteRule(c)
+
+	assert.Equal(t, http.StatusNoContent, c.Writer.Status())
+}
+
+func TestAlertHandler_GetDeliveries(t *testing.T) {
+	mockService := &services.MockAlertService{}
+	handler := NewAlertHandler(mockService)
+
+	delivery := &models.WebhookDelivery{ID: "d1", RuleId: "1", Status: models.AlertStateFiring, Attempt: 1, Delivered: true}
+	mockService.On("GetRule", "1").Return(&models.AlertRule{ID: "1"}, nil)
+	mockService.On("GetDeliveries", "1").Return([]*models.WebhookDelivery{delivery}, nil)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request, _ = http.NewRequest("GET", "/alerts/1/deliveries", nil)
+	c.Params = gin.Params{{Key: "id", Value: "1"}}
+
+	handler.GetDeliveries(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	var deliveries []*models.WebhookDelivery
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &deliveries))
+	assert.Equal(t, []*models.WebhookDelivery{delivery}, deliveries)
+}
@@ -0,0 +1,53 @@
+package handlers
+
+import (
+	"dataflow/services"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"time"
+)
+
+type AnomalyHandler struct {
+	service services.AnomalyService
+}
+
+func NewAnomalyHandler(service services.AnomalyService) *AnomalyHandler {
+	return &AnomalyHandler{service: service}
+}
+
+func (h *AnomalyHandler) GetAnomalies(c *gin.Context) {
+	from, err := parseDay(c.Query("from"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "status": http.StatusBadRequest})
+		return
+	}
+	to, err := parseDay(c.Query("to"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "status": http.StatusBadRequest})
+		return
+	}
+
+	anomalies, err := h.service.DetectAnomalies(c.Request.Context(), from, to, c.Query("store_id"))
+	if err != nil {
+		if errors.Is(err, services.ErrWrongDate) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "status": http.StatusBadRequest})
+		} else if errors.Is(err, services.ErrForbidden) {
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error(), "status": http.StatusForbidden})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error(), "status": http.StatusInternalServerError})
+		}
+		return
+	}
+	c.JSON(http.StatusOK, anomalies)
+}
+
+func parseDay(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.DateOnly, value); err == nil {
+		return t, nil
+	}
+	return time.Parse(time.RFC3339, value)
+}
@@ -0,0 +1,73 @@
+package handlers
+
+import (
+	"dataflow/models"
+	"dataflow/services"
+	"encoding/json"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestAnomalyHandler_GetAnomalies(t *testing.T) {
+	mockService := &services.MockAnomalyService{}
+	handler := NewAnomalyHandler(mockService)
+
+	from := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2024, 6, 30, 0, 0, 0, 0, time.UTC)
+	anomaly := &models.Anomaly{
+		StoreId:       "6789",
+		Date:          "2024-06-11",
+		Revenue:       20,
+		Baseline:      1000,
+		ExpectedRange: models.ExpectedRange{Low: 900, High: 1100},
+		Severity:      42.5,
+		Direction:     services.DirectionBelow,
+	}
+
+	mockService.On("DetectAnomalies", from, to, "6789").Return([]*models.Anomaly{anomaly}, nil)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request, _ = http.NewRequest("GET", "/anomalies?from=2024-06-01&to=2024-06-30&store_id=6789", nil)
+
+	handler.GetAnomalies(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	var anomalies []*models.Anomaly
+	err := json.Unmarshal(w.Body.Bytes(), &anomalies)
+	assert.NoError(t, err)
+	assert.Equal(t, []*models.Anomaly{anomaly}, anomalies)
+}
+
+func TestAnomalyHandler_GetAnomalies_InvalidDate(t *testing.T) {
+	handler := NewAnomalyHandler(&services.MockAnomalyService{})
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request, _ = http.NewRequest("GET", "/anomalies?from=June", nil)
+
+	handler.GetAnomalies(c)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestAnomalyHandler_GetAnomalies_WrongDate(t *testing.T) {
+	mockService := &services.MockAnomalyService{}
+	handler := NewAnomalyHandler(mockService)
+
+	mockService.On("DetectAnomalies", mock.Anything, mock.Anything, "").Return([]*models.Anomaly{}, services.ErrWrongDate)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request, _ = http.NewRequest("GET", "/anomalies?from=2024-06-30&to=2024-06-01", nil)
+
+	handler.GetAnomalies(c)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "start date must be before end date")
+}
@@ -0,0 +1,74 @@
+package handlers
+
+import (
+	"dataflow/repo"
+	"dataflow/services"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	defaultAuditLimit = 100
+	maxAuditLimit     = 1000
+)
+
+type AuditHandler struct {
+	audit *services.AuditLog
+}
+
+func NewAuditHandler(audit *services.AuditLog) *AuditHandler {
+	return &AuditHandler{audit: audit}
+}
+
+// GetEntries lists audit entries in sequence order. Pass the last sequence
+// number seen as "after" to fetch the next page.
+func (h *AuditHandler) GetEntries(c *gin.Context) {
+	from, to, err := parseRange(c.Query("from"), c.Query("to"), time.UTC, nil, repo.RangeHalfOpen)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "status": http.StatusBadRequest})
+		return
+	}
+	filter := repo.AuditFilter{
+		Subject: c.Query("subject"),
+		Action:  c.Query("action"),
+		StoreId: c.Query("store_id"),
+		From:    from,
+		To:      to,
+		Limit:   defaultAuditLimit,
+	}
+	if after := c.Query("after"); after != "" {
+		if filter.AfterSequence, err = strconv.ParseUint(after, 10, 64); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid after", "status": http.StatusBadRequest})
+			return
+		}
+	}
+	if limit := c.Query("limit"); limit != "" {
+		if filter.Limit, err = strconv.Atoi(limit); err != nil || filter.Limit <= 0 || filter.Limit > maxAuditLimit {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be between 1 and " + strconv.Itoa(maxAuditLimit), "status": http.StatusBadRequest})
+			return
+		}
+	}
+
+	entries, err := h.audit.GetEntries(filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error(), "status": http.StatusInternalServerError})
+		return
+	}
+	c.JSON(http.StatusOK, entries)
+}
+
+// Verify recomputes the hash chain. A broken chain is reported with 409.
+func (h *AuditHandler) Verify(c *gin.Context) {
+	verification, err := h.audit.Verify()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error(), "status": http.StatusInternalServerError})
+		return
+	}
+	status := http.StatusOK
+	if !verification.Valid {
+		status = http.StatusConflict
+	}
+	c.JSON(status, verification)
+}
@@ -0,0 +1,74 @@
+package handlers
+
+import (
+	"context"
+	"dataflow/models"
+	"dataflow/repo"
+	"dataflow/services"
+	"encoding/json"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func setupAuditRouter(t *testing.T) *gin.Engine {
+	audit := services.NewAuditLog(repo.NewInMemoryAuditRepository())
+	for _, storeId := range []string{"6789", "9876", "6789"} {
+		_, err := audit.Record(context.Background(), models.AuditActionAddSale, storeId, nil, &models.Sale{StoreId: storeId})
+		assert.NoError(t, err)
+	}
+
+	handler := NewAuditHandler(audit)
+	router := gin.New()
+	router.GET("/audit", handler.GetEntries)
+	router.GET("/audit/verify", handler.Verify)
+	return router
+}
+
+func TestAuditHandler_GetEntries(t *testing.T) {
+	router := setupAuditRouter(t)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/audit?store_id=6789&limit=1", nil)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	var entries []*models.AuditEntry
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &entries))
+	assert.Len(t, entries, 1)
+	assert.Equal(t, uint64(1), entries[0].Sequence)
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/audit?store_id=6789&after=1", nil)
+	router.ServeHTTP(w, req)
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &entries))
+	assert.Len(t, entries, 1)
+	assert.Equal(t, uint64(3), entries[0].Sequence)
+}
+
+func TestAuditHandler_GetEntries_InvalidQuery(t *testing.T) {
+	router := setupAuditRouter(t)
+
+	for _, query := range []string{"limit=0", "limit=5000", "after=x", "from=yesterday"} {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/audit?"+query, nil)
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusBadRequest, w.Code, query)
+	}
+}
+
+func TestAuditHandler_Verify(t *testing.T) {
+	router := setupAuditRouter(t)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/audit/verify", nil)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	var verification models.AuditVerification
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &verification))
+	assert.True(t, verification.Valid)
+	assert.Equal(t, 3, verification.Entries)
+}
@@ -0,0 +1,154 @@
+package handlers
+
+import (
+	"dataflow/auth"
+	"dataflow/models"
+	"dataflow/repo"
+	"dataflow/services"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
+
+type CatalogHandler struct {
+	service services.CatalogService
+}
+
+func NewCatalogHandler(service services.CatalogService) *CatalogHandler {
+	return &CatalogHandler{service: service}
+}
+
+func (h *CatalogHandler) CreateStore(c *gin.Context) {
+	// New entries are active unless the request says otherwise.
+	store := models.Store{Active: true}
+	if err := c.ShouldBindJSON(&store); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "status": http.StatusBadRequest})
+		return
+	}
+	if err := h.service.CreateStore(c.Request.Context(), &store); err != nil {
+		catalogError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, &store)
+}
+
+// GetStores lists the stores the principal may read the sales of.
+func (h *CatalogHandler) GetStores(c *gin.Context) {
+	stores, err := h.service.GetAllStores(c.Request.Context())
+	if err != nil {
+		catalogError(c, err)
+		return
+	}
+	principal, _ := auth.PrincipalFromContext(c.Request.Context())
+	accessible := make([]*models.Store, 0, len(stores))
+	for _, store := range stores {
+		if auth.CanAccessStore(principal, store.ID) {
+			accessible = append(accessible, store)
+		}
+	}
+	c.JSON(http.StatusOK, accessible)
+}
+
+func (h *CatalogHandler) GetStore(c *gin.Context) {
+	principal, _ := auth.PrincipalFromContext(c.Request.Context())
+	if !auth.CanAccessStore(principal, c.Param("id")) {
+		catalogError(c, services.ErrForbidden)
+		return
+	}
+	store, err := h.service.GetStore(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		catalogError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, store)
+}
+
+func (h *CatalogHandler) UpdateStore(c *gin.Context) {
+	store := models.Store{Active: true}
+	if err := c.ShouldBindJSON(&store); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "status": http.StatusBadRequest})
+		return
+	}
+	store.ID = c.Param("id")
+	if err := h.service.UpdateStore(c.Request.Context(), &store); err != nil {
+		catalogError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, &store)
+}
+
+func (h *CatalogHandler) DeleteStore(c *gin.Context) {
+	if err := h.service.DeleteStore(c.Request.Context(), c.Param("id")); err != nil {
+		catalogError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func (h *CatalogHandler) CreateProduct(c *gin.Context) {
+	product := models.Product{Active: true}
+	if err := c.ShouldBindJSON(&product); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "status": http.StatusBadRequest})
+		return
+	}
+	if err := h.service.CreateProduct(c.Request.Context(), &product); err != nil {
+		catalogError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, &product)
+}
+
+func (h *CatalogHandler) GetProducts(c *gin.Context) {
+	products, err := h.service.GetAllProducts(c.Request.Context())
+	if err != nil {
+		catalogError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, products)
+}
+
+func (h *CatalogHandler) GetProduct(c *gin.Context) {
+	product, err := h.service.GetProduct(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		catalogError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, product)
+}
+
+func (h *CatalogHandler) UpdateProduct(c *gin.Context) {
+	product := models.Product{Active: true}
+	if err := c.ShouldBindJSON(&product); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "status": http.StatusBadRequest})
+		return
+	}
+	product.ID = c.Param("id")
+	if err := h.service.UpdateProduct(c.Request.Context(), &product); err != nil {
+		catalogError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, &product)
+}
+
+func (h *CatalogHandler) DeleteProduct(c *gin.Context) {
+	if err := h.service.DeleteProduct(c.Request.Context(), c.Param("id")); err != nil {
+		catalogError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func catalogError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrInvalidCatalogEntry):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "status": http.StatusBadRequest})
+	case errors.Is(err, services.ErrForbidden):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error(), "status": http.StatusForbidden})
+	case errors.Is(err, repo.ErrStoreNotFound), errors.Is(err, repo.ErrProductNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error(), "status": http.StatusNotFound})
+	case errors.Is(err, repo.ErrStoreAlreadyExists), errors.Is(err, repo.ErrProductAlreadyExists), errors.Is(err, services.ErrCatalogEntryInUse):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error(), "status": http.StatusConflict})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error(), "status": http.StatusInternalServerError})
+	}
+}
@@ -0,0 +1,282 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"dataflow/auth"
+	"dataflow/models"
+	"dataflow/repo"
+	"dataflow/services"
+	"encoding/json"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func setupCatalog() services.CatalogService {
+	catalog := services.NewCatalogService(repo.NewInMemoryStoreRepository(), repo.NewInMemoryProductRepository(), repo.NewInMemoryRepository())
+	catalog.CreateStore(context.Background(), &models.Store{ID: "6789", Name: "Downtown", Active: true})
+	catalog.CreateStore(context.Background(), &models.Store{ID: "9876", Name: "Airport", Active: true})
+	catalog.CreateProduct(context.Background(), &models.Product{ID: "12345", Name: "Espresso", Active: true})
+	return catalog
+}
+
+func TestCatalogHandler_CreateStore(t *testing.T) {
+	handler := NewCatalogHandler(setupCatalog())
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request, _ = http.NewRequest("POST", "/stores", bytes.NewBufferString(`{"id":"1111","name":"Harbour","timezone":"Europe/Lisbon"}`))
+	c.Request.Header.Set("Content-Type", "application/json")
+
+	handler.CreateStore(c)
+
+	assert.Equal(t, http.StatusCreated, w.Code)
+	var store models.Store
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &store))
+	assert.Equal(t, models.Store{ID: "1111", Name: "Harbour", Timezone: "Europe/Lisbon", Active: true}, store)
+
+	for body, status := range map[string]int{
+		`{"id":"1111","name":"Harbour"}`:                http.StatusConflict,
+		`{"id":"2222","name":"x","timezone":"Nowhere"}`: http.StatusBadRequest,
+		`{"id":"2222","name":"x","active":"sometimes"}`: http.StatusBadRequest,
+	} {
+		w = httptest.NewRecorder()
+		c, _ = gin.CreateTestContext(w)
+		c.Request, _ = http.NewRequest("POST", "/stores", bytes.NewBufferString(body))
+		c.Request.Header.Set("Content-Type", "application/json")
+
+		handler.CreateStore(c)
+
+		assert.Equal(t, status, w.Code, body)
+	}
+}
+
+func TestCatalogHandler_GetStores_Scope(t *testing.T) {
+	handler := NewCatalogHandler(setupCatalog())
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodGet, "/stores", nil)
+	c.Request = c.Request.WithContext(auth.WithPrincipal(c.Request.Context(), &models.Principal{Subject: "test", Roles: []string{auth.RoleStoreManager}, Stores: []string{"9876"}}))
+
+	handler.GetStores(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	var stores []*models.Store
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &stores))
+	assert.Equal(t, []*models.Store{{ID: "9876", Name: "Airport", Active: true}}, stores)
+
+	w = httptest.NewRecorder()
+	c, _ = gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodGet, "/stores/6789", nil)
+	c.Request = c.Request.WithContext(auth.WithPrincipal(c.Request.Context(), &models.Principal{Subject: "test", Roles: []string{auth.RoleStoreManager}, Stores: []string{"9876"}}))
+	c.Params = gin.Params{{Key: "id", Value: "6789"}}
+
+	handler.GetStore(c)
+
+	assert.Equal(t, http.StatusForbidden, w.Code)
+}
+
+func TestCatalogHandler_UpdateProduct(t *testing.T) {
+	handler := NewCatalogHandler(setupCatalog())
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request, _ = http.NewRequest("PUT", "/products/12345", bytes.NewBufferString(`{"name":"Espresso","category":"coffee","sku":"ESP-1","active":false}`))
+	c.Request.Header.Set("Content-Type", "application/json")
+	c.Params = gin.Params{{Key: "id", Value: "12345"}}
+
+	handler.UpdateProduct(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	var product models.Product
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &product))
+	assert.Equal(t, models.Product{ID: "12345", Name: "Espresso", Category: "coffee", SKU: "ESP-1"}, product)
+
+	w = httptest.NewRecorder()
+	c, _ = gin.CreateTestContext(w)
+	c.Request, _ = http.NewRequest("DELETE", "/products/missing", nil)
+	c.Params = gin.Params{{Key: "id", Value: "missing"}}
+
+	handler.DeleteProduct(c)
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestDataHandler_GetData_Enriched(t *testing.T) {
+	mockService := &services.MockService{}
+	handler := NewDataHandler(mockService, setupCatalog(), nil)
+
+	mockService.On("GetAllSales").Return([]*models.Sale{
+		{ID: "1", ProductId: "12345", StoreId: "6789", QuantitySold: 1, SalePrice: 2, SaleDate: time.Date(2024, 6, 15, 0, 0, 0, 0, time.UTC)},
+		{ID: "2", ProductId: "54321", StoreId: "678", QuantitySold: 1, SalePrice: 2, SaleDate: time.Date(2024, 6, 15, 0, 0, 0, 0, time.UTC)},
+	}, nil)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodGet, "/data", nil)
+
+	handler.GetData(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	var sales []map[string]interface{}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &sales))
+	if assert.Len(t, sales, 2) {
+		assert.Equal(t, "Downtown", sales[0]["store_name"])
+		assert.Equal(t, "Espresso", sales[0]["product_name"])
+		assert.NotContains(t, sales[1], "store_name")
+		assert.NotContains(t, sales[1], "product_name")
+	}
+}
+
+func TestDataHandler_Calculate_Enriched(t *testing.T) {
+	mockService := &services.MockService{}
+	handler := NewDataHandler(mockService, setupCatalog(), nil)
+
+	mockService.On("CalculateSales", time.Time{}, time.Time{}, "6789").Return(new(big.Float).SetFloat64(2), nil)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request, _ = http.NewRequest("POST", "/calculate", bytes.NewBufferString(`{"operation":"total_sales","store_id":"6789"}`))
+	c.Request.Header.Set("Content-Type", "application/json")
+
+	handler.Calculate(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	var response CalculateResponse
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, "Downtown", response.StoreName)
+
+	mockService.On("CalculateBatch", time.Time{}, time.Time{}, []string{"6789", "678"}, []string{"sale_count"}).Return(&models.BatchResult{
+		StoreIds:   []string{"6789", "678"},
+		Operations: []string{"sale_count"},
+		Cells:      map[string]map[string]models.CalculationCell{},
+	}, nil)
+
+	w = httptest.NewRecorder()
+	c, _ = gin.CreateTestContext(w)
+	c.Request, _ = http.NewRequest("POST", "/calculate", bytes.NewBufferString(`{"store_ids":["6789","678"],"operations":["sale_count"]}`))
+	c.Request.Header.Set("Content-Type", "application/json")
+
+	handler.Calculate(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	var batch BatchCalculateResponse
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &batch))
+	assert.Equal(t, map[string]string{"6789": "Downtown"}, batch.StoreNames)
+}
+
+func TestDataHandler_AddData_UnknownReference(t *testing.T) {
+	mockService := &services.MockService{}
+	handler := NewDataHandler(services.NewCatalogCheckingDataService(mockService, setupCatalog(), true), nil, nil)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request, _ = http.NewRequest("POST", "/data", bytes.NewBufferString(`{"product_id":"12345","store_id":"678","quantity_sold":1,"sale_price":2,"sale_date":"2024-06-15T00:00:00Z"}`))
+	c.Request.Header.Set("Content-Type", "application/json")
+
+	handler.AddData(c)
+
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	assert.Contains(t, w.Body.String(), `store \"678\" isn't in the catalog`)
+	mockService.AssertNotCalled(t, "AddSale")
+}
+
+func TestDataHandler_Calculate_Rollup(t *testing.T) {
+	catalog := setupCatalog()
+	catalog.UpdateStore(context.Background(), &models.Store{ID: "6789", Name: "Downtown", District: "center", Region: "north", Active: true})
+	mockService := &services.MockService{}
+	handler := NewDataHandler(mockService, catalog, nil)
+
+	mockService.On("Aggregate", models.AggregateQuery{GroupBy: []string{models.DimensionStore}}).Return([]*models.AggregateGroup{
+		{StoreId: "6789", Revenue: big.NewFloat(0.1).SetPrec(20), Units: 1, Count: 1},
+		{StoreId: "9876", Revenue: big.NewFloat(0.2).SetPrec(20), Units: 2, Count: 1},
+	}, nil)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request, _ = http.NewRequest("POST", "/calculate", bytes.NewBufferString(`{"level":"region","drill_down":true}`))
+	c.Request.Header.Set("Content-Type", "application/json")
+
+	handler.Calculate(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{
+		"start_date": "", "end_date": "", "level": "region",
+		"total_sales": "0.3", "units_sold": 3, "sale_count": 2,
+		"groups": [
+			{"level": "region", "key": "", "total_sales": "0.2", "units_sold": 2, "sale_count": 1, "children": [
+				{"level": "district", "key": "", "total_sales": "0.2", "units_sold": 2, "sale_count": 1, "children": [
+					{"level": "store", "key": "9876", "name": "Airport", "total_sales": "0.2", "units_sold": 2, "sale_count": 1}
+				]}
+			]},
+			{"level": "region", "key": "north", "total_sales": "0.1", "units_sold": 1, "sale_count": 1, "children": [
+				{"level": "district", "key": "center", "total_sales": "0.1", "units_sold": 1, "sale_count": 1, "children": [
+					{"level": "store", "key": "6789", "name": "Downtown", "total_sales": "0.1", "units_sold": 1, "sale_count": 1}
+				]}
+			]}
+		]
+	}`, w.Body.String())
+
+	for _, body := range []string{`{"level":"galaxy"}`, `{"level":"region","store_id":"6789"}`, `{"level":"region","operation":"average_sale"}`} {
+		w = httptest.NewRecorder()
+		c, _ = gin.CreateTestContext(w)
+		c.Request, _ = http.NewRequest("POST", "/calculate", bytes.NewBufferString(body))
+		c.Request.Header.Set("Content-Type", "application/json")
+
+		handler.Calculate(c)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code, body)
+	}
+}
+
+func TestDataHandler_Calculate_StoreTimezone(t *testing.T) {
+	catalog := setupCatalog()
+	catalog.UpdateStore(context.Background(), &models.Store{ID: "6789", Name: "Downtown", Timezone: "America/New_York", Active: true})
+	catalog.UpdateStore(context.Background(), &models.Store{ID: "9876", Name: "Airport", Timezone: "America/Chicago", Active: true})
+	mockService := &services.MockService{}
+	handler := NewDataHandler(mockService, catalog, nil)
+
+	// June 15 in New York is 04:00 UTC on June 15 to 04:00 UTC on June 16.
+	startDate := time.Date(2024, 6, 15, 4, 0, 0, 0, time.UTC)
+	endDate := time.Date(2024, 6, 16, 4, 0, 0, 0, time.UTC)
+	mockService.On("CalculateSales", mock.MatchedBy(startDate.Equal), mock.MatchedBy(endDate.Equal), "6789").Return(new(big.Float).SetFloat64(2), nil)
+	// A requested time zone overrides the store's.
+	mockService.On("CalculateSales", mock.MatchedBy(time.Date(2024, 6, 15, 0, 0, 0, 0, time.UTC).Equal), mock.MatchedBy(time.Date(2024, 6, 16, 0, 0, 0, 0, time.UTC).Equal), "6789").Return(new(big.Float).SetFloat64(3), nil)
+
+	for body, total := range map[string]string{
+		`{"operation":"total_sales","store_id":"6789","start_date":"2024-06-15","end_date":"2024-06-15"}`:                  `"2"`,
+		`{"operation":"total_sales","store_id":"6789","start_date":"2024-06-15","end_date":"2024-06-15","timezone":"UTC"}`: `"3"`,
+	} {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request, _ = http.NewRequest("POST", "/calculate", bytes.NewBufferString(body))
+		c.Request.Header.Set("Content-Type", "application/json")
+
+		handler.Calculate(c)
+
+		assert.Equal(t, http.StatusOK, w.Code, body)
+		assert.Contains(t, w.Body.String(), `"total_sales":`+total, body)
+	}
+
+	// Dates of stores in different time zones are ambiguous.
+	for _, body := range []string{
+		`{"store_ids":["6789","9876"],"operations":["sale_count"],"start_date":"2024-06-15"}`,
+		`{"operation":"total_sales","store_id":"6789","start_date":"2024-06-15","timezone":"Mars/Olympus"}`,
+	} {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request, _ = http.NewRequest("POST", "/calculate", bytes.NewBufferString(body))
+		c.Request.Header.Set("Content-Type", "application/json")
+
+		handler.Calculate(c)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code, body)
+	}
+}
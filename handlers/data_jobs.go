@@ -0,0 +1,178 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"dataflow/models"
+	"dataflow/repo"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+const (
+	JobTypeCalculate = "calculate"
+	JobTypeExport    = "export"
+)
+
+const (
+	ExportFormatJSON = "json"
+	ExportFormatCSV  = "csv"
+)
+
+// exportProgressStep is the number of exported sales between progress and
+// cancellation checks.
+const exportProgressStep = 1000
+
+type ExportRequest struct {
+	StoreId   string `json:"store_id,omitempty"`
+	StartDate string `json:"start_date,omitempty"`
+	EndDate   string `json:"end_date,omitempty"`
+	Format    string `json:"format,omitempty"`
+	// Range is half_open or closed, as in CalculateRequest.
+	Range string `json:"range,omitempty"`
+	// Timezone is the IANA time zone that dates such as 2024-06-15 resolve
+	// in, instead of the store's time zone.
+	Timezone string `json:"timezone,omitempty"`
+}
+
+// CalculateJob runs a CalculateRequest as an asynchronous job; the result is
+// the CalculateResponse that POST /calculate would return.
+func (h *DataHandler) CalculateJob(ctx context.Context, request json.RawMessage, progress func(int)) ([]byte, string, error) {
+	var calculateRequest CalculateRequest
+	if err := json.Unmarshal(request, &calculateRequest); err != nil {
+		return nil, "", fmt.Errorf("invalid calculate request: %w", err)
+	}
+	calculateResponse, err := h.calculate(ctx, calculateRequest, progress)
+	if err != nil {
+		return nil, "", err
+	}
+	result, err := json.Marshal(calculateResponse)
+	if err != nil {
+		return nil, "", err
+	}
+	return result, "application/json", nil
+}
+
+// ExportJob writes the sales matching an ExportRequest as JSON or CSV.
+func (h *DataHandler) ExportJob(ctx context.Context, request json.RawMessage, progress func(int)) ([]byte, string, error) {
+	var exportRequest ExportRequest
+	if err := json.Unmarshal(request, &exportRequest); err != nil {
+		return nil, "", fmt.Errorf("invalid export request: %w", err)
+	}
+	if exportRequest.Format == "" {
+		exportRequest.Format = ExportFormatJSON
+	}
+	if exportRequest.Format != ExportFormatJSON && exportRequest.Format != ExportFormatCSV {
+		return nil, "", errors.New("unsupported export format")
+	}
+	startDate, endDate, err := h.parseLocalRange(ctx, exportRequest.StartDate, exportRequest.EndDate, exportRequest.Timezone, exportRequest.Range, []string{exportRequest.StoreId})
+	if err != nil {
+		return nil, "", err
+	}
+
+	sales, err := h.exportSales(ctx, startDate, endDate, exportRequest.StoreId)
+	if err != nil {
+		return nil, "", err
+	}
+
+	var buf bytes.Buffer
+	var encode func(sale *models.Sale) error
+	var finish func() error
+	contentType := "application/json"
+	if exportRequest.Format == ExportFormatCSV {
+		contentType = "text/csv"
+		writer := csv.NewWriter(&buf)
+		writer.Write([]string{"id", "product_id", "store_id", "quantity_sold", "sale_price", "sale_date"})
+		encode = func(sale *models.Sale) error {
+			return writer.Write([]string{
+				sale.ID,
+				sale.ProductId,
+				sale.StoreId,
+				strconv.Itoa(sale.QuantitySold),
+				strconv.FormatFloat(sale.SalePrice, 'f', -1, 64),
+				sale.SaleDate.Format(time.RFC3339),
+			})
+		}
+		finish = func() error {
+			writer.Flush()
+			return writer.Error()
+		}
+	} else {
+		buf.WriteByte('[')
+		encode = func(sale *models.Sale) error {
+			if buf.Len() > 1 {
+				buf.WriteByte(',')
+			}
+			data, err := json.Marshal(sale)
+			buf.Write(data)
+			return err
+		}
+		finish = func() error {
+			return buf.WriteByte(']')
+		}
+	}
+
+	for i, sale := range sales {
+		if i%exportProgressStep == 0 {
+			if err := ctx.Err(); err != nil {
+				return nil, "", err
+			}
+			progress(i * 100 / len(sales))
+		}
+		if err := encode(sale); err != nil {
+			return nil, "", err
+		}
+	}
+	if err := finish(); err != nil {
+		return nil, "", err
+	}
+	return buf.Bytes(), contentType, nil
+}
+
+// calculateStores calculates a batch one store at a time, so that a job can
+// report its progress and be cancelled between stores.
+func (h *DataHandler) calculateStores(ctx context.Context, startDate time.Time, endDate time.Time, storeIds []string, operations []string, progress func(int)) (*models.BatchResult, error) {
+	result := &models.BatchResult{
+		StoreIds:   make([]string, 0, len(storeIds)),
+		Operations: operations,
+		Cells:      make(map[string]map[string]models.CalculationCell),
+	}
+	for i, storeId := range storeIds {
+		if _, ok := result.Cells[storeId]; ok {
+			continue
+		}
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		progress(i * 100 / len(storeIds))
+		store, err := h.service.CalculateBatch(ctx, startDate, endDate, []string{storeId}, operations)
+		if err != nil {
+			return nil, err
+		}
+		result.StoreIds = append(result.StoreIds, storeId)
+		result.Cells[storeId] = store.Cells[storeId]
+		result.Operations = store.Operations
+	}
+	return result, nil
+}
+
+func (h *DataHandler) exportSales(ctx context.Context, startDate time.Time, endDate time.Time, storeId string) ([]*models.Sale, error) {
+	if storeId != "" {
+		return h.service.GetSalesInRange(ctx, startDate, endDate, storeId)
+	}
+	all, err := h.service.GetAllSales(ctx)
+	if err != nil {
+		return nil, err
+	}
+	sales := make([]*models.Sale, 0, len(all))
+	for _, sale := range all {
+		if repo.InRange(sale.SaleDate, startDate, endDate) {
+			sales = append(sales, sale)
+		}
+	}
+	return sales, nil
+}
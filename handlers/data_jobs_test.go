@@ -0,0 +1,128 @@
+package handlers
+
+import (
+	"context"
+	"dataflow/models"
+	"dataflow/services"
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"math/big"
+	"testing"
+	"time"
+)
+
+func noProgress(int) {}
+
+func TestDataHandler_CalculateJob(t *testing.T) {
+	handler := setupHandler()
+
+	startDate := time.Date(2024, 6, 1, 14, 30, 0, 0, time.UTC)
+	endDate := time.Date(2024, 6, 16, 14, 30, 0, 0, time.UTC)
+	handler.service.(*services.MockService).On("CalculateSales", startDate, endDate, "6789").Return(new(big.Float).SetFloat64(199.9), nil)
+
+	request, _ := json.Marshal(CalculateRequest{
+		Operation: "total_sales",
+		StoreId:   "6789",
+		StartDate: startDate.Format(time.RFC3339),
+		EndDate:   endDate.Format(time.RFC3339),
+	})
+
+	result, contentType, err := handler.CalculateJob(context.Background(), request, noProgress)
+	assert.NoError(t, err)
+	assert.Equal(t, "application/json", contentType)
+	var calculateResponse CalculateResponse
+	assert.NoError(t, json.Unmarshal(result, &calculateResponse))
+	assert.Equal(t, "6789", calculateResponse.StoreId)
+}
+
+func TestDataHandler_CalculateJob_InvalidOperation(t *testing.T) {
+	handler := setupHandler()
+
+	_, _, err := handler.CalculateJob(context.Background(), json.RawMessage(`{"operation":"profit"}`), noProgress)
+	assert.EqualError(t, err, "unsupported operation")
+}
+
+func TestDataHandler_ExportJob_CSV(t *testing.T) {
+	handler := setupHandler()
+
+	sale := &models.Sale{
+		ID:           "1",
+		ProductId:    "12345",
+		StoreId:      "6789",
+		QuantitySold: 10,
+		SalePrice:    19.99,
+		SaleDate:     time.Date(2024, 6, 15, 14, 30, 0, 0, time.UTC),
+	}
+	handler.service.(*services.MockService).On("GetSalesInRange", time.Time{}, time.Time{}, "6789").Return([]*models.Sale{sale}, nil)
+
+	result, contentType, err := handler.ExportJob(context.Background(), json.RawMessage(`{"store_id":"6789","format":"csv"}`), noProgress)
+	assert.NoError(t, err)
+	assert.Equal(t, "text/csv", contentType)
+	assert.Equal(t, "id,product_id,store_id,quantity_sold,sale_price,sale_date\n1,12345,6789,10,19.99,2024-06-15T14:30:00Z\n", string(result))
+}
+
+func TestDataHandler_ExportJob_JSON(t *testing.T) {
+	handler := setupHandler()
+
+	sale1 := &models.Sale{ID: "1", StoreId: "6789", SaleDate: time.Date(2024, 6, 15, 14, 30, 0, 0, time.UTC)}
+	sale2 := &models.Sale{ID: "2", StoreId: "9876", SaleDate: time.Date(2024, 6, 30, 10, 0, 0, 0, time.UTC)}
+	handler.service.(*services.MockService).On("GetAllSales").Return([]*models.Sale{sale1, sale2}, nil)
+
+	result, contentType, err := handler.ExportJob(context.Background(), json.RawMessage(`{"end_date":"2024-06-20T00:00:00Z"}`), noProgress)
+	assert.NoError(t, err)
+	assert.Equal(t, "application/json", contentType)
+	var sales []*models.Sale
+	assert.NoError(t, json.Unmarshal(result, &sales))
+	assert.Equal(t, []*models.Sale{sale1}, sales)
+}
+
+func TestDataHandler_ExportJob_Cancelled(t *testing.T) {
+	handler := setupHandler()
+
+	handler.service.(*services.MockService).On("GetAllSales").Return([]*models.Sale{{ID: "1"}}, nil)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, _, err := handler.ExportJob(ctx, json.RawMessage(`{}`), noProgress)
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func TestDataHandler_CalculateJob_BatchProgress(t *testing.T) {
+	handler := setupHandler()
+
+	mockService := handler.service.(*services.MockService)
+	for _, storeId := range []string{"6789", "9876"} {
+		mockService.On("CalculateBatch", time.Time{}, time.Time{}, []string{storeId}, []string{"total_sales"}).Return(&models.BatchResult{
+			StoreIds:   []string{storeId},
+			Operations: []string{"total_sales"},
+			Cells: map[string]map[string]models.CalculationCell{
+				storeId: {"total_sales": {Value: big.NewFloat(10)}},
+			},
+		}, nil)
+	}
+
+	var percents []int
+	result, _, err := handler.CalculateJob(context.Background(), json.RawMessage(`{"operation":"total_sales","store_ids":["6789","9876","6789"]}`), func(percent int) {
+		percents = append(percents, percent)
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []int{0, 33}, percents)
+	var batchResponse BatchCalculateResponse
+	assert.NoError(t, json.Unmarshal(result, &batchResponse))
+	assert.Equal(t, []string{"6789", "9876"}, batchResponse.StoreIds)
+	assert.Contains(t, batchResponse.Results, "9876")
+	mockService.AssertNumberOfCalls(t, "CalculateBatch", 2)
+}
+
+func TestDataHandler_ExportJob_Timezone(t *testing.T) {
+	handler := setupHandler()
+
+	tokyo, _ := time.LoadLocation("Asia/Tokyo")
+	startDate := time.Date(2024, 6, 15, 0, 0, 0, 0, tokyo)
+	endDate := time.Date(2024, 6, 17, 0, 0, 0, 0, tokyo)
+	handler.service.(*services.MockService).On("GetSalesInRange", startDate, endDate, "6789").Return([]*models.Sale{}, nil)
+
+	result, _, err := handler.ExportJob(context.Background(), json.RawMessage(`{"store_id":"6789","start_date":"2024-06-15","end_date":"2024-06-16","timezone":"Asia/Tokyo"}`), noProgress)
+	assert.NoError(t, err)
+	assert.Equal(t, "[]", string(result))
+}
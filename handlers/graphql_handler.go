@@ -0,0 +1,79 @@
+package handlers
+
+import (
+	"bytes"
+	"dataflow/graphql"
+	"dataflow/services"
+	"encoding/json"
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
+
+type GraphQLHandler struct {
+	service services.DataService
+	schema  *graphql.Schema
+	limits  graphql.Limits
+}
+
+func NewGraphQLHandler(service services.DataService, limits graphql.Limits) *GraphQLHandler {
+	schema, err := newGraphQLSchema()
+	if err != nil {
+		panic(err)
+	}
+	return &GraphQLHandler{service: service, schema: schema, limits: limits}
+}
+
+// Query executes a GraphQL query sent as a JSON body, or in the query,
+// operationName and variables parameters of a GET. Requests that fail to
+// parse or validate, or exceed the limits, are answered with 400; errors
+// while resolving fields are reported next to the data with 200.
+func (h *GraphQLHandler) Query(c *gin.Context) {
+	var request graphql.Request
+	if c.Request.Method == http.MethodGet {
+		request.Query = c.Query("query")
+		request.OperationName = c.Query("operationName")
+		if variables := c.Query("variables"); variables != "" {
+			if err := decodeJSON([]byte(variables), &request.Variables); err != nil {
+				graphQLError(c, "invalid variables: "+err.Error())
+				return
+			}
+		}
+	} else {
+		body, err := c.GetRawData()
+		if err == nil {
+			err = decodeJSON(body, &request)
+		}
+		if err != nil {
+			graphQLError(c, "invalid request: "+err.Error())
+			return
+		}
+	}
+	if request.Query == "" {
+		graphQLError(c, "query is required")
+		return
+	}
+
+	ctx := withGraphQLLoader(c.Request.Context(), h.service)
+	response := h.schema.Execute(ctx, request, h.limits)
+	status := http.StatusOK
+	if !response.Executed {
+		status = http.StatusBadRequest
+	}
+	c.JSON(status, response)
+}
+
+// Schema returns the schema in the GraphQL schema definition language.
+func (h *GraphQLHandler) Schema(c *gin.Context) {
+	c.String(http.StatusOK, h.schema.String())
+}
+
+// decodeJSON keeps numbers as json.Number, as graphql.Request expects.
+func decodeJSON(data []byte, v interface{}) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	return decoder.Decode(v)
+}
+
+func graphQLError(c *gin.Context, message string) {
+	c.JSON(http.StatusBadRequest, &graphql.Response{Errors: []*graphql.Error{{Message: message}}})
+}
@@ -0,0 +1,215 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"dataflow/auth"
+	"dataflow/graphql"
+	"dataflow/models"
+	"dataflow/repo"
+	"dataflow/services"
+	"encoding/json"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+)
+
+// countingService counts the calls that reach the data service.
+type countingService struct {
+	services.DataService
+	calls map[string]int
+}
+
+func (s *countingService) GetAllSales(ctx context.Context) ([]*models.Sale, error) {
+	s.calls["GetAllSales"]++
+	return s.DataService.GetAllSales(ctx)
+}
+
+func (s *countingService) Aggregate(ctx context.Context, query models.AggregateQuery) ([]*models.AggregateGroup, error) {
+	s.calls["Aggregate"]++
+	return s.DataService.Aggregate(ctx, query)
+}
+
+func setupGraphQLHandler(t *testing.T, limits graphql.Limits) (*GraphQLHandler, *countingService) {
+	repository := repo.NewInMemoryRepository()
+	for _, sale := range []*models.Sale{
+		{ProductId: "p1", StoreId: "s1", QuantitySold: 2, SalePrice: 5, SaleDate: time.Date(2024, 6, 10, 9, 0, 0, 0, time.UTC)},
+		{ProductId: "p2", StoreId: "s1", QuantitySold: 1, SalePrice: 20, SaleDate: time.Date(2024, 6, 15, 9, 0, 0, 0, time.UTC)},
+		{ProductId: "p1", StoreId: "s2", QuantitySold: 3, SalePrice: 5, SaleDate: time.Date(2024, 7, 1, 9, 0, 0, 0, time.UTC)},
+	} {
+		assert.NoError(t, repository.AddSale(context.Background(), sale))
+	}
+	service := &countingService{DataService: services.NewAuthorizingDataService(services.NewDataService(repository)), calls: map[string]int{}}
+	return NewGraphQLHandler(service, limits), service
+}
+
+func postGraphQL(handler *GraphQLHandler, principal *models.Principal, request graphql.Request) *httptest.ResponseRecorder {
+	body, _ := json.Marshal(request)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodPost, "/graphql", bytes.NewReader(body))
+	c.Request = c.Request.WithContext(auth.WithPrincipal(c.Request.Context(), principal))
+	handler.Query(c)
+	return w
+}
+
+var graphQLAnalyst = &models.Principal{Subject: "analyst", Roles: []string{auth.RoleAnalyst}}
+
+func TestGraphQLHandler_Query(t *testing.T) {
+	handler, service := setupGraphQLHandler(t, graphql.Limits{})
+
+	w := postGraphQL(handler, graphQLAnalyst, graphql.Request{Query: `{
+		stores {
+			id
+			aggregate { revenue units count }
+			sales(from: "2024-06-12") { saleDate amount: salePrice product { id aggregate { count } } }
+			products { id }
+		}
+		products { id stores { id } }
+	}`})
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"data": {
+		"stores": [
+			{
+				"id": "s1",
+				"aggregate": {"revenue": "30", "units": 3, "count": 2},
+				"sales": [{"saleDate": "2024-06-15T09:00:00Z", "amount": 20, "product": {"id": "p2", "aggregate": {"count": 1}}}],
+				"products": [{"id": "p1"}, {"id": "p2"}]
+			},
+			{
+				"id": "s2",
+				"aggregate": {"revenue": "15", "units": 3, "count": 1},
+				"sales": [{"saleDate": "2024-07-01T09:00:00Z", "amount": 5, "product": {"id": "p1", "aggregate": {"count": 2}}}],
+				"products": [{"id": "p1"}]
+			}
+		],
+		"products": [{"id": "p1", "stores": [{"id": "s1"}, {"id": "s2"}]}, {"id": "p2", "stores": [{"id": "s1"}]}]
+	}}`, w.Body.String())
+	// The sales are loaded once per request, and each aggregate field is
+	// resolved for all its stores or products with a single call.
+	assert.Equal(t, 1, service.calls["GetAllSales"])
+	assert.Equal(t, 2, service.calls["Aggregate"])
+}
+
+func TestGraphQLHandler_Query_Aggregate(t *testing.T) {
+	handler, _ := setupGraphQLHandler(t, graphql.Limits{})
+
+	w := postGraphQL(handler, graphQLAnalyst, graphql.Request{
+		Query: `query ($groupBy: [Dimension!]) {
+			aggregate(to: "2024-06-30T00:00:00Z", groupBy: $groupBy) { store { id } period revenue averageSale }
+			total: aggregate { product { id } revenue count }
+		}`,
+		Variables: map[string]interface{}{"groupBy": []interface{}{"STORE", "DAY"}},
+	})
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"data": {
+		"aggregate": [
+			{"store": {"id": "s1"}, "period": "2024-06-10", "revenue": "10", "averageSale": "10"},
+			{"store": {"id": "s1"}, "period": "2024-06-15", "revenue": "20", "averageSale": "20"}
+		],
+		"total": [{"product": null, "revenue": "45", "count": 3}]
+	}}`, w.Body.String())
+}
+
+func TestGraphQLHandler_Query_Range(t *testing.T) {
+	handler, _ := setupGraphQLHandler(t, graphql.Limits{})
+
+	w := postGraphQL(handler, graphQLAnalyst, graphql.Request{Query: `{
+		halfOpen: sales(to: "2024-07-01T09:00:00Z") { productId }
+		closed: sales(to: "2024-07-01T09:00:00Z", range: CLOSED) { productId }
+		day: sales(from: "2024-06-15", to: "2024-06-15") { productId }
+		utc: aggregate(to: "2024-06-30") { count }
+		honolulu: aggregate(to: "2024-06-30", timezone: "Pacific/Honolulu") { count }
+	}`})
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"data": {
+		"halfOpen": [{"productId": "p1"}, {"productId": "p2"}],
+		"closed": [{"productId": "p1"}, {"productId": "p2"}, {"productId": "p1"}],
+		"day": [{"productId": "p2"}],
+		"utc": [{"count": 2}],
+		"honolulu": [{"count": 3}]
+	}}`, w.Body.String())
+}
+
+func TestGraphQLHandler_Query_ScopedPrincipal(t *testing.T) {
+	handler, _ := setupGraphQLHandler(t, graphql.Limits{})
+	manager := &models.Principal{Subject: "manager", Roles: []string{auth.RoleStoreManager}, Stores: []string{"s2"}}
+
+	w := postGraphQL(handler, manager, graphql.Request{Query: `{
+		stores { id }
+		s1: store(id: "s1") { id }
+		total: aggregate { count }
+		product(id: "p1") { aggregate { units } }
+	}`})
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"data": {"stores": [{"id": "s2"}], "s1": null, "total": [{"count": 1}], "product": {"aggregate": {"units": 3}}}}`, w.Body.String())
+
+	w = postGraphQL(handler, manager, graphql.Request{Query: `{ sales(storeId: "s1") { id } }`})
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"data": null, "errors": [
+		{"message": "forbidden: no access to store \"s1\"", "locations": [{"line": 1, "column": 3}], "path": ["sales"]}
+	]}`, w.Body.String())
+}
+
+func TestGraphQLHandler_Query_Invalid(t *testing.T) {
+	handler, service := setupGraphQLHandler(t, graphql.Limits{MaxComplexity: 1000, MaxDepth: 5})
+
+	w := postGraphQL(handler, graphQLAnalyst, graphql.Request{Query: `{ stores { name } }`})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.JSONEq(t, `{"errors": [{"message": "cannot query field \"name\" on type Store", "locations": [{"line": 1, "column": 12}]}]}`, w.Body.String())
+
+	w = postGraphQL(handler, graphQLAnalyst, graphql.Request{Query: `{ stores(limit: 100) { sales(limit: 100) { id } } }`})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "query complexity 10101 exceeds the limit of 1000")
+
+	w = postGraphQL(handler, graphQLAnalyst, graphql.Request{Query: `{ stores { products { stores { products { stores { id } } } } } }`})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "query depth 6 exceeds the limit of 5")
+	assert.Empty(t, service.calls)
+
+	w = postGraphQL(handler, graphQLAnalyst, graphql.Request{Query: `{ aggregate(from: "2024-07-01", to: "2024-06-01") { count } }`})
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), services.ErrWrongDate.Error())
+
+	w = httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodPost, "/graphql", bytes.NewBufferString("{"))
+	handler.Query(c)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestGraphQLHandler_Query_Get(t *testing.T) {
+	handler, _ := setupGraphQLHandler(t, graphql.Limits{})
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	query := url.Values{
+		"query":     {`query ($store: ID!) { sales(storeId: $store) { quantitySold saleDate store { id } } }`},
+		"variables": {`{"store": "s2"}`},
+	}
+	c.Request = httptest.NewRequest(http.MethodGet, "/graphql?"+query.Encode(), nil)
+	c.Request = c.Request.WithContext(auth.WithPrincipal(c.Request.Context(), graphQLAnalyst))
+	handler.Query(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"data": {"sales": [{"quantitySold": 3, "saleDate": "2024-07-01T09:00:00Z", "store": {"id": "s2"}}]}}`, w.Body.String())
+}
+
+func TestGraphQLHandler_Schema(t *testing.T) {
+	handler, _ := setupGraphQLHandler(t, graphql.Limits{})
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodGet, "/graphql/schema", nil)
+	handler.Schema(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "aggregate(from: DateTime, to: DateTime, range: RangeBounds! = HALF_OPEN, storeIds: [ID!], productIds: [ID!], groupBy: [Dimension!], timezone: String, limit: Int! = 100): [Aggregate!]!")
+}
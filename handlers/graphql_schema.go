@@ -0,0 +1,595 @@
+package handlers
+
+import (
+	"context"
+	"dataflow/graphql"
+	"dataflow/models"
+	"dataflow/repo"
+	"dataflow/services"
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+	"time"
+)
+
+// graphQLStore and graphQLProduct are the sources of the Store and Product
+// types. Stores and products have no records of their own: they exist
+// through their sales.
+type graphQLStore struct{ id string }
+type graphQLProduct struct{ id string }
+
+type graphQLLoaderKey struct{}
+
+// graphQLLoader memoizes the sales of one request, so that however many
+// objects a query selects, they are resolved from a single GetAllSales call.
+type graphQLLoader struct {
+	service   services.DataService
+	loaded    bool
+	err       error
+	sales     []*models.Sale
+	byStore   map[string][]*models.Sale
+	byProduct map[string][]*models.Sale
+}
+
+func withGraphQLLoader(ctx context.Context, service services.DataService) context.Context {
+	return context.WithValue(ctx, graphQLLoaderKey{}, &graphQLLoader{service: service})
+}
+
+func loaderFrom(ctx context.Context) *graphQLLoader {
+	return ctx.Value(graphQLLoaderKey{}).(*graphQLLoader)
+}
+
+func (l *graphQLLoader) load(ctx context.Context) error {
+	if l.loaded {
+		return l.err
+	}
+	l.loaded = true
+	l.sales, l.err = l.service.GetAllSales(ctx)
+	if l.err != nil {
+		return l.err
+	}
+	sortSales(l.sales)
+	l.byStore = make(map[string][]*models.Sale)
+	l.byProduct = make(map[string][]*models.Sale)
+	for _, sale := range l.sales {
+		l.byStore[sale.StoreId] = append(l.byStore[sale.StoreId], sale)
+		l.byProduct[sale.ProductId] = append(l.byProduct[sale.ProductId], sale)
+	}
+	return nil
+}
+
+// sortSales orders sales by date, then ID, so that pages are stable.
+func sortSales(sales []*models.Sale) {
+	sort.SliceStable(sales, func(i, j int) bool {
+		if !sales[i].SaleDate.Equal(sales[j].SaleDate) {
+			return sales[i].SaleDate.Before(sales[j].SaleDate)
+		}
+		return sales[i].ID < sales[j].ID
+	})
+}
+
+func newGraphQLSchema() (*graphql.Schema, error) {
+	dateTime := &graphql.Scalar{
+		Name:        "DateTime",
+		Description: "An RFC 3339 timestamp. Arguments also accept a date, 2006-01-02, which starts at midnight UTC, or in the aggregate's timezone; as to, a date includes the whole day.",
+		Serialize: func(value interface{}) (interface{}, error) {
+			t, ok := value.(time.Time)
+			if !ok {
+				return nil, fmt.Errorf("DateTime can't represent %T", value)
+			}
+			return t.Format(time.RFC3339Nano), nil
+		},
+		// Arguments are kept as strings, since a date only becomes a time
+		// once graphQLRange knows the time zone and the side of the range.
+		Parse: func(value interface{}) (interface{}, error) {
+			s, ok := value.(string)
+			if !ok {
+				return nil, errors.New("DateTime must be a string")
+			}
+			if _, _, _, err := parseTime(s, time.UTC, nil); err != nil {
+				return nil, err
+			}
+			return s, nil
+		},
+	}
+	decimal := &graphql.Scalar{
+		Name:        "Decimal",
+		Description: "An exact decimal number, as a string.",
+		Serialize: func(value interface{}) (interface{}, error) {
+			f, ok := value.(*big.Float)
+			if !ok {
+				return nil, fmt.Errorf("Decimal can't represent %T", value)
+			}
+			return f.Text('g', -1), nil
+		},
+		Parse: func(value interface{}) (interface{}, error) {
+			return nil, errors.New("Decimal is an output type")
+		},
+	}
+	dimension := &graphql.Enum{
+		Name:        "Dimension",
+		Description: "A dimension that aggregates can be grouped by.",
+		Values: []*graphql.EnumValue{
+			{Name: "STORE", Value: models.DimensionStore},
+			{Name: "PRODUCT", Value: models.DimensionProduct},
+			{Name: "DAY", Value: models.DimensionDay, Description: "The day of the sale in its store's time zone."},
+			{Name: "WEEK", Value: models.DimensionWeek, Description: "The ISO week of the sale in its store's time zone, such as 2024-W24."},
+			{Name: "MONTH", Value: models.DimensionMonth, Description: "The month of the sale in its store's time zone."},
+			{Name: "FISCAL_WEEK", Value: models.DimensionFiscalWeek, Description: "The week of the fiscal calendar, such as FY2024-W01."},
+			{Name: "FISCAL_PERIOD", Value: models.DimensionFiscalPeriod, Description: "The period of the fiscal calendar, such as FY2024-P01."},
+			{Name: "FISCAL_QUARTER", Value: models.DimensionFiscalQuarter, Description: "The quarter of the fiscal calendar, such as FY2024-Q1."},
+			{Name: "FISCAL_YEAR", Value: models.DimensionFiscalYear, Description: "The fiscal year, such as FY2024."},
+		},
+	}
+
+	rangeBounds := &graphql.Enum{
+		Name:        "RangeBounds",
+		Description: "Whether a range includes the sales at its to time.",
+		Values: []*graphql.EnumValue{
+			{Name: "HALF_OPEN", Value: repo.RangeHalfOpen, Description: "Sales at or after from and before to."},
+			{Name: "CLOSED", Value: repo.RangeClosed, Description: "Sales at or after from and at or before to."},
+		},
+	}
+
+	rangeArgs := func(limit string) []*graphql.ArgDef {
+		args := []*graphql.ArgDef{
+			{Name: "from", Type: "DateTime", Description: "Only sales at or after this time."},
+			{Name: "to", Type: "DateTime", Description: "Only sales before this time, or at it too with a CLOSED range."},
+			{Name: "range", Type: "RangeBounds!", Default: "HALF_OPEN"},
+		}
+		if limit != "" {
+			args = append(args,
+				&graphql.ArgDef{Name: "limit", Type: "Int!", Default: limit},
+				&graphql.ArgDef{Name: "offset", Type: "Int!", Default: "0"},
+			)
+		}
+		return args
+	}
+	pageArgs := []*graphql.ArgDef{
+		{Name: "limit", Type: "Int!", Default: "100"},
+		{Name: "offset", Type: "Int!", Default: "0"},
+	}
+
+	sale := &graphql.Object{
+		Name: "Sale",
+		Fields: []*graphql.FieldDef{
+			{Name: "id", Type: "ID!", Resolve: saleField(func(s *models.Sale) interface{} { return s.ID })},
+			{Name: "productId", Type: "ID!", Resolve: saleField(func(s *models.Sale) interface{} { return s.ProductId })},
+			{Name: "storeId", Type: "ID!", Resolve: saleField(func(s *models.Sale) interface{} { return s.StoreId })},
+			{Name: "quantitySold", Type: "Int!", Resolve: saleField(func(s *models.Sale) interface{} { return s.QuantitySold })},
+			{Name: "salePrice", Type: "Float!", Resolve: saleField(func(s *models.Sale) interface{} { return s.SalePrice })},
+			{Name: "saleDate", Type: "DateTime!", Resolve: saleField(func(s *models.Sale) interface{} { return s.SaleDate })},
+			{Name: "store", Type: "Store!", Resolve: saleField(func(s *models.Sale) interface{} { return &graphQLStore{s.StoreId} })},
+			{Name: "product", Type: "Product!", Resolve: saleField(func(s *models.Sale) interface{} { return &graphQLProduct{s.ProductId} })},
+		},
+	}
+	store := &graphql.Object{
+		Name:        "Store",
+		Description: "A store that has sales.",
+		Fields: []*graphql.FieldDef{
+			{Name: "id", Type: "ID!", Resolve: storeField(func(s *graphQLStore) interface{} { return s.id })},
+			{Name: "sales", Type: "[Sale!]!", Args: rangeArgs("100"), Resolve: resolveStoreSales},
+			{Name: "products", Type: "[Product!]!", Args: pageArgs, Resolve: resolveStoreProducts},
+			{Name: "aggregate", Type: "Aggregate!", Args: rangeArgs(""), Resolve: resolveStoreAggregates},
+		},
+	}
+	product := &graphql.Object{
+		Name:        "Product",
+		Description: "A product that has sales.",
+		Fields: []*graphql.FieldDef{
+			{Name: "id", Type: "ID!", Resolve: productField(func(p *graphQLProduct) interface{} { return p.id })},
+			{Name: "sales", Type: "[Sale!]!", Args: rangeArgs("100"), Resolve: resolveProductSales},
+			{Name: "stores", Type: "[Store!]!", Args: pageArgs, Resolve: resolveProductStores},
+			{Name: "aggregate", Type: "Aggregate!", Args: rangeArgs(""), Resolve: resolveProductAggregates},
+		},
+	}
+	aggregate := &graphql.Object{
+		Name:        "Aggregate",
+		Description: "Totals of a set of sales. The keys of the dimensions the sales weren't grouped by are null.",
+		Fields: []*graphql.FieldDef{
+			{Name: "store", Type: "Store", Resolve: groupField(func(g *models.AggregateGroup) interface{} {
+				if g.StoreId == "" {
+					return nil
+				}
+				return &graphQLStore{g.StoreId}
+			})},
+			{Name: "product", Type: "Product", Resolve: groupField(func(g *models.AggregateGroup) interface{} {
+				if g.ProductId == "" {
+					return nil
+				}
+				return &graphQLProduct{g.ProductId}
+			})},
+			{Name: "period", Type: "String", Description: "The day (2006-01-02), ISO week (2006-W01) or month (2006-01) of the sales.", Resolve: groupField(func(g *models.AggregateGroup) interface{} {
+				if g.Period == "" {
+					return nil
+				}
+				return g.Period
+			})},
+			{Name: "revenue", Type: "Decimal!", Resolve: groupField(func(g *models.AggregateGroup) interface{} { return g.Revenue })},
+			{Name: "units", Type: "Int!", Resolve: groupField(func(g *models.AggregateGroup) interface{} { return g.Units })},
+			{Name: "count", Type: "Int!", Resolve: groupField(func(g *models.AggregateGroup) interface{} { return g.Count })},
+			{Name: "averageSale", Type: "Decimal", Resolve: groupField(func(g *models.AggregateGroup) interface{} {
+				if average := g.AverageSale(); average != nil {
+					return average
+				}
+				return nil
+			})},
+		},
+	}
+	query := &graphql.Object{
+		Name: "Query",
+		Fields: []*graphql.FieldDef{
+			{Name: "sale", Type: "Sale", Args: []*graphql.ArgDef{{Name: "id", Type: "ID!"}}, Resolve: resolveSale},
+			{
+				Name:        "sales",
+				Type:        "[Sale!]!",
+				Description: "Sales ordered by date.",
+				Args:        append([]*graphql.ArgDef{{Name: "storeId", Type: "ID"}}, rangeArgs("100")...),
+				Resolve:     resolveSales,
+			},
+			{Name: "store", Type: "Store", Args: []*graphql.ArgDef{{Name: "id", Type: "ID!"}}, Resolve: resolveStore},
+			{Name: "stores", Type: "[Store!]!", Args: pageArgs, Resolve: resolveStores},
+			{Name: "product", Type: "Product", Args: []*graphql.ArgDef{{Name: "id", Type: "ID!"}}, Resolve: resolveProduct},
+			{Name: "products", Type: "[Product!]!", Args: pageArgs, Resolve: resolveProducts},
+			{
+				Name:        "aggregate",
+				Type:        "[Aggregate!]!",
+				Description: "Totals of the matching sales, one per group ordered by the group keys, or a single total without groupBy.",
+				Args: append(rangeArgs(""),
+					&graphql.ArgDef{Name: "storeIds", Type: "[ID!]"},
+					&graphql.ArgDef{Name: "productIds", Type: "[ID!]"},
+					&graphql.ArgDef{Name: "groupBy", Type: "[Dimension!]"},
+					&graphql.ArgDef{Name: "timezone", Type: "String", Description: "IANA time zone of the days, weeks and months instead of the stores' time zones."},
+					&graphql.ArgDef{Name: "limit", Type: "Int!", Default: "100"},
+				),
+				Resolve: resolveAggregate,
+			},
+		},
+	}
+	return graphql.NewSchema(query, sale, store, product, aggregate, dimension, rangeBounds, dateTime, decimal)
+}
+
+func saleField(get func(*models.Sale) interface{}) graphql.Resolver {
+	return func(ctx context.Context, sources []interface{}, args map[string]interface{}) ([]interface{}, error) {
+		values := make([]interface{}, len(sources))
+		for i, source := range sources {
+			values[i] = get(source.(*models.Sale))
+		}
+		return values, nil
+	}
+}
+
+func storeField(get func(*graphQLStore) interface{}) graphql.Resolver {
+	return func(ctx context.Context, sources []interface{}, args map[string]interface{}) ([]interface{}, error) {
+		values := make([]interface{}, len(sources))
+		for i, source := range sources {
+			values[i] = get(source.(*graphQLStore))
+		}
+		return values, nil
+	}
+}
+
+func productField(get func(*graphQLProduct) interface{}) graphql.Resolver {
+	return func(ctx context.Context, sources []interface{}, args map[string]interface{}) ([]interface{}, error) {
+		values := make([]interface{}, len(sources))
+		for i, source := range sources {
+			values[i] = get(source.(*graphQLProduct))
+		}
+		return values, nil
+	}
+}
+
+func groupField(get func(*models.AggregateGroup) interface{}) graphql.Resolver {
+	return func(ctx context.Context, sources []interface{}, args map[string]interface{}) ([]interface{}, error) {
+		values := make([]interface{}, len(sources))
+		for i, source := range sources {
+			values[i] = get(source.(*models.AggregateGroup))
+		}
+		return values, nil
+	}
+}
+
+// graphQLRange returns the range of the from, to and range arguments, with
+// dates in location, as parseRange does; a missing or null argument leaves
+// the range open.
+func graphQLRange(args map[string]interface{}, location *time.Location) (time.Time, time.Time, error) {
+	start, _ := args["from"].(string)
+	end, _ := args["to"].(string)
+	bounds, _ := args["range"].(string)
+	from, to, err := parseRange(start, end, location, nil, bounds)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	if !from.IsZero() && !to.IsZero() && from.After(to) {
+		return time.Time{}, time.Time{}, services.ErrWrongDate
+	}
+	return from, to, nil
+}
+
+// page returns the page of items selected by the limit and offset
+// arguments.
+func page[T any](items []T, args map[string]interface{}) ([]interface{}, error) {
+	limit, offset := args["limit"].(int), args["offset"].(int)
+	if limit < 0 || offset < 0 {
+		return nil, errors.New("limit and offset must not be negative")
+	}
+	offset = min(offset, len(items))
+	items = items[offset:min(offset+limit, len(items))]
+	values := make([]interface{}, len(items))
+	for i, item := range items {
+		values[i] = item
+	}
+	return values, nil
+}
+
+func salesInRange(sales []*models.Sale, from, to time.Time) []*models.Sale {
+	var selected []*models.Sale
+	for _, sale := range sales {
+		if repo.InRange(sale.SaleDate, from, to) {
+			selected = append(selected, sale)
+		}
+	}
+	return selected
+}
+
+func resolveSale(ctx context.Context, sources []interface{}, args map[string]interface{}) ([]interface{}, error) {
+	sale, err := loaderFrom(ctx).service.GetSale(ctx, args["id"].(string))
+	if errors.Is(err, repo.ErrSaleNotFound) {
+		return []interface{}{nil}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return []interface{}{sale}, nil
+}
+
+func resolveSales(ctx context.Context, sources []interface{}, args map[string]interface{}) ([]interface{}, error) {
+	from, to, err := graphQLRange(args, time.UTC)
+	if err != nil {
+		return nil, err
+	}
+	l := loaderFrom(ctx)
+	var sales []*models.Sale
+	if storeId, ok := args["storeId"].(string); ok {
+		if sales, err = l.service.GetSalesInRange(ctx, from, to, storeId); err != nil {
+			return nil, err
+		}
+		sortSales(sales)
+	} else {
+		if err := l.load(ctx); err != nil {
+			return nil, err
+		}
+		sales = salesInRange(l.sales, from, to)
+	}
+	values, err := page(sales, args)
+	return []interface{}{values}, err
+}
+
+func resolveStore(ctx context.Context, sources []interface{}, args map[string]interface{}) ([]interface{}, error) {
+	l := loaderFrom(ctx)
+	if err := l.load(ctx); err != nil {
+		return nil, err
+	}
+	id := args["id"].(string)
+	if _, ok := l.byStore[id]; !ok {
+		return []interface{}{nil}, nil
+	}
+	return []interface{}{&graphQLStore{id}}, nil
+}
+
+func resolveStores(ctx context.Context, sources []interface{}, args map[string]interface{}) ([]interface{}, error) {
+	l := loaderFrom(ctx)
+	if err := l.load(ctx); err != nil {
+		return nil, err
+	}
+	stores, err := page(sortedStores(l.byStore), args)
+	return []interface{}{stores}, err
+}
+
+func resolveProduct(ctx context.Context, sources []interface{}, args map[string]interface{}) ([]interface{}, error) {
+	l := loaderFrom(ctx)
+	if err := l.load(ctx); err != nil {
+		return nil, err
+	}
+	id := args["id"].(string)
+	if _, ok := l.byProduct[id]; !ok {
+		return []interface{}{nil}, nil
+	}
+	return []interface{}{&graphQLProduct{id}}, nil
+}
+
+func resolveProducts(ctx context.Context, sources []interface{}, args map[string]interface{}) ([]interface{}, error) {
+	l := loaderFrom(ctx)
+	if err := l.load(ctx); err != nil {
+		return nil, err
+	}
+	products, err := page(sortedProducts(l.byProduct), args)
+	return []interface{}{products}, err
+}
+
+func sortedStores(sales map[string][]*models.Sale) []*graphQLStore {
+	stores := make([]*graphQLStore, 0, len(sales))
+	for id := range sales {
+		stores = append(stores, &graphQLStore{id})
+	}
+	sort.Slice(stores, func(i, j int) bool { return stores[i].id < stores[j].id })
+	return stores
+}
+
+func sortedProducts(sales map[string][]*models.Sale) []*graphQLProduct {
+	products := make([]*graphQLProduct, 0, len(sales))
+	for id := range sales {
+		products = append(products, &graphQLProduct{id})
+	}
+	sort.Slice(products, func(i, j int) bool { return products[i].id < products[j].id })
+	return products
+}
+
+func resolveStoreSales(ctx context.Context, sources []interface{}, args map[string]interface{}) ([]interface{}, error) {
+	return resolveSalesOf(ctx, sources, args, func(l *graphQLLoader, source interface{}) []*models.Sale {
+		return l.byStore[source.(*graphQLStore).id]
+	})
+}
+
+func resolveProductSales(ctx context.Context, sources []interface{}, args map[string]interface{}) ([]interface{}, error) {
+	return resolveSalesOf(ctx, sources, args, func(l *graphQLLoader, source interface{}) []*models.Sale {
+		return l.byProduct[source.(*graphQLProduct).id]
+	})
+}
+
+func resolveSalesOf(ctx context.Context, sources []interface{}, args map[string]interface{}, salesOf func(*graphQLLoader, interface{}) []*models.Sale) ([]interface{}, error) {
+	from, to, err := graphQLRange(args, time.UTC)
+	if err != nil {
+		return nil, err
+	}
+	l := loaderFrom(ctx)
+	if err := l.load(ctx); err != nil {
+		return nil, err
+	}
+	values := make([]interface{}, len(sources))
+	for i, source := range sources {
+		if values[i], err = page(salesInRange(salesOf(l, source), from, to), args); err != nil {
+			return nil, err
+		}
+	}
+	return values, nil
+}
+
+func resolveStoreProducts(ctx context.Context, sources []interface{}, args map[string]interface{}) ([]interface{}, error) {
+	l := loaderFrom(ctx)
+	if err := l.load(ctx); err != nil {
+		return nil, err
+	}
+	values := make([]interface{}, len(sources))
+	for i, source := range sources {
+		products := make(map[string][]*models.Sale)
+		for _, sale := range l.byStore[source.(*graphQLStore).id] {
+			products[sale.ProductId] = append(products[sale.ProductId], sale)
+		}
+		var err error
+		if values[i], err = page(sortedProducts(products), args); err != nil {
+			return nil, err
+		}
+	}
+	return values, nil
+}
+
+func resolveProductStores(ctx context.Context, sources []interface{}, args map[string]interface{}) ([]interface{}, error) {
+	l := loaderFrom(ctx)
+	if err := l.load(ctx); err != nil {
+		return nil, err
+	}
+	values := make([]interface{}, len(sources))
+	for i, source := range sources {
+		stores := make(map[string][]*models.Sale)
+		for _, sale := range l.byProduct[source.(*graphQLProduct).id] {
+			stores[sale.StoreId] = append(stores[sale.StoreId], sale)
+		}
+		var err error
+		if values[i], err = page(sortedStores(stores), args); err != nil {
+			return nil, err
+		}
+	}
+	return values, nil
+}
+
+// resolveStoreAggregates totals the sales of every store of the level with
+// a single Aggregate call grouped by store.
+func resolveStoreAggregates(ctx context.Context, sources []interface{}, args map[string]interface{}) ([]interface{}, error) {
+	ids := make([]string, len(sources))
+	for i, source := range sources {
+		ids[i] = source.(*graphQLStore).id
+	}
+	from, to, err := graphQLRange(args, time.UTC)
+	if err != nil {
+		return nil, err
+	}
+	groups, err := loaderFrom(ctx).service.Aggregate(ctx, models.AggregateQuery{
+		StartDate: from,
+		EndDate:   to,
+		StoreIds:  ids,
+		GroupBy:   []string{models.DimensionStore},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return groupsByKey(ids, groups, func(g *models.AggregateGroup) string { return g.StoreId }), nil
+}
+
+// resolveProductAggregates is resolveStoreAggregates for products.
+func resolveProductAggregates(ctx context.Context, sources []interface{}, args map[string]interface{}) ([]interface{}, error) {
+	ids := make([]string, len(sources))
+	for i, source := range sources {
+		ids[i] = source.(*graphQLProduct).id
+	}
+	from, to, err := graphQLRange(args, time.UTC)
+	if err != nil {
+		return nil, err
+	}
+	groups, err := loaderFrom(ctx).service.Aggregate(ctx, models.AggregateQuery{
+		StartDate:  from,
+		EndDate:    to,
+		ProductIds: ids,
+		GroupBy:    []string{models.DimensionProduct},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return groupsByKey(ids, groups, func(g *models.AggregateGroup) string { return g.ProductId }), nil
+}
+
+// groupsByKey returns the group of each id, or an empty group for ids
+// without sales. The groups are stripped of their keys, which the parent
+// object already holds.
+func groupsByKey(ids []string, groups []*models.AggregateGroup, key func(*models.AggregateGroup) string) []interface{} {
+	byKey := make(map[string]*models.AggregateGroup, len(groups))
+	for _, group := range groups {
+		byKey[key(group)] = &models.AggregateGroup{Revenue: group.Revenue, Units: group.Units, Count: group.Count}
+	}
+	values := make([]interface{}, len(ids))
+	for i, id := range ids {
+		group, ok := byKey[id]
+		if !ok {
+			group = &models.AggregateGroup{Revenue: new(big.Float)}
+		}
+		values[i] = group
+	}
+	return values
+}
+
+func resolveAggregate(ctx context.Context, sources []interface{}, args map[string]interface{}) ([]interface{}, error) {
+	query := models.AggregateQuery{
+		StoreIds:   stringList(args["storeIds"]),
+		ProductIds: stringList(args["productIds"]),
+		GroupBy:    stringList(args["groupBy"]),
+	}
+	location := time.UTC
+	if timezone, ok := args["timezone"].(string); ok {
+		var err error
+		if location, err = time.LoadLocation(timezone); err != nil {
+			return nil, fmt.Errorf("unknown timezone %q", timezone)
+		}
+		query.Location = location
+	}
+	from, to, err := graphQLRange(args, location)
+	if err != nil {
+		return nil, err
+	}
+	query.StartDate, query.EndDate = from, to
+	groups, err := loaderFrom(ctx).service.Aggregate(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	values, err := page(groups, map[string]interface{}{"limit": args["limit"], "offset": 0})
+	return []interface{}{values}, err
+}
+
+func stringList(value interface{}) []string {
+	list, _ := value.([]interface{})
+	values := make([]string, len(list))
+	for i, item := range list {
+		values[i] = item.(string)
+	}
+	return values
+}
@@ -1,30 +1,62 @@
 package handlers
 
 import (
+	"context"
 	"dataflow/models"
 	"dataflow/repo"
 	"dataflow/services"
 	"errors"
+	"fmt"
 	"github.com/gin-gonic/gin"
+	"log/slog"
 	"math/big"
 	"net/http"
+	"slices"
+	"strings"
 	"time"
 )
 
 type DataHandler struct {
-	service services.DataService
+	service  services.DataService
+	catalog  services.CatalogService
+	rollups  services.RollupService
+	calendar *services.Calendar
 }
 
-func NewDataHandler(service services.DataService) *DataHandler {
-	return &DataHandler{service: service}
+// NewDataHandler serves sales of service. Sales and calculation results are
+// enriched with store and product names from catalog, which also provides the
+// hierarchies of rollups, unless it's nil. Fiscal periods in requests are
+// those of calendar, or of services.DefaultCalendar when it's nil.
+func NewDataHandler(service services.DataService, catalog services.CatalogService, calendar *services.Calendar) *DataHandler {
+	if calendar == nil {
+		calendar = services.DefaultCalendar
+	}
+	handler := &DataHandler{service: service, catalog: catalog, calendar: calendar}
+	if catalog != nil {
+		handler.rollups = services.NewRollupService(service, catalog)
+	}
+	return handler
+}
+
+// SaleResponse is a sale with the names of its store and product, when the
+// catalog has them.
+type SaleResponse struct {
+	*models.Sale
+	StoreName   string `json:"store_name,omitempty"`
+	ProductName string `json:"product_name,omitempty"`
 }
 
 func (h *DataHandler) GetData(c *gin.Context) {
-	sales, err := h.service.GetAllSales()
+	sales, err := h.service.GetAllSales(c.Request.Context())
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{})
+		if errors.Is(err, services.ErrForbidden) {
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error(), "status": http.StatusForbidden})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{})
+		}
+		return
 	}
-	c.JSON(http.StatusOK, sales)
+	c.JSON(http.StatusOK, h.enrichSales(c.Request.Context(), sales))
 }
 
 func (h *DataHandler) AddData(c *gin.Context) {
@@ -34,10 +66,14 @@ func (h *DataHandler) AddData(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
-	err = h.service.AddSale(sale)
+	err = h.service.AddSale(c.Request.Context(), sale)
 	if err != nil {
 		if errors.Is(err, repo.ErrSaleAlreadyExists) {
 			c.JSON(http.StatusConflict, gin.H{"error": err.Error(), "status": http.StatusConflict})
+		} else if errors.Is(err, services.ErrUnknownReference) {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error(), "status": http.StatusUnprocessableEntity})
+		} else if errors.Is(err, services.ErrForbidden) {
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error(), "status": http.StatusForbidden})
 		} else {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error(), "status": http.StatusInternalServerError})
 		}
@@ -47,19 +83,71 @@ func (h *DataHandler) AddData(c *gin.Context) {
 }
 
 type CalculateRequest struct {
-	Operation string `json:"operation"`
-	StoreId   string `json:"store_id"`
-	StartDate string `json:"start_date,omitempty"`
-	EndDate   string `json:"end_date,omitempty"`
+	Operation  string   `json:"operation"`
+	StoreId    string   `json:"store_id"`
+	StartDate  string   `json:"start_date,omitempty"`
+	EndDate    string   `json:"end_date,omitempty"`
+	StoreIds   []string `json:"store_ids,omitempty"`
+	Operations []string `json:"operations,omitempty"`
+	// Level rolls the sales up to a level of the store or product hierarchy;
+	// DrillDown nests the levels below it.
+	Level     string `json:"level,omitempty"`
+	DrillDown bool   `json:"drill_down,omitempty"`
+	// Timezone is the IANA time zone that dates such as 2024-06-15 resolve
+	// in, instead of the stores' time zone.
+	Timezone string `json:"timezone,omitempty"`
+	// GroupBy breaks the totals down by day, week, month or fiscal period.
+	GroupBy string `json:"group_by,omitempty"`
+	// Range is half_open, the default, for sales from StartDate until before
+	// EndDate, or closed to include sales at an EndDate time as well.
+	Range string `json:"range,omitempty"`
 }
 
 type CalculateResponse struct {
 	StoreId    string     `json:"store_id"`
+	StoreName  string     `json:"store_name,omitempty"`
 	StartDate  string     `json:"start_date"`
 	EndDate    string     `json:"end_date"`
 	TotalSales *big.Float `json:"total_sales"`
 }
 
+type BatchCalculateResponse struct {
+	StartDate  string                                       `json:"start_date"`
+	EndDate    string                                       `json:"end_date"`
+	StoreIds   []string                                     `json:"store_ids"`
+	StoreNames map[string]string                            `json:"store_names,omitempty"`
+	Operations []string                                     `json:"operations"`
+	Results    map[string]map[string]models.CalculationCell `json:"results"`
+}
+
+// RollupResponse holds the totals of every group at the requested level,
+// which add up exactly to the overall totals.
+type RollupResponse struct {
+	StartDate  string               `json:"start_date"`
+	EndDate    string               `json:"end_date"`
+	Level      string               `json:"level"`
+	TotalSales *big.Float           `json:"total_sales"`
+	UnitsSold  int64                `json:"units_sold"`
+	SaleCount  int64                `json:"sale_count"`
+	Groups     []*models.RollupNode `json:"groups"`
+}
+
+type PeriodsResponse struct {
+	StartDate string          `json:"start_date"`
+	EndDate   string          `json:"end_date"`
+	GroupBy   string          `json:"group_by"`
+	Periods   []*PeriodTotals `json:"periods"`
+}
+
+// PeriodTotals holds the totals of the sales of a period, such as 2024-06 or
+// FY2024-P05.
+type PeriodTotals struct {
+	Period     string     `json:"period"`
+	TotalSales *big.Float `json:"total_sales"`
+	UnitsSold  int64      `json:"units_sold"`
+	SaleCount  int64      `json:"sale_count"`
+}
+
 func (h *DataHandler) Calculate(c *gin.Context) {
 	var calculateRequest CalculateRequest
 	err := c.ShouldBindJSON(&calculateRequest)
@@ -68,45 +156,422 @@ func (h *DataHandler) Calculate(c *gin.Context) {
 		return
 	}
 
-	if calculateRequest.Operation != "total_sales" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "unsupported operation"})
+	calculateResponse, err := h.calculate(c.Request.Context(), calculateRequest, nil)
+	if err != nil {
+		var badRequest badRequestError
+		if errors.As(err, &badRequest) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		} else if errors.Is(err, services.ErrWrongDate) || errors.Is(err, services.ErrUnsupportedDimension) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "status": http.StatusBadRequest})
+		} else if errors.Is(err, services.ErrForbidden) {
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error(), "status": http.StatusForbidden})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error(), "status": http.StatusInternalServerError})
+		}
 		return
 	}
+	c.JSON(http.StatusOK, calculateResponse)
+}
 
-	var startDate time.Time
-	var endDate time.Time
+type QueryRequest struct {
+	Query   string `json:"query" binding:"required"`
+	Explain bool   `json:"explain,omitempty"`
+	// Timezone is the time zone that dates in the query resolve in, by
+	// default the one the queried stores share in the catalog.
+	Timezone string `json:"timezone,omitempty"`
+}
 
-	if calculateRequest.StartDate != "" {
-		t, err := time.Parse(time.RFC3339, calculateRequest.StartDate)
-		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
-			return
-		}
-		startDate = t
+// Query runs a statement of the SQL dialect of package salesql. An explained
+// query, by the explain field or an EXPLAIN prefix, returns its plan instead
+// of its rows.
+func (h *DataHandler) Query(c *gin.Context) {
+	var queryRequest QueryRequest
+	err := c.ShouldBindJSON(&queryRequest)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
 	}
-	if calculateRequest.EndDate != "" {
-		t, err := time.Parse(time.RFC3339, calculateRequest.EndDate)
-		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+
+	var location *time.Location
+	if queryRequest.Timezone != "" {
+		if location, err = h.location(c.Request.Context(), queryRequest.Timezone, nil); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "status": http.StatusBadRequest})
 			return
 		}
-		endDate = t
 	}
 
-	totalSales, err := h.service.CalculateSales(startDate, endDate, calculateRequest.StoreId)
+	result, err := h.service.Query(c.Request.Context(), models.SalesQuery{Statement: queryRequest.Query, Explain: queryRequest.Explain, Location: location})
 	if err != nil {
-		if errors.Is(err, services.ErrWrongDate) {
+		if errors.Is(err, services.ErrInvalidQuery) {
 			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "status": http.StatusBadRequest})
+		} else if errors.Is(err, services.ErrForbidden) {
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error(), "status": http.StatusForbidden})
 		} else {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error(), "status": http.StatusInternalServerError})
 		}
 		return
 	}
-	calculateResponse := CalculateResponse{
+	if result.Plan != nil {
+		c.JSON(http.StatusOK, gin.H{"columns": result.Columns, "plan": result.Plan})
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+// calculate returns a CalculateResponse, a BatchCalculateResponse when the
+// request lists several stores or operations, a RollupResponse when it asks
+// for a hierarchy level, or a PeriodsResponse when it groups by period.
+// A non-nil progress is told the percentage of a batch's stores calculated.
+func (h *DataHandler) calculate(ctx context.Context, calculateRequest CalculateRequest, progress func(int)) (interface{}, error) {
+	if calculateRequest.GroupBy != "" {
+		return h.periods(ctx, calculateRequest)
+	}
+	if calculateRequest.Level != "" {
+		return h.rollup(ctx, calculateRequest)
+	}
+	if len(calculateRequest.StoreIds) > 0 || len(calculateRequest.Operations) > 0 {
+		return h.calculateBatch(ctx, calculateRequest, progress)
+	}
+	if calculateRequest.Operation != "total_sales" {
+		return nil, badRequestError{errors.New("unsupported operation")}
+	}
+
+	startDate, endDate, err := h.parseLocalRange(ctx, calculateRequest.StartDate, calculateRequest.EndDate, calculateRequest.Timezone, calculateRequest.Range, []string{calculateRequest.StoreId})
+	if err != nil {
+		return nil, err
+	}
+
+	totalSales, err := h.service.CalculateSales(ctx, startDate, endDate, calculateRequest.StoreId)
+	if err != nil {
+		return nil, err
+	}
+	return &CalculateResponse{
 		StoreId:    calculateRequest.StoreId,
+		StoreName:  h.storeNames(ctx)[calculateRequest.StoreId],
 		TotalSales: totalSales,
 		StartDate:  calculateRequest.StartDate,
 		EndDate:    calculateRequest.EndDate,
+	}, nil
+}
+
+func (h *DataHandler) calculateBatch(ctx context.Context, calculateRequest CalculateRequest, progress func(int)) (*BatchCalculateResponse, error) {
+	storeIds := calculateRequest.StoreIds
+	if len(storeIds) == 0 {
+		storeIds = []string{calculateRequest.StoreId}
 	}
-	c.JSON(http.StatusOK, calculateResponse)
+	operations := calculateRequest.Operations
+	if len(operations) == 0 {
+		if calculateRequest.Operation == "" {
+			return nil, badRequestError{errors.New("operations are required")}
+		}
+		operations = []string{calculateRequest.Operation}
+	}
+
+	startDate, endDate, err := h.parseLocalRange(ctx, calculateRequest.StartDate, calculateRequest.EndDate, calculateRequest.Timezone, calculateRequest.Range, storeIds)
+	if err != nil {
+		return nil, err
+	}
+
+	var result *models.BatchResult
+	if progress == nil || slices.Contains(storeIds, services.AllStores) {
+		result, err = h.service.CalculateBatch(ctx, startDate, endDate, storeIds, operations)
+	} else {
+		result, err = h.calculateStores(ctx, startDate, endDate, storeIds, operations, progress)
+	}
+	if err != nil {
+		return nil, err
+	}
+	var storeNames map[string]string
+	if names := h.storeNames(ctx); len(names) > 0 {
+		storeNames = make(map[string]string)
+		for _, storeId := range result.StoreIds {
+			if name, ok := names[storeId]; ok {
+				storeNames[storeId] = name
+			}
+		}
+	}
+	return &BatchCalculateResponse{
+		StartDate:  calculateRequest.StartDate,
+		EndDate:    calculateRequest.EndDate,
+		StoreIds:   result.StoreIds,
+		StoreNames: storeNames,
+		Operations: result.Operations,
+		Results:    result.Cells,
+	}, nil
+}
+
+func (h *DataHandler) rollup(ctx context.Context, calculateRequest CalculateRequest) (*RollupResponse, error) {
+	if h.rollups == nil {
+		return nil, badRequestError{errors.New("rollups need the store and product catalog")}
+	}
+	if len(calculateRequest.StoreIds) > 0 || len(calculateRequest.Operations) > 0 || calculateRequest.StoreId != "" {
+		return nil, badRequestError{errors.New("a rollup covers every store, so it can't be combined with store_id, store_ids or operations")}
+	}
+	switch calculateRequest.Operation {
+	case "", services.MetricTotalSales, services.MetricUnitsSold, services.MetricSaleCount:
+	default:
+		return nil, badRequestError{errors.New("rollups support total_sales, units_sold and sale_count, which they all report")}
+	}
+
+	startDate, endDate, err := h.parseLocalRange(ctx, calculateRequest.StartDate, calculateRequest.EndDate, calculateRequest.Timezone, calculateRequest.Range, []string{services.AllStores})
+	if err != nil {
+		return nil, err
+	}
+
+	root, err := h.rollups.Rollup(ctx, models.RollupQuery{
+		StartDate: startDate,
+		EndDate:   endDate,
+		Level:     calculateRequest.Level,
+		DrillDown: calculateRequest.DrillDown,
+	})
+	if err != nil {
+		return nil, err
+	}
+	groups := root.Children
+	if groups == nil {
+		groups = []*models.RollupNode{}
+	}
+	return &RollupResponse{
+		StartDate:  calculateRequest.StartDate,
+		EndDate:    calculateRequest.EndDate,
+		Level:      calculateRequest.Level,
+		TotalSales: root.Revenue,
+		UnitsSold:  root.Units,
+		SaleCount:  root.Count,
+		Groups:     groups,
+	}, nil
+}
+
+func (h *DataHandler) periods(ctx context.Context, calculateRequest CalculateRequest) (*PeriodsResponse, error) {
+	switch calculateRequest.GroupBy {
+	case models.DimensionDay, models.DimensionWeek, models.DimensionMonth,
+		models.DimensionFiscalWeek, models.DimensionFiscalPeriod, models.DimensionFiscalQuarter, models.DimensionFiscalYear:
+	default:
+		return nil, badRequestError{fmt.Errorf("group_by %q must be day, week, month, fiscal_week, fiscal_period, fiscal_quarter or fiscal_year", calculateRequest.GroupBy)}
+	}
+	if calculateRequest.Level != "" || len(calculateRequest.Operations) > 0 {
+		return nil, badRequestError{errors.New("group_by can't be combined with level or operations")}
+	}
+	switch calculateRequest.Operation {
+	case "", services.MetricTotalSales, services.MetricUnitsSold, services.MetricSaleCount:
+	default:
+		return nil, badRequestError{errors.New("periods support total_sales, units_sold and sale_count, which they all report")}
+	}
+	storeIds := calculateRequest.StoreIds
+	if len(storeIds) == 0 {
+		storeIds = []string{calculateRequest.StoreId}
+	}
+
+	startDate, endDate, err := h.parseLocalRange(ctx, calculateRequest.StartDate, calculateRequest.EndDate, calculateRequest.Timezone, calculateRequest.Range, storeIds)
+	if err != nil {
+		return nil, err
+	}
+	query := models.AggregateQuery{
+		StartDate: startDate,
+		EndDate:   endDate,
+		GroupBy:   []string{calculateRequest.GroupBy},
+	}
+	if !slices.Contains(storeIds, "") && !slices.Contains(storeIds, services.AllStores) {
+		query.StoreIds = storeIds
+	}
+	// Without a timezone, sales are bucketed in the time zone of their store.
+	if calculateRequest.Timezone != "" {
+		if query.Location, err = h.location(ctx, calculateRequest.Timezone, nil); err != nil {
+			return nil, err
+		}
+	}
+
+	groups, err := h.service.Aggregate(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	periods := make([]*PeriodTotals, 0, len(groups))
+	for _, group := range groups {
+		periods = append(periods, &PeriodTotals{Period: group.Period, TotalSales: group.Revenue, UnitsSold: group.Units, SaleCount: group.Count})
+	}
+	return &PeriodsResponse{
+		StartDate: calculateRequest.StartDate,
+		EndDate:   calculateRequest.EndDate,
+		GroupBy:   calculateRequest.GroupBy,
+		Periods:   periods,
+	}, nil
+}
+
+// enrichSales adds the catalog names of the stores and products of sales.
+func (h *DataHandler) enrichSales(ctx context.Context, sales []*models.Sale) []*SaleResponse {
+	if sales == nil {
+		return nil
+	}
+	storeNames := h.storeNames(ctx)
+	productNames := h.productNames(ctx)
+	enriched := make([]*SaleResponse, len(sales))
+	for i, sale := range sales {
+		enriched[i] = &SaleResponse{Sale: sale, StoreName: storeNames[sale.StoreId], ProductName: productNames[sale.ProductId]}
+	}
+	return enriched
+}
+
+// storeNames maps store IDs to names. Names are a convenience, so a catalog
+// failure leaves responses without them rather than failing the request.
+func (h *DataHandler) storeNames(ctx context.Context) map[string]string {
+	if h.catalog == nil {
+		return nil
+	}
+	stores, err := h.catalog.GetAllStores(ctx)
+	if err != nil {
+		slog.WarnContext(ctx, "couldn't get store names", slog.Any("error", err))
+		return nil
+	}
+	names := make(map[string]string, len(stores))
+	for _, store := range stores {
+		names[store.ID] = store.Name
+	}
+	return names
+}
+
+func (h *DataHandler) productNames(ctx context.Context) map[string]string {
+	if h.catalog == nil {
+		return nil
+	}
+	products, err := h.catalog.GetAllProducts(ctx)
+	if err != nil {
+		slog.WarnContext(ctx, "couldn't get product names", slog.Any("error", err))
+		return nil
+	}
+	names := make(map[string]string, len(products))
+	for _, product := range products {
+		names[product.ID] = product.Name
+	}
+	return names
+}
+
+// badRequestError marks errors caused by the request itself rather than by
+// the service.
+type badRequestError struct {
+	error
+}
+
+// parseRange parses the half-open range from start until before end, or the
+// range up to and including end when bounds is closed. Bounds are RFC 3339
+// times, or dates (2006-01-02) and fiscal periods of calendar (FY2024-P01)
+// that cover whole days of location: a start date or period includes the
+// sales from its first midnight on, an end date or period those until the
+// midnight that ends it, whatever bounds is, so that days shortened or
+// lengthened by DST are covered exactly. Fiscal periods aren't accepted
+// without calendar.
+func parseRange(start string, end string, location *time.Location, calendar *services.Calendar, bounds string) (time.Time, time.Time, error) {
+	var startDate time.Time
+	var endDate time.Time
+
+	switch bounds {
+	case "", repo.RangeHalfOpen, repo.RangeClosed:
+	default:
+		return time.Time{}, time.Time{}, badRequestError{fmt.Errorf("range %q must be half_open or closed", bounds)}
+	}
+	if start != "" {
+		first, _, _, err := parseTime(start, location, calendar)
+		if err != nil {
+			return time.Time{}, time.Time{}, badRequestError{err}
+		}
+		startDate = first
+	}
+	if end != "" {
+		first, next, days, err := parseTime(end, location, calendar)
+		if err != nil {
+			return time.Time{}, time.Time{}, badRequestError{err}
+		}
+		endDate = repo.EndOf(first, bounds)
+		if days {
+			endDate = next
+		}
+	}
+	return startDate, endDate, nil
+}
+
+// parseTime parses an RFC 3339 time, or a date or fiscal period, whose first
+// instant in location and the first instant after it are returned along with
+// whether it was one.
+func parseTime(value string, location *time.Location, calendar *services.Calendar) (time.Time, time.Time, bool, error) {
+	t, err := time.Parse(time.RFC3339, value)
+	if err == nil {
+		return t, t, false, nil
+	}
+	if date, dateErr := time.Parse(time.DateOnly, value); dateErr == nil {
+		return repo.StartOfDay(date, location), repo.StartOfDay(date.AddDate(0, 0, 1), location), true, nil
+	}
+	if calendar != nil && isFiscalPeriod(value) {
+		first, next, periodErr := calendar.Range(value)
+		if periodErr != nil {
+			return time.Time{}, time.Time{}, false, periodErr
+		}
+		return repo.StartOfDay(first, location), repo.StartOfDay(next, location), true, nil
+	}
+	return time.Time{}, time.Time{}, false, err
+}
+
+// parseLocalRange is parseRange in the time zone of the stores, which is
+// only looked up when the range has dates or fiscal periods.
+func (h *DataHandler) parseLocalRange(ctx context.Context, start string, end string, timezone string, bounds string, storeIds []string) (time.Time, time.Time, error) {
+	location := time.UTC
+	if timezone != "" || spansDays(start) || spansDays(end) {
+		var err error
+		if location, err = h.location(ctx, timezone, storeIds); err != nil {
+			return time.Time{}, time.Time{}, err
+		}
+	}
+	return parseRange(start, end, location, h.calendar, bounds)
+}
+
+func spansDays(value string) bool {
+	_, err := time.Parse(time.DateOnly, value)
+	return err == nil || isFiscalPeriod(value)
+}
+
+func isFiscalPeriod(value string) bool {
+	return strings.HasPrefix(value, "FY")
+}
+
+// location returns the time zone that the dates of a request for the stores
+// resolve in: timezone if it's set, otherwise the catalog time zone the
+// stores share, or UTC. Stores in different time zones need timezone.
+func (h *DataHandler) location(ctx context.Context, timezone string, storeIds []string) (*time.Location, error) {
+	if timezone != "" {
+		location, err := time.LoadLocation(timezone)
+		if err != nil {
+			return nil, badRequestError{fmt.Errorf("unknown timezone %q", timezone)}
+		}
+		return location, nil
+	}
+	if h.catalog == nil {
+		return time.UTC, nil
+	}
+	locations, err := h.catalog.StoreLocations(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var location *time.Location
+	use := func(storeLocation *time.Location) error {
+		if location != nil && location.String() != storeLocation.String() {
+			return badRequestError{fmt.Errorf("the stores are in different time zones, %s and %s, so dates need a timezone", location, storeLocation)}
+		}
+		location = storeLocation
+		return nil
+	}
+	for _, storeId := range storeIds {
+		if storeId == "" || storeId == services.AllStores {
+			for _, storeLocation := range locations {
+				if err := use(storeLocation); err != nil {
+					return nil, err
+				}
+			}
+		} else if storeLocation, ok := locations[storeId]; ok {
+			if err := use(storeLocation); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if location == nil {
+		return time.UTC, nil
+	}
+	return location, nil
 }
@@ -3,8 +3,10 @@ package handlers
 import (
 	"bytes"
 	"dataflow/models"
+	"dataflow/repo"
 	"dataflow/services"
 	"encoding/json"
+	"fmt"
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
@@ -17,7 +19,7 @@ import (
 
 func setupHandler() *DataHandler {
 	mockService := &services.MockService{}
-	handler := NewDataHandler(mockService)
+	handler := NewDataHandler(mockService, nil, nil)
 	return handler
 }
 
@@ -45,6 +47,7 @@ func TestDataHandler_GetData(t *testing.T) {
 
 	w := httptest.NewRecorder()
 	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodGet, "/data", nil)
 
 	handler.GetData(c)
 
@@ -57,6 +60,20 @@ func TestDataHandler_GetData(t *testing.T) {
 	assert.Contains(t, sales, sale2)
 }
 
+func TestDataHandler_GetData_Forbidden(t *testing.T) {
+	handler := setupHandler()
+
+	handler.service.(*services.MockService).On("GetAllSales").Return([]*models.Sale(nil), services.ErrForbidden)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodGet, "/data", nil)
+
+	handler.GetData(c)
+
+	assert.Equal(t, http.StatusForbidden, w.Code)
+}
+
 func TestDataHandler_AddData(t *testing.T) {
 	handler := setupHandler()
 
@@ -127,6 +144,35 @@ func TestDataHandler_AddData_InvalidJSON(t *testing.T) {
 	assert.Equal(t, http.StatusBadRequest, w.Code)
 }
 
+func TestDataHandler_Calculate_ClosedRange(t *testing.T) {
+	handler := setupHandler()
+
+	startDate := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	endDate := time.Date(2024, 6, 16, 0, 0, 0, 0, time.UTC)
+	// A closed range includes sales at its end.
+	handler.service.(*services.MockService).On("CalculateSales", startDate, endDate.Add(time.Nanosecond), "6789").Return(big.NewFloat(2), nil)
+
+	for body, code := range map[string]int{
+		`{"operation":"total_sales","store_id":"6789","start_date":"2024-06-01T00:00:00Z","end_date":"2024-06-16T00:00:00Z","range":"closed"}`: http.StatusOK,
+		`{"operation":"total_sales","store_id":"6789","start_date":"2024-06-01T00:00:00Z","end_date":"2024-06-16T00:00:00Z","range":"open"}`:   http.StatusBadRequest,
+	} {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request, _ = http.NewRequest("POST", "/calculate", bytes.NewBufferString(body))
+		c.Request.Header.Set("Content-Type", "application/json")
+
+		handler.Calculate(c)
+
+		assert.Equal(t, code, w.Code, body)
+	}
+
+	// Dates already cover their whole day.
+	start, end, err := parseRange("2024-06-01", "2024-06-15", time.UTC, nil, repo.RangeClosed)
+	assert.NoError(t, err)
+	assert.Equal(t, startDate, start)
+	assert.Equal(t, endDate, end)
+}
+
 func TestDataHandler_Calculate_InvalidJSON(t *testing.T) {
 	handler := setupHandler()
 
@@ -247,3 +293,229 @@ func TestDataHandler_Calculate_EmptyEndDate(t *testing.T) {
 	assert.Equal(t, "6789", calculateResponse.StoreId)
 	assert.NotNil(t, calculateResponse.TotalSales)
 }
+
+func TestDataHandler_Calculate_Batch(t *testing.T) {
+	handler := setupHandler()
+
+	startDate := time.Date(2024, 6, 1, 14, 30, 0, 0, time.UTC)
+	endDate := time.Date(2024, 6, 16, 14, 30, 0, 0, time.UTC)
+	storeIds := []string{"all"}
+	operations := []string{"total_sales", "profit"}
+
+	calculateRequest := CalculateRequest{
+		StoreIds:   storeIds,
+		Operations: operations,
+		StartDate:  startDate.Format(time.RFC3339),
+		EndDate:    endDate.Format(time.RFC3339),
+	}
+
+	handler.service.(*services.MockService).On("CalculateBatch", startDate, endDate, storeIds, operations).Return(&models.BatchResult{
+		StoreIds:   []string{"6789"},
+		Operations: operations,
+		Cells: map[string]map[string]models.CalculationCell{
+			"6789": {
+				"total_sales": {Value: new(big.Float).SetFloat64(199.9)},
+				"profit":      {Error: "unsupported operation"},
+			},
+		},
+	}, nil)
+
+	jsonData, _ := json.Marshal(calculateRequest)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request, _ = http.NewRequest("POST", "/calculate", bytes.NewBuffer(jsonData))
+	c.Request.Header.Set("Content-Type", "application/json")
+
+	handler.Calculate(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	var batchResponse BatchCalculateResponse
+	err := json.Unmarshal(w.Body.Bytes(), &batchResponse)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"6789"}, batchResponse.StoreIds)
+	assert.Equal(t, "199.9", batchResponse.Results["6789"]["total_sales"].Value.String())
+	assert.Equal(t, "unsupported operation", batchResponse.Results["6789"]["profit"].Error)
+}
+
+func TestDataHandler_Calculate_BatchWithoutOperations(t *testing.T) {
+	handler := setupHandler()
+
+	calculateRequest := CalculateRequest{StoreIds: []string{"6789", "9876"}}
+
+	jsonData, _ := json.Marshal(calculateRequest)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request, _ = http.NewRequest("POST", "/calculate", bytes.NewBuffer(jsonData))
+	c.Request.Header.Set("Content-Type", "application/json")
+
+	handler.Calculate(c)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "operations are required")
+}
+
+func TestDataHandler_Query(t *testing.T) {
+	handler := setupHandler()
+
+	statement := "SELECT store_id, SUM(amount) FROM sales GROUP BY store_id"
+	handler.service.(*services.MockService).On("Query", models.SalesQuery{Statement: statement}).Return(&models.QueryResult{
+		Columns: []string{"store_id", "sum(amount)"},
+		Rows:    [][]interface{}{{"6789", 199.9}},
+	}, nil)
+
+	jsonData, _ := json.Marshal(QueryRequest{Query: statement})
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request, _ = http.NewRequest("POST", "/query", bytes.NewBuffer(jsonData))
+	c.Request.Header.Set("Content-Type", "application/json")
+
+	handler.Query(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"columns": ["store_id", "sum(amount)"], "rows": [["6789", 199.9]]}`, w.Body.String())
+}
+
+func TestDataHandler_Query_Explain(t *testing.T) {
+	handler := setupHandler()
+
+	statement := "SELECT id FROM sales WHERE store_id = '6789'"
+	handler.service.(*services.MockService).On("Query", models.SalesQuery{Statement: statement, Explain: true}).Return(&models.QueryResult{
+		Columns: []string{"id"},
+		Plan:    []*models.PlanStep{{Operation: "IndexScan", Detail: "sales by store index: store_id IN ('6789')"}},
+	}, nil)
+
+	jsonData, _ := json.Marshal(QueryRequest{Query: statement, Explain: true})
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request, _ = http.NewRequest("POST", "/query", bytes.NewBuffer(jsonData))
+	c.Request.Header.Set("Content-Type", "application/json")
+
+	handler.Query(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"columns": ["id"], "plan": [{"operation": "IndexScan", "detail": "sales by store index: store_id IN ('6789')"}]}`, w.Body.String())
+}
+
+func TestDataHandler_Query_Timezone(t *testing.T) {
+	handler := setupHandler()
+
+	statement := "SELECT COUNT(*) FROM sales WHERE sale_date >= '2024-06-01'"
+	paris, _ := time.LoadLocation("Europe/Paris")
+	handler.service.(*services.MockService).On("Query", models.SalesQuery{Statement: statement, Location: paris}).Return(&models.QueryResult{
+		Columns: []string{"count(*)"},
+		Rows:    [][]interface{}{{2}},
+	}, nil)
+
+	for timezone, code := range map[string]int{"Europe/Paris": http.StatusOK, "Mars/Olympus": http.StatusBadRequest} {
+		jsonData, _ := json.Marshal(QueryRequest{Query: statement, Timezone: timezone})
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request, _ = http.NewRequest("POST", "/query", bytes.NewBuffer(jsonData))
+		c.Request.Header.Set("Content-Type", "application/json")
+
+		handler.Query(c)
+
+		assert.Equal(t, code, w.Code, timezone)
+	}
+}
+
+func TestDataHandler_Query_Invalid(t *testing.T) {
+	handler := setupHandler()
+
+	handler.service.(*services.MockService).On("Query", mock.Anything).Return(nil, fmt.Errorf("%w: unknown column name (line 1, column 8)", services.ErrInvalidQuery))
+
+	jsonData, _ := json.Marshal(QueryRequest{Query: "SELECT name FROM sales"})
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request, _ = http.NewRequest("POST", "/query", bytes.NewBuffer(jsonData))
+	c.Request.Header.Set("Content-Type", "application/json")
+
+	handler.Query(c)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "unknown column name")
+
+	w = httptest.NewRecorder()
+	c, _ = gin.CreateTestContext(w)
+	c.Request, _ = http.NewRequest("POST", "/query", bytes.NewBufferString(`{}`))
+	c.Request.Header.Set("Content-Type", "application/json")
+
+	handler.Query(c)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestParseRange_Dates(t *testing.T) {
+	paris, _ := time.LoadLocation("Europe/Paris")
+	santiago, _ := time.LoadLocation("America/Santiago")
+
+	for _, test := range []struct {
+		date       string
+		location   *time.Location
+		start, end time.Time
+	}{
+		{"2024-06-15", time.UTC, time.Date(2024, 6, 15, 0, 0, 0, 0, time.UTC), time.Date(2024, 6, 16, 0, 0, 0, 0, time.UTC)},
+		// The day clocks go forward has 23 hours, the day they go back 25.
+		{"2024-03-31", paris, time.Date(2024, 3, 30, 23, 0, 0, 0, time.UTC), time.Date(2024, 3, 31, 22, 0, 0, 0, time.UTC)},
+		{"2024-10-27", paris, time.Date(2024, 10, 26, 22, 0, 0, 0, time.UTC), time.Date(2024, 10, 27, 23, 0, 0, 0, time.UTC)},
+		// Santiago skips from 00:00 to 01:00, so the day starts at 01:00.
+		{"2024-09-08", santiago, time.Date(2024, 9, 8, 4, 0, 0, 0, time.UTC), time.Date(2024, 9, 9, 3, 0, 0, 0, time.UTC)},
+	} {
+		start, end, err := parseRange(test.date, test.date, test.location, nil, repo.RangeHalfOpen)
+		assert.NoError(t, err, test.date)
+		assert.True(t, start.Equal(test.start), "%s: start %s", test.date, start)
+		assert.True(t, end.Equal(test.end), "%s: end %s", test.date, end)
+	}
+
+	// Times are absolute, whatever the time zone.
+	start, _, err := parseRange("2024-06-15T10:00:00Z", "", paris, nil, repo.RangeHalfOpen)
+	assert.NoError(t, err)
+	assert.Equal(t, time.Date(2024, 6, 15, 10, 0, 0, 0, time.UTC), start)
+
+	_, _, err = parseRange("15/06/2024", "", paris, nil, repo.RangeHalfOpen)
+	assert.Error(t, err)
+}
+
+func TestDataHandler_Calculate_FiscalPeriods(t *testing.T) {
+	calendar, _ := services.NewCalendar(services.CalendarOptions{Pattern: services.Calendar454, StartMonth: time.February, WeekStart: time.Sunday})
+	mockService := &services.MockService{}
+	handler := NewDataHandler(mockService, nil, calendar)
+
+	// FY2024-P02 to FY2024-P03 run from March 3 to May 4, 2024.
+	mockService.On("Aggregate", models.AggregateQuery{
+		StartDate: time.Date(2024, 3, 3, 0, 0, 0, 0, time.UTC),
+		EndDate:   time.Date(2024, 5, 5, 0, 0, 0, 0, time.UTC),
+		StoreIds:  []string{"6789"},
+		GroupBy:   []string{models.DimensionFiscalPeriod},
+	}).Return([]*models.AggregateGroup{
+		{Period: "FY2024-P02", Revenue: big.NewFloat(10), Units: 2, Count: 1},
+		{Period: "FY2024-P03", Revenue: big.NewFloat(5.5), Units: 1, Count: 1},
+	}, nil)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request, _ = http.NewRequest("POST", "/calculate", bytes.NewBufferString(`{"store_id":"6789","group_by":"fiscal_period","start_date":"FY2024-P02","end_date":"FY2024-P03"}`))
+	c.Request.Header.Set("Content-Type", "application/json")
+
+	handler.Calculate(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"start_date":"FY2024-P02","end_date":"FY2024-P03","group_by":"fiscal_period","periods":[
+		{"period":"FY2024-P02","total_sales":"10","units_sold":2,"sale_count":1},
+		{"period":"FY2024-P03","total_sales":"5.5","units_sold":1,"sale_count":1}]}`, w.Body.String())
+
+	for _, body := range []string{
+		`{"store_id":"6789","group_by":"store"}`,
+		`{"store_id":"6789","group_by":"month","level":"region"}`,
+		`{"operation":"total_sales","store_id":"6789","start_date":"FY2024-P13"}`,
+	} {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request, _ = http.NewRequest("POST", "/calculate", bytes.NewBufferString(body))
+		c.Request.Header.Set("Content-Type", "application/json")
+
+		handler.Calculate(c)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code, body)
+	}
+}
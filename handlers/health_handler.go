@@ -0,0 +1,32 @@
+package handlers
+
+import (
+	"dataflow/models"
+	"dataflow/services"
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
+
+type HealthHandler struct {
+	health *services.Health
+}
+
+func NewHealthHandler(health *services.Health) *HealthHandler {
+	return &HealthHandler{health: health}
+}
+
+// Healthz answers 200 for as long as the process can handle requests at all.
+func (h *HealthHandler) Healthz(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"status": models.HealthStatusOK})
+}
+
+// Readyz answers 503 while starting, while shutting down and while a
+// dependency such as the repository is unreachable.
+func (h *HealthHandler) Readyz(c *gin.Context) {
+	readiness := h.health.Readiness(c.Request.Context())
+	if readiness.Status != models.HealthStatusOK {
+		c.JSON(http.StatusServiceUnavailable, readiness)
+		return
+	}
+	c.JSON(http.StatusOK, readiness)
+}
@@ -0,0 +1,77 @@
+package handlers
+
+import (
+	"context"
+	"dataflow/models"
+	"dataflow/services"
+	"encoding/json"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func setupHealthRouter(health *services.Health) *gin.Engine {
+	handler := NewHealthHandler(health)
+	router := gin.New()
+	router.GET("/healthz", handler.Healthz)
+	router.GET("/readyz", handler.Readyz)
+	return router
+}
+
+func getReadiness(t *testing.T, router *gin.Engine) (int, *models.Readiness) {
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/readyz", nil)
+	router.ServeHTTP(w, req)
+	var readiness models.Readiness
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &readiness))
+	return w.Code, &readiness
+}
+
+func TestHealthHandler_Healthz(t *testing.T) {
+	router := setupHealthRouter(services.NewHealth())
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/healthz", nil)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"status":"ok"}`, w.Body.String())
+}
+
+func TestHealthHandler_Readyz_Lifecycle(t *testing.T) {
+	health := services.NewHealth()
+	router := setupHealthRouter(health)
+
+	code, readiness := getReadiness(t, router)
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, "starting", readiness.Checks["server"])
+
+	health.SetReady()
+	code, readiness = getReadiness(t, router)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, models.HealthStatusOK, readiness.Status)
+
+	health.SetShuttingDown()
+	code, readiness = getReadiness(t, router)
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, "shutting down", readiness.Checks["server"])
+}
+
+func TestHealthHandler_Readyz_FailingCheck(t *testing.T) {
+	health := services.NewHealth(
+		services.HealthCheck{Name: "repository", Check: func(ctx context.Context) error { return errors.New("connection refused") }},
+		services.HealthCheck{Name: "cache", Check: func(ctx context.Context) error { return nil }},
+	)
+	health.SetReady()
+	router := setupHealthRouter(health)
+
+	code, readiness := getReadiness(t, router)
+
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, models.HealthStatusUnavailable, readiness.Status)
+	assert.Equal(t, "connection refused", readiness.Checks["repository"])
+	assert.Equal(t, models.HealthStatusOK, readiness.Checks["cache"])
+}
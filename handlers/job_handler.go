@@ -0,0 +1,88 @@
+package handlers
+
+import (
+	"dataflow/models"
+	"dataflow/repo"
+	"dataflow/services"
+	"encoding/json"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
+
+type JobRequest struct {
+	Type    string          `json:"type" binding:"required"`
+	Request json.RawMessage `json:"request" binding:"required"`
+}
+
+type JobHandler struct {
+	service services.JobService
+}
+
+func NewJobHandler(service services.JobService) *JobHandler {
+	return &JobHandler{service: service}
+}
+
+func (h *JobHandler) Submit(c *gin.Context) {
+	var jobRequest JobRequest
+	if err := c.ShouldBindJSON(&jobRequest); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "status": http.StatusBadRequest})
+		return
+	}
+	job, err := h.service.Submit(c.Request.Context(), jobRequest.Type, jobRequest.Request)
+	if err != nil {
+		jobError(c, err)
+		return
+	}
+	c.Header("Location", "/jobs/"+job.ID)
+	c.JSON(http.StatusAccepted, job)
+}
+
+func (h *JobHandler) GetJob(c *gin.Context) {
+	job, err := h.service.GetJob(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		jobError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, job)
+}
+
+func (h *JobHandler) GetResult(c *gin.Context) {
+	job, err := h.service.GetJob(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		jobError(c, err)
+		return
+	}
+	switch job.Status {
+	case models.JobStatusSucceeded:
+		c.Data(http.StatusOK, job.ContentType, job.Result)
+	case models.JobStatusFailed, models.JobStatusCancelled:
+		c.JSON(http.StatusConflict, gin.H{"error": job.Error, "status": http.StatusConflict, "job_status": job.Status})
+	default:
+		jobError(c, services.ErrJobNotFinished)
+	}
+}
+
+func (h *JobHandler) Cancel(c *gin.Context) {
+	job, err := h.service.Cancel(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		jobError(c, err)
+		return
+	}
+	c.JSON(http.StatusAccepted, job)
+}
+
+func jobError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrUnsupportedJobType):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "status": http.StatusBadRequest})
+	case errors.Is(err, repo.ErrJobNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error(), "status": http.StatusNotFound})
+	case errors.Is(err, services.ErrJobFinished), errors.Is(err, services.ErrJobNotFinished):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error(), "status": http.StatusConflict})
+	case errors.Is(err, services.ErrJobQueueFull):
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error(), "status": http.StatusServiceUnavailable})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error(), "status": http.StatusInternalServerError})
+	}
+}
@@ -0,0 +1,117 @@
+package handlers
+
+import (
+	"bytes"
+	"dataflow/models"
+	"dataflow/repo"
+	"dataflow/services"
+	"encoding/json"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestJobHandler_Submit(t *testing.T) {
+	mockService := &services.MockJobService{}
+	handler := NewJobHandler(mockService)
+
+	request := json.RawMessage(`{"operation":"total_sales","store_id":"6789"}`)
+	mockService.On("Submit", JobTypeCalculate, request).Return(&models.Job{ID: "1", Type: JobTypeCalculate, Status: models.JobStatusQueued}, nil)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request, _ = http.NewRequest("POST", "/jobs", bytes.NewBufferString(`{"type":"calculate","request":{"operation":"total_sales","store_id":"6789"}}`))
+	c.Request.Header.Set("Content-Type", "application/json")
+
+	handler.Submit(c)
+
+	assert.Equal(t, http.StatusAccepted, w.Code)
+	assert.Equal(t, "/jobs/1", w.Header().Get("Location"))
+	var job models.Job
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &job))
+	assert.Equal(t, models.JobStatusQueued, job.Status)
+}
+
+func TestJobHandler_Submit_QueueFull(t *testing.T) {
+	mockService := &services.MockJobService{}
+	handler := NewJobHandler(mockService)
+
+	mockService.On("Submit", JobTypeExport, json.RawMessage(`{}`)).Return(nil, services.ErrJobQueueFull)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request, _ = http.NewRequest("POST", "/jobs", bytes.NewBufferString(`{"type":"export","request":{}}`))
+	c.Request.Header.Set("Content-Type", "application/json")
+
+	handler.Submit(c)
+
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+}
+
+func TestJobHandler_GetJob_NotFound(t *testing.T) {
+	mockService := &services.MockJobService{}
+	handler := NewJobHandler(mockService)
+
+	mockService.On("GetJob", "missing").Return(nil, repo.ErrJobNotFound)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request, _ = http.NewRequest("GET", "/jobs/missing", nil)
+	c.Params = gin.Params{{Key: "id", Value: "missing"}}
+
+	handler.GetJob(c)
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestJobHandler_GetResult(t *testing.T) {
+	mockService := &services.MockJobService{}
+	handler := NewJobHandler(mockService)
+
+	mockService.On("GetJob", "1").Return(&models.Job{ID: "1", Status: models.JobStatusSucceeded, Result: []byte("id,product_id\n"), ContentType: "text/csv"}, nil)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request, _ = http.NewRequest("GET", "/jobs/1/result", nil)
+	c.Params = gin.Params{{Key: "id", Value: "1"}}
+
+	handler.GetResult(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "text/csv", w.Header().Get("Content-Type"))
+	assert.Equal(t, "id,product_id\n", w.Body.String())
+}
+
+func TestJobHandler_GetResult_NotFinished(t *testing.T) {
+	mockService := &services.MockJobService{}
+	handler := NewJobHandler(mockService)
+
+	mockService.On("GetJob", "1").Return(&models.Job{ID: "1", Status: models.JobStatusRunning}, nil)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request, _ = http.NewRequest("GET", "/jobs/1/result", nil)
+	c.Params = gin.Params{{Key: "id", Value: "1"}}
+
+	handler.GetResult(c)
+
+	assert.Equal(t, http.StatusConflict, w.Code)
+}
+
+func TestJobHandler_Cancel(t *testing.T) {
+	mockService := &services.MockJobService{}
+	handler := NewJobHandler(mockService)
+
+	mockService.On("Cancel", "1").Return(&models.Job{ID: "1", Status: models.JobStatusRunning}, nil)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request, _ = http.NewRequest("DELETE", "/jobs/1", nil)
+	c.Params = gin.Params{{Key: "id", Value: "1"}}
+
+	handler.Cancel(c)
+
+	assert.Equal(t, http.StatusAccepted, w.Code)
+}
@@ -0,0 +1,158 @@
+package handlers
+
+import (
+	"dataflow/auth"
+	"dataflow/models"
+	"dataflow/services"
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+const (
+	ActionSubscribe   = "subscribe"
+	ActionUnsubscribe = "unsubscribe"
+)
+
+const (
+	liveWriteWait    = 10 * time.Second
+	livePongWait     = 60 * time.Second
+	livePingInterval = livePongWait * 9 / 10
+	liveMaxMessage   = 1024
+)
+
+type LiveRequest struct {
+	Action  string `json:"action"`
+	StoreId string `json:"store_id"`
+	Metric  string `json:"metric"`
+}
+
+type LiveMessage struct {
+	Type string `json:"type"`
+	*models.LiveAggregate
+	Error string `json:"error,omitempty"`
+}
+
+type LiveHandler struct {
+	aggregator   *services.LiveAggregator
+	upgrader     websocket.Upgrader
+	pingInterval time.Duration
+	pongWait     time.Duration
+}
+
+func NewLiveHandler(aggregator *services.LiveAggregator) *LiveHandler {
+	return &LiveHandler{
+		aggregator:   aggregator,
+		pingInterval: livePingInterval,
+		pongWait:     livePongWait,
+	}
+}
+
+// Aggregates upgrades the request to a WebSocket. Clients send LiveRequest
+// messages to (un)subscribe to a store's metric and receive an "aggregate"
+// message with the current value and on every change.
+func (h *LiveHandler) Aggregates(c *gin.Context) {
+	principal, _ := auth.PrincipalFromContext(c.Request.Context())
+	if !auth.HasPermission(principal, auth.PermissionReadSales) {
+		c.JSON(http.StatusForbidden, gin.H{"error": services.ErrForbidden.Error(), "status": http.StatusForbidden})
+		return
+	}
+	conn, err := h.upgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		// The upgrader has already replied with an HTTP error.
+		return
+	}
+	defer conn.Close()
+
+	watch := h.aggregator.Watch()
+	defer watch.Close()
+
+	replies := make(chan LiveMessage, 16)
+	readerDone := make(chan struct{})
+	go h.read(conn, principal, watch, replies, readerDone)
+
+	ping := time.NewTicker(h.pingInterval)
+	defer ping.Stop()
+	for {
+		select {
+		case <-readerDone:
+			return
+		case <-watch.Done():
+			deadline := time.Now().Add(liveWriteWait)
+			conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseGoingAway, "server shutting down"), deadline)
+			return
+		case reply := <-replies:
+			if !h.write(conn, reply) {
+				return
+			}
+		case <-watch.Ready():
+			for _, aggregate := range watch.Drain() {
+				if !h.write(conn, LiveMessage{Type: "aggregate", LiveAggregate: &aggregate}) {
+					return
+				}
+			}
+		case <-ping.C:
+			if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(liveWriteWait)); err != nil {
+				return
+			}
+		}
+	}
+}
+
+func (h *LiveHandler) read(conn *websocket.Conn, principal *models.Principal, watch *services.AggregateWatch, replies chan<- LiveMessage, done chan<- struct{}) {
+	defer close(done)
+	conn.SetReadLimit(liveMaxMessage)
+	conn.SetReadDeadline(time.Now().Add(h.pongWait))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(h.pongWait))
+	})
+
+	for {
+		var request LiveRequest
+		if err := conn.ReadJSON(&request); err != nil {
+			if websocket.IsUnexpectedCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway) {
+				slog.Warn("live aggregates connection closed", slog.Any("error", err))
+			}
+			return
+		}
+		conn.SetReadDeadline(time.Now().Add(h.pongWait))
+
+		var reply LiveMessage
+		switch request.Action {
+		case ActionSubscribe:
+			if request.StoreId == "" {
+				reply = LiveMessage{Type: "error", Error: "store_id is required"}
+			} else if !auth.CanAccessStore(principal, request.StoreId) {
+				reply = LiveMessage{Type: "error", Error: services.ErrForbidden.Error()}
+			} else if err := watch.Subscribe(request.StoreId, request.Metric); err != nil {
+				reply = LiveMessage{Type: "error", Error: err.Error()}
+			} else {
+				continue
+			}
+		case ActionUnsubscribe:
+			watch.Unsubscribe(request.StoreId, request.Metric)
+			continue
+		default:
+			reply = LiveMessage{Type: "error", Error: "unsupported action"}
+		}
+
+		select {
+		case replies <- reply:
+		case <-watch.Done():
+			return
+		}
+	}
+}
+
+func (h *LiveHandler) write(conn *websocket.Conn, message LiveMessage) bool {
+	conn.SetWriteDeadline(time.Now().Add(liveWriteWait))
+	if err := conn.WriteJSON(message); err != nil {
+		if err != websocket.ErrCloseSent {
+			slog.Warn("couldn't write live aggregate", slog.Any("error", err))
+		}
+		return false
+	}
+	return true
+}
@@ -0,0 +1,78 @@
+package handlers
+
+import (
+	"context"
+	"dataflow/auth"
+	"dataflow/models"
+	"dataflow/services"
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func setupLiveServer(t *testing.T) (*services.SaleBroker, *websocket.Conn, context.CancelFunc) {
+	broker := services.NewSaleBroker(10, 10)
+	aggregator := services.NewLiveAggregator(broker, nil, nil)
+	ctx, cancel := context.WithCancel(context.Background())
+	go aggregator.Run(ctx)
+	assert.Eventually(t, func() bool { return broker.Subscribers() == 1 }, time.Second, time.Millisecond)
+
+	router := gin.New()
+	router.Use(auth.Anonymous())
+	router.GET("/live/aggregates", NewLiveHandler(aggregator).Aggregates)
+	server := httptest.NewServer(router)
+	t.Cleanup(server.Close)
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http")+"/live/aggregates", nil)
+	assert.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	return broker, conn, cancel
+}
+
+func TestLiveHandler_Aggregates(t *testing.T) {
+	broker, conn, cancel := setupLiveServer(t)
+	defer cancel()
+
+	err := conn.WriteJSON(LiveRequest{Action: ActionSubscribe, StoreId: "6789", Metric: services.MetricTotalSales})
+	assert.NoError(t, err)
+
+	var initial LiveMessage
+	assert.NoError(t, conn.ReadJSON(&initial))
+	assert.Equal(t, "aggregate", initial.Type)
+	assert.Equal(t, 0.0, initial.Value)
+
+	broker.Publish(&models.Sale{StoreId: "6789", QuantitySold: 10, SalePrice: 19.99, SaleDate: time.Now()})
+
+	var update LiveMessage
+	assert.NoError(t, conn.ReadJSON(&update))
+	assert.Equal(t, "aggregate", update.Type)
+	assert.Equal(t, "6789", update.StoreId)
+	assert.Equal(t, services.MetricTotalSales, update.Metric)
+	assert.Equal(t, 199.9, update.Value)
+}
+
+func TestLiveHandler_Aggregates_UnsupportedMetric(t *testing.T) {
+	_, conn, cancel := setupLiveServer(t)
+	defer cancel()
+
+	conn.WriteJSON(LiveRequest{Action: ActionSubscribe, StoreId: "6789", Metric: "profit"})
+
+	var reply LiveMessage
+	assert.NoError(t, conn.ReadJSON(&reply))
+	assert.Equal(t, "error", reply.Type)
+	assert.Equal(t, services.ErrUnsupportedMetric.Error(), reply.Error)
+}
+
+func TestLiveHandler_Aggregates_Shutdown(t *testing.T) {
+	_, conn, cancel := setupLiveServer(t)
+
+	cancel()
+
+	_, _, err := conn.ReadMessage()
+	assert.True(t, websocket.IsCloseError(err, websocket.CloseGoingAway))
+}
@@ -0,0 +1,88 @@
+package handlers
+
+import (
+	"dataflow/auth"
+	"dataflow/models"
+	"dataflow/services"
+	"github.com/gin-contrib/sse"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const defaultHeartbeatInterval = 15 * time.Second
+
+type StreamHandler struct {
+	broker    *services.SaleBroker
+	heartbeat time.Duration
+}
+
+func NewStreamHandler(broker *services.SaleBroker) *StreamHandler {
+	return &StreamHandler{broker: broker, heartbeat: defaultHeartbeatInterval}
+}
+
+func (h *StreamHandler) StreamSales(c *gin.Context) {
+	var lastEventID uint64
+	if header := c.GetHeader("Last-Event-ID"); header != "" {
+		id, err := strconv.ParseUint(header, 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid Last-Event-ID", "status": http.StatusBadRequest})
+			return
+		}
+		lastEventID = id
+	}
+	storeId := c.Query("store_id")
+	principal, _ := auth.PrincipalFromContext(c.Request.Context())
+	if !auth.HasPermission(principal, auth.PermissionReadSales) {
+		c.JSON(http.StatusForbidden, gin.H{"error": services.ErrForbidden.Error(), "status": http.StatusForbidden})
+		return
+	}
+
+	sub, replay := h.broker.Subscribe(lastEventID)
+	defer sub.Close()
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+
+	for _, event := range replay {
+		writeSaleEvent(c, principal, storeId, event)
+	}
+	c.Writer.Flush()
+
+	heartbeat := time.NewTicker(h.heartbeat)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-c.Request.Context().Done():
+			return
+		case event, ok := <-sub.Events():
+			if !ok {
+				// The subscriber fell behind or the server is shutting down; the
+				// client reconnects with Last-Event-ID and resumes from the buffer.
+				return
+			}
+			writeSaleEvent(c, principal, storeId, event)
+			c.Writer.Flush()
+		case <-heartbeat.C:
+			c.Writer.WriteString(": heartbeat\n\n")
+			c.Writer.Flush()
+		}
+	}
+}
+
+// writeSaleEvent skips events outside the requested store and the caller's
+// store scope.
+func writeSaleEvent(c *gin.Context, principal *models.Principal, storeId string, event services.SaleEvent) {
+	if (storeId != "" && event.Sale.StoreId != storeId) || !auth.CanAccessStore(principal, event.Sale.StoreId) {
+		return
+	}
+	c.Render(-1, sse.Event{
+		Id:    strconv.FormatUint(event.ID, 10),
+		Event: "sale",
+		Data:  event.Sale,
+	})
+}
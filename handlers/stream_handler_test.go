@@ -0,0 +1,124 @@
+package handlers
+
+import (
+	"dataflow/auth"
+	"dataflow/models"
+	"dataflow/services"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func streamSales(t *testing.T, broker *services.SaleBroker, req *http.Request, publish func()) *httptest.ResponseRecorder {
+	handler := NewStreamHandler(broker)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = req.WithContext(auth.WithPrincipal(req.Context(), auth.AnonymousPrincipal))
+
+	done := make(chan struct{})
+	go func() {
+		handler.StreamSales(c)
+		close(done)
+	}()
+
+	assert.Eventually(t, func() bool { return broker.Subscribers() == 1 }, time.Second, time.Millisecond)
+	publish()
+	// Closing the broker ends the stream once the published events are written.
+	broker.Close()
+	<-done
+	return w
+}
+
+func TestStreamHandler_StreamSales(t *testing.T) {
+	broker := services.NewSaleBroker(10, 10)
+	req, _ := http.NewRequest("GET", "/data/stream?store_id=6789", nil)
+
+	w := streamSales(t, broker, req, func() {
+		broker.Publish(&models.Sale{ID: "a", StoreId: "6789"})
+		broker.Publish(&models.Sale{ID: "b", StoreId: "9876"})
+		broker.Publish(&models.Sale{ID: "c", StoreId: "6789"})
+	})
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "text/event-stream", w.Header().Get("Content-Type"))
+	body := w.Body.String()
+	assert.Contains(t, body, "id:1\nevent:sale\n")
+	assert.Contains(t, body, `"id":"a"`)
+	assert.NotContains(t, body, `"id":"b"`)
+	assert.Contains(t, body, "id:3\nevent:sale\n")
+}
+
+func TestStreamHandler_StreamSales_LastEventID(t *testing.T) {
+	broker := services.NewSaleBroker(10, 10)
+	broker.Publish(&models.Sale{ID: "a", StoreId: "6789"})
+	broker.Publish(&models.Sale{ID: "b", StoreId: "6789"})
+
+	req, _ := http.NewRequest("GET", "/data/stream", nil)
+	req.Header.Set("Last-Event-ID", "1")
+
+	w := streamSales(t, broker, req, func() {
+		broker.Publish(&models.Sale{ID: "c", StoreId: "6789"})
+	})
+
+	body := w.Body.String()
+	assert.NotContains(t, body, `"id":"a"`)
+	assert.Contains(t, body, `"id":"b"`)
+	assert.Contains(t, body, `"id":"c"`)
+}
+
+func TestStreamHandler_StreamSales_InvalidLastEventID(t *testing.T) {
+	handler := NewStreamHandler(services.NewSaleBroker(10, 10))
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request, _ = http.NewRequest("GET", "/data/stream", nil)
+	c.Request.Header.Set("Last-Event-ID", "abc")
+
+	handler.StreamSales(c)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestStreamHandler_StreamSales_StoreScope(t *testing.T) {
+	broker := services.NewSaleBroker(10, 10)
+	principal := &models.Principal{Subject: "manager", Roles: []string{auth.RoleStoreManager}, Stores: []string{"6789"}}
+	req, _ := http.NewRequest("GET", "/data/stream", nil)
+	handler := NewStreamHandler(broker)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = req.WithContext(auth.WithPrincipal(req.Context(), principal))
+
+	done := make(chan struct{})
+	go func() {
+		handler.StreamSales(c)
+		close(done)
+	}()
+	assert.Eventually(t, func() bool { return broker.Subscribers() == 1 }, time.Second, time.Millisecond)
+	broker.Publish(&models.Sale{ID: "a", StoreId: "6789"})
+	broker.Publish(&models.Sale{ID: "b", StoreId: "9876"})
+	broker.Close()
+	<-done
+
+	body := w.Body.String()
+	assert.Contains(t, body, `"id":"a"`)
+	assert.NotContains(t, body, `"id":"b"`)
+}
+
+func TestStreamHandler_StreamSales_Forbidden(t *testing.T) {
+	handler := NewStreamHandler(services.NewSaleBroker(10, 10))
+	principal := &models.Principal{Subject: "writer", Roles: []string{auth.RoleIntegration}}
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request, _ = http.NewRequest("GET", "/data/stream", nil)
+	c.Request = c.Request.WithContext(auth.WithPrincipal(c.Request.Context(), principal))
+
+	handler.StreamSales(c)
+
+	assert.Equal(t, http.StatusForbidden, w.Code)
+}
@@ -0,0 +1,151 @@
+package logging
+
+import (
+	"context"
+	"dataflow/models"
+	"dataflow/repo"
+	"dataflow/services"
+	"errors"
+	"log/slog"
+	"math/big"
+	"time"
+)
+
+type loggingRepository struct {
+	inner repo.Repository
+}
+
+// NewRepository logs every operation at debug level and failed operations
+// at error level, with the request ID of the calling request.
+func NewRepository(inner repo.Repository) repo.Repository {
+	return &loggingRepository{inner: inner}
+}
+
+func (r *loggingRepository) AddSale(ctx context.Context, sale *models.Sale) error {
+	start := time.Now()
+	err := r.inner.AddSale(ctx, sale)
+	logOperation(ctx, "repository", "add_sale", start, err, slog.String("store_id", sale.StoreId), slog.String("sale_id", sale.ID))
+	return err
+}
+
+func (r *loggingRepository) GetSale(ctx context.Context, id string) (*models.Sale, error) {
+	start := time.Now()
+	sale, err := r.inner.GetSale(ctx, id)
+	logOperation(ctx, "repository", "get_sale", start, err, slog.String("sale_id", id))
+	return sale, err
+}
+
+func (r *loggingRepository) GetAllSales(ctx context.Context) ([]*models.Sale, error) {
+	start := time.Now()
+	sales, err := r.inner.GetAllSales(ctx)
+	logOperation(ctx, "repository", "get_all_sales", start, err, slog.Int("result_size", len(sales)))
+	return sales, err
+}
+
+func (r *loggingRepository) GetSalesInRange(ctx context.Context, startDate time.Time, endDate time.Time, storeId string) ([]*models.Sale, error) {
+	start := time.Now()
+	sales, err := r.inner.GetSalesInRange(ctx, startDate, endDate, storeId)
+	logOperation(ctx, "repository", "get_sales_in_range", start, err, slog.String("store_id", storeId), slog.Int("result_size", len(sales)))
+	return sales, err
+}
+
+func (r *loggingRepository) HasStoreIndex() bool {
+	return repo.HasStoreIndex(r.inner)
+}
+
+func (r *loggingRepository) GetSalesByStores(ctx context.Context, startDate time.Time, endDate time.Time, storeIds []string) ([]*models.Sale, error) {
+	start := time.Now()
+	sales, err := repo.GetSalesByStores(ctx, r.inner, startDate, endDate, storeIds)
+	logOperation(ctx, "repository", "get_sales_by_stores", start, err, slog.Any("store_ids", storeIds), slog.Int("result_size", len(sales)))
+	return sales, err
+}
+
+type loggingDataService struct {
+	inner services.DataService
+}
+
+// NewDataService logs failed calls with their wrapped error chain. Errors
+// caused by the caller, such as a reversed date range or a missing
+// permission, are logged at warn level.
+func NewDataService(inner services.DataService) services.DataService {
+	return &loggingDataService{inner: inner}
+}
+
+func (ds *loggingDataService) GetSale(ctx context.Context, id string) (*models.Sale, error) {
+	start := time.Now()
+	sale, err := ds.inner.GetSale(ctx, id)
+	logOperation(ctx, "service", "get_sale", start, err, slog.String("sale_id", id))
+	return sale, err
+}
+
+func (ds *loggingDataService) GetAllSales(ctx context.Context) ([]*models.Sale, error) {
+	start := time.Now()
+	sales, err := ds.inner.GetAllSales(ctx)
+	logOperation(ctx, "service", "get_all_sales", start, err, slog.Int("result_size", len(sales)))
+	return sales, err
+}
+
+func (ds *loggingDataService) GetSalesInRange(ctx context.Context, startDate time.Time, endDate time.Time, storeId string) ([]*models.Sale, error) {
+	start := time.Now()
+	sales, err := ds.inner.GetSalesInRange(ctx, startDate, endDate, storeId)
+	logOperation(ctx, "service", "get_sales_in_range", start, err, slog.String("store_id", storeId), slog.Int("result_size", len(sales)))
+	return sales, err
+}
+
+func (ds *loggingDataService) AddSale(ctx context.Context, sale *models.Sale) error {
+	start := time.Now()
+	err := ds.inner.AddSale(ctx, sale)
+	logOperation(ctx, "service", "add_sale", start, err, slog.String("store_id", sale.StoreId))
+	return err
+}
+
+func (ds *loggingDataService) CalculateSales(ctx context.Context, startDate time.Time, endDate time.Time, storeId string) (*big.Float, error) {
+	start := time.Now()
+	total, err := ds.inner.CalculateSales(ctx, startDate, endDate, storeId)
+	logOperation(ctx, "service", "calculate_sales", start, err, slog.String("store_id", storeId))
+	return total, err
+}
+
+func (ds *loggingDataService) CalculateBatch(ctx context.Context, startDate time.Time, endDate time.Time, storeIds []string, operations []string) (*models.BatchResult, error) {
+	start := time.Now()
+	result, err := ds.inner.CalculateBatch(ctx, startDate, endDate, storeIds, operations)
+	logOperation(ctx, "service", "calculate_batch", start, err, slog.Any("store_ids", storeIds), slog.Any("operations", operations))
+	return result, err
+}
+
+func (ds *loggingDataService) Aggregate(ctx context.Context, query models.AggregateQuery) ([]*models.AggregateGroup, error) {
+	start := time.Now()
+	groups, err := ds.inner.Aggregate(ctx, query)
+	logOperation(ctx, "service", "aggregate", start, err, slog.Any("store_ids", query.StoreIds), slog.Any("group_by", query.GroupBy), slog.Int("result_size", len(groups)))
+	return groups, err
+}
+
+func (ds *loggingDataService) Query(ctx context.Context, query models.SalesQuery) (*models.QueryResult, error) {
+	start := time.Now()
+	result, err := ds.inner.Query(ctx, query)
+	attrs := []slog.Attr{slog.String("statement", query.Statement), slog.Any("store_ids", query.StoreIds)}
+	if result != nil {
+		attrs = append(attrs, slog.Int("result_size", len(result.Rows)))
+	}
+	logOperation(ctx, "service", "query", start, err, attrs...)
+	return result, err
+}
+
+func logOperation(ctx context.Context, layer string, operation string, start time.Time, err error, attrs ...slog.Attr) {
+	level := slog.LevelDebug
+	switch {
+	case errors.Is(err, services.ErrWrongDate), errors.Is(err, services.ErrForbidden), errors.Is(err, services.ErrUnsupportedDimension), errors.Is(err, services.ErrInvalidQuery), errors.Is(err, services.ErrUnknownReference), errors.Is(err, repo.ErrSaleAlreadyExists), errors.Is(err, repo.ErrSaleNotFound):
+		level = slog.LevelWarn
+	case err != nil:
+		level = slog.LevelError
+	}
+	attrs = append(attrs,
+		slog.String("layer", layer),
+		slog.String("operation", operation),
+		slog.Duration("duration", time.Since(start)),
+	)
+	if err != nil {
+		attrs = append(attrs, slog.Any("error", err))
+	}
+	slog.Default().LogAttrs(ctx, level, layer+" operation", attrs...)
+}
@@ -0,0 +1,103 @@
+// Package logging configures log/slog and enriches every record with the
+// request ID and trace context found in the logging call's context.
+package logging
+
+import (
+	"context"
+	"dataflow/requestid"
+	"errors"
+	"fmt"
+	"go.opentelemetry.io/otel/trace"
+	"io"
+	"log/slog"
+	"strings"
+)
+
+const (
+	FormatJSON = "json"
+	FormatText = "text"
+)
+
+type Config struct {
+	// Level is debug, info (default), warn or error.
+	Level string
+	// Format is json (default) or text.
+	Format string
+}
+
+func New(w io.Writer, config Config) (*slog.Logger, error) {
+	var level slog.Level
+	if config.Level != "" {
+		if err := level.UnmarshalText([]byte(config.Level)); err != nil {
+			return nil, fmt.Errorf("invalid log level %q", config.Level)
+		}
+	}
+	opts := &slog.HandlerOptions{Level: level, ReplaceAttr: replaceError}
+
+	var handler slog.Handler
+	switch strings.ToLower(config.Format) {
+	case "", FormatJSON:
+		handler = slog.NewJSONHandler(w, opts)
+	case FormatText:
+		handler = slog.NewTextHandler(w, opts)
+	default:
+		return nil, fmt.Errorf("invalid log format %q", config.Format)
+	}
+	return slog.New(contextHandler{handler}), nil
+}
+
+// contextHandler adds request_id, trace_id and span_id to records logged with
+// a context that carries them.
+type contextHandler struct {
+	slog.Handler
+}
+
+func (h contextHandler) Handle(ctx context.Context, record slog.Record) error {
+	if id := requestid.FromContext(ctx); id != "" {
+		record.AddAttrs(slog.String("request_id", id))
+	}
+	if span := trace.SpanContextFromContext(ctx); span.IsValid() {
+		record.AddAttrs(slog.String("trace_id", span.TraceID().String()), slog.String("span_id", span.SpanID().String()))
+	}
+	return h.Handler.Handle(ctx, record)
+}
+
+func (h contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return contextHandler{h.Handler.WithAttrs(attrs)}
+}
+
+func (h contextHandler) WithGroup(name string) slog.Handler {
+	return contextHandler{h.Handler.WithGroup(name)}
+}
+
+// replaceError logs error values with their message and the messages of
+// every error they wrap, outermost first, so the root cause stays visible.
+func replaceError(groups []string, attr slog.Attr) slog.Attr {
+	err, ok := attr.Value.Any().(error)
+	if !ok || attr.Value.Kind() != slog.KindAny {
+		return attr
+	}
+	return slog.Group(attr.Key,
+		slog.String("message", err.Error()),
+		slog.Any("chain", chain(err)),
+	)
+}
+
+func chain(err error) []string {
+	var messages []string
+	queue := []error{err}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		messages = append(messages, current.Error())
+		switch wrapped := current.(type) {
+		case interface{ Unwrap() []error }:
+			queue = append(queue, wrapped.Unwrap()...)
+		default:
+			if next := errors.Unwrap(current); next != nil {
+				queue = append(queue, next)
+			}
+		}
+	}
+	return messages
+}
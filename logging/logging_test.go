@@ -0,0 +1,97 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"dataflow/requestid"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/trace"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func decodeLine(t *testing.T, buf *bytes.Buffer) map[string]interface{} {
+	var record map[string]interface{}
+	assert.NoError(t, json.Unmarshal(buf.Bytes(), &record))
+	return record
+}
+
+func TestNew_AddsRequestAndTraceIds(t *testing.T) {
+	var buf bytes.Buffer
+	logger, err := New(&buf, Config{})
+	assert.NoError(t, err)
+
+	span := trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID: trace.TraceID{1},
+		SpanID:  trace.SpanID{2},
+	})
+	ctx := trace.ContextWithSpanContext(requestid.NewContext(context.Background(), "req-1"), span)
+	logger.InfoContext(ctx, "hello")
+
+	record := decodeLine(t, &buf)
+	assert.Equal(t, "req-1", record["request_id"])
+	assert.Equal(t, span.TraceID().String(), record["trace_id"])
+	assert.Equal(t, span.SpanID().String(), record["span_id"])
+}
+
+func TestNew_LogsErrorChain(t *testing.T) {
+	var buf bytes.Buffer
+	logger, err := New(&buf, Config{})
+	assert.NoError(t, err)
+
+	root := errors.New("disk full")
+	logger.Error("failed", slog.Any("error", fmt.Errorf("couldn't save sale: %w", root)))
+
+	record := decodeLine(t, &buf)
+	logged := record["error"].(map[string]interface{})
+	assert.Equal(t, "couldn't save sale: disk full", logged["message"])
+	assert.Equal(t, []interface{}{"couldn't save sale: disk full", "disk full"}, logged["chain"])
+}
+
+func TestNew_Level(t *testing.T) {
+	var buf bytes.Buffer
+	logger, err := New(&buf, Config{Level: "warn", Format: "text"})
+	assert.NoError(t, err)
+
+	logger.Info("dropped")
+	assert.Empty(t, buf.String())
+	logger.Warn("kept")
+	assert.Contains(t, buf.String(), "msg=kept")
+}
+
+func TestNew_InvalidConfig(t *testing.T) {
+	_, err := New(&bytes.Buffer{}, Config{Level: "loud"})
+	assert.Error(t, err)
+	_, err = New(&bytes.Buffer{}, Config{Format: "xml"})
+	assert.Error(t, err)
+}
+
+func TestMiddleware_LogsServerErrorsAtErrorLevel(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	var buf bytes.Buffer
+	logger, err := New(&buf, Config{})
+	assert.NoError(t, err)
+
+	router := gin.New()
+	router.Use(requestid.Middleware(), Middleware(logger))
+	router.GET("/fail", func(c *gin.Context) {
+		_ = c.Error(errors.New("boom"))
+		c.Status(http.StatusInternalServerError)
+	})
+	req := httptest.NewRequest(http.MethodGet, "/fail", nil)
+	req.Header.Set(requestid.Header, "req-2")
+	router.ServeHTTP(httptest.NewRecorder(), req)
+
+	record := decodeLine(t, &buf)
+	assert.Equal(t, "ERROR", record["level"])
+	assert.Equal(t, "/fail", record["route"])
+	assert.Equal(t, float64(http.StatusInternalServerError), record["status"])
+	assert.Equal(t, "req-2", record["request_id"])
+	assert.Equal(t, "boom", record["error"].(map[string]interface{})["message"])
+}
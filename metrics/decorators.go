@@ -0,0 +1,117 @@
+package metrics
+
+import (
+	"context"
+	"dataflow/models"
+	"dataflow/repo"
+	"dataflow/services"
+	"log/slog"
+	"math/big"
+	"time"
+)
+
+type instrumentedRepository struct {
+	inner   repo.Repository
+	metrics *Metrics
+}
+
+// NewRepository times every operation of inner and tracks the number of
+// stored sales, starting from the sales already in inner.
+func NewRepository(inner repo.Repository, m *Metrics) repo.Repository {
+	if sales, err := inner.GetAllSales(context.Background()); err != nil {
+		slog.Error("couldn't count stored sales", slog.Any("error", err))
+	} else {
+		m.storedSales.Set(float64(len(sales)))
+	}
+	return &instrumentedRepository{inner: inner, metrics: m}
+}
+
+func (r *instrumentedRepository) AddSale(ctx context.Context, sale *models.Sale) error {
+	start := time.Now()
+	err := r.inner.AddSale(ctx, sale)
+	r.observe("add_sale", start, err)
+	if err == nil {
+		r.metrics.storedSales.Inc()
+		r.metrics.ingestedSales.WithLabelValues(sale.StoreId).Inc()
+	}
+	return err
+}
+
+func (r *instrumentedRepository) GetSale(ctx context.Context, id string) (*models.Sale, error) {
+	start := time.Now()
+	sale, err := r.inner.GetSale(ctx, id)
+	r.observe("get_sale", start, err)
+	return sale, err
+}
+
+func (r *instrumentedRepository) GetAllSales(ctx context.Context) ([]*models.Sale, error) {
+	start := time.Now()
+	sales, err := r.inner.GetAllSales(ctx)
+	r.observe("get_all_sales", start, err)
+	return sales, err
+}
+
+func (r *instrumentedRepository) GetSalesInRange(ctx context.Context, startDate time.Time, endDate time.Time, storeId string) ([]*models.Sale, error) {
+	start := time.Now()
+	sales, err := r.inner.GetSalesInRange(ctx, startDate, endDate, storeId)
+	r.observe("get_sales_in_range", start, err)
+	return sales, err
+}
+
+func (r *instrumentedRepository) HasStoreIndex() bool {
+	return repo.HasStoreIndex(r.inner)
+}
+
+func (r *instrumentedRepository) GetSalesByStores(ctx context.Context, startDate time.Time, endDate time.Time, storeIds []string) ([]*models.Sale, error) {
+	start := time.Now()
+	sales, err := repo.GetSalesByStores(ctx, r.inner, startDate, endDate, storeIds)
+	r.observe("get_sales_by_stores", start, err)
+	return sales, err
+}
+
+func (r *instrumentedRepository) observe(operation string, start time.Time, err error) {
+	r.metrics.repositoryDuration.WithLabelValues(operation, outcome(err)).Observe(time.Since(start).Seconds())
+}
+
+// instrumentedDataService times calculations; the other methods are only
+// timed at the repository level.
+type instrumentedDataService struct {
+	services.DataService
+	metrics *Metrics
+}
+
+func NewDataService(inner services.DataService, m *Metrics) services.DataService {
+	return &instrumentedDataService{DataService: inner, metrics: m}
+}
+
+func (ds *instrumentedDataService) CalculateSales(ctx context.Context, startDate time.Time, endDate time.Time, storeId string) (*big.Float, error) {
+	start := time.Now()
+	total, err := ds.DataService.CalculateSales(ctx, startDate, endDate, storeId)
+	ds.observe(services.MetricTotalSales, start, err)
+	return total, err
+}
+
+func (ds *instrumentedDataService) CalculateBatch(ctx context.Context, startDate time.Time, endDate time.Time, storeIds []string, operations []string) (*models.BatchResult, error) {
+	start := time.Now()
+	result, err := ds.DataService.CalculateBatch(ctx, startDate, endDate, storeIds, operations)
+	ds.observe("batch", start, err)
+	return result, err
+}
+
+func (ds *instrumentedDataService) Aggregate(ctx context.Context, query models.AggregateQuery) ([]*models.AggregateGroup, error) {
+	start := time.Now()
+	groups, err := ds.DataService.Aggregate(ctx, query)
+	ds.observe("aggregate", start, err)
+	return groups, err
+}
+
+func (ds *instrumentedDataService) Query(ctx context.Context, query models.SalesQuery) (*models.QueryResult, error) {
+	start := time.Now()
+	result, err := ds.DataService.Query(ctx, query)
+	ds.observe("query", start, err)
+	return result, err
+}
+
+func (ds *instrumentedDataService) observe(operation string, start time.Time, err error) {
+	ds.metrics.calculationDuration.WithLabelValues(operation, outcome(err)).Observe(time.Since(start).Seconds())
+}
@@ -0,0 +1,75 @@
+package metrics
+
+import (
+	"context"
+	"dataflow/models"
+	"dataflow/repo"
+	"dataflow/services"
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestMiddleware(t *testing.T) {
+	m := New()
+	router := gin.New()
+	router.Use(m.Middleware())
+	router.GET("/alerts/:id", func(c *gin.Context) { c.Status(http.StatusNotFound) })
+	router.GET("/metrics", gin.WrapH(m.Handler()))
+
+	for _, path := range []string{"/alerts/1", "/alerts/2", "/missing"} {
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", path, nil))
+	}
+
+	assert.Equal(t, float64(2), testutil.ToFloat64(m.requests.WithLabelValues("/alerts/:id", "GET", "404")))
+	assert.Equal(t, float64(1), testutil.ToFloat64(m.requests.WithLabelValues("unmatched", "GET", "404")))
+	assert.Equal(t, 2, testutil.CollectAndCount(m.requestDuration))
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `dataflow_http_requests_total{method="GET",route="/alerts/:id",status="404"} 2`)
+	assert.Contains(t, w.Body.String(), "dataflow_http_request_duration_seconds_bucket")
+	assert.Contains(t, w.Body.String(), "go_goroutines")
+}
+
+func TestRepository(t *testing.T) {
+	m := New()
+	inner := repo.NewInMemoryRepository()
+	inner.AddSale(context.Background(), &models.Sale{StoreId: "6789"})
+	repository := NewRepository(inner, m)
+	assert.Equal(t, float64(1), testutil.ToFloat64(m.storedSales))
+
+	assert.Nil(t, repository.AddSale(context.Background(), &models.Sale{StoreId: "6789"}))
+	assert.Nil(t, repository.AddSale(context.Background(), &models.Sale{StoreId: "9876"}))
+	_, err := repository.GetSalesInRange(context.Background(), time.Time{}, time.Time{}, "6789")
+	assert.Nil(t, err)
+
+	assert.Equal(t, float64(3), testutil.ToFloat64(m.storedSales))
+	assert.Equal(t, float64(1), testutil.ToFloat64(m.ingestedSales.WithLabelValues("6789")))
+	assert.Equal(t, float64(1), testutil.ToFloat64(m.ingestedSales.WithLabelValues("9876")))
+	assert.Equal(t, 2, testutil.CollectAndCount(m.repositoryDuration))
+}
+
+func TestDataService(t *testing.T) {
+	m := New()
+	mockService := new(services.MockService)
+	service := NewDataService(mockService, m)
+
+	mockService.On("CalculateSales", time.Time{}, time.Time{}, "6789").Return(new(big.Float), nil)
+	mockService.On("CalculateSales", time.Time{}, time.Time{}, "9876").Return(new(big.Float), services.ErrWrongDate)
+
+	service.CalculateSales(context.Background(), time.Time{}, time.Time{}, "6789")
+	service.CalculateSales(context.Background(), time.Time{}, time.Time{}, "9876")
+
+	assert.Equal(t, 2, testutil.CollectAndCount(m.calculationDuration))
+	w := httptest.NewRecorder()
+	m.Handler().ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+	assert.Contains(t, w.Body.String(), `dataflow_calculation_duration_seconds_count{operation="total_sales",outcome="error"} 1`)
+	assert.Contains(t, w.Body.String(), `dataflow_calculation_duration_seconds_count{operation="total_sales",outcome="success"} 1`)
+}
@@ -0,0 +1,77 @@
+package models
+
+import (
+	"math/big"
+	"time"
+)
+
+// Dimensions that sales can be grouped by.
+const (
+	DimensionStore   = "store"
+	DimensionProduct = "product"
+	DimensionDay     = "day"
+	DimensionWeek    = "week"
+	DimensionMonth   = "month"
+
+	DimensionFiscalWeek    = "fiscal_week"
+	DimensionFiscalPeriod  = "fiscal_period"
+	DimensionFiscalQuarter = "fiscal_quarter"
+	DimensionFiscalYear    = "fiscal_year"
+)
+
+// Calendar names the fiscal periods of dates.
+type Calendar interface {
+	// Period returns the label of the fiscal week, period, quarter or year,
+	// by dimension, that the local date of t falls in.
+	Period(t time.Time, dimension string) string
+}
+
+// AggregateQuery selects sales by date range, store and product, and groups
+// them by the GroupBy dimensions in order. Empty StoreIds or ProductIds don't
+// restrict the sales.
+type AggregateQuery struct {
+	StartDate  time.Time
+	EndDate    time.Time
+	StoreIds   []string
+	ProductIds []string
+	GroupBy    []string
+	// Location is the time zone of the day, week and month of every sale.
+	// When it's nil, sales are bucketed in the time zone of their store in
+	// StoreLocations, or in UTC.
+	Location       *time.Location
+	StoreLocations map[string]*time.Location
+	// Calendar names the fiscal periods; without one, fiscal years are
+	// calendar years.
+	Calendar Calendar
+}
+
+// LocationOf returns the time zone that the sales of a store are bucketed in.
+func (q *AggregateQuery) LocationOf(storeId string) *time.Location {
+	if q.Location != nil {
+		return q.Location
+	}
+	if location, ok := q.StoreLocations[storeId]; ok {
+		return location
+	}
+	return time.UTC
+}
+
+// AggregateGroup holds the totals of one group. Only the keys of the grouped
+// dimensions are set; Period is a day (2006-01-02), an ISO week (2006-W01), a
+// month (2006-01) or a fiscal period such as FY2006-P01.
+type AggregateGroup struct {
+	StoreId   string     `json:"store_id,omitempty"`
+	ProductId string     `json:"product_id,omitempty"`
+	Period    string     `json:"period,omitempty"`
+	Revenue   *big.Float `json:"revenue"`
+	Units     int64      `json:"units"`
+	Count     int64      `json:"count"`
+}
+
+// AverageSale is the revenue per sale, or nil when the group has no sales.
+func (g *AggregateGroup) AverageSale() *big.Float {
+	if g.Count == 0 {
+		return nil
+	}
+	return new(big.Float).SetPrec(64).Quo(g.Revenue, new(big.Float).SetInt64(g.Count))
+}
@@ -0,0 +1,50 @@
+package models
+
+import "time"
+
+const (
+	AlertTypeThreshold = "threshold"
+	AlertTypeNoSales   = "no_sales"
+)
+
+const (
+	AlertStateOk       = "ok"
+	AlertStateFiring   = "firing"
+	AlertStateResolved = "resolved"
+)
+
+type AlertRule struct {
+	ID            string  `json:"id"`
+	Name          string  `json:"name"`
+	Type          string  `json:"type"`
+	StoreId       string  `json:"store_id,omitempty"`
+	Comparator    string  `json:"comparator,omitempty"`
+	Threshold     float64 `json:"threshold,omitempty"`
+	Window        string  `json:"window,omitempty"`
+	WebhookURL    string  `json:"webhook_url"`
+	WebhookSecret string  `json:"webhook_secret,omitempty"`
+	State         string  `json:"state,omitempty"`
+}
+
+type AlertNotification struct {
+	RuleId    string    `json:"rule_id"`
+	RuleName  string    `json:"rule_name"`
+	Status    string    `json:"status"`
+	StoreId   string    `json:"store_id,omitempty"`
+	Value     float64   `json:"value"`
+	Threshold float64   `json:"threshold,omitempty"`
+	Message   string    `json:"message"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+type WebhookDelivery struct {
+	ID         string    `json:"id"`
+	RuleId     string    `json:"rule_id"`
+	Status     string    `json:"status"`
+	URL        string    `json:"url"`
+	Attempt    int       `json:"attempt"`
+	StatusCode int       `json:"status_code,omitempty"`
+	Error      string    `json:"error,omitempty"`
+	Delivered  bool      `json:"delivered"`
+	Timestamp  time.Time `json:"timestamp"`
+}
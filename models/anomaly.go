@@ -0,0 +1,16 @@
+package models
+
+type ExpectedRange struct {
+	Low  float64 `json:"low"`
+	High float64 `json:"high"`
+}
+
+type Anomaly struct {
+	StoreId       string        `json:"store_id"`
+	Date          string        `json:"date"`
+	Revenue       float64       `json:"revenue"`
+	Baseline      float64       `json:"baseline"`
+	ExpectedRange ExpectedRange `json:"expected_range"`
+	Severity      float64       `json:"severity"`
+	Direction     string        `json:"direction"`
+}
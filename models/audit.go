@@ -0,0 +1,36 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+const (
+	// AuditActionAddSale is recorded before a sale is stored.
+	AuditActionAddSale = "sale.add"
+	// AuditActionAddSaleFailed follows AuditActionAddSale when storing failed.
+	AuditActionAddSaleFailed = "sale.add.failed"
+)
+
+// AuditEntry records one mutation. Hash covers every other field, including
+// PrevHash, so editing or removing an entry breaks the chain after it.
+type AuditEntry struct {
+	Sequence  uint64          `json:"sequence"`
+	Timestamp time.Time       `json:"timestamp"`
+	Principal *Principal      `json:"principal,omitempty"`
+	Action    string          `json:"action"`
+	StoreId   string          `json:"store_id,omitempty"`
+	RequestId string          `json:"request_id,omitempty"`
+	Before    json.RawMessage `json:"before,omitempty"`
+	After     json.RawMessage `json:"after,omitempty"`
+	PrevHash  string          `json:"prev_hash"`
+	Hash      string          `json:"hash"`
+}
+
+type AuditVerification struct {
+	Valid   bool `json:"valid"`
+	Entries int  `json:"entries"`
+	// Sequence is the first entry that failed verification.
+	Sequence uint64 `json:"sequence,omitempty"`
+	Error    string `json:"error,omitempty"`
+}
@@ -0,0 +1,14 @@
+package models
+
+import "math/big"
+
+type CalculationCell struct {
+	Value *big.Float `json:"value,omitempty"`
+	Error string     `json:"error,omitempty"`
+}
+
+type BatchResult struct {
+	StoreIds   []string
+	Operations []string
+	Cells      map[string]map[string]CalculationCell
+}
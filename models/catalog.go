@@ -0,0 +1,27 @@
+package models
+
+// Store is a store of the catalog, which sales reference by ID. District,
+// Region and Country place it in the store hierarchy that rollups total
+// sales along.
+type Store struct {
+	ID       string `json:"id"`
+	Name     string `json:"name"`
+	District string `json:"district,omitempty"`
+	Region   string `json:"region,omitempty"`
+	Country  string `json:"country,omitempty"`
+	// Timezone is an IANA time zone name, such as Europe/Paris.
+	Timezone string `json:"timezone,omitempty"`
+	// Active is cleared for stores that are closed but still have sales.
+	Active bool `json:"active"`
+}
+
+// Product is a product of the catalog, which sales reference by ID.
+// Subcategory and Category place it in the product hierarchy.
+type Product struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	Subcategory string `json:"subcategory,omitempty"`
+	Category    string `json:"category,omitempty"`
+	SKU         string `json:"sku,omitempty"`
+	Active      bool   `json:"active"`
+}
@@ -0,0 +1,13 @@
+package models
+
+const (
+	HealthStatusOK          = "ok"
+	HealthStatusUnavailable = "unavailable"
+)
+
+// Readiness is the result of the readiness checks: "ok" or the error of each
+// check by name.
+type Readiness struct {
+	Status string            `json:"status"`
+	Checks map[string]string `json:"checks"`
+}
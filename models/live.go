@@ -0,0 +1,9 @@
+package models
+
+type LiveAggregate struct {
+	StoreId string  `json:"store_id"`
+	Metric  string  `json:"metric"`
+	Date    string  `json:"date"`
+	Value   float64 `json:"value"`
+	EventId uint64  `json:"event_id"`
+}
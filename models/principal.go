@@ -0,0 +1,8 @@
+package models
+
+type Principal struct {
+	Subject string   `json:"subject"`
+	Method  string   `json:"method"`
+	Roles   []string `json:"roles,omitempty"`
+	Stores  []string `json:"stores,omitempty"`
+}
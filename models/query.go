@@ -0,0 +1,31 @@
+package models
+
+import "time"
+
+// SalesQuery is a statement in the SQL dialect of POST /query. Explain asks
+// for the plan of the statement instead of its rows. Non-empty StoreIds
+// restrict the query to the sales of those stores. Dates in the statement
+// resolve in Location, or else in the time zone in StoreLocations that the
+// queried stores share, or UTC.
+type SalesQuery struct {
+	Statement      string
+	Explain        bool
+	StoreIds       []string
+	Location       *time.Location
+	StoreLocations map[string]*time.Location
+}
+
+// QueryResult holds the rows of a query, each with a value per column, or
+// the plan of an explained query.
+type QueryResult struct {
+	Columns []string        `json:"columns"`
+	Rows    [][]interface{} `json:"rows"`
+	Plan    []*PlanStep     `json:"plan,omitempty"`
+}
+
+// PlanStep is an operation of a query plan, such as FullScan, IndexScan,
+// Filter, Aggregate, Sort or Limit.
+type PlanStep struct {
+	Operation string `json:"operation"`
+	Detail    string `json:"detail"`
+}
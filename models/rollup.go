@@ -0,0 +1,47 @@
+package models
+
+import (
+	"math/big"
+	"time"
+)
+
+// Levels of the store and product hierarchies, from the leaves up.
+const (
+	LevelStore       = "store"
+	LevelDistrict    = "district"
+	LevelRegion      = "region"
+	LevelCountry     = "country"
+	LevelProduct     = "product"
+	LevelSubcategory = "subcategory"
+	LevelCategory    = "category"
+)
+
+var (
+	StoreHierarchy   = []string{LevelStore, LevelDistrict, LevelRegion, LevelCountry}
+	ProductHierarchy = []string{LevelProduct, LevelSubcategory, LevelCategory}
+)
+
+// RollupQuery totals the sales in a date range at Level of its hierarchy.
+// With DrillDown, each group nests the groups of the levels below it down to
+// single stores or products.
+type RollupQuery struct {
+	StartDate time.Time
+	EndDate   time.Time
+	Level     string
+	DrillDown bool
+}
+
+// RollupNode holds the totals of one group of a hierarchy level. Key is the
+// store or product ID at the leaf level and the district, region, country,
+// subcategory or category name above it; it is empty for the stores or
+// products that the catalog doesn't place at that level. The totals of a node
+// are the exact sums of the totals of its children.
+type RollupNode struct {
+	Level    string        `json:"level"`
+	Key      string        `json:"key"`
+	Name     string        `json:"name,omitempty"`
+	Revenue  *big.Float    `json:"total_sales"`
+	Units    int64         `json:"units_sold"`
+	Count    int64         `json:"sale_count"`
+	Children []*RollupNode `json:"children,omitempty"`
+}
@@ -0,0 +1,186 @@
+package openapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// maxValidatedBody bounds the request bodies read for validation.
+const maxValidatedBody = 1 << 20
+
+type Options struct {
+	// ValidateResponses buffers the responses of documented operations and
+	// replaces those that don't match the document with a 500. It is meant
+	// for tests and staging.
+	ValidateResponses bool
+}
+
+// Middleware rejects requests to documented operations whose query
+// parameters or JSON body don't match the document with 400. Routes the
+// document doesn't describe pass through unchecked.
+func (d *Document) Middleware(options Options) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		operation := d.Operation(c.Request.Method, c.FullPath())
+		if operation == nil {
+			c.Next()
+			return
+		}
+		if err := d.validateRequest(c.Request, operation); err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error(), "status": http.StatusBadRequest})
+			return
+		}
+		if !options.ValidateResponses {
+			c.Next()
+			return
+		}
+
+		writer := &bufferedWriter{ResponseWriter: c.Writer, status: http.StatusOK}
+		c.Writer = writer
+		c.Next()
+		c.Writer = writer.ResponseWriter
+
+		if err := d.validateResponse(operation, writer.status, writer.Header().Get("Content-Type"), writer.body.Bytes()); err != nil {
+			slog.ErrorContext(c.Request.Context(), "response doesn't match the OpenAPI document",
+				slog.String("operation", operation.OperationID), slog.Int("status", writer.status), slog.Any("error", err))
+			c.Writer.Header().Del("Content-Length")
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "response doesn't match the API specification: " + err.Error(), "status": http.StatusInternalServerError})
+			return
+		}
+		c.Writer.WriteHeader(writer.status)
+		c.Writer.Write(writer.body.Bytes())
+	}
+}
+
+func (d *Document) validateRequest(request *http.Request, operation *Operation) error {
+	query := request.URL.Query()
+	for _, parameter := range operation.Parameters {
+		if parameter.In != "query" {
+			continue
+		}
+		value, ok := query[parameter.Name]
+		if !ok {
+			if parameter.Required {
+				return fmt.Errorf("query parameter %s is required", parameter.Name)
+			}
+			continue
+		}
+		if err := parameter.Schema.validate(queryValue(value[0], parameter.Schema), "query."+parameter.Name, d.resolve); err != nil {
+			return err
+		}
+	}
+
+	if operation.RequestBody == nil {
+		return nil
+	}
+	media, ok := operation.RequestBody.Content["application/json"]
+	if !ok || media.Schema == nil {
+		return nil
+	}
+	body, err := io.ReadAll(io.LimitReader(request.Body, maxValidatedBody+1))
+	if err != nil {
+		return fmt.Errorf("couldn't read body: %w", err)
+	}
+	if len(body) > maxValidatedBody {
+		return fmt.Errorf("body is larger than %d bytes", maxValidatedBody)
+	}
+	request.Body = io.NopCloser(bytes.NewReader(body))
+	if len(bytes.TrimSpace(body)) == 0 {
+		if operation.RequestBody.Required {
+			return fmt.Errorf("body is required")
+		}
+		return nil
+	}
+	value, err := decode(body)
+	if err != nil {
+		return fmt.Errorf("body: %w", err)
+	}
+	return media.Schema.validate(value, "body", d.resolve)
+}
+
+func (d *Document) validateResponse(operation *Operation, status int, contentType string, body []byte) error {
+	response := d.response(operation, status)
+	if response == nil {
+		return fmt.Errorf("status %d isn't documented", status)
+	}
+	if len(response.Content) == 0 {
+		return nil
+	}
+	mediaType, _, _ := strings.Cut(contentType, ";")
+	media, ok := response.Content[strings.TrimSpace(mediaType)]
+	if !ok {
+		return fmt.Errorf("content type %q isn't documented for status %d", contentType, status)
+	}
+	if media.Schema == nil {
+		return nil
+	}
+	value, err := decode(body)
+	if err != nil {
+		return err
+	}
+	return media.Schema.validate(value, "response", d.resolve)
+}
+
+// queryValue converts a query parameter to the JSON type its schema expects,
+// leaving it a string when it doesn't parse so that validation reports it.
+func queryValue(value string, schema *Schema) interface{} {
+	if schema == nil {
+		return value
+	}
+	for _, t := range schema.Type {
+		switch t {
+		case "integer", "number":
+			if _, err := strconv.ParseFloat(value, 64); err == nil {
+				return json.Number(value)
+			}
+		case "boolean":
+			if parsed, err := strconv.ParseBool(value); err == nil {
+				return parsed
+			}
+		}
+	}
+	return value
+}
+
+// bufferedWriter holds back the response until it has been validated.
+type bufferedWriter struct {
+	gin.ResponseWriter
+	status  int
+	written bool
+	body    bytes.Buffer
+}
+
+func (w *bufferedWriter) WriteHeader(status int) {
+	w.status = status
+	w.written = true
+}
+
+func (w *bufferedWriter) WriteHeaderNow() {}
+
+func (w *bufferedWriter) Write(data []byte) (int, error) {
+	w.written = true
+	return w.body.Write(data)
+}
+
+func (w *bufferedWriter) WriteString(s string) (int, error) {
+	w.written = true
+	return w.body.WriteString(s)
+}
+
+func (w *bufferedWriter) Status() int {
+	return w.status
+}
+
+func (w *bufferedWriter) Size() int {
+	return w.body.Len()
+}
+
+func (w *bufferedWriter) Written() bool {
+	return w.written
+}
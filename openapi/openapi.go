@@ -0,0 +1,185 @@
+// Package openapi serves the OpenAPI document of the API and validates
+// requests, and optionally responses, against it.
+package openapi
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"strings"
+)
+
+//go:embed openapi.json
+var document []byte
+
+//go:embed docs.html
+var docsPage []byte
+
+// Document is the part of an OpenAPI 3.1 document needed for validation.
+type Document struct {
+	Paths      map[string]map[string]*Operation `json:"paths"`
+	Components struct {
+		Schemas   map[string]*Schema   `json:"schemas"`
+		Responses map[string]*Response `json:"responses"`
+	} `json:"components"`
+}
+
+type Operation struct {
+	OperationID string               `json:"operationId"`
+	Parameters  []*Parameter         `json:"parameters"`
+	RequestBody *RequestBody         `json:"requestBody"`
+	Responses   map[string]*Response `json:"responses"`
+}
+
+type Parameter struct {
+	Name     string  `json:"name"`
+	In       string  `json:"in"`
+	Required bool    `json:"required"`
+	Schema   *Schema `json:"schema"`
+}
+
+type RequestBody struct {
+	Required bool                 `json:"required"`
+	Content  map[string]MediaType `json:"content"`
+}
+
+type Response struct {
+	Ref     string               `json:"$ref"`
+	Content map[string]MediaType `json:"content"`
+}
+
+type MediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+const (
+	schemaPrefix   = "#/components/schemas/"
+	responsePrefix = "#/components/responses/"
+)
+
+// Load parses the embedded document.
+func Load() (*Document, error) {
+	var doc Document
+	if err := json.Unmarshal(document, &doc); err != nil {
+		return nil, fmt.Errorf("couldn't parse OpenAPI document: %w", err)
+	}
+	for name, schema := range doc.Components.Schemas {
+		if err := schema.compile(); err != nil {
+			return nil, fmt.Errorf("schema %s: %w", name, err)
+		}
+	}
+	for path, item := range doc.Paths {
+		for method, operation := range item {
+			schemas := make([]*Schema, 0)
+			for _, parameter := range operation.Parameters {
+				schemas = append(schemas, parameter.Schema)
+			}
+			if operation.RequestBody != nil {
+				for _, media := range operation.RequestBody.Content {
+					schemas = append(schemas, media.Schema)
+				}
+			}
+			for _, response := range operation.Responses {
+				for _, media := range response.Content {
+					schemas = append(schemas, media.Schema)
+				}
+			}
+			for _, schema := range schemas {
+				if err := schema.compile(); err != nil {
+					return nil, fmt.Errorf("%s %s: %w", strings.ToUpper(method), path, err)
+				}
+			}
+		}
+	}
+	return &doc, nil
+}
+
+// Schema returns the component schema with the given name.
+func (d *Document) Schema(name string) (*Schema, bool) {
+	schema, ok := d.Components.Schemas[name]
+	return schema, ok
+}
+
+// Operation returns the operation for a method and a gin route such as
+// "/jobs/:id", or nil if the document doesn't describe it.
+func (d *Document) Operation(method string, route string) *Operation {
+	item, ok := d.Paths[specPath(route)]
+	if !ok {
+		return nil
+	}
+	return item[strings.ToLower(method)]
+}
+
+// Validate checks a JSON value against a component schema.
+func (d *Document) Validate(schemaName string, data []byte) error {
+	schema, ok := d.Schema(schemaName)
+	if !ok {
+		return fmt.Errorf("unknown schema %s", schemaName)
+	}
+	value, err := decode(data)
+	if err != nil {
+		return err
+	}
+	return schema.validate(value, schemaName, d.resolve)
+}
+
+func (d *Document) resolve(ref string) (*Schema, error) {
+	schema, ok := d.Components.Schemas[strings.TrimPrefix(ref, schemaPrefix)]
+	if !strings.HasPrefix(ref, schemaPrefix) || !ok {
+		return nil, fmt.Errorf("unresolved reference %s", ref)
+	}
+	return schema, nil
+}
+
+// response returns the response of operation for status, following a
+// reference to a shared response.
+func (d *Document) response(operation *Operation, status int) *Response {
+	response, ok := operation.Responses[fmt.Sprint(status)]
+	if !ok {
+		response, ok = operation.Responses["default"]
+	}
+	if !ok {
+		return nil
+	}
+	if response.Ref != "" {
+		return d.Components.Responses[strings.TrimPrefix(response.Ref, responsePrefix)]
+	}
+	return response
+}
+
+// specPath turns gin's ":id" path parameters into OpenAPI's "{id}".
+func specPath(route string) string {
+	segments := strings.Split(route, "/")
+	for i, segment := range segments {
+		if strings.HasPrefix(segment, ":") || strings.HasPrefix(segment, "*") {
+			segments[i] = "{" + segment[1:] + "}"
+		}
+	}
+	return strings.Join(segments, "/")
+}
+
+func decode(data []byte) (interface{}, error) {
+	decoder := json.NewDecoder(strings.NewReader(string(data)))
+	decoder.UseNumber()
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return nil, fmt.Errorf("invalid JSON: %w", err)
+	}
+	if decoder.More() {
+		return nil, fmt.Errorf("invalid JSON: unexpected data after the top-level value")
+	}
+	return value, nil
+}
+
+// Handler serves the document.
+func Handler(c *gin.Context) {
+	c.Data(http.StatusOK, "application/json", document)
+}
+
+// DocsHandler serves a page that renders the document. It has no external
+// dependencies, so it works without internet access.
+func DocsHandler(c *gin.Context) {
+	c.Data(http.StatusOK, "text/html; charset=utf-8", docsPage)
+}
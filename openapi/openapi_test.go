@@ -0,0 +1,328 @@
+package openapi
+
+import (
+	"dataflow/auth"
+	"dataflow/handlers"
+	"dataflow/models"
+	"dataflow/repo"
+	"dataflow/services"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func loadDocument(t *testing.T) *Document {
+	doc, err := Load()
+	assert.NoError(t, err)
+	return doc
+}
+
+// TestSchemasMatchTypes fails when a field is added to or removed from a Go
+// type without updating openapi.json.
+func TestSchemasMatchTypes(t *testing.T) {
+	doc := loadDocument(t)
+	for name, value := range map[string]interface{}{
+		"Sale":                   models.Sale{},
+		"SaleResponse":           handlers.SaleResponse{},
+		"NewSale":                models.Sale{},
+		"CalculateRequest":       handlers.CalculateRequest{},
+		"CalculateResponse":      handlers.CalculateResponse{},
+		"BatchCalculateResponse": handlers.BatchCalculateResponse{},
+		"RollupResponse":         handlers.RollupResponse{},
+		"RollupNode":             models.RollupNode{},
+		"PeriodsResponse":        handlers.PeriodsResponse{},
+		"PeriodTotals":           handlers.PeriodTotals{},
+		"QueryRequest":           handlers.QueryRequest{},
+		"QueryResult":            models.QueryResult{},
+		"PlanStep":               models.PlanStep{},
+		"Store":                  models.Store{},
+		"StoreInput":             models.Store{},
+		"Product":                models.Product{},
+		"ProductInput":           models.Product{},
+		"CalculationCell":        models.CalculationCell{},
+		"Readiness":              models.Readiness{},
+	} {
+		schema, ok := doc.Schema(name)
+		if !assert.True(t, ok, name) {
+			continue
+		}
+		var properties []string
+		for property := range schema.Properties {
+			properties = append(properties, property)
+		}
+		sort.Strings(properties)
+		assert.Equal(t, jsonFields(reflect.TypeOf(value)), properties, name)
+	}
+}
+
+// jsonFields lists the JSON names of the fields of t, including those of
+// embedded structs.
+func jsonFields(t reflect.Type) []string {
+	var fields []string
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if field.Anonymous && name == "" {
+			embedded := field.Type
+			if embedded.Kind() == reflect.Pointer {
+				embedded = embedded.Elem()
+			}
+			fields = append(fields, jsonFields(embedded)...)
+		} else if name != "" && name != "-" {
+			fields = append(fields, name)
+		}
+	}
+	sort.Strings(fields)
+	return fields
+}
+
+func TestReferencesResolve(t *testing.T) {
+	doc := loadDocument(t)
+	var check func(schema *Schema, where string)
+	check = func(schema *Schema, where string) {
+		if schema == nil {
+			return
+		}
+		if schema.Ref != "" {
+			_, err := doc.resolve(schema.Ref)
+			assert.NoError(t, err, where)
+		}
+		check(schema.Items, where)
+		for _, option := range schema.OneOf {
+			check(option, where)
+		}
+		for _, property := range schema.Properties {
+			check(property, where)
+		}
+		if schema.AdditionalProperties != nil {
+			check(schema.AdditionalProperties.schema, where)
+		}
+	}
+	for name, schema := range doc.Components.Schemas {
+		check(schema, name)
+	}
+	for path, item := range doc.Paths {
+		for method, operation := range item {
+			if operation.RequestBody != nil {
+				for _, media := range operation.RequestBody.Content {
+					check(media.Schema, method+" "+path)
+				}
+			}
+			for status := range operation.Responses {
+				var code int
+				if status != "default" {
+					code = atoi(status)
+				}
+				response := doc.response(operation, code)
+				if assert.NotNil(t, response, method+" "+path+" "+status) {
+					for _, media := range response.Content {
+						check(media.Schema, method+" "+path)
+					}
+				}
+			}
+		}
+	}
+}
+
+func atoi(value string) int {
+	n := 0
+	for _, r := range value {
+		n = n*10 + int(r-'0')
+	}
+	return n
+}
+
+func setupRouter(t *testing.T) *gin.Engine {
+	doc := loadDocument(t)
+	repository := repo.NewInMemoryRepository()
+	catalog := services.NewCatalogService(repo.NewInMemoryStoreRepository(), repo.NewInMemoryProductRepository(), repository)
+	handler := handlers.NewDataHandler(services.NewDataService(repository), catalog, nil)
+	catalogHandler := handlers.NewCatalogHandler(catalog)
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		admin := &models.Principal{Subject: "admin", Roles: []string{auth.RoleAdmin}}
+		c.Request = c.Request.WithContext(auth.WithPrincipal(c.Request.Context(), admin))
+	})
+	router.Use(doc.Middleware(Options{ValidateResponses: true}))
+	router.GET("/data", handler.GetData)
+	router.POST("/data", handler.AddData)
+	router.POST("/calculate", handler.Calculate)
+	router.POST("/query", handler.Query)
+	router.GET("/stores", catalogHandler.GetStores)
+	router.GET("/stores/:id", catalogHandler.GetStore)
+	router.POST("/stores", catalogHandler.CreateStore)
+	router.PUT("/stores/:id", catalogHandler.UpdateStore)
+	router.DELETE("/stores/:id", catalogHandler.DeleteStore)
+	router.GET("/products", catalogHandler.GetProducts)
+	router.GET("/products/:id", catalogHandler.GetProduct)
+	router.POST("/products", catalogHandler.CreateProduct)
+	router.PUT("/products/:id", catalogHandler.UpdateProduct)
+	router.DELETE("/products/:id", catalogHandler.DeleteProduct)
+	router.GET("/openapi.json", Handler)
+	return router
+}
+
+func serve(router *gin.Engine, method string, path string, body string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, req)
+	return w
+}
+
+// TestHandlersMatchDocument runs the data handlers with response validation,
+// so any response that drifts from the document fails with a 500.
+func TestHandlersMatchDocument(t *testing.T) {
+	router := setupRouter(t)
+
+	w := serve(router, "GET", "/data", "")
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	w = serve(router, "POST", "/stores", `{"id":"6789","name":"Paris","country":"FR","timezone":"Europe/Paris"}`)
+	assert.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	w = serve(router, "POST", "/products", `{"id":"12345","name":"Umbrella","category":"Rainwear"}`)
+	assert.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+
+	for _, body := range []string{
+		`{"product_id":"12345","store_id":"6789","quantity_sold":10,"sale_price":19.99,"sale_date":"2024-06-15T14:30:00Z"}`,
+		`{"product_id":"54321","store_id":"9876","quantity_sold":5,"sale_price":9.99,"sale_date":"2024-06-16T10:00:00Z"}`,
+	} {
+		w = serve(router, "POST", "/data", body)
+		assert.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	}
+
+	w = serve(router, "GET", "/data", "")
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Contains(t, w.Body.String(), `"store_name":"Paris","product_name":"Umbrella"`)
+
+	w = serve(router, "POST", "/calculate", `{"operation":"total_sales","store_id":"6789","start_date":"2024-06-01T00:00:00Z","end_date":"2024-07-01T00:00:00Z"}`)
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Contains(t, w.Body.String(), `"store_name":"Paris"`)
+
+	w = serve(router, "POST", "/calculate", `{"store_ids":["all"],"operations":["total_sales","average_sale"]}`)
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Contains(t, w.Body.String(), `"store_names":{"6789":"Paris"}`)
+
+	w = serve(router, "POST", "/calculate", `{"level":"product","drill_down":true}`)
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Contains(t, w.Body.String(), `"name":"Umbrella"`)
+
+	w = serve(router, "POST", "/calculate", `{"operation":"median"}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code, w.Body.String())
+	assert.Contains(t, w.Body.String(), "unsupported operation")
+
+	w = serve(router, "POST", "/query", `{"query":"SELECT store_id, SUM(amount), COUNT(*) FROM sales GROUP BY store_id"}`)
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Contains(t, w.Body.String(), `"rows":[["6789"`)
+
+	w = serve(router, "POST", "/query", `{"query":"SELECT * FROM sales WHERE store_id = '0000'","explain":true}`)
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Contains(t, w.Body.String(), `"plan":[`)
+
+	w = serve(router, "POST", "/query", `{"query":"SELECT nothing FROM sales"}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code, w.Body.String())
+
+	w = serve(router, "GET", "/openapi.json", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"openapi": "3.1.0"`)
+}
+
+func TestCatalogMatchesDocument(t *testing.T) {
+	router := setupRouter(t)
+
+	for _, request := range []struct {
+		method, path, body string
+		status             int
+	}{
+		{"POST", "/stores", `{"id":"6789","name":"Paris","district":"Paris 1","region":"IDF","country":"FR","timezone":"Europe/Paris"}`, http.StatusCreated},
+		{"POST", "/stores", `{"id":"6789","name":"Paris"}`, http.StatusConflict},
+		{"POST", "/stores", `{"id":"9876","name":"Lyon","timezone":"Mars/Olympus"}`, http.StatusBadRequest},
+		{"GET", "/stores", "", http.StatusOK},
+		{"GET", "/stores/6789", "", http.StatusOK},
+		{"GET", "/stores/0000", "", http.StatusNotFound},
+		{"PUT", "/stores/6789", `{"name":"Paris Opéra","active":false}`, http.StatusOK},
+		{"PUT", "/stores/0000", `{"name":"Nowhere"}`, http.StatusNotFound},
+		{"DELETE", "/stores/6789", "", http.StatusNoContent},
+		{"DELETE", "/stores/6789", "", http.StatusNotFound},
+		{"POST", "/products", `{"id":"12345","name":"Umbrella","subcategory":"Umbrellas","category":"Rainwear","sku":"UMB-1"}`, http.StatusCreated},
+		{"GET", "/products", "", http.StatusOK},
+		{"GET", "/products/12345", "", http.StatusOK},
+		{"PUT", "/products/12345", `{"name":"Large umbrella"}`, http.StatusOK},
+		{"DELETE", "/products/12345", "", http.StatusNoContent},
+		{"GET", "/products/12345", "", http.StatusNotFound},
+	} {
+		w := serve(router, request.method, request.path, request.body)
+		assert.Equal(t, request.status, w.Code, request.method+" "+request.path+": "+w.Body.String())
+	}
+
+	for body, message := range map[string]string{
+		`{"id":"1"}`:                          "body.name is required",
+		`{"id":"1","name":""}`:                "body.name must be at least 1 characters long",
+		`{"id":"1","name":"A","open":true}`:   "body.open is not allowed",
+		`{"id":"1","name":"A","active":"no"}`: "body.active must be boolean, got string",
+	} {
+		w := serve(router, "POST", "/stores", body)
+		assert.Equal(t, http.StatusBadRequest, w.Code, body)
+		assert.Contains(t, w.Body.String(), message, body)
+	}
+}
+
+func TestMiddleware_RejectsInvalidRequests(t *testing.T) {
+	router := setupRouter(t)
+
+	for body, message := range map[string]string{
+		``: "body is required",
+		`{"product_id":"1","store_id":"6789","quantity_sold":"ten","sale_price":1,"sale_date":"2024-06-15T14:30:00Z"}`:          "body.quantity_sold must be integer, got string",
+		`{"product_id":"1","store_id":"6789","quantity_sold":1.5,"sale_price":1,"sale_date":"2024-06-15T14:30:00Z"}`:            "body.quantity_sold must be integer, got number",
+		`{"product_id":"1","quantity_sold":1,"sale_price":1,"sale_date":"2024-06-15T14:30:00Z"}`:                                "body.store_id is required",
+		`{"product_id":"1","store_id":"","quantity_sold":1,"sale_price":1,"sale_date":"2024-06-15T14:30:00Z"}`:                  "body.store_id must be at least 1 characters long",
+		`{"product_id":"1","store_id":"6789","quantity_sold":1,"sale_price":1,"sale_date":"yesterday"}`:                         "body.sale_date must be an RFC 3339 date-time",
+		`{"product_id":"1","store_id":"6789","quantity":1,"quantity_sold":1,"sale_price":1,"sale_date":"2024-06-15T14:30:00Z"}`: "body.quantity is not allowed",
+		`[1, 2]`:         "body must be object, got array",
+		`{"product_id":`: "invalid JSON",
+	} {
+		w := serve(router, "POST", "/data", body)
+		assert.Equal(t, http.StatusBadRequest, w.Code, body)
+		assert.Contains(t, w.Body.String(), message, body)
+	}
+
+	w := serve(router, "POST", "/calculate", `{"operations":["total_sales","median"]}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "body.operations[1] must be one of")
+
+	w = serve(router, "POST", "/query", `{"explain":true}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "body.query is required")
+
+	w = serve(router, "GET", "/data", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestMiddleware_RejectsUndocumentedResponses(t *testing.T) {
+	doc := loadDocument(t)
+	router := gin.New()
+	router.Use(doc.Middleware(Options{ValidateResponses: true}))
+	router.GET("/data", func(c *gin.Context) {
+		c.JSON(http.StatusOK, []gin.H{{"id": 1}})
+	})
+	router.GET("/undocumented", func(c *gin.Context) {
+		c.JSON(http.StatusTeapot, gin.H{"anything": true})
+	})
+
+	w := serve(router, "GET", "/data", "")
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.Contains(t, w.Body.String(), "response[0].product_id is required")
+
+	w = serve(router, "GET", "/undocumented", "")
+	assert.Equal(t, http.StatusTeapot, w.Code)
+}
+
+func TestSpecPath(t *testing.T) {
+	assert.Equal(t, "/jobs/{id}/result", specPath("/jobs/:id/result"))
+	assert.Equal(t, "/data", specPath("/data"))
+}
@@ -0,0 +1,230 @@
+package openapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Schema is the subset of JSON Schema 2020-12 that the document uses.
+type Schema struct {
+	Ref                  string             `json:"$ref"`
+	Type                 types              `json:"type"`
+	Format               string             `json:"format"`
+	Enum                 []interface{}      `json:"enum"`
+	Pattern              string             `json:"pattern"`
+	MinLength            *int               `json:"minLength"`
+	Properties           map[string]*Schema `json:"properties"`
+	Required             []string           `json:"required"`
+	AdditionalProperties *additional        `json:"additionalProperties"`
+	Items                *Schema            `json:"items"`
+	OneOf                []*Schema          `json:"oneOf"`
+
+	pattern *regexp.Regexp
+}
+
+// types is a JSON Schema type, either a single name or a list of names.
+type types []string
+
+func (t *types) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*t = types{single}
+		return nil
+	}
+	var list []string
+	if err := json.Unmarshal(data, &list); err != nil {
+		return fmt.Errorf("type must be a string or a list of strings")
+	}
+	*t = list
+	return nil
+}
+
+// additional is additionalProperties: false, true or a schema.
+type additional struct {
+	allowed bool
+	schema  *Schema
+}
+
+func (a *additional) UnmarshalJSON(data []byte) error {
+	if err := json.Unmarshal(data, &a.allowed); err == nil {
+		return nil
+	}
+	a.allowed = true
+	return json.Unmarshal(data, &a.schema)
+}
+
+// compile prepares the patterns of s and every schema nested in it.
+func (s *Schema) compile() error {
+	if s == nil {
+		return nil
+	}
+	if s.Pattern != "" {
+		pattern, err := regexp.Compile(s.Pattern)
+		if err != nil {
+			return fmt.Errorf("invalid pattern %q: %w", s.Pattern, err)
+		}
+		s.pattern = pattern
+	}
+	nested := append([]*Schema{s.Items}, s.OneOf...)
+	for _, property := range s.Properties {
+		nested = append(nested, property)
+	}
+	if s.AdditionalProperties != nil {
+		nested = append(nested, s.AdditionalProperties.schema)
+	}
+	for _, schema := range nested {
+		if err := schema.compile(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// resolver looks up "#/components/schemas/<name>" references.
+type resolver func(ref string) (*Schema, error)
+
+// validate checks value, decoded with json.Decoder.UseNumber, against schema.
+// path names the value in errors, e.g. "body.store_ids[1]".
+func (s *Schema) validate(value interface{}, path string, resolve resolver) error {
+	if s.Ref != "" {
+		target, err := resolve(s.Ref)
+		if err != nil {
+			return err
+		}
+		return target.validate(value, path, resolve)
+	}
+	if len(s.OneOf) > 0 {
+		var errs []string
+		matched := 0
+		for _, option := range s.OneOf {
+			if err := option.validate(value, path, resolve); err != nil {
+				errs = append(errs, err.Error())
+			} else {
+				matched++
+			}
+		}
+		if matched != 1 {
+			if matched == 0 {
+				return fmt.Errorf("%s matches none of the allowed schemas: %s", path, strings.Join(errs, "; "))
+			}
+			return fmt.Errorf("%s matches more than one of the allowed schemas", path)
+		}
+		return nil
+	}
+
+	if len(s.Type) > 0 && !s.hasType(value) {
+		return fmt.Errorf("%s must be %s, got %s", path, strings.Join(s.Type, " or "), typeOf(value))
+	}
+	if len(s.Enum) > 0 && !inEnum(value, s.Enum) {
+		return fmt.Errorf("%s must be one of %v", path, s.Enum)
+	}
+
+	switch value := value.(type) {
+	case string:
+		return s.validateString(value, path)
+	case map[string]interface{}:
+		return s.validateObject(value, path, resolve)
+	case []interface{}:
+		if s.Items != nil {
+			for i, item := range value {
+				if err := s.Items.validate(item, fmt.Sprintf("%s[%d]", path, i), resolve); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func (s *Schema) validateString(value string, path string) error {
+	if s.MinLength != nil && len([]rune(value)) < *s.MinLength {
+		return fmt.Errorf("%s must be at least %d characters long", path, *s.MinLength)
+	}
+	if s.pattern != nil {
+		if !s.pattern.MatchString(value) {
+			return fmt.Errorf("%s must match %s", path, s.Pattern)
+		}
+	}
+	if s.Format == "date-time" {
+		if _, err := time.Parse(time.RFC3339, value); err != nil {
+			return fmt.Errorf("%s must be an RFC 3339 date-time", path)
+		}
+	}
+	return nil
+}
+
+func (s *Schema) validateObject(value map[string]interface{}, path string, resolve resolver) error {
+	for _, name := range s.Required {
+		if _, ok := value[name]; !ok {
+			return fmt.Errorf("%s.%s is required", path, name)
+		}
+	}
+	// Sorted, so that the first error is the same on every request.
+	names := make([]string, 0, len(value))
+	for name := range value {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		property, ok := s.Properties[name]
+		if !ok && s.AdditionalProperties != nil {
+			if !s.AdditionalProperties.allowed {
+				return fmt.Errorf("%s.%s is not allowed", path, name)
+			}
+			property = s.AdditionalProperties.schema
+		}
+		if property == nil {
+			continue
+		}
+		if err := property.validate(value[name], path+"."+name, resolve); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *Schema) hasType(value interface{}) bool {
+	actual := typeOf(value)
+	for _, expected := range s.Type {
+		if expected == actual || (expected == "number" && actual == "integer") {
+			return true
+		}
+	}
+	return false
+}
+
+func typeOf(value interface{}) string {
+	switch value := value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case string:
+		return "string"
+	case json.Number:
+		if f, err := value.Float64(); err == nil && f == math.Trunc(f) && !strings.ContainsAny(value.String(), ".eE") {
+			return "integer"
+		}
+		return "number"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	default:
+		return fmt.Sprintf("%T", value)
+	}
+}
+
+func inEnum(value interface{}, enum []interface{}) bool {
+	for _, allowed := range enum {
+		if fmt.Sprint(allowed) == fmt.Sprint(value) {
+			return true
+		}
+	}
+	return false
+}
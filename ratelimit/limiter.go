@@ -0,0 +1,140 @@
+// Package ratelimit throttles API clients with token buckets per route class
+// and enforces daily request quotas.
+package ratelimit
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// ClassIngest covers requests that write sales.
+	ClassIngest = "ingest"
+	// ClassQuery covers every other request.
+	ClassQuery = "query"
+)
+
+// Limit allows Rate requests per second on average and bursts of up to Burst
+// requests. A zero Rate disables the limit.
+type Limit struct {
+	Rate  float64
+	Burst int
+}
+
+// ParseLimit parses "<rate>/s" or "<rate>/m", optionally followed by
+// ",<burst>", e.g. "5/s,20". The burst defaults to the rate per second,
+// rounded up.
+func ParseLimit(value string) (Limit, error) {
+	spec, burstSpec, hasBurst := strings.Cut(strings.TrimSpace(value), ",")
+	count, unit, ok := strings.Cut(spec, "/")
+	if !ok {
+		return Limit{}, fmt.Errorf("invalid rate limit %q", value)
+	}
+	rate, err := strconv.ParseFloat(count, 64)
+	if err != nil || rate < 0 {
+		return Limit{}, fmt.Errorf("invalid rate limit %q", value)
+	}
+	switch unit {
+	case "s":
+	case "m":
+		rate /= 60
+	default:
+		return Limit{}, fmt.Errorf("invalid rate limit %q: unit must be s or m", value)
+	}
+	limit := Limit{Rate: rate, Burst: int(math.Ceil(rate))}
+	if hasBurst {
+		if limit.Burst, err = strconv.Atoi(strings.TrimSpace(burstSpec)); err != nil || limit.Burst < 1 {
+			return Limit{}, fmt.Errorf("invalid rate limit %q: burst must be a positive integer", value)
+		}
+	}
+	if limit.Burst < 1 {
+		limit.Burst = 1
+	}
+	return limit, nil
+}
+
+// Result describes a limiter decision in terms of the RateLimit-* headers.
+type Result struct {
+	Allowed   bool
+	Limit     int
+	Remaining int
+	// Reset is the time until the bucket is full again.
+	Reset time.Duration
+	// RetryAfter is the time until the next request is allowed when the
+	// request was rejected.
+	RetryAfter time.Duration
+}
+
+type bucket struct {
+	tokens  float64
+	updated time.Time
+	limit   Limit
+}
+
+// Limiter keeps one token bucket per key.
+type Limiter struct {
+	now func() time.Time
+
+	mu      sync.Mutex
+	buckets map[string]*bucket
+	calls   int
+}
+
+// sweepInterval is the number of calls between removals of full buckets,
+// which keeps memory bounded by the number of recently active clients.
+const sweepInterval = 10000
+
+func NewLimiter() *Limiter {
+	return &Limiter{now: time.Now, buckets: make(map[string]*bucket)}
+}
+
+// Allow takes a token from the key's bucket if one is available.
+func (l *Limiter) Allow(key string, limit Limit) Result {
+	if limit.Rate <= 0 {
+		return Result{Allowed: true}
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	l.calls++
+	if l.calls%sweepInterval == 0 {
+		l.sweep(now)
+	}
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(limit.Burst), updated: now, limit: limit}
+		l.buckets[key] = b
+	}
+	b.tokens = math.Min(float64(limit.Burst), b.tokens+now.Sub(b.updated).Seconds()*limit.Rate)
+	b.updated = now
+	b.limit = limit
+
+	result := Result{Limit: limit.Burst}
+	if b.tokens >= 1 {
+		b.tokens--
+		result.Allowed = true
+	} else {
+		result.RetryAfter = seconds((1 - b.tokens) / limit.Rate)
+	}
+	result.Remaining = int(b.tokens)
+	result.Reset = seconds((float64(limit.Burst) - b.tokens) / limit.Rate)
+	return result
+}
+
+func (l *Limiter) sweep(now time.Time) {
+	for key, b := range l.buckets {
+		if b.tokens+now.Sub(b.updated).Seconds()*b.limit.Rate >= float64(b.limit.Burst) {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
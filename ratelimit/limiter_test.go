@@ -0,0 +1,60 @@
+package ratelimit
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestParseLimit(t *testing.T) {
+	limit, err := ParseLimit("5/s,20")
+	assert.Nil(t, err)
+	assert.Equal(t, Limit{Rate: 5, Burst: 20}, limit)
+
+	limit, err = ParseLimit("120/m")
+	assert.Nil(t, err)
+	assert.Equal(t, Limit{Rate: 2, Burst: 2}, limit)
+
+	limit, err = ParseLimit("0/s")
+	assert.Nil(t, err)
+	assert.Equal(t, float64(0), limit.Rate)
+
+	for _, invalid := range []string{"", "5", "5/h", "x/s", "-1/s", "5/s,0", "5/s,x"} {
+		_, err := ParseLimit(invalid)
+		assert.NotNil(t, err, invalid)
+	}
+}
+
+func TestLimiter_Allow(t *testing.T) {
+	now := time.Date(2024, 6, 15, 12, 0, 0, 0, time.UTC)
+	limiter := NewLimiter()
+	limiter.now = func() time.Time { return now }
+	limit := Limit{Rate: 2, Burst: 3}
+
+	for i := 2; i >= 0; i-- {
+		result := limiter.Allow("a", limit)
+		assert.True(t, result.Allowed)
+		assert.Equal(t, 3, result.Limit)
+		assert.Equal(t, i, result.Remaining)
+	}
+
+	result := limiter.Allow("a", limit)
+	assert.False(t, result.Allowed)
+	assert.Equal(t, 500*time.Millisecond, result.RetryAfter)
+	assert.Equal(t, 1500*time.Millisecond, result.Reset)
+
+	// Other keys have their own bucket.
+	assert.True(t, limiter.Allow("b", limit).Allowed)
+
+	now = now.Add(500 * time.Millisecond)
+	assert.True(t, limiter.Allow("a", limit).Allowed)
+	assert.False(t, limiter.Allow("a", limit).Allowed)
+}
+
+func TestLimiter_Unlimited(t *testing.T) {
+	limiter := NewLimiter()
+	for i := 0; i < 100; i++ {
+		assert.True(t, limiter.Allow("a", Limit{}).Allowed)
+	}
+	assert.Empty(t, limiter.buckets)
+}
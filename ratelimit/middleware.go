@@ -0,0 +1,103 @@
+package ratelimit
+
+import (
+	"dataflow/auth"
+	"dataflow/repo"
+	"github.com/gin-gonic/gin"
+	"log/slog"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const quotaPeriodLayout = "2006-01-02"
+
+type Config struct {
+	Ingest Limit
+	Query  Limit
+	// DailyQuota is the number of requests a client may make per UTC day.
+	// Zero disables the quota.
+	DailyQuota int64
+}
+
+// RateLimiter throttles each client separately per route class and counts
+// its requests against the daily quota. Clients are identified by their
+// principal, or by IP address when the request is anonymous.
+type RateLimiter struct {
+	limiter *Limiter
+	quotas  repo.QuotaRepository
+	config  Config
+	now     func() time.Time
+}
+
+func NewRateLimiter(quotas repo.QuotaRepository, config Config) *RateLimiter {
+	limiter := NewLimiter()
+	rl := &RateLimiter{limiter: limiter, quotas: quotas, config: config, now: time.Now}
+	limiter.now = func() time.Time { return rl.now() }
+	return rl
+}
+
+// Classify puts sale writes into the ingest class and everything else into
+// the query class, so that a flood of writes can't starve queries.
+func Classify(c *gin.Context) string {
+	if c.Request.Method == http.MethodPost && c.FullPath() == "/data" {
+		return ClassIngest
+	}
+	return ClassQuery
+}
+
+// Middleware must run after authentication. It answers 429 with Retry-After
+// when the bucket is empty or the daily quota is used up, and sets the
+// RateLimit-Limit, RateLimit-Remaining and RateLimit-Reset headers.
+func (rl *RateLimiter) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		client := clientKey(c)
+		class := Classify(c)
+		limit := rl.config.Query
+		if class == ClassIngest {
+			limit = rl.config.Ingest
+		}
+
+		result := rl.limiter.Allow(client+"|"+class, limit)
+		if limit.Rate > 0 {
+			c.Header("RateLimit-Limit", strconv.Itoa(result.Limit))
+			c.Header("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+			c.Header("RateLimit-Reset", ceilSeconds(result.Reset))
+		}
+		if !result.Allowed {
+			reject(c, result.RetryAfter, "rate limit exceeded")
+			return
+		}
+
+		if rl.config.DailyQuota > 0 {
+			now := rl.now().UTC()
+			used, err := rl.quotas.Increment(client, now.Format(quotaPeriodLayout), 1)
+			if err != nil {
+				// Quota tracking failing shouldn't take the API down with it.
+				slog.ErrorContext(c.Request.Context(), "couldn't track quota", slog.String("client", client), slog.Any("error", err))
+			} else if used > rl.config.DailyQuota {
+				midnight := time.Date(now.Year(), now.Month(), now.Day()+1, 0, 0, 0, 0, time.UTC)
+				reject(c, midnight.Sub(now), "daily quota exceeded")
+				return
+			}
+		}
+		c.Next()
+	}
+}
+
+func clientKey(c *gin.Context) string {
+	if principal, ok := auth.PrincipalFromContext(c.Request.Context()); ok && principal.Method != auth.MethodAnonymous {
+		return principal.Method + ":" + principal.Subject
+	}
+	return "ip:" + c.ClientIP()
+}
+
+func reject(c *gin.Context, retryAfter time.Duration, message string) {
+	c.Header("Retry-After", ceilSeconds(max(retryAfter, time.Second)))
+	c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{"error": message, "status": http.StatusTooManyRequests})
+}
+
+func ceilSeconds(d time.Duration) string {
+	return strconv.Itoa(int(math.Ceil(d.Seconds())))
+}
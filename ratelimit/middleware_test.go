@@ -0,0 +1,77 @@
+package ratelimit
+
+import (
+	"dataflow/auth"
+	"dataflow/models"
+	"dataflow/repo"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func setupRouter(config Config, now *time.Time) *gin.Engine {
+	rateLimiter := NewRateLimiter(repo.NewInMemoryQuotaRepository(), config)
+	rateLimiter.now = func() time.Time { return *now }
+
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		if subject := c.GetHeader("X-Subject"); subject != "" {
+			principal := &models.Principal{Subject: subject, Method: auth.MethodAPIKey}
+			c.Request = c.Request.WithContext(auth.WithPrincipal(c.Request.Context(), principal))
+		}
+	})
+	router.Use(rateLimiter.Middleware())
+	router.GET("/data", func(c *gin.Context) { c.Status(http.StatusOK) })
+	router.POST("/data", func(c *gin.Context) { c.Status(http.StatusCreated) })
+	return router
+}
+
+func request(router *gin.Engine, method string, subject string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(method, "/data", nil)
+	req.Header.Set("X-Subject", subject)
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestMiddleware_RateLimit(t *testing.T) {
+	now := time.Date(2024, 6, 15, 12, 0, 0, 0, time.UTC)
+	router := setupRouter(Config{Ingest: Limit{Rate: 1, Burst: 2}, Query: Limit{Rate: 10, Burst: 10}}, &now)
+
+	w := request(router, "POST", "pos")
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Equal(t, "2", w.Header().Get("RateLimit-Limit"))
+	assert.Equal(t, "1", w.Header().Get("RateLimit-Remaining"))
+	assert.Equal(t, "1", w.Header().Get("RateLimit-Reset"))
+
+	assert.Equal(t, http.StatusCreated, request(router, "POST", "pos").Code)
+	w = request(router, "POST", "pos")
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, "1", w.Header().Get("Retry-After"))
+	assert.Equal(t, "0", w.Header().Get("RateLimit-Remaining"))
+
+	// Flooding ingest neither affects queries nor other clients.
+	assert.Equal(t, http.StatusOK, request(router, "GET", "pos").Code)
+	assert.Equal(t, http.StatusCreated, request(router, "POST", "other").Code)
+
+	now = now.Add(time.Second)
+	assert.Equal(t, http.StatusCreated, request(router, "POST", "pos").Code)
+}
+
+func TestMiddleware_DailyQuota(t *testing.T) {
+	now := time.Date(2024, 6, 15, 18, 0, 0, 0, time.UTC)
+	router := setupRouter(Config{DailyQuota: 2}, &now)
+
+	assert.Equal(t, http.StatusOK, request(router, "GET", "analyst").Code)
+	assert.Equal(t, http.StatusCreated, request(router, "POST", "analyst").Code)
+	w := request(router, "GET", "analyst")
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, "21600", w.Header().Get("Retry-After"))
+	assert.Empty(t, w.Header().Get("RateLimit-Limit"))
+
+	now = now.Add(6 * time.Hour)
+	assert.Equal(t, http.StatusOK, request(router, "GET", "analyst").Code)
+}
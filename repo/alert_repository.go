@@ -0,0 +1,101 @@
+package repo
+
+import (
+	"dataflow/models"
+	"errors"
+	"github.com/google/uuid"
+	"sync"
+)
+
+var ErrAlertRuleNotFound = errors.New("alert rule not found")
+
+// maxDeliveries bounds the in-memory delivery log; the oldest entries are
+// dropped first.
+const maxDeliveries = 1000
+
+type AlertRepository interface {
+	AddRule(rule *models.AlertRule) error
+	GetRule(id string) (*models.AlertRule, error)
+	GetAllRules() ([]*models.AlertRule, error)
+	UpdateRule(rule *models.AlertRule) error
+	DeleteRule(id string) error
+	AddDelivery(delivery *models.WebhookDelivery) error
+	GetDeliveries(ruleId string) ([]*models.WebhookDelivery, error)
+}
+
+type InMemoryAlertRepository struct {
+	rules      sync.Map
+	mu         sync.Mutex
+	deliveries []*models.WebhookDelivery
+}
+
+func NewInMemoryAlertRepository() *InMemoryAlertRepository {
+	return &InMemoryAlertRepository{}
+}
+
+func (repo *InMemoryAlertRepository) AddRule(rule *models.AlertRule) error {
+	rule.ID = uuid.New().String()
+	stored := *rule
+	repo.rules.Store(rule.ID, &stored)
+	return nil
+}
+
+func (repo *InMemoryAlertRepository) GetRule(id string) (*models.AlertRule, error) {
+	v, ok := repo.rules.Load(id)
+	if !ok {
+		return nil, ErrAlertRuleNotFound
+	}
+	rule := *v.(*models.AlertRule)
+	return &rule, nil
+}
+
+func (repo *InMemoryAlertRepository) GetAllRules() ([]*models.AlertRule, error) {
+	var rules []*models.AlertRule
+	repo.rules.Range(func(k, v interface{}) bool {
+		rule := *v.(*models.AlertRule)
+		rules = append(rules, &rule)
+		return true
+	})
+	return rules, nil
+}
+
+func (repo *InMemoryAlertRepository) UpdateRule(rule *models.AlertRule) error {
+	if _, ok := repo.rules.Load(rule.ID); !ok {
+		return ErrAlertRuleNotFound
+	}
+	stored := *rule
+	repo.rules.Store(rule.ID, &stored)
+	return nil
+}
+
+func (repo *InMemoryAlertRepository) DeleteRule(id string) error {
+	if _, loaded := repo.rules.LoadAndDelete(id); !loaded {
+		return ErrAlertRuleNotFound
+	}
+	return nil
+}
+
+func (repo *InMemoryAlertRepository) AddDelivery(delivery *models.WebhookDelivery) error {
+	delivery.ID = uuid.New().String()
+	stored := *delivery
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+	repo.deliveries = append(repo.deliveries, &stored)
+	if len(repo.deliveries) > maxDeliveries {
+		repo.deliveries = repo.deliveries[len(repo.deliveries)-maxDeliveries:]
+	}
+	return nil
+}
+
+func (repo *InMemoryAlertRepository) GetDeliveries(ruleId string) ([]*models.WebhookDelivery, error) {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+	deliveries := make([]*models.WebhookDelivery, 0)
+	for _, delivery := range repo.deliveries {
+		if ruleId == "" || delivery.RuleId == ruleId {
+			d := *delivery
+			deliveries = append(deliveries, &d)
+		}
+	}
+	return deliveries, nil
+}
@@ -0,0 +1,74 @@
+package repo
+
+import (
+	"dataflow/models"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestInMemoryAlertRepository_AddRule(t *testing.T) {
+	repo := NewInMemoryAlertRepository()
+
+	rule := &models.AlertRule{Name: "low revenue", Type: models.AlertTypeThreshold, StoreId: "6789"}
+
+	err := repo.AddRule(rule)
+	assert.Nil(t, err)
+	assert.NotEmpty(t, rule.ID)
+
+	stored, err := repo.GetRule(rule.ID)
+	assert.Nil(t, err)
+	assert.Equal(t, rule, stored)
+}
+
+func TestInMemoryAlertRepository_UpdateRule(t *testing.T) {
+	repo := NewInMemoryAlertRepository()
+
+	rule := &models.AlertRule{Name: "low revenue", Type: models.AlertTypeThreshold, StoreId: "6789"}
+	repo.AddRule(rule)
+
+	rule.Threshold = 500
+	err := repo.UpdateRule(rule)
+	assert.Nil(t, err)
+
+	stored, _ := repo.GetRule(rule.ID)
+	assert.Equal(t, 500.0, stored.Threshold)
+}
+
+func TestInMemoryAlertRepository_UpdateRule_NotFound(t *testing.T) {
+	repo := NewInMemoryAlertRepository()
+
+	err := repo.UpdateRule(&models.AlertRule{ID: "missing"})
+	assert.ErrorIs(t, err, ErrAlertRuleNotFound)
+}
+
+func TestInMemoryAlertRepository_DeleteRule(t *testing.T) {
+	repo := NewInMemoryAlertRepository()
+
+	rule := &models.AlertRule{Name: "low revenue"}
+	repo.AddRule(rule)
+
+	assert.Nil(t, repo.DeleteRule(rule.ID))
+	assert.ErrorIs(t, repo.DeleteRule(rule.ID), ErrAlertRuleNotFound)
+
+	rules, err := repo.GetAllRules()
+	assert.Nil(t, err)
+	assert.Empty(t, rules)
+}
+
+func TestInMemoryAlertRepository_GetDeliveries(t *testing.T) {
+	repo := NewInMemoryAlertRepository()
+
+	repo.AddDelivery(&models.WebhookDelivery{RuleId: "1", Attempt: 1, Timestamp: time.Now()})
+	repo.AddDelivery(&models.WebhookDelivery{RuleId: "2", Attempt: 1, Timestamp: time.Now()})
+	repo.AddDelivery(&models.WebhookDelivery{RuleId: "1", Attempt: 2, Timestamp: time.Now()})
+
+	deliveries, err := repo.GetDeliveries("1")
+	assert.Nil(t, err)
+	assert.Len(t, deliveries, 2)
+	assert.Equal(t, 1, deliveries[0].Attempt)
+	assert.Equal(t, 2, deliveries[1].Attempt)
+
+	all, _ := repo.GetDeliveries("")
+	assert.Len(t, all, 3)
+}
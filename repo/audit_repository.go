@@ -0,0 +1,191 @@
+package repo
+
+import (
+	"bufio"
+	"dataflow/models"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+var ErrAuditSequence = errors.New("audit entry out of sequence")
+
+// AuditFilter selects audit entries. Zero fields match everything.
+type AuditFilter struct {
+	Subject string
+	Action  string
+	StoreId string
+	From    time.Time
+	To      time.Time
+	// AfterSequence returns only entries with a greater sequence number, for
+	// paging through the log.
+	AfterSequence uint64
+	Limit         int
+}
+
+// AuditRepository is append-only: entries can't be changed or removed once
+// written.
+type AuditRepository interface {
+	Append(entry *models.AuditEntry) error
+	GetEntries(filter AuditFilter) ([]*models.AuditEntry, error)
+	GetLast() (*models.AuditEntry, error)
+}
+
+type InMemoryAuditRepository struct {
+	mu      sync.RWMutex
+	entries []*models.AuditEntry
+}
+
+func NewInMemoryAuditRepository() *InMemoryAuditRepository {
+	return &InMemoryAuditRepository{}
+}
+
+func (repo *InMemoryAuditRepository) Append(entry *models.AuditEntry) error {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+	if err := checkSequence(repo.entries, entry); err != nil {
+		return err
+	}
+	stored := *entry
+	repo.entries = append(repo.entries, &stored)
+	return nil
+}
+
+func (repo *InMemoryAuditRepository) GetEntries(filter AuditFilter) ([]*models.AuditEntry, error) {
+	repo.mu.RLock()
+	defer repo.mu.RUnlock()
+	return filterEntries(repo.entries, filter), nil
+}
+
+func (repo *InMemoryAuditRepository) GetLast() (*models.AuditEntry, error) {
+	repo.mu.RLock()
+	defer repo.mu.RUnlock()
+	return lastEntry(repo.entries), nil
+}
+
+// FileAuditRepository appends entries as newline-delimited JSON and syncs the
+// file after every write. Existing entries are loaded when it is opened.
+type FileAuditRepository struct {
+	mu      sync.RWMutex
+	file    *os.File
+	entries []*models.AuditEntry
+}
+
+func NewFileAuditRepository(path string) (*FileAuditRepository, error) {
+	entries, err := ReadAuditLog(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't open audit log: %w", err)
+	}
+	return &FileAuditRepository{file: file, entries: entries}, nil
+}
+
+// ReadAuditLog reads every entry of a newline-delimited JSON audit log
+// without checking the chain.
+func ReadAuditLog(path string) ([]*models.AuditEntry, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var entries []*models.AuditEntry
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var entry models.AuditEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return nil, fmt.Errorf("couldn't parse audit log line %d: %w", line, err)
+		}
+		entries = append(entries, &entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("couldn't read audit log: %w", err)
+	}
+	return entries, nil
+}
+
+func (repo *FileAuditRepository) Append(entry *models.AuditEntry) error {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+	if err := checkSequence(repo.entries, entry); err != nil {
+		return err
+	}
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	if _, err := repo.file.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("couldn't write audit log: %w", err)
+	}
+	if err := repo.file.Sync(); err != nil {
+		return fmt.Errorf("couldn't sync audit log: %w", err)
+	}
+	stored := *entry
+	repo.entries = append(repo.entries, &stored)
+	return nil
+}
+
+func (repo *FileAuditRepository) GetEntries(filter AuditFilter) ([]*models.AuditEntry, error) {
+	repo.mu.RLock()
+	defer repo.mu.RUnlock()
+	return filterEntries(repo.entries, filter), nil
+}
+
+func (repo *FileAuditRepository) GetLast() (*models.AuditEntry, error) {
+	repo.mu.RLock()
+	defer repo.mu.RUnlock()
+	return lastEntry(repo.entries), nil
+}
+
+func (repo *FileAuditRepository) Close() error {
+	return repo.file.Close()
+}
+
+func checkSequence(entries []*models.AuditEntry, entry *models.AuditEntry) error {
+	var expected uint64 = 1
+	if last := lastEntry(entries); last != nil {
+		expected = last.Sequence + 1
+	}
+	if entry.Sequence != expected {
+		return fmt.Errorf("%w: got %d, want %d", ErrAuditSequence, entry.Sequence, expected)
+	}
+	return nil
+}
+
+func lastEntry(entries []*models.AuditEntry) *models.AuditEntry {
+	if len(entries) == 0 {
+		return nil
+	}
+	entry := *entries[len(entries)-1]
+	return &entry
+}
+
+func filterEntries(entries []*models.AuditEntry, filter AuditFilter) []*models.AuditEntry {
+	matched := make([]*models.AuditEntry, 0)
+	for _, entry := range entries {
+		if entry.Sequence <= filter.AfterSequence ||
+			(filter.Action != "" && entry.Action != filter.Action) ||
+			(filter.StoreId != "" && entry.StoreId != filter.StoreId) ||
+			(filter.Subject != "" && (entry.Principal == nil || entry.Principal.Subject != filter.Subject)) ||
+			(!filter.From.IsZero() && entry.Timestamp.Before(filter.From)) ||
+			(!filter.To.IsZero() && !entry.Timestamp.Before(filter.To)) {
+			continue
+		}
+		copied := *entry
+		matched = append(matched, &copied)
+		if filter.Limit > 0 && len(matched) == filter.Limit {
+			break
+		}
+	}
+	return matched
+}
@@ -0,0 +1,87 @@
+package repo
+
+import (
+	"dataflow/models"
+	"github.com/stretchr/testify/assert"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func auditEntries() []*models.AuditEntry {
+	base := time.Date(2024, 6, 15, 12, 0, 0, 0, time.UTC)
+	return []*models.AuditEntry{
+		{Sequence: 1, Timestamp: base, Action: models.AuditActionAddSale, StoreId: "6789", Principal: &models.Principal{Subject: "pos"}, Hash: "a"},
+		{Sequence: 2, Timestamp: base.Add(time.Hour), Action: models.AuditActionAddSale, StoreId: "9876", Principal: &models.Principal{Subject: "pos"}, PrevHash: "a", Hash: "b"},
+		{Sequence: 3, Timestamp: base.Add(2 * time.Hour), Action: models.AuditActionAddSale, StoreId: "6789", Principal: &models.Principal{Subject: "admin"}, PrevHash: "b", Hash: "c"},
+	}
+}
+
+func TestInMemoryAuditRepository_Append(t *testing.T) {
+	repo := NewInMemoryAuditRepository()
+
+	last, err := repo.GetLast()
+	assert.Nil(t, err)
+	assert.Nil(t, last)
+
+	for _, entry := range auditEntries() {
+		assert.Nil(t, repo.Append(entry))
+	}
+	last, _ = repo.GetLast()
+	assert.Equal(t, uint64(3), last.Sequence)
+
+	assert.ErrorIs(t, repo.Append(&models.AuditEntry{Sequence: 3}), ErrAuditSequence)
+	assert.ErrorIs(t, repo.Append(&models.AuditEntry{Sequence: 5}), ErrAuditSequence)
+}
+
+func TestInMemoryAuditRepository_GetEntries(t *testing.T) {
+	repo := NewInMemoryAuditRepository()
+	for _, entry := range auditEntries() {
+		repo.Append(entry)
+	}
+
+	entries, _ := repo.GetEntries(AuditFilter{StoreId: "6789"})
+	assert.Len(t, entries, 2)
+
+	entries, _ = repo.GetEntries(AuditFilter{Subject: "admin"})
+	assert.Len(t, entries, 1)
+	assert.Equal(t, uint64(3), entries[0].Sequence)
+
+	entries, _ = repo.GetEntries(AuditFilter{AfterSequence: 1, Limit: 1})
+	assert.Len(t, entries, 1)
+	assert.Equal(t, uint64(2), entries[0].Sequence)
+
+	base := time.Date(2024, 6, 15, 12, 0, 0, 0, time.UTC)
+	entries, _ = repo.GetEntries(AuditFilter{From: base.Add(time.Hour), To: base.Add(2 * time.Hour)})
+	assert.Len(t, entries, 1)
+	assert.Equal(t, uint64(2), entries[0].Sequence)
+
+	// Returned entries are copies.
+	entries[0].Hash = "tampered"
+	stored, _ := repo.GetEntries(AuditFilter{AfterSequence: 1, Limit: 1})
+	assert.Equal(t, "b", stored[0].Hash)
+}
+
+func TestFileAuditRepository_Reopen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.ndjson")
+
+	repo, err := NewFileAuditRepository(path)
+	assert.Nil(t, err)
+	for _, entry := range auditEntries() {
+		assert.Nil(t, repo.Append(entry))
+	}
+	assert.Nil(t, repo.Close())
+
+	reopened, err := NewFileAuditRepository(path)
+	assert.Nil(t, err)
+	defer reopened.Close()
+
+	entries, _ := reopened.GetEntries(AuditFilter{})
+	assert.Equal(t, auditEntries(), entries)
+	assert.ErrorIs(t, reopened.Append(&models.AuditEntry{Sequence: 1}), ErrAuditSequence)
+	assert.Nil(t, reopened.Append(&models.AuditEntry{Sequence: 4, PrevHash: "c", Hash: "d"}))
+
+	read, err := ReadAuditLog(path)
+	assert.Nil(t, err)
+	assert.Len(t, read, 4)
+}
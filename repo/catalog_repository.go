@@ -0,0 +1,325 @@
+package repo
+
+import (
+	"dataflow/models"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+)
+
+var (
+	ErrStoreNotFound        = errors.New("store not found")
+	ErrStoreAlreadyExists   = errors.New("store already exists")
+	ErrProductNotFound      = errors.New("product not found")
+	ErrProductAlreadyExists = errors.New("product already exists")
+)
+
+type StoreRepository interface {
+	AddStore(store *models.Store) error
+	GetStore(id string) (*models.Store, error)
+	GetAllStores() ([]*models.Store, error)
+	UpdateStore(store *models.Store) error
+	DeleteStore(id string) error
+}
+
+type ProductRepository interface {
+	AddProduct(product *models.Product) error
+	GetProduct(id string) (*models.Product, error)
+	GetAllProducts() ([]*models.Product, error)
+	UpdateProduct(product *models.Product) error
+	DeleteProduct(id string) error
+}
+
+// InMemoryStoreRepository stores copies of the stores, keyed by their ID.
+type InMemoryStoreRepository struct {
+	stores sync.Map
+}
+
+func NewInMemoryStoreRepository() *InMemoryStoreRepository {
+	return &InMemoryStoreRepository{}
+}
+
+func (repo *InMemoryStoreRepository) AddStore(store *models.Store) error {
+	stored := *store
+	if _, loaded := repo.stores.LoadOrStore(store.ID, &stored); loaded {
+		return ErrStoreAlreadyExists
+	}
+	return nil
+}
+
+func (repo *InMemoryStoreRepository) GetStore(id string) (*models.Store, error) {
+	v, ok := repo.stores.Load(id)
+	if !ok {
+		return nil, ErrStoreNotFound
+	}
+	store := *v.(*models.Store)
+	return &store, nil
+}
+
+func (repo *InMemoryStoreRepository) GetAllStores() ([]*models.Store, error) {
+	stores := make([]*models.Store, 0)
+	repo.stores.Range(func(k, v interface{}) bool {
+		store := *v.(*models.Store)
+		stores = append(stores, &store)
+		return true
+	})
+	return stores, nil
+}
+
+func (repo *InMemoryStoreRepository) UpdateStore(store *models.Store) error {
+	if _, ok := repo.stores.Load(store.ID); !ok {
+		return ErrStoreNotFound
+	}
+	stored := *store
+	repo.stores.Store(store.ID, &stored)
+	return nil
+}
+
+func (repo *InMemoryStoreRepository) DeleteStore(id string) error {
+	if _, loaded := repo.stores.LoadAndDelete(id); !loaded {
+		return ErrStoreNotFound
+	}
+	return nil
+}
+
+// InMemoryProductRepository stores copies of the products, keyed by their ID.
+type InMemoryProductRepository struct {
+	products sync.Map
+}
+
+func NewInMemoryProductRepository() *InMemoryProductRepository {
+	return &InMemoryProductRepository{}
+}
+
+func (repo *InMemoryProductRepository) AddProduct(product *models.Product) error {
+	stored := *product
+	if _, loaded := repo.products.LoadOrStore(product.ID, &stored); loaded {
+		return ErrProductAlreadyExists
+	}
+	return nil
+}
+
+func (repo *InMemoryProductRepository) GetProduct(id string) (*models.Product, error) {
+	v, ok := repo.products.Load(id)
+	if !ok {
+		return nil, ErrProductNotFound
+	}
+	product := *v.(*models.Product)
+	return &product, nil
+}
+
+func (repo *InMemoryProductRepository) GetAllProducts() ([]*models.Product, error) {
+	products := make([]*models.Product, 0)
+	repo.products.Range(func(k, v interface{}) bool {
+		product := *v.(*models.Product)
+		products = append(products, &product)
+		return true
+	})
+	return products, nil
+}
+
+func (repo *InMemoryProductRepository) UpdateProduct(product *models.Product) error {
+	if _, ok := repo.products.Load(product.ID); !ok {
+		return ErrProductNotFound
+	}
+	stored := *product
+	repo.products.Store(product.ID, &stored)
+	return nil
+}
+
+func (repo *InMemoryProductRepository) DeleteProduct(id string) error {
+	if _, loaded := repo.products.LoadAndDelete(id); !loaded {
+		return ErrProductNotFound
+	}
+	return nil
+}
+
+// FileStoreRepository keeps stores in memory and writes all of them to a JSON
+// file after every change, replacing the file atomically. The file is read
+// when the repository is opened.
+type FileStoreRepository struct {
+	memory *InMemoryStoreRepository
+	path   string
+
+	mu sync.Mutex
+}
+
+func NewFileStoreRepository(path string) (*FileStoreRepository, error) {
+	var stores []*models.Store
+	if err := readCatalogFile(path, &stores); err != nil {
+		return nil, fmt.Errorf("couldn't read store file: %w", err)
+	}
+	repository := &FileStoreRepository{memory: NewInMemoryStoreRepository(), path: path}
+	for _, store := range stores {
+		if err := repository.memory.AddStore(store); err != nil {
+			return nil, fmt.Errorf("couldn't read store file: %w: %q", err, store.ID)
+		}
+	}
+	return repository, nil
+}
+
+func (repo *FileStoreRepository) AddStore(store *models.Store) error {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+	if err := repo.memory.AddStore(store); err != nil {
+		return err
+	}
+	if err := repo.write(); err != nil {
+		repo.memory.stores.Delete(store.ID)
+		return err
+	}
+	return nil
+}
+
+func (repo *FileStoreRepository) GetStore(id string) (*models.Store, error) {
+	return repo.memory.GetStore(id)
+}
+
+func (repo *FileStoreRepository) GetAllStores() ([]*models.Store, error) {
+	return repo.memory.GetAllStores()
+}
+
+func (repo *FileStoreRepository) UpdateStore(store *models.Store) error {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+	previous, err := repo.memory.GetStore(store.ID)
+	if err != nil {
+		return err
+	}
+	repo.memory.UpdateStore(store)
+	if err := repo.write(); err != nil {
+		repo.memory.UpdateStore(previous)
+		return err
+	}
+	return nil
+}
+
+func (repo *FileStoreRepository) DeleteStore(id string) error {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+	previous, err := repo.memory.GetStore(id)
+	if err != nil {
+		return err
+	}
+	repo.memory.DeleteStore(id)
+	if err := repo.write(); err != nil {
+		repo.memory.AddStore(previous)
+		return err
+	}
+	return nil
+}
+
+func (repo *FileStoreRepository) write() error {
+	stores, _ := repo.memory.GetAllStores()
+	sort.Slice(stores, func(i, j int) bool { return stores[i].ID < stores[j].ID })
+	if err := writeCatalogFile(repo.path, stores); err != nil {
+		return fmt.Errorf("couldn't write store file: %w", err)
+	}
+	return nil
+}
+
+// FileProductRepository is the FileStoreRepository of products.
+type FileProductRepository struct {
+	memory *InMemoryProductRepository
+	path   string
+
+	mu sync.Mutex
+}
+
+func NewFileProductRepository(path string) (*FileProductRepository, error) {
+	var products []*models.Product
+	if err := readCatalogFile(path, &products); err != nil {
+		return nil, fmt.Errorf("couldn't read product file: %w", err)
+	}
+	repository := &FileProductRepository{memory: NewInMemoryProductRepository(), path: path}
+	for _, product := range products {
+		if err := repository.memory.AddProduct(product); err != nil {
+			return nil, fmt.Errorf("couldn't read product file: %w: %q", err, product.ID)
+		}
+	}
+	return repository, nil
+}
+
+func (repo *FileProductRepository) AddProduct(product *models.Product) error {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+	if err := repo.memory.AddProduct(product); err != nil {
+		return err
+	}
+	if err := repo.write(); err != nil {
+		repo.memory.products.Delete(product.ID)
+		return err
+	}
+	return nil
+}
+
+func (repo *FileProductRepository) GetProduct(id string) (*models.Product, error) {
+	return repo.memory.GetProduct(id)
+}
+
+func (repo *FileProductRepository) GetAllProducts() ([]*models.Product, error) {
+	return repo.memory.GetAllProducts()
+}
+
+func (repo *FileProductRepository) UpdateProduct(product *models.Product) error {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+	previous, err := repo.memory.GetProduct(product.ID)
+	if err != nil {
+		return err
+	}
+	repo.memory.UpdateProduct(product)
+	if err := repo.write(); err != nil {
+		repo.memory.UpdateProduct(previous)
+		return err
+	}
+	return nil
+}
+
+func (repo *FileProductRepository) DeleteProduct(id string) error {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+	previous, err := repo.memory.GetProduct(id)
+	if err != nil {
+		return err
+	}
+	repo.memory.DeleteProduct(id)
+	if err := repo.write(); err != nil {
+		repo.memory.AddProduct(previous)
+		return err
+	}
+	return nil
+}
+
+func (repo *FileProductRepository) write() error {
+	products, _ := repo.memory.GetAllProducts()
+	sort.Slice(products, func(i, j int) bool { return products[i].ID < products[j].ID })
+	if err := writeCatalogFile(repo.path, products); err != nil {
+		return fmt.Errorf("couldn't write product file: %w", err)
+	}
+	return nil
+}
+
+// readCatalogFile decodes the JSON array in the file at path into v, leaving
+// v empty when there is no file yet.
+func readCatalogFile(path string, v interface{}) error {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+func writeCatalogFile(path string, v interface{}) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	return replaceFile(path, append(data, '\n'))
+}
@@ -0,0 +1,109 @@
+package repo
+
+import (
+	"dataflow/models"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestInMemoryStoreRepository(t *testing.T) {
+	repo := NewInMemoryStoreRepository()
+
+	store := &models.Store{ID: "6789", Name: "Downtown", Region: "north", Active: true}
+	assert.Nil(t, repo.AddStore(store))
+	assert.ErrorIs(t, repo.AddStore(store), ErrStoreAlreadyExists)
+
+	// The repository keeps a copy.
+	store.Name = "Uptown"
+	stored, err := repo.GetStore("6789")
+	assert.Nil(t, err)
+	assert.Equal(t, "Downtown", stored.Name)
+
+	assert.Nil(t, repo.UpdateStore(store))
+	stores, err := repo.GetAllStores()
+	assert.Nil(t, err)
+	assert.Equal(t, []*models.Store{store}, stores)
+
+	assert.Nil(t, repo.DeleteStore("6789"))
+	_, err = repo.GetStore("6789")
+	assert.ErrorIs(t, err, ErrStoreNotFound)
+	assert.ErrorIs(t, repo.DeleteStore("6789"), ErrStoreNotFound)
+	assert.ErrorIs(t, repo.UpdateStore(store), ErrStoreNotFound)
+}
+
+func TestInMemoryProductRepository(t *testing.T) {
+	repo := NewInMemoryProductRepository()
+
+	products, err := repo.GetAllProducts()
+	assert.Nil(t, err)
+	assert.NotNil(t, products)
+	assert.Empty(t, products)
+
+	product := &models.Product{ID: "12345", Name: "Espresso", Category: "coffee", SKU: "ESP-1", Active: true}
+	assert.Nil(t, repo.AddProduct(product))
+	assert.ErrorIs(t, repo.AddProduct(product), ErrProductAlreadyExists)
+
+	product.Active = false
+	assert.Nil(t, repo.UpdateProduct(product))
+	stored, err := repo.GetProduct("12345")
+	assert.Nil(t, err)
+	assert.Equal(t, product, stored)
+
+	assert.Nil(t, repo.DeleteProduct("12345"))
+	_, err = repo.GetProduct("12345")
+	assert.ErrorIs(t, err, ErrProductNotFound)
+	assert.ErrorIs(t, repo.UpdateProduct(product), ErrProductNotFound)
+}
+
+func TestFileStoreRepository_Reopen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sales.stores.json")
+	repo, err := NewFileStoreRepository(path)
+	assert.Nil(t, err)
+
+	downtown := &models.Store{ID: "6789", Name: "Downtown", Region: "north", Timezone: "America/New_York", Active: true}
+	assert.Nil(t, repo.AddStore(downtown))
+	assert.Nil(t, repo.AddStore(&models.Store{ID: "9876", Name: "Airport"}))
+	assert.ErrorIs(t, repo.AddStore(downtown), ErrStoreAlreadyExists)
+	downtown.District = "center"
+	assert.Nil(t, repo.UpdateStore(downtown))
+	assert.Nil(t, repo.DeleteStore("9876"))
+	assert.ErrorIs(t, repo.DeleteStore("9876"), ErrStoreNotFound)
+
+	reopened, err := NewFileStoreRepository(path)
+	assert.Nil(t, err)
+	stores, err := reopened.GetAllStores()
+	assert.Nil(t, err)
+	assert.Equal(t, []*models.Store{downtown}, stores)
+}
+
+func TestFileProductRepository_Reopen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sales.products.json")
+	repo, err := NewFileProductRepository(path)
+	assert.Nil(t, err)
+
+	coffee := &models.Product{ID: "12345", Name: "Coffee", Category: "drinks", Active: true}
+	assert.Nil(t, repo.AddProduct(coffee))
+	assert.Nil(t, repo.AddProduct(&models.Product{ID: "54321", Name: "Tea"}))
+	coffee.SKU = "COF-1"
+	assert.Nil(t, repo.UpdateProduct(coffee))
+	assert.Nil(t, repo.DeleteProduct("54321"))
+
+	reopened, err := NewFileProductRepository(path)
+	assert.Nil(t, err)
+	products, err := reopened.GetAllProducts()
+	assert.Nil(t, err)
+	assert.Equal(t, []*models.Product{coffee}, products)
+}
+
+func TestNewFileStoreRepository_Corrupt(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sales.stores.json")
+	assert.Nil(t, os.WriteFile(path, []byte(`[{"id":"6789"},{"id":"6789"}]`), 0o600))
+	_, err := NewFileStoreRepository(path)
+	assert.ErrorIs(t, err, ErrStoreAlreadyExists)
+
+	assert.Nil(t, os.WriteFile(path, []byte(`[{"id":`), 0o600))
+	_, err = NewFileStoreRepository(path)
+	assert.Error(t, err)
+}
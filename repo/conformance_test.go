@@ -0,0 +1,15 @@
+package repo_test
+
+import (
+	"dataflow/repo/repotest"
+	"testing"
+)
+
+func TestRangeConformance(t *testing.T) {
+	for name, repository := range repotest.Backends(t) {
+		t.Run(name, func(t *testing.T) {
+			repotest.AddRangeSales(t, repository)
+			repotest.TestRange(t, repository)
+		})
+	}
+}
@@ -0,0 +1,345 @@
+package repo
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"dataflow/models"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+var ErrRepositoryClosed = errors.New("repository is closed")
+
+// FileRepository keeps sales in memory and appends every added sale to a
+// newline-delimited JSON file, which is replayed when the repository is
+// opened.
+type FileRepository struct {
+	memory *InMemoryRepository
+
+	mu   sync.Mutex
+	file *os.File
+}
+
+// NewFileRepository opens or creates the sales file at path and loads its
+// sales. A final line cut short by a crash is truncated; any other damage
+// is an error, see ScanSalesFile and CompactSalesFile.
+func NewFileRepository(path string) (*FileRepository, error) {
+	scan, err := ScanSalesFile(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	if scan != nil {
+		if len(scan.Corrupt) > 0 || len(scan.Duplicates) > 0 {
+			return nil, fmt.Errorf("sales file %s has %d corrupt and %d duplicate lines, run compact to repair it", path, len(scan.Corrupt), len(scan.Duplicates))
+		}
+	}
+
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR|os.O_APPEND, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't open sales file: %w", err)
+	}
+	repository := &FileRepository{memory: NewInMemoryRepository(), file: file}
+	if scan == nil {
+		return repository, nil
+	}
+	if scan.TornTail {
+		slog.Warn("truncating incomplete last line of sales file", slog.String("path", path), slog.Int64("size", scan.ValidSize))
+		if err := file.Truncate(scan.ValidSize); err != nil {
+			file.Close()
+			return nil, fmt.Errorf("couldn't truncate sales file: %w", err)
+		}
+	}
+	if err := terminateLastLine(file, scan.ValidSize); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("couldn't repair sales file: %w", err)
+	}
+	for _, sale := range scan.Sales {
+		repository.memory.Restore(sale)
+	}
+	return repository, nil
+}
+
+// terminateLastLine appends a newline if a hand-edited file doesn't end in
+// one, so that the next sale starts on a line of its own.
+func terminateLastLine(file *os.File, size int64) error {
+	if size == 0 {
+		return nil
+	}
+	last := make([]byte, 1)
+	if _, err := file.ReadAt(last, size-1); err != nil {
+		return err
+	}
+	if last[0] == '\n' {
+		return nil
+	}
+	_, err := file.Write([]byte{'\n'})
+	return err
+}
+
+func (r *FileRepository) GetSale(ctx context.Context, id string) (*models.Sale, error) {
+	return r.memory.GetSale(ctx, id)
+}
+
+func (r *FileRepository) GetAllSales(ctx context.Context) ([]*models.Sale, error) {
+	return r.memory.GetAllSales(ctx)
+}
+
+func (r *FileRepository) GetSalesInRange(ctx context.Context, startDate time.Time, endDate time.Time, storeId string) ([]*models.Sale, error) {
+	return r.memory.GetSalesInRange(ctx, startDate, endDate, storeId)
+}
+
+func (r *FileRepository) HasStoreIndex() bool {
+	return true
+}
+
+func (r *FileRepository) GetSalesByStores(ctx context.Context, startDate time.Time, endDate time.Time, storeIds []string) ([]*models.Sale, error) {
+	return r.memory.GetSalesByStores(ctx, startDate, endDate, storeIds)
+}
+
+// AddSale returns once the sale has been synced to disk.
+func (r *FileRepository) AddSale(ctx context.Context, sale *models.Sale) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.file == nil {
+		return ErrRepositoryClosed
+	}
+	if err := r.memory.AddSale(ctx, sale); err != nil {
+		return err
+	}
+	line, err := json.Marshal(sale)
+	if err == nil {
+		_, err = r.file.Write(append(line, '\n'))
+	}
+	if err == nil {
+		err = r.file.Sync()
+	}
+	if err != nil {
+		r.memory.remove(sale)
+		return fmt.Errorf("couldn't write sale: %w", err)
+	}
+	return nil
+}
+
+// AddSales adds sales with a single write and sync, such as for a bulk
+// import. Either every sale is stored or none is.
+func (r *FileRepository) AddSales(ctx context.Context, sales []*models.Sale) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.file == nil {
+		return ErrRepositoryClosed
+	}
+	info, err := r.file.Stat()
+	if err != nil {
+		return fmt.Errorf("couldn't write sales: %w", err)
+	}
+	var buf bytes.Buffer
+	added := make([]*models.Sale, 0, len(sales))
+	for _, sale := range sales {
+		if err = r.memory.AddSale(ctx, sale); err != nil {
+			break
+		}
+		added = append(added, sale)
+		var line []byte
+		if line, err = json.Marshal(sale); err != nil {
+			break
+		}
+		buf.Write(append(line, '\n'))
+	}
+	if err == nil {
+		_, err = r.file.Write(buf.Bytes())
+		if err == nil {
+			err = r.file.Sync()
+		}
+		if err != nil {
+			// Don't leave part of the batch behind for the next open to find.
+			r.file.Truncate(info.Size())
+		}
+	}
+	if err != nil {
+		for _, sale := range added {
+			r.memory.remove(sale)
+		}
+		return fmt.Errorf("couldn't write sales: %w", err)
+	}
+	return nil
+}
+
+func (r *FileRepository) Ping(ctx context.Context) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.file == nil {
+		return ErrRepositoryClosed
+	}
+	_, err := r.file.Stat()
+	return err
+}
+
+func (r *FileRepository) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.file == nil {
+		return nil
+	}
+	err := r.file.Sync()
+	if closeErr := r.file.Close(); err == nil {
+		err = closeErr
+	}
+	r.file = nil
+	return err
+}
+
+// SalesFileScan describes the contents of a sales file. Line numbers start
+// at 1.
+type SalesFileScan struct {
+	// Sales holds the first occurrence of every valid sale, in file order.
+	Sales []*models.Sale
+	Lines int
+	// Corrupt lists lines that aren't a JSON sale with an ID.
+	Corrupt []int
+	// Duplicates lists lines repeating the ID of an earlier sale.
+	Duplicates []int
+	// TornTail is set when the file doesn't end in a newline and its last
+	// line isn't valid, as left behind by a crash during a write.
+	TornTail bool
+	// ValidSize is the size of the file without a torn last line.
+	ValidSize int64
+}
+
+// Intact reports whether the file can be opened without repairs.
+func (s *SalesFileScan) Intact() bool {
+	return len(s.Corrupt) == 0 && len(s.Duplicates) == 0 && !s.TornTail
+}
+
+// ScanSalesFile reads a sales file written by FileRepository without
+// modifying it.
+func ScanSalesFile(path string) (*SalesFileScan, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't open sales file: %w", err)
+	}
+	defer file.Close()
+
+	scan := &SalesFileScan{}
+	seen := make(map[string]struct{})
+	reader := bufio.NewReader(file)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if len(line) == 0 && errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil && !errors.Is(err, io.EOF) {
+			return nil, fmt.Errorf("couldn't read sales file: %w", err)
+		}
+		scan.Lines++
+		complete := bytes.HasSuffix(line, []byte{'\n'})
+
+		var sale models.Sale
+		if len(bytes.TrimSpace(line)) == 0 {
+			// Blank lines are ignored.
+		} else if jsonErr := json.Unmarshal(line, &sale); jsonErr != nil || sale.ID == "" {
+			if !complete {
+				scan.TornTail = true
+				break
+			}
+			scan.Corrupt = append(scan.Corrupt, scan.Lines)
+		} else if _, ok := seen[sale.ID]; ok {
+			scan.Duplicates = append(scan.Duplicates, scan.Lines)
+		} else {
+			seen[sale.ID] = struct{}{}
+			scan.Sales = append(scan.Sales, &sale)
+		}
+		scan.ValidSize += int64(len(line))
+		if !complete {
+			break
+		}
+	}
+	return scan, nil
+}
+
+// CompactSalesFile rewrites a sales file with only its valid, unique sales,
+// ordered by sale date and ID. The new file replaces the old one atomically.
+// It must not run while a server has the file open.
+func CompactSalesFile(path string) (*SalesFileScan, error) {
+	scan, err := ScanSalesFile(path)
+	if err != nil {
+		return nil, err
+	}
+	sales := append([]*models.Sale(nil), scan.Sales...)
+	sort.SliceStable(sales, func(i, j int) bool {
+		if !sales[i].SaleDate.Equal(sales[j].SaleDate) {
+			return sales[i].SaleDate.Before(sales[j].SaleDate)
+		}
+		return sales[i].ID < sales[j].ID
+	})
+
+	temp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".compact-*")
+	if err != nil {
+		return nil, fmt.Errorf("couldn't create compacted file: %w", err)
+	}
+	defer os.Remove(temp.Name())
+	writer := bufio.NewWriter(temp)
+	encoder := json.NewEncoder(writer)
+	for _, sale := range sales {
+		if err = encoder.Encode(sale); err != nil {
+			break
+		}
+	}
+	if err == nil {
+		err = writer.Flush()
+	}
+	if err == nil {
+		err = temp.Sync()
+	}
+	if closeErr := temp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(temp.Name(), path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("couldn't write compacted file: %w", err)
+	}
+	if dir, err := os.Open(filepath.Dir(path)); err == nil {
+		dir.Sync()
+		dir.Close()
+	}
+	return scan, nil
+}
+
+// replaceFile atomically replaces the file at path with data by renaming a
+// synced temporary file over it. The temporary file is named after path with
+// a leading dot.
+func replaceFile(path string, data []byte) error {
+	temp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+"-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(temp.Name())
+	_, err = temp.Write(data)
+	if err == nil {
+		err = temp.Sync()
+	}
+	if closeErr := temp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(temp.Name(), path)
+	}
+	if err != nil {
+		return err
+	}
+	if dir, err := os.Open(filepath.Dir(path)); err == nil {
+		dir.Sync()
+		dir.Close()
+	}
+	return nil
+}
@@ -0,0 +1,129 @@
+package repo
+
+import (
+	"context"
+	"dataflow/models"
+	"github.com/stretchr/testify/assert"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func newTestSale(storeId string, day int) *models.Sale {
+	return &models.Sale{
+		ProductId:    "12345",
+		StoreId:      storeId,
+		QuantitySold: 2,
+		SalePrice:    9.99,
+		SaleDate:     time.Date(2024, 6, day, 12, 0, 0, 0, time.UTC),
+	}
+}
+
+func TestFileRepository_Reopen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sales.ndjson")
+	repository, err := NewFileRepository(path)
+	assert.NoError(t, err)
+	sale := newTestSale("6789", 15)
+	assert.NoError(t, repository.AddSale(context.Background(), sale))
+	assert.NoError(t, repository.AddSale(context.Background(), newTestSale("9876", 16)))
+	assert.NoError(t, repository.Close())
+	assert.ErrorIs(t, repository.AddSale(context.Background(), newTestSale("6789", 17)), ErrRepositoryClosed)
+
+	reopened, err := NewFileRepository(path)
+	assert.NoError(t, err)
+	defer reopened.Close()
+	sales, err := reopened.GetAllSales(context.Background())
+	assert.NoError(t, err)
+	assert.Len(t, sales, 2)
+	inRange, err := reopened.GetSalesInRange(context.Background(), time.Time{}, time.Time{}, "6789")
+	assert.NoError(t, err)
+	assert.Equal(t, []*models.Sale{sale}, inRange)
+	assert.NoError(t, reopened.Ping(context.Background()))
+}
+
+func TestFileRepository_AddSales(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sales.ndjson")
+	repository, err := NewFileRepository(path)
+	assert.NoError(t, err)
+	assert.NoError(t, repository.AddSales(context.Background(), []*models.Sale{newTestSale("6789", 15), newTestSale("9876", 16)}))
+	before, _ := os.ReadFile(path)
+
+	unwritable := newTestSale("6789", 17)
+	unwritable.SalePrice = math.NaN()
+	assert.Error(t, repository.AddSales(context.Background(), []*models.Sale{newTestSale("6789", 17), unwritable}))
+	sales, _ := repository.GetAllSales(context.Background())
+	assert.Len(t, sales, 2)
+	assert.NoError(t, repository.Close())
+	after, _ := os.ReadFile(path)
+	assert.Equal(t, before, after)
+
+	reopened, err := NewFileRepository(path)
+	assert.NoError(t, err)
+	defer reopened.Close()
+	sales, _ = reopened.GetAllSales(context.Background())
+	assert.Len(t, sales, 2)
+}
+
+func TestFileRepository_TruncatesTornTail(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sales.ndjson")
+	repository, err := NewFileRepository(path)
+	assert.NoError(t, err)
+	assert.NoError(t, repository.AddSale(context.Background(), newTestSale("6789", 15)))
+	assert.NoError(t, repository.Close())
+	info, _ := os.Stat(path)
+	file, _ := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0o600)
+	file.WriteString(`{"id":"torn","store_`)
+	file.Close()
+
+	reopened, err := NewFileRepository(path)
+	assert.NoError(t, err)
+	assert.NoError(t, reopened.AddSale(context.Background(), newTestSale("6789", 16)))
+	assert.NoError(t, reopened.Close())
+
+	scan, err := ScanSalesFile(path)
+	assert.NoError(t, err)
+	assert.True(t, scan.Intact())
+	assert.Len(t, scan.Sales, 2)
+	assert.Greater(t, scan.ValidSize, info.Size())
+}
+
+func TestFileRepository_RejectsCorruptFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sales.ndjson")
+	assert.NoError(t, os.WriteFile(path, []byte("{\"id\":\"a\"}\nnot json\n{\"id\":\"a\"}\n"), 0o600))
+
+	_, err := NewFileRepository(path)
+
+	assert.ErrorContains(t, err, "run compact")
+}
+
+func TestScanAndCompactSalesFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sales.ndjson")
+	content := `{"id":"b","store_id":"6789","sale_date":"2024-06-16T00:00:00Z"}
+not json
+
+{"id":"a","store_id":"6789","sale_date":"2024-06-15T00:00:00Z"}
+{"id":"b","store_id":"9876","sale_date":"2024-06-17T00:00:00Z"}
+{"id":"c","store_`
+	assert.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+
+	scan, err := ScanSalesFile(path)
+	assert.NoError(t, err)
+	assert.False(t, scan.Intact())
+	assert.Equal(t, 6, scan.Lines)
+	assert.Equal(t, []int{2}, scan.Corrupt)
+	assert.Equal(t, []int{5}, scan.Duplicates)
+	assert.True(t, scan.TornTail)
+
+	_, err = CompactSalesFile(path)
+	assert.NoError(t, err)
+
+	compacted, err := ScanSalesFile(path)
+	assert.NoError(t, err)
+	assert.True(t, compacted.Intact())
+	assert.Len(t, compacted.Sales, 2)
+	assert.Equal(t, "a", compacted.Sales[0].ID)
+	assert.Equal(t, "b", compacted.Sales[1].ID)
+	assert.Equal(t, "6789", compacted.Sales[1].StoreId)
+}
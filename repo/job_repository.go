@@ -0,0 +1,193 @@
+package repo
+
+import (
+	"dataflow/models"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+var ErrJobNotFound = errors.New("job not found")
+
+type JobRepository interface {
+	AddJob(job *models.Job) error
+	GetJob(id string) (*models.Job, error)
+	GetAllJobs() ([]*models.Job, error)
+	UpdateJob(job *models.Job) error
+	DeleteJob(id string) error
+}
+
+type InMemoryJobRepository struct {
+	data sync.Map
+}
+
+func NewInMemoryJobRepository() *InMemoryJobRepository {
+	return &InMemoryJobRepository{}
+}
+
+func (repo *InMemoryJobRepository) AddJob(job *models.Job) error {
+	job.ID = uuid.New().String()
+	stored := *job
+	repo.data.Store(job.ID, &stored)
+	return nil
+}
+
+func (repo *InMemoryJobRepository) GetJob(id string) (*models.Job, error) {
+	v, ok := repo.data.Load(id)
+	if !ok {
+		return nil, ErrJobNotFound
+	}
+	job := *v.(*models.Job)
+	return &job, nil
+}
+
+func (repo *InMemoryJobRepository) GetAllJobs() ([]*models.Job, error) {
+	var jobs []*models.Job
+	repo.data.Range(func(k, v interface{}) bool {
+		job := *v.(*models.Job)
+		jobs = append(jobs, &job)
+		return true
+	})
+	return jobs, nil
+}
+
+func (repo *InMemoryJobRepository) UpdateJob(job *models.Job) error {
+	if _, ok := repo.data.Load(job.ID); !ok {
+		return ErrJobNotFound
+	}
+	stored := *job
+	repo.data.Store(job.ID, &stored)
+	return nil
+}
+
+func (repo *InMemoryJobRepository) DeleteJob(id string) error {
+	if _, loaded := repo.data.LoadAndDelete(id); !loaded {
+		return ErrJobNotFound
+	}
+	return nil
+}
+
+// FileJobRepository keeps jobs in memory and every job in a JSON file of its
+// own in a directory, which is replaced atomically whenever the job changes.
+// Jobs left queued or running by a stopped process are loaded again when the
+// repository is opened.
+type FileJobRepository struct {
+	memory *InMemoryJobRepository
+	dir    string
+
+	mu sync.Mutex
+}
+
+// jobRecord is how a job is written to its file; unlike the API
+// representation it keeps the submitter and the result.
+type jobRecord struct {
+	*models.Job
+	Principal   *models.Principal `json:"principal,omitempty"`
+	Result      []byte            `json:"result,omitempty"`
+	ContentType string            `json:"content_type,omitempty"`
+}
+
+// NewFileJobRepository opens or creates the job directory at dir and loads
+// its jobs. Files that a crash left half written are removed.
+func NewFileJobRepository(dir string) (*FileJobRepository, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("couldn't create job directory: %w", err)
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't read job directory: %w", err)
+	}
+	repository := &FileJobRepository{memory: NewInMemoryJobRepository(), dir: dir}
+	for _, entry := range entries {
+		path := filepath.Join(dir, entry.Name())
+		if strings.HasPrefix(entry.Name(), ".") {
+			slog.Warn("removing incomplete job file", slog.String("path", path))
+			os.Remove(path)
+			continue
+		}
+		if entry.IsDir() || filepath.Ext(entry.Name()) != ".json" {
+			continue
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("couldn't read job file: %w", err)
+		}
+		var record jobRecord
+		if err := json.Unmarshal(data, &record); err != nil || record.Job == nil || record.ID == "" {
+			return nil, fmt.Errorf("job file %s isn't a job", path)
+		}
+		job := record.Job
+		job.Principal, job.Result, job.ContentType = record.Principal, record.Result, record.ContentType
+		repository.memory.data.Store(job.ID, job)
+	}
+	return repository, nil
+}
+
+// AddJob returns once the job has been synced to disk.
+func (repo *FileJobRepository) AddJob(job *models.Job) error {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+	if err := repo.memory.AddJob(job); err != nil {
+		return err
+	}
+	if err := repo.write(job); err != nil {
+		repo.memory.data.Delete(job.ID)
+		return err
+	}
+	return nil
+}
+
+func (repo *FileJobRepository) GetJob(id string) (*models.Job, error) {
+	return repo.memory.GetJob(id)
+}
+
+func (repo *FileJobRepository) GetAllJobs() ([]*models.Job, error) {
+	return repo.memory.GetAllJobs()
+}
+
+func (repo *FileJobRepository) UpdateJob(job *models.Job) error {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+	if _, err := repo.memory.GetJob(job.ID); err != nil {
+		return err
+	}
+	if err := repo.write(job); err != nil {
+		return err
+	}
+	return repo.memory.UpdateJob(job)
+}
+
+func (repo *FileJobRepository) DeleteJob(id string) error {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+	if _, err := repo.memory.GetJob(id); err != nil {
+		return err
+	}
+	if err := os.Remove(repo.path(id)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("couldn't delete job file: %w", err)
+	}
+	return repo.memory.DeleteJob(id)
+}
+
+func (repo *FileJobRepository) path(id string) string {
+	return filepath.Join(repo.dir, id+".json")
+}
+
+// write replaces the job's file, so that a crash leaves either the old or
+// the new version behind.
+func (repo *FileJobRepository) write(job *models.Job) error {
+	data, err := json.Marshal(jobRecord{Job: job, Principal: job.Principal, Result: job.Result, ContentType: job.ContentType})
+	if err == nil {
+		err = replaceFile(repo.path(job.ID), data)
+	}
+	if err != nil {
+		return fmt.Errorf("couldn't write job: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,91 @@
+package repo
+
+import (
+	"dataflow/models"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestInMemoryJobRepository_AddJob(t *testing.T) {
+	repo := NewInMemoryJobRepository()
+
+	job := &models.Job{Type: "calculate", Status: models.JobStatusQueued}
+	err := repo.AddJob(job)
+	assert.Nil(t, err)
+	assert.NotEmpty(t, job.ID)
+
+	stored, err := repo.GetJob(job.ID)
+	assert.Nil(t, err)
+	assert.Equal(t, job, stored)
+}
+
+func TestInMemoryJobRepository_UpdateJob(t *testing.T) {
+	repo := NewInMemoryJobRepository()
+
+	job := &models.Job{Type: "calculate", Status: models.JobStatusQueued}
+	repo.AddJob(job)
+
+	job.Status = models.JobStatusRunning
+	job.Progress = 50
+	assert.Nil(t, repo.UpdateJob(job))
+
+	stored, _ := repo.GetJob(job.ID)
+	assert.Equal(t, models.JobStatusRunning, stored.Status)
+	assert.Equal(t, 50, stored.Progress)
+
+	assert.ErrorIs(t, repo.UpdateJob(&models.Job{ID: "missing"}), ErrJobNotFound)
+}
+
+func TestInMemoryJobRepository_DeleteJob(t *testing.T) {
+	repo := NewInMemoryJobRepository()
+
+	job := &models.Job{Type: "export"}
+	repo.AddJob(job)
+
+	assert.Nil(t, repo.DeleteJob(job.ID))
+	_, err := repo.GetJob(job.ID)
+	assert.ErrorIs(t, err, ErrJobNotFound)
+
+	jobs, err := repo.GetAllJobs()
+	assert.Nil(t, err)
+	assert.Empty(t, jobs)
+}
+
+func TestFileJobRepository_Reopen(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "jobs")
+	repo, err := NewFileJobRepository(dir)
+	assert.Nil(t, err)
+
+	principal := &models.Principal{Subject: "manager-1", Roles: []string{"store_manager"}, Stores: []string{"6789"}}
+	running := &models.Job{Type: "calculate", Status: models.JobStatusQueued, Principal: principal}
+	assert.Nil(t, repo.AddJob(running))
+	running.Status = models.JobStatusRunning
+	running.Progress = 50
+	assert.Nil(t, repo.UpdateJob(running))
+	succeeded := &models.Job{Type: "export", Status: models.JobStatusSucceeded, Result: []byte("id\n"), ContentType: "text/csv"}
+	assert.Nil(t, repo.AddJob(succeeded))
+	deleted := &models.Job{Type: "export"}
+	assert.Nil(t, repo.AddJob(deleted))
+	assert.Nil(t, repo.DeleteJob(deleted.ID))
+	// A crash while replacing a job file leaves a temporary file behind.
+	assert.Nil(t, os.WriteFile(filepath.Join(dir, "."+running.ID+"-1"), []byte(`{"id":`), 0o600))
+
+	reopened, err := NewFileJobRepository(dir)
+	assert.Nil(t, err)
+	jobs, err := reopened.GetAllJobs()
+	assert.Nil(t, err)
+	assert.Len(t, jobs, 2)
+	stored, err := reopened.GetJob(running.ID)
+	assert.Nil(t, err)
+	assert.Equal(t, running, stored)
+	stored, err = reopened.GetJob(succeeded.ID)
+	assert.Nil(t, err)
+	assert.Equal(t, succeeded, stored)
+	_, err = reopened.GetJob(deleted.ID)
+	assert.ErrorIs(t, err, ErrJobNotFound)
+
+	files, _ := os.ReadDir(dir)
+	assert.Len(t, files, 2)
+}
@@ -1,6 +1,7 @@
 package repo
 
 import (
+	"context"
 	"dataflow/models"
 	"github.com/stretchr/testify/mock"
 	"time"
@@ -10,17 +11,23 @@ type MockRepository struct {
 	mock.Mock
 }
 
-func (m *MockRepository) AddSale(sale *models.Sale) error {
+func (m *MockRepository) AddSale(ctx context.Context, sale *models.Sale) error {
 	args := m.Called(sale)
 	return args.Error(0)
 }
 
-func (m *MockRepository) GetAllSales() ([]*models.Sale, error) {
+func (m *MockRepository) GetSale(ctx context.Context, id string) (*models.Sale, error) {
+	args := m.Called(id)
+	sale, _ := args.Get(0).(*models.Sale)
+	return sale, args.Error(1)
+}
+
+func (m *MockRepository) GetAllSales(ctx context.Context) ([]*models.Sale, error) {
 	args := m.Called()
 	return args.Get(0).([]*models.Sale), args.Error(1)
 }
 
-func (m *MockRepository) GetSalesInRange(startDate time.Time, endDate time.Time, storeId string) ([]*models.Sale, error) {
+func (m *MockRepository) GetSalesInRange(ctx context.Context, startDate time.Time, endDate time.Time, storeId string) ([]*models.Sale, error) {
 	args := m.Called(startDate, endDate, storeId)
 	return args.Get(0).([]*models.Sale), args.Error(1)
 }
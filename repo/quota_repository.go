@@ -0,0 +1,150 @@
+package repo
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+)
+
+// QuotaRepository counts requests per client and period, e.g. per UTC day.
+// Implementations must make Increment atomic so that concurrent instances
+// sharing a durable store never over-admit.
+type QuotaRepository interface {
+	// Increment adds n to the client's usage in the period and returns the
+	// new total.
+	Increment(client string, period string, n int64) (int64, error)
+	GetUsage(client string, period string) (int64, error)
+}
+
+type InMemoryQuotaRepository struct {
+	mu     sync.Mutex
+	period string
+	usage  map[string]int64
+}
+
+func NewInMemoryQuotaRepository() *InMemoryQuotaRepository {
+	return &InMemoryQuotaRepository{usage: make(map[string]int64)}
+}
+
+// Increment only keeps counters of the latest period; usage of earlier periods
+// is dropped when a new one starts. Periods must sort chronologically, e.g.
+// "2006-01-02".
+func (repo *InMemoryQuotaRepository) Increment(client string, period string, n int64) (int64, error) {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+	if period > repo.period {
+		repo.period = period
+		repo.usage = make(map[string]int64)
+	} else if period < repo.period {
+		return 0, nil
+	}
+	repo.usage[client] += n
+	return repo.usage[client], nil
+}
+
+func (repo *InMemoryQuotaRepository) GetUsage(client string, period string) (int64, error) {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+	if period != repo.period {
+		return 0, nil
+	}
+	return repo.usage[client], nil
+}
+
+// FileQuotaRepository counts like InMemoryQuotaRepository and appends every
+// increment to a newline-delimited JSON file, which is replayed when the
+// repository is opened, so that quotas don't reset on restarts. The file is
+// emptied when a new period starts. Writes aren't synced: a crash of the
+// process loses nothing, one of the machine may lose the latest increments.
+// The file must not be shared by several servers.
+type FileQuotaRepository struct {
+	memory *InMemoryQuotaRepository
+
+	mu   sync.Mutex
+	file *os.File
+}
+
+type quotaIncrement struct {
+	Client string `json:"client"`
+	Period string `json:"period"`
+	N      int64  `json:"n"`
+}
+
+// NewFileQuotaRepository opens or creates the quota file at path and replays
+// its increments. Lines that can't be read, such as one cut short by a crash,
+// are skipped.
+func NewFileQuotaRepository(path string) (*FileQuotaRepository, error) {
+	repository := &FileQuotaRepository{memory: NewInMemoryQuotaRepository()}
+	existing, err := os.Open(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("couldn't open quota file: %w", err)
+	}
+	if err == nil {
+		scanner := bufio.NewScanner(existing)
+		for scanner.Scan() {
+			var increment quotaIncrement
+			if json.Unmarshal(scanner.Bytes(), &increment) == nil {
+				repository.memory.Increment(increment.Client, increment.Period, increment.N)
+			}
+		}
+		err = scanner.Err()
+		existing.Close()
+		if err != nil {
+			return nil, fmt.Errorf("couldn't read quota file: %w", err)
+		}
+	}
+
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't open quota file: %w", err)
+	}
+	// A torn last line must not swallow the next increment.
+	if _, err := file.Write([]byte{'\n'}); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("couldn't open quota file: %w", err)
+	}
+	repository.file = file
+	return repository, nil
+}
+
+func (repo *FileQuotaRepository) Increment(client string, period string, n int64) (int64, error) {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+	if repo.file == nil {
+		return 0, ErrRepositoryClosed
+	}
+	if period < repo.memory.period {
+		return 0, nil
+	}
+	if period > repo.memory.period {
+		if err := repo.file.Truncate(0); err != nil {
+			return 0, fmt.Errorf("couldn't reset quota file: %w", err)
+		}
+	}
+	line, err := json.Marshal(quotaIncrement{Client: client, Period: period, N: n})
+	if err == nil {
+		_, err = repo.file.Write(append(line, '\n'))
+	}
+	if err != nil {
+		return 0, fmt.Errorf("couldn't write quota: %w", err)
+	}
+	return repo.memory.Increment(client, period, n)
+}
+
+func (repo *FileQuotaRepository) GetUsage(client string, period string) (int64, error) {
+	return repo.memory.GetUsage(client, period)
+}
+
+func (repo *FileQuotaRepository) Close() error {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+	if repo.file == nil {
+		return nil
+	}
+	err := repo.file.Close()
+	repo.file = nil
+	return err
+}
@@ -0,0 +1,57 @@
+package repo
+
+import (
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestInMemoryQuotaRepository_Increment(t *testing.T) {
+	repo := NewInMemoryQuotaRepository()
+
+	used, err := repo.Increment("pos", "2024-06-15", 1)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(1), used)
+	used, _ = repo.Increment("pos", "2024-06-15", 2)
+	assert.Equal(t, int64(3), used)
+	used, _ = repo.Increment("other", "2024-06-15", 1)
+	assert.Equal(t, int64(1), used)
+
+	used, _ = repo.Increment("pos", "2024-06-16", 1)
+	assert.Equal(t, int64(1), used)
+	usage, _ := repo.GetUsage("pos", "2024-06-15")
+	assert.Equal(t, int64(0), usage)
+	usage, _ = repo.GetUsage("pos", "2024-06-16")
+	assert.Equal(t, int64(1), usage)
+}
+
+func TestFileQuotaRepository_Reopen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sales.quotas.ndjson")
+	repo, err := NewFileQuotaRepository(path)
+	assert.Nil(t, err)
+	repo.Increment("pos", "2024-06-15", 5)
+	repo.Increment("pos", "2024-06-16", 2)
+	repo.Increment("other", "2024-06-16", 1)
+	assert.Nil(t, repo.Close())
+	// A crash while writing leaves a torn last line.
+	file, _ := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0o600)
+	file.Write([]byte(`{"client":"pos","per`))
+	file.Close()
+
+	reopened, err := NewFileQuotaRepository(path)
+	assert.Nil(t, err)
+	defer reopened.Close()
+	usage, _ := reopened.GetUsage("pos", "2024-06-15")
+	assert.Equal(t, int64(0), usage)
+	usage, _ = reopened.GetUsage("other", "2024-06-16")
+	assert.Equal(t, int64(1), usage)
+	used, err := reopened.Increment("pos", "2024-06-16", 1)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(3), used)
+
+	// A new period empties the file.
+	reopened.Increment("pos", "2024-06-17", 1)
+	data, _ := os.ReadFile(path)
+	assert.Equal(t, `{"client":"pos","period":"2024-06-17","n":1}`+"\n", string(data))
+}
@@ -1,30 +1,89 @@
 package repo
 
 import (
+	"context"
 	"dataflow/models"
 	"errors"
 	"github.com/google/uuid"
+	"slices"
 	"sync"
 	"time"
 )
 
-var ErrSaleAlreadyExists = errors.New("sale already exists")
+var (
+	ErrSaleAlreadyExists = errors.New("sale already exists")
+	ErrSaleNotFound      = errors.New("sale not found")
+)
 
 type Repository interface {
-	AddSale(sale *models.Sale) error
-	GetAllSales() ([]*models.Sale, error)
-	GetSalesInRange(startDate time.Time, endDate time.Time, storeId string) ([]*models.Sale, error)
+	AddSale(ctx context.Context, sale *models.Sale) error
+	GetSale(ctx context.Context, id string) (*models.Sale, error)
+	GetAllSales(ctx context.Context) ([]*models.Sale, error)
+	// GetSalesInRange returns the sales of a store dated in the half-open
+	// range [startDate, endDate), as InRange decides.
+	GetSalesInRange(ctx context.Context, startDate time.Time, endDate time.Time, storeId string) ([]*models.Sale, error)
+}
+
+// Pinger is implemented by repositories whose storage can become unreachable.
+// Repositories that don't implement it are always considered reachable.
+type Pinger interface {
+	Ping(ctx context.Context) error
+}
+
+// Ping checks that repository can serve requests.
+func Ping(ctx context.Context, repository Repository) error {
+	if pinger, ok := repository.(Pinger); ok {
+		return pinger.Ping(ctx)
+	}
+	return nil
+}
+
+// StoreIndex is implemented by repositories that can look up the sales of
+// some stores without scanning all sales. Decorators implement it for any
+// repository they wrap, so HasStoreIndex reports whether the lookup is
+// actually indexed.
+type StoreIndex interface {
+	HasStoreIndex() bool
+	GetSalesByStores(ctx context.Context, startDate time.Time, endDate time.Time, storeIds []string) ([]*models.Sale, error)
+}
+
+// HasStoreIndex reports whether repository indexes its sales by store.
+func HasStoreIndex(repository Repository) bool {
+	index, ok := repository.(StoreIndex)
+	return ok && index.HasStoreIndex()
+}
+
+// GetSalesByStores returns the sales of storeIds within the bounds of
+// GetSalesInRange, from the store index of repository when it has one and
+// with one GetSalesInRange call per store otherwise.
+func GetSalesByStores(ctx context.Context, repository Repository, startDate time.Time, endDate time.Time, storeIds []string) ([]*models.Sale, error) {
+	if HasStoreIndex(repository) {
+		return repository.(StoreIndex).GetSalesByStores(ctx, startDate, endDate, storeIds)
+	}
+	var sales []*models.Sale
+	for _, storeId := range storeIds {
+		storeSales, err := repository.GetSalesInRange(ctx, startDate, endDate, storeId)
+		if err != nil {
+			return nil, err
+		}
+		sales = append(sales, storeSales...)
+	}
+	return sales, nil
 }
 
 type InMemoryRepository struct {
 	data sync.Map
+
+	// mu guards byStore, which indexes the sales in data by store and ID.
+	mu      sync.RWMutex
+	byStore map[string]map[string]*models.Sale
 }
 
 func NewInMemoryRepository() *InMemoryRepository {
 	return &InMemoryRepository{}
 }
 
-func (repo *InMemoryRepository) GetAllSales() ([]*models.Sale, error) {
+func (repo *InMemoryRepository) GetAllSales(ctx context.Context) ([]*models.Sale, error) {
 	var sales []*models.Sale
 	repo.data.Range(func(k, v interface{}) bool {
 		sales = append(sales, v.(*models.Sale))
@@ -33,25 +92,116 @@ func (repo *InMemoryRepository) GetAllSales() ([]*models.Sale, error) {
 	return sales, nil
 }
 
-func (repo *InMemoryRepository) AddSale(sale *models.Sale) error {
+func (repo *InMemoryRepository) GetSale(ctx context.Context, id string) (*models.Sale, error) {
+	sale, ok := repo.data.Load(id)
+	if !ok {
+		return nil, ErrSaleNotFound
+	}
+	return sale.(*models.Sale), nil
+}
+
+func (repo *InMemoryRepository) AddSale(ctx context.Context, sale *models.Sale) error {
 	sale.ID = uuid.New().String()
-	_, loaded := repo.data.LoadOrStore(sale.ID, sale)
-	if loaded {
+	if !repo.store(sale) {
 		return ErrSaleAlreadyExists
 	}
 	return nil
 }
 
-func (repo *InMemoryRepository) GetSalesInRange(startDate time.Time, endDate time.Time, storeId string) ([]*models.Sale, error) {
+// Restore stores a sale under its existing ID, e.g. when loading it from a
+// file, and reports whether the ID was new.
+func (repo *InMemoryRepository) Restore(sale *models.Sale) bool {
+	return repo.store(sale)
+}
+
+func (repo *InMemoryRepository) store(sale *models.Sale) bool {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+	if _, loaded := repo.data.LoadOrStore(sale.ID, sale); loaded {
+		return false
+	}
+	if repo.byStore == nil {
+		repo.byStore = make(map[string]map[string]*models.Sale)
+	}
+	sales, ok := repo.byStore[sale.StoreId]
+	if !ok {
+		sales = make(map[string]*models.Sale)
+		repo.byStore[sale.StoreId] = sales
+	}
+	sales[sale.ID] = sale
+	return true
+}
+
+// remove undoes store, e.g. when a sale couldn't be persisted.
+func (repo *InMemoryRepository) remove(sale *models.Sale) {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+	repo.data.Delete(sale.ID)
+	delete(repo.byStore[sale.StoreId], sale.ID)
+}
+
+func (repo *InMemoryRepository) GetSalesInRange(ctx context.Context, startDate time.Time, endDate time.Time, storeId string) ([]*models.Sale, error) {
+	return repo.GetSalesByStores(ctx, startDate, endDate, []string{storeId})
+}
+
+func (repo *InMemoryRepository) HasStoreIndex() bool {
+	return true
+}
+
+func (repo *InMemoryRepository) GetSalesByStores(ctx context.Context, startDate time.Time, endDate time.Time, storeIds []string) ([]*models.Sale, error) {
+	repo.mu.RLock()
+	defer repo.mu.RUnlock()
 	var sales []*models.Sale
-	repo.data.Range(func(k, v interface{}) bool {
-		sale := v.(*models.Sale)
-		if storeId == sale.StoreId &&
-			(endDate.IsZero() || sale.SaleDate.Before(endDate)) &&
-			(startDate.IsZero() || sale.SaleDate.After(startDate)) {
-			sales = append(sales, sale)
+	for i, storeId := range storeIds {
+		if slices.Contains(storeIds[:i], storeId) {
+			continue
 		}
-		return true
-	})
+		for _, sale := range repo.byStore[storeId] {
+			if InRange(sale.SaleDate, startDate, endDate) {
+				sales = append(sales, sale)
+			}
+		}
+	}
 	return sales, nil
 }
+
+// Bounds of date ranges given by clients.
+const (
+	// RangeHalfOpen ranges [start, end) include sales at their start but not
+	// at their end, so that consecutive ranges neither overlap nor leave gaps.
+	// It's the default, and the ranges that repositories take.
+	RangeHalfOpen = "half_open"
+	// RangeClosed ranges [start, end] include sales at their end as well.
+	RangeClosed = "closed"
+)
+
+// InRange reports whether date lies in the half-open range [startDate,
+// endDate), the bounds of GetSalesInRange and of every query on sale dates.
+// A zero bound leaves its side open.
+func InRange(date time.Time, startDate time.Time, endDate time.Time) bool {
+	return (startDate.IsZero() || !date.Before(startDate)) && (endDate.IsZero() || date.Before(endDate))
+}
+
+// EndOf returns the end of the half-open range that covers a range ending at
+// endDate with bounds. The end of a closed range moves a nanosecond later,
+// the resolution of sale dates.
+func EndOf(endDate time.Time, bounds string) time.Time {
+	if bounds == RangeClosed && !endDate.IsZero() {
+		return endDate.Add(time.Nanosecond)
+	}
+	return endDate
+}
+
+// StartOfDay returns the first instant of the date of day in location, which
+// is the transition on days whose clocks skip midnight. A zero day stays
+// zero, an open bound.
+func StartOfDay(day time.Time, location *time.Location) time.Time {
+	if day.IsZero() {
+		return day
+	}
+	t := time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, location)
+	if t.Day() != day.Day() {
+		_, t = t.ZoneBounds()
+	}
+	return t
+}
@@ -1,6 +1,7 @@
 package repo
 
 import (
+	"context"
 	"dataflow/models"
 	"github.com/stretchr/testify/assert"
 	"testing"
@@ -25,10 +26,10 @@ func TestInMemoryRepository_GetAllSales(t *testing.T) {
 		SaleDate:     time.Date(2024, 6, 16, 10, 0, 0, 0, time.UTC),
 	}
 
-	repo.AddSale(sale1)
-	repo.AddSale(sale2)
+	repo.AddSale(context.Background(), sale1)
+	repo.AddSale(context.Background(), sale2)
 
-	sales, err := repo.GetAllSales()
+	sales, err := repo.GetAllSales(context.Background())
 
 	assert.Nil(t, err)
 	assert.Equal(t, 2, len(sales))
@@ -47,10 +48,30 @@ func TestInMemoryRepository_AddSale(t *testing.T) {
 		SaleDate:     time.Date(2024, 6, 15, 14, 30, 0, 0, time.UTC),
 	}
 
-	err := repo.AddSale(&sale)
+	err := repo.AddSale(context.Background(), &sale)
 	assert.Nil(t, err)
 }
 
+func TestInMemoryRepository_GetSale(t *testing.T) {
+	repo := NewInMemoryRepository()
+
+	sale := &models.Sale{
+		ProductId:    "12345",
+		StoreId:      "6789",
+		QuantitySold: 10,
+		SalePrice:    19.99,
+		SaleDate:     time.Date(2024, 6, 15, 14, 30, 0, 0, time.UTC),
+	}
+	repo.AddSale(context.Background(), sale)
+
+	found, err := repo.GetSale(context.Background(), sale.ID)
+	assert.Nil(t, err)
+	assert.Equal(t, sale, found)
+
+	_, err = repo.GetSale(context.Background(), "missing")
+	assert.ErrorIs(t, err, ErrSaleNotFound)
+}
+
 func TestInMemoryRepository_GetSalesInRange(t *testing.T) {
 	repo := NewInMemoryRepository()
 
@@ -69,13 +90,13 @@ func TestInMemoryRepository_GetSalesInRange(t *testing.T) {
 		SaleDate:     time.Date(2024, 6, 30, 10, 0, 0, 0, time.UTC),
 	}
 
-	repo.AddSale(sale1)
-	repo.AddSale(sale2)
+	repo.AddSale(context.Background(), sale1)
+	repo.AddSale(context.Background(), sale2)
 
 	startDate := time.Date(2024, 6, 1, 14, 30, 0, 0, time.UTC)
 	endDate := time.Date(2024, 6, 16, 14, 30, 0, 0, time.UTC)
 
-	sales, err := repo.GetSalesInRange(startDate, endDate, sale1.StoreId)
+	sales, err := repo.GetSalesInRange(context.Background(), startDate, endDate, sale1.StoreId)
 	assert.Nil(t, err)
 	assert.Equal(t, 1, len(sales))
 	assert.Contains(t, sales, sale1)
@@ -99,12 +120,12 @@ func TestInMemoryRepository_GetSalesInRange_ZeroStartDate(t *testing.T) {
 		SaleDate:     time.Date(2024, 6, 30, 10, 0, 0, 0, time.UTC),
 	}
 
-	repo.AddSale(sale1)
-	repo.AddSale(sale2)
+	repo.AddSale(context.Background(), sale1)
+	repo.AddSale(context.Background(), sale2)
 
 	endDate := time.Date(2024, 6, 16, 14, 30, 0, 0, time.UTC)
 
-	sales, err := repo.GetSalesInRange(time.Time{}, endDate, sale1.StoreId)
+	sales, err := repo.GetSalesInRange(context.Background(), time.Time{}, endDate, sale1.StoreId)
 	assert.Nil(t, err)
 	assert.Equal(t, 1, len(sales))
 	assert.Contains(t, sales, sale1)
@@ -128,12 +149,12 @@ func TestInMemoryRepository_GetSalesInRange_ZeroEndDate(t *testing.T) {
 		SaleDate:     time.Date(2024, 6, 30, 10, 0, 0, 0, time.UTC),
 	}
 
-	repo.AddSale(sale1)
-	repo.AddSale(sale2)
+	repo.AddSale(context.Background(), sale1)
+	repo.AddSale(context.Background(), sale2)
 
 	startDate := time.Date(2024, 6, 1, 14, 30, 0, 0, time.UTC)
 
-	sales, err := repo.GetSalesInRange(startDate, time.Time{}, sale1.StoreId)
+	sales, err := repo.GetSalesInRange(context.Background(), startDate, time.Time{}, sale1.StoreId)
 	assert.Nil(t, err)
 	assert.Equal(t, 1, len(sales))
 	assert.Contains(t, sales, sale1)
@@ -157,11 +178,39 @@ func TestInMemoryRepository_GetSalesInRange_ZeroStartDateAndEndDate(t *testing.T
 		SaleDate:     time.Date(2024, 6, 30, 10, 0, 0, 0, time.UTC),
 	}
 
-	repo.AddSale(sale1)
-	repo.AddSale(sale2)
+	repo.AddSale(context.Background(), sale1)
+	repo.AddSale(context.Background(), sale2)
 
-	sales, err := repo.GetSalesInRange(time.Time{}, time.Time{}, sale1.StoreId)
+	sales, err := repo.GetSalesInRange(context.Background(), time.Time{}, time.Time{}, sale1.StoreId)
 	assert.Nil(t, err)
 	assert.Equal(t, 1, len(sales))
 	assert.Contains(t, sales, sale1)
 }
+
+func TestInMemoryRepository_GetSalesByStores(t *testing.T) {
+	repo := NewInMemoryRepository()
+
+	sale1 := &models.Sale{StoreId: "6789", SaleDate: time.Date(2024, 6, 15, 14, 30, 0, 0, time.UTC)}
+	sale2 := &models.Sale{StoreId: "9876", SaleDate: time.Date(2024, 6, 16, 10, 0, 0, 0, time.UTC)}
+	sale3 := &models.Sale{StoreId: "9876", SaleDate: time.Date(2024, 7, 1, 10, 0, 0, 0, time.UTC)}
+	sale4 := &models.Sale{StoreId: "1111", SaleDate: time.Date(2024, 6, 16, 10, 0, 0, 0, time.UTC)}
+	for _, sale := range []*models.Sale{sale1, sale2, sale3, sale4} {
+		repo.AddSale(context.Background(), sale)
+	}
+	repo.remove(sale4)
+
+	assert.True(t, HasStoreIndex(repo))
+	sales, err := repo.GetSalesByStores(context.Background(), time.Time{}, time.Date(2024, 6, 30, 0, 0, 0, 0, time.UTC), []string{"6789", "9876", "6789", "1111"})
+	assert.Nil(t, err)
+	assert.ElementsMatch(t, []*models.Sale{sale1, sale2}, sales)
+}
+
+func TestStartOfDay(t *testing.T) {
+	paris, _ := time.LoadLocation("Europe/Paris")
+	havana, _ := time.LoadLocation("America/Havana")
+
+	assert.Equal(t, time.Date(2024, 6, 15, 0, 0, 0, 0, paris), StartOfDay(time.Date(2024, 6, 15, 23, 0, 0, 0, time.UTC), paris))
+	// Havana's clocks skip from midnight to 1:00 when summer time starts.
+	assert.Equal(t, time.Date(2024, 3, 10, 1, 0, 0, 0, havana), StartOfDay(time.Date(2024, 3, 10, 0, 0, 0, 0, time.UTC), havana))
+	assert.True(t, StartOfDay(time.Time{}, paris).IsZero())
+}
@@ -0,0 +1,125 @@
+// Package repotest holds the conformance tests that every sales repository
+// backend, and every query path on top of them, must pass.
+package repotest
+
+import (
+	"context"
+	"dataflow/models"
+	"dataflow/repo"
+	"github.com/stretchr/testify/assert"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+)
+
+// RangeStore sells at and around the bounds of [RangeStart, RangeEnd).
+const RangeStore = "6789"
+
+var (
+	RangeStart = time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	RangeEnd   = time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC)
+)
+
+// Backends returns a new, empty repository of every backend by name.
+func Backends(t *testing.T) map[string]repo.Repository {
+	file, err := repo.NewFileRepository(filepath.Join(t.TempDir(), "sales.ndjson"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { file.Close() })
+	return map[string]repo.Repository{
+		"memory": repo.NewInMemoryRepository(),
+		"file":   file,
+	}
+}
+
+// AddRangeSales adds a sale of RangeStore at each bound of the range and a
+// nanosecond either side of it, and one inside it. Each sale is a single unit
+// at 1, with its position as its product ID.
+func AddRangeSales(t *testing.T, repository repo.Repository) {
+	for _, sale := range rangeSales() {
+		if err := repository.AddSale(context.Background(), sale); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func rangeSales() []*models.Sale {
+	var sales []*models.Sale
+	for product, date := range map[string]time.Time{
+		"before-start": RangeStart.Add(-time.Nanosecond),
+		"at-start":     RangeStart,
+		"after-start":  RangeStart.Add(time.Nanosecond),
+		"inside":       RangeStart.Add(RangeEnd.Sub(RangeStart) / 2),
+		"before-end":   RangeEnd.Add(-time.Nanosecond),
+		"at-end":       RangeEnd,
+		"after-end":    RangeEnd.Add(time.Nanosecond),
+	} {
+		sales = append(sales, &models.Sale{ProductId: product, StoreId: RangeStore, QuantitySold: 1, SalePrice: 1, SaleDate: date})
+	}
+	return sales
+}
+
+// InRange returns the product IDs of the range sales that [RangeStart,
+// RangeEnd) covers, or [RangeStart, RangeEnd] when bounds is closed, in date
+// order.
+func InRange(bounds string) []string {
+	var sales []*models.Sale
+	for _, sale := range rangeSales() {
+		if repo.InRange(sale.SaleDate, RangeStart, repo.EndOf(RangeEnd, bounds)) {
+			sales = append(sales, sale)
+		}
+	}
+	return ProductIds(sales)
+}
+
+// ProductIds returns the product IDs of sales in date order.
+func ProductIds(sales []*models.Sale) []string {
+	sorted := append([]*models.Sale(nil), sales...)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].SaleDate.Before(sorted[j].SaleDate)
+	})
+	ids := make([]string, len(sorted))
+	for i, sale := range sorted {
+		ids[i] = sale.ProductId
+	}
+	return ids
+}
+
+// TestRange checks that the range methods of repository, which has the range
+// sales, agree on the bounds of half-open and closed ranges.
+func TestRange(t *testing.T, repository repo.Repository) {
+	ctx := context.Background()
+	assert.Equal(t, []string{"at-start", "after-start", "inside", "before-end"}, InRange(repo.RangeHalfOpen))
+	assert.Equal(t, []string{"at-start", "after-start", "inside", "before-end", "at-end"}, InRange(repo.RangeClosed))
+
+	for _, bounds := range []string{repo.RangeHalfOpen, repo.RangeClosed} {
+		end := repo.EndOf(RangeEnd, bounds)
+
+		sales, err := repository.GetSalesInRange(ctx, RangeStart, end, RangeStore)
+		assert.NoError(t, err)
+		assert.Equal(t, InRange(bounds), ProductIds(sales), "GetSalesInRange, %s", bounds)
+
+		sales, err = repo.GetSalesByStores(ctx, repository, RangeStart, end, []string{RangeStore})
+		assert.NoError(t, err)
+		assert.Equal(t, InRange(bounds), ProductIds(sales), "GetSalesByStores, %s", bounds)
+	}
+
+	// A zero bound leaves its side open.
+	sales, err := repository.GetSalesInRange(ctx, time.Time{}, RangeStart, RangeStore)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"before-start"}, ProductIds(sales))
+	sales, err = repository.GetSalesInRange(ctx, RangeEnd, time.Time{}, RangeStore)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"at-end", "after-end"}, ProductIds(sales))
+
+	// Consecutive ranges neither overlap nor leave gaps.
+	before, err := repository.GetSalesInRange(ctx, time.Time{}, RangeEnd, RangeStore)
+	assert.NoError(t, err)
+	after, err := repository.GetSalesInRange(ctx, RangeEnd, time.Time{}, RangeStore)
+	assert.NoError(t, err)
+	all, err := repository.GetAllSales(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, ProductIds(all), ProductIds(append(before, after...)))
+}
@@ -0,0 +1,49 @@
+package requestid
+
+import (
+	"context"
+	"github.com/google/uuid"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"strings"
+)
+
+// MetadataKey is Header as a gRPC metadata key.
+var MetadataKey = strings.ToLower(Header)
+
+// UnaryServerInterceptor is Middleware for unary gRPC calls. The ID is read
+// from and returned in the "x-request-id" metadata.
+func UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		id := fromMetadata(ctx)
+		grpc.SetHeader(ctx, metadata.Pairs(MetadataKey, id))
+		return handler(NewContext(ctx, id), req)
+	}
+}
+
+// StreamServerInterceptor is UnaryServerInterceptor for streaming calls.
+func StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		id := fromMetadata(stream.Context())
+		stream.SetHeader(metadata.Pairs(MetadataKey, id))
+		return handler(srv, &serverStream{ServerStream: stream, ctx: NewContext(stream.Context(), id)})
+	}
+}
+
+func fromMetadata(ctx context.Context) string {
+	md, _ := metadata.FromIncomingContext(ctx)
+	if values := md.Get(MetadataKey); len(values) > 0 && valid(values[0]) {
+		return values[0]
+	}
+	return uuid.New().String()
+}
+
+// serverStream replaces the context of a stream.
+type serverStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *serverStream) Context() context.Context {
+	return s.ctx
+}
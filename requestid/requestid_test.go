@@ -0,0 +1,41 @@
+package requestid
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestMiddleware(t *testing.T) {
+	var seen string
+	router := gin.New()
+	router.Use(Middleware())
+	router.GET("/data", func(c *gin.Context) {
+		seen = FromContext(c.Request.Context())
+		c.Status(http.StatusOK)
+	})
+
+	for name, tc := range map[string]struct {
+		header string
+		keep   bool
+	}{
+		"generated":  {"", false},
+		"incoming":   {"abc-123", true},
+		"whitespace": {"abc 123", false},
+		"too long":   {strings.Repeat("a", maxLength+1), false},
+	} {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/data", nil)
+		if tc.header != "" {
+			req.Header.Set(Header, tc.header)
+		}
+		router.ServeHTTP(w, req)
+
+		assert.NotEmpty(t, seen, name)
+		assert.Equal(t, seen, w.Header().Get(Header), name)
+		assert.Equal(t, tc.keep, seen == tc.header, name)
+	}
+}
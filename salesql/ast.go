@@ -0,0 +1,163 @@
+package salesql
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrInvalidQuery is wrapped by the errors for statements that don't parse
+// or don't fit the sales table.
+var ErrInvalidQuery = errors.New("invalid query")
+
+func errorf(pos position, format string, args ...interface{}) error {
+	return fmt.Errorf("%w: %s (line %d, column %d)", ErrInvalidQuery, fmt.Sprintf(format, args...), pos.line, pos.column)
+}
+
+type statement struct {
+	explain bool
+	// items is empty for SELECT *.
+	items   []*selectItem
+	where   expr
+	groupBy []*valueExpr
+	orderBy []*orderItem
+	// limit is -1 without a LIMIT clause.
+	limit  int
+	offset int
+}
+
+type selectItem struct {
+	value *valueExpr
+	alias string
+}
+
+// name is the column name of the item in the result.
+func (item *selectItem) name() string {
+	if item.alias != "" {
+		return item.alias
+	}
+	return item.value.String()
+}
+
+// valueExpr is a column, or an aggregate function of a column. column is
+// empty for COUNT(*).
+type valueExpr struct {
+	function string
+	column   string
+	pos      position
+}
+
+func (v *valueExpr) String() string {
+	switch {
+	case v.function == "":
+		return v.column
+	case v.column == "":
+		return v.function + "(*)"
+	default:
+		return v.function + "(" + v.column + ")"
+	}
+}
+
+// orderItem orders by a value, which may name a select item by its alias,
+// or by the 1-based position of a select item.
+type orderItem struct {
+	value   *valueExpr
+	ordinal int
+	desc    bool
+	pos     position
+}
+
+func (item *orderItem) String() string {
+	s := fmt.Sprint(item.ordinal)
+	if item.value != nil {
+		s = item.value.String()
+	}
+	if item.desc {
+		return s + " DESC"
+	}
+	return s
+}
+
+// expr is a condition of the WHERE clause.
+type expr interface {
+	String() string
+}
+
+type andExpr struct {
+	left, right expr
+}
+
+func (e *andExpr) String() string {
+	return e.left.String() + " AND " + e.right.String()
+}
+
+type orExpr struct {
+	left, right expr
+}
+
+func (e *orExpr) String() string {
+	return "(" + e.left.String() + " OR " + e.right.String() + ")"
+}
+
+type notExpr struct {
+	operand expr
+}
+
+func (e *notExpr) String() string {
+	if _, ok := e.operand.(*andExpr); ok {
+		return "NOT (" + e.operand.String() + ")"
+	}
+	return "NOT " + e.operand.String()
+}
+
+type comparison struct {
+	column string
+	op     string
+	value  literal
+	pos    position
+}
+
+func (e *comparison) String() string {
+	return e.column + " " + e.op + " " + e.value.text
+}
+
+type inExpr struct {
+	column  string
+	values  []literal
+	negated bool
+	pos     position
+}
+
+func (e *inExpr) String() string {
+	values := make([]string, len(e.values))
+	for i, value := range e.values {
+		values[i] = value.text
+	}
+	op := " IN ("
+	if e.negated {
+		op = " NOT IN ("
+	}
+	return e.column + op + strings.Join(values, ", ") + ")"
+}
+
+type betweenExpr struct {
+	column    string
+	low, high literal
+	negated   bool
+	pos       position
+}
+
+func (e *betweenExpr) String() string {
+	op := " BETWEEN "
+	if e.negated {
+		op = " NOT BETWEEN "
+	}
+	return e.column + op + e.low.text + " AND " + e.high.text
+}
+
+type literal struct {
+	kind  tokenKind
+	value string
+	text  string
+	pos   position
+}
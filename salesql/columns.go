@@ -0,0 +1,160 @@
+package salesql
+
+import (
+	"cmp"
+	"dataflow/models"
+	"dataflow/repo"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type columnType int
+
+const (
+	typeString columnType = iota
+	typeInt
+	typeFloat
+	typeTime
+)
+
+// column reads a field of a sale as a string, int64, float64 or time.Time.
+type column struct {
+	name string
+	typ  columnType
+	get  func(sale *models.Sale) interface{}
+}
+
+// modelColumns are the fields of models.Sale, in the order of SELECT *.
+var modelColumns = []*column{
+	{name: "id", typ: typeString, get: func(sale *models.Sale) interface{} { return sale.ID }},
+	{name: "product_id", typ: typeString, get: func(sale *models.Sale) interface{} { return sale.ProductId }},
+	{name: "store_id", typ: typeString, get: func(sale *models.Sale) interface{} { return sale.StoreId }},
+	{name: "quantity_sold", typ: typeInt, get: func(sale *models.Sale) interface{} { return int64(sale.QuantitySold) }},
+	{name: "sale_price", typ: typeFloat, get: func(sale *models.Sale) interface{} { return sale.SalePrice }},
+	{name: "sale_date", typ: typeTime, get: func(sale *models.Sale) interface{} { return sale.SaleDate }},
+}
+
+// amountColumn is the revenue of a sale, quantity_sold × sale_price.
+var amountColumn = &column{name: "amount", typ: typeFloat, get: func(sale *models.Sale) interface{} {
+	return round(float64(sale.QuantitySold) * sale.SalePrice)
+}}
+
+func lookupColumn(name string, pos position) (*column, error) {
+	if name == amountColumn.name {
+		return amountColumn, nil
+	}
+	for _, column := range modelColumns {
+		if column.name == name {
+			return column, nil
+		}
+	}
+	return nil, errorf(pos, "unknown column %s", name)
+}
+
+// parse converts a literal to a value comparable with the column. Numbers
+// are accepted for string columns, since IDs are often numeric, and dates
+// are the midnight that starts them in the time zone of dates.
+func (c *column) parse(value literal, dates *dateZone) (interface{}, error) {
+	switch c.typ {
+	case typeInt, typeFloat:
+		if value.kind != tokenNumber {
+			return nil, errorf(value.pos, "%s is compared with a number, found %s", c.name, value.text)
+		}
+		return strconv.ParseFloat(value.value, 64)
+	case typeTime:
+		if value.kind == tokenString {
+			if date, err := time.Parse(time.RFC3339Nano, value.value); err == nil {
+				return date, nil
+			}
+			if date, err := time.Parse(time.DateOnly, value.value); err == nil {
+				location, err := dates.location(value.pos)
+				if err != nil {
+					return nil, err
+				}
+				return repo.StartOfDay(date, location), nil
+			}
+		}
+		return nil, errorf(value.pos, "%s is compared with a date (2006-01-02) or an RFC 3339 time, found %s", c.name, value.text)
+	default:
+		return value.value, nil
+	}
+}
+
+// Locations are the time zones that date literals resolve in: Location if
+// it's set, otherwise the time zone in Stores that the queried stores share,
+// or UTC. The zero value resolves dates in UTC.
+type Locations struct {
+	Location *time.Location
+	Stores   map[string]*time.Location
+}
+
+// dateZone resolves the time zone of date literals once the stores that a
+// query is restricted to are known.
+type dateZone struct {
+	locations Locations
+	// storeIds are the stores the query is restricted to, every store when
+	// restricted is false.
+	storeIds   []string
+	restricted bool
+}
+
+// location returns the time zone of the date literal at pos. Stores in
+// different time zones need Locations.Location.
+func (z *dateZone) location(pos position) (*time.Location, error) {
+	if z.locations.Location != nil {
+		return z.locations.Location, nil
+	}
+	var location *time.Location
+	for storeId, storeLocation := range z.locations.Stores {
+		if z.restricted && !slices.Contains(z.storeIds, storeId) {
+			continue
+		}
+		if location != nil && location.String() != storeLocation.String() {
+			return nil, errorf(pos, "the stores are in different time zones, %s and %s, so dates need a timezone", location, storeLocation)
+		}
+		location = storeLocation
+	}
+	if location == nil {
+		return time.UTC, nil
+	}
+	return location, nil
+}
+
+// compare orders values of the same column type; numbers of either type
+// compare with each other, and nil, the result of aggregates over no sales,
+// comes first.
+func compare(a, b interface{}) int {
+	switch {
+	case a == nil && b == nil:
+		return 0
+	case a == nil:
+		return -1
+	case b == nil:
+		return 1
+	}
+	switch a := a.(type) {
+	case string:
+		return strings.Compare(a, b.(string))
+	case time.Time:
+		return a.Compare(b.(time.Time))
+	default:
+		return cmp.Compare(toFloat(a), toFloat(b))
+	}
+}
+
+func toFloat(value interface{}) float64 {
+	if n, ok := value.(int64); ok {
+		return float64(n)
+	}
+	return value.(float64)
+}
+
+// round drops the binary noise of float64 arithmetic on decimal prices,
+// such as 19.99 × 10 = 199.89999999999998, by keeping 15 significant
+// digits.
+func round(value float64) float64 {
+	rounded, _ := strconv.ParseFloat(strconv.FormatFloat(value, 'g', 15, 64), 64)
+	return rounded
+}
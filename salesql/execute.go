@@ -0,0 +1,201 @@
+package salesql
+
+import (
+	"context"
+	"dataflow/models"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// row is a result row with the sale it comes from, the first sale of its
+// group for aggregated rows, to order it by columns that aren't selected.
+type row struct {
+	sale   *models.Sale
+	values []interface{}
+}
+
+// Execute runs the plan. Rows are ordered by the ORDER BY keys, then by sale
+// date and ID, or by the grouped columns for aggregated rows.
+func (p *Plan) Execute(ctx context.Context) ([][]interface{}, error) {
+	sales, err := p.access.fetch(ctx, p.repository)
+	if err != nil {
+		return nil, err
+	}
+	matched := make([]*models.Sale, 0, len(sales))
+	for _, sale := range sales {
+		if p.filter == nil || p.filter(sale) {
+			matched = append(matched, sale)
+		}
+	}
+
+	var rows []*row
+	if p.aggregated {
+		rows = p.aggregate(matched)
+	} else {
+		sort.Slice(matched, func(i, j int) bool {
+			if !matched[i].SaleDate.Equal(matched[j].SaleDate) {
+				return matched[i].SaleDate.Before(matched[j].SaleDate)
+			}
+			return matched[i].ID < matched[j].ID
+		})
+		for _, sale := range matched {
+			values := make([]interface{}, len(p.items))
+			for i, item := range p.items {
+				values[i] = item.column.get(sale)
+			}
+			rows = append(rows, &row{sale: sale, values: values})
+		}
+	}
+	sort.SliceStable(rows, func(i, j int) bool {
+		for _, key := range p.orderBy {
+			if c := key.compare(rows[i], rows[j]); c != 0 {
+				return c < 0
+			}
+		}
+		return false
+	})
+
+	rows = rows[min(p.offset, len(rows)):]
+	if p.limit >= 0 {
+		rows = rows[:min(p.limit, len(rows))]
+	}
+	result := make([][]interface{}, len(rows))
+	for i, row := range rows {
+		result[i] = row.values
+	}
+	return result, nil
+}
+
+func (key *orderKey) compare(a *row, b *row) int {
+	var c int
+	if key.index >= 0 {
+		c = compare(a.values[key.index], b.values[key.index])
+	} else {
+		c = compare(key.column.get(a.sale), key.column.get(b.sale))
+	}
+	if key.desc {
+		return -c
+	}
+	return c
+}
+
+// aggregate groups sales by the grouped columns, ordered by their values.
+// Without GROUP BY, all sales form one group, even when there are none.
+func (p *Plan) aggregate(sales []*models.Sale) []*row {
+	type group struct {
+		sale         *models.Sale
+		accumulators []*accumulator
+	}
+	newGroup := func(sale *models.Sale) *group {
+		g := &group{sale: sale, accumulators: make([]*accumulator, len(p.items))}
+		for i := range p.items {
+			g.accumulators[i] = &accumulator{}
+		}
+		return g
+	}
+	groups := make(map[string]*group)
+	var ordered []*group
+	for _, sale := range sales {
+		keys := make([]string, len(p.groupBy))
+		for i, column := range p.groupBy {
+			keys[i] = fmt.Sprint(column.get(sale))
+		}
+		key := strings.Join(keys, "\x00")
+		g, ok := groups[key]
+		if !ok {
+			g = newGroup(sale)
+			groups[key] = g
+			ordered = append(ordered, g)
+		}
+		for i, item := range p.items {
+			if item.function == "" {
+				continue
+			}
+			var value interface{}
+			if item.column != nil {
+				value = item.column.get(sale)
+			}
+			g.accumulators[i].add(value)
+		}
+	}
+	if len(p.groupBy) == 0 && len(ordered) == 0 {
+		ordered = append(ordered, newGroup(nil))
+	}
+	sort.Slice(ordered, func(i, j int) bool {
+		for _, column := range p.groupBy {
+			if c := compare(column.get(ordered[i].sale), column.get(ordered[j].sale)); c != 0 {
+				return c < 0
+			}
+		}
+		return false
+	})
+
+	rows := make([]*row, len(ordered))
+	for i, g := range ordered {
+		values := make([]interface{}, len(p.items))
+		for j, item := range p.items {
+			if item.function == "" {
+				values[j] = item.column.get(g.sale)
+			} else {
+				values[j] = g.accumulators[j].result(item)
+			}
+		}
+		rows[i] = &row{sale: g.sale, values: values}
+	}
+	return rows
+}
+
+// accumulator computes an aggregate function over the values of a group.
+// The values are nil for COUNT(*).
+type accumulator struct {
+	count    int64
+	sum      float64
+	intSum   int64
+	min, max interface{}
+}
+
+func (a *accumulator) add(value interface{}) {
+	a.count++
+	switch v := value.(type) {
+	case nil:
+		return
+	case int64:
+		a.intSum += v
+	case float64:
+		a.sum += v
+	}
+	if a.min == nil || compare(value, a.min) < 0 {
+		a.min = value
+	}
+	if a.max == nil || compare(value, a.max) > 0 {
+		a.max = value
+	}
+}
+
+// result returns the value of the item's function. As in SQL, SUM, AVG, MIN
+// and MAX of no values are nil.
+func (a *accumulator) result(item *boundItem) interface{} {
+	if item.function == "count" {
+		return a.count
+	}
+	if a.count == 0 {
+		return nil
+	}
+	switch item.function {
+	case "sum":
+		if item.column.typ == typeInt {
+			return a.intSum
+		}
+		return round(a.sum)
+	case "avg":
+		if item.column.typ == typeInt {
+			return round(float64(a.intSum) / float64(a.count))
+		}
+		return round(a.sum / float64(a.count))
+	case "min":
+		return a.min
+	default:
+		return a.max
+	}
+}
@@ -0,0 +1,136 @@
+package salesql
+
+import (
+	"fmt"
+	"strings"
+)
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenIdent
+	tokenNumber
+	tokenString
+	tokenSymbol
+)
+
+type token struct {
+	kind tokenKind
+	// value is lowercased for identifiers, which covers keywords, and
+	// unquoted for strings.
+	value string
+	text  string
+	pos   position
+}
+
+func (t token) String() string {
+	if t.kind == tokenEOF {
+		return "end of query"
+	}
+	return fmt.Sprintf("%q", t.text)
+}
+
+type position struct {
+	line   int
+	column int
+}
+
+// lexer splits a statement into tokens, skipping whitespace and -- comments.
+type lexer struct {
+	source string
+	offset int
+	line   int
+	column int
+}
+
+func newLexer(source string) *lexer {
+	return &lexer{source: source, line: 1, column: 1}
+}
+
+func (l *lexer) advance(n int) {
+	for _, r := range l.source[l.offset : l.offset+n] {
+		if r == '\n' {
+			l.line++
+			l.column = 1
+		} else {
+			l.column++
+		}
+	}
+	l.offset += n
+}
+
+func (l *lexer) next() (token, error) {
+	l.skipIgnored()
+	pos := position{line: l.line, column: l.column}
+	if l.offset >= len(l.source) {
+		return token{kind: tokenEOF, pos: pos}, nil
+	}
+	rest := l.source[l.offset:]
+	c := rest[0]
+	switch {
+	case c == '_' || isLetter(c):
+		n := 1
+		for n < len(rest) && (rest[n] == '_' || isLetter(rest[n]) || isDigit(rest[n])) {
+			n++
+		}
+		l.advance(n)
+		return token{kind: tokenIdent, value: strings.ToLower(rest[:n]), text: rest[:n], pos: pos}, nil
+	case isDigit(c) || c == '-' || (c == '.' && len(rest) > 1 && isDigit(rest[1])):
+		n := 1
+		for n < len(rest) && (isDigit(rest[n]) || rest[n] == '.') {
+			n++
+		}
+		if rest[:n] == "-" || strings.Count(rest[:n], ".") > 1 {
+			return token{}, errorf(pos, "invalid number %q", rest[:n])
+		}
+		l.advance(n)
+		return token{kind: tokenNumber, value: rest[:n], text: rest[:n], pos: pos}, nil
+	case c == '\'':
+		var value strings.Builder
+		for n := 1; n < len(rest); n++ {
+			if rest[n] != '\'' {
+				value.WriteByte(rest[n])
+			} else if n+1 < len(rest) && rest[n+1] == '\'' {
+				value.WriteByte('\'')
+				n++
+			} else {
+				l.advance(n + 1)
+				return token{kind: tokenString, value: value.String(), text: rest[:n+1], pos: pos}, nil
+			}
+		}
+		return token{}, errorf(pos, "unterminated string")
+	}
+	for _, symbol := range []string{"<=", ">=", "<>", "!=", "=", "<", ">", "(", ")", ",", "*", ";"} {
+		if strings.HasPrefix(rest, symbol) {
+			l.advance(len(symbol))
+			return token{kind: tokenSymbol, value: symbol, text: symbol, pos: pos}, nil
+		}
+	}
+	return token{}, errorf(pos, "unexpected character %q", c)
+}
+
+func (l *lexer) skipIgnored() {
+	for l.offset < len(l.source) {
+		switch rest := l.source[l.offset:]; {
+		case rest[0] == ' ' || rest[0] == '\t' || rest[0] == '\n' || rest[0] == '\r':
+			l.advance(1)
+		case strings.HasPrefix(rest, "--"):
+			n := strings.IndexByte(rest, '\n')
+			if n < 0 {
+				n = len(rest)
+			}
+			l.advance(n)
+		default:
+			return
+		}
+	}
+}
+
+func isLetter(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
@@ -0,0 +1,369 @@
+package salesql
+
+import (
+	"slices"
+	"strconv"
+)
+
+// keywords can't be used as column names or aliases.
+var keywords = []string{
+	"explain", "select", "from", "where", "group", "order", "by", "asc", "desc",
+	"limit", "offset", "and", "or", "not", "in", "between", "as",
+}
+
+var functions = []string{"sum", "count", "avg", "min", "max"}
+
+// parse parses a statement of the form
+//
+//	[EXPLAIN] SELECT items FROM sales [WHERE condition]
+//	[GROUP BY columns] [ORDER BY keys] [LIMIT n [OFFSET m]]
+func parse(source string) (*statement, error) {
+	p := &parser{lexer: newLexer(source)}
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+	stmt := &statement{limit: -1}
+	if p.accept("explain") {
+		stmt.explain = true
+	}
+	if err := p.expect("select"); err != nil {
+		return nil, err
+	}
+	if err := p.selectItems(stmt); err != nil {
+		return nil, err
+	}
+	if err := p.expect("from"); err != nil {
+		return nil, err
+	}
+	if p.token.kind != tokenIdent || slices.Contains(keywords, p.token.value) {
+		return nil, p.unexpected("a table")
+	}
+	if p.token.value != "sales" {
+		return nil, errorf(p.token.pos, "unknown table %s, the only table is sales", p.token)
+	}
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+
+	var err error
+	if p.accept("where") {
+		if stmt.where, err = p.or(); err != nil {
+			return nil, err
+		}
+	}
+	if p.accept("group") {
+		if err := p.expect("by"); err != nil {
+			return nil, err
+		}
+		for {
+			pos := p.token.pos
+			column, err := p.column()
+			if err != nil {
+				return nil, err
+			}
+			stmt.groupBy = append(stmt.groupBy, &valueExpr{column: column, pos: pos})
+			if !p.acceptSymbol(",") {
+				break
+			}
+		}
+	}
+	if p.accept("order") {
+		if err := p.expect("by"); err != nil {
+			return nil, err
+		}
+		for {
+			item, err := p.orderItem()
+			if err != nil {
+				return nil, err
+			}
+			stmt.orderBy = append(stmt.orderBy, item)
+			if !p.acceptSymbol(",") {
+				break
+			}
+		}
+	}
+	if p.accept("limit") {
+		if stmt.limit, err = p.count(); err != nil {
+			return nil, err
+		}
+		if p.accept("offset") {
+			if stmt.offset, err = p.count(); err != nil {
+				return nil, err
+			}
+		}
+	}
+	p.acceptSymbol(";")
+	if p.err != nil || p.token.kind != tokenEOF {
+		return nil, p.unexpected("end of query")
+	}
+	return stmt, nil
+}
+
+// parser keeps the first lexer error in err, so that the accept methods can
+// report whether they matched; the error is returned where the parser next
+// expects a token.
+type parser struct {
+	lexer *lexer
+	token token
+	err   error
+}
+
+func (p *parser) advance() error {
+	token, err := p.lexer.next()
+	if err != nil {
+		p.err = err
+		p.token = token
+		return err
+	}
+	p.token = token
+	return nil
+}
+
+func (p *parser) unexpected(expected string) error {
+	if p.err != nil {
+		return p.err
+	}
+	return errorf(p.token.pos, "expected %s, found %s", expected, p.token)
+}
+
+// accept consumes the keyword if it's the current token.
+func (p *parser) accept(keyword string) bool {
+	if p.token.kind != tokenIdent || p.token.value != keyword {
+		return false
+	}
+	return p.advance() == nil
+}
+
+func (p *parser) acceptSymbol(symbol string) bool {
+	if p.token.kind != tokenSymbol || p.token.value != symbol {
+		return false
+	}
+	return p.advance() == nil
+}
+
+func (p *parser) expect(keyword string) error {
+	if p.token.kind != tokenIdent || p.token.value != keyword {
+		return p.unexpected(keyword)
+	}
+	return p.advance()
+}
+
+func (p *parser) expectSymbol(symbol string) error {
+	if p.token.kind != tokenSymbol || p.token.value != symbol {
+		return p.unexpected(strconv.Quote(symbol))
+	}
+	return p.advance()
+}
+
+func (p *parser) name(expected string) (string, error) {
+	if p.token.kind != tokenIdent || slices.Contains(keywords, p.token.value) {
+		return "", p.unexpected(expected)
+	}
+	name := p.token.value
+	return name, p.advance()
+}
+
+func (p *parser) column() (string, error) {
+	return p.name("a column")
+}
+
+func (p *parser) count() (int, error) {
+	if p.token.kind != tokenNumber {
+		return 0, p.unexpected("a number")
+	}
+	n, err := strconv.Atoi(p.token.value)
+	if err != nil || n < 0 {
+		return 0, errorf(p.token.pos, "%s isn't a valid count", p.token)
+	}
+	return n, p.advance()
+}
+
+func (p *parser) selectItems(stmt *statement) error {
+	if p.acceptSymbol("*") {
+		return nil
+	}
+	for {
+		value, err := p.value()
+		if err != nil {
+			return err
+		}
+		item := &selectItem{value: value}
+		if p.accept("as") {
+			if item.alias, err = p.name("an alias"); err != nil {
+				return err
+			}
+		} else if p.token.kind == tokenIdent && !slices.Contains(keywords, p.token.value) {
+			item.alias = p.token.value
+			if err := p.advance(); err != nil {
+				return err
+			}
+		}
+		stmt.items = append(stmt.items, item)
+		if !p.acceptSymbol(",") {
+			return nil
+		}
+	}
+}
+
+// value parses a column or an aggregate function call.
+func (p *parser) value() (*valueExpr, error) {
+	pos := p.token.pos
+	name, err := p.column()
+	if err != nil {
+		return nil, err
+	}
+	if !p.acceptSymbol("(") {
+		return &valueExpr{column: name, pos: pos}, nil
+	}
+	if !slices.Contains(functions, name) {
+		return nil, errorf(pos, "unknown function %s, use one of SUM, COUNT, AVG, MIN and MAX", name)
+	}
+	value := &valueExpr{function: name, pos: pos}
+	if name == "count" && p.acceptSymbol("*") {
+		return value, p.expectSymbol(")")
+	}
+	if value.column, err = p.column(); err != nil {
+		return nil, err
+	}
+	return value, p.expectSymbol(")")
+}
+
+func (p *parser) orderItem() (*orderItem, error) {
+	item := &orderItem{pos: p.token.pos}
+	if p.token.kind == tokenNumber {
+		n, err := strconv.Atoi(p.token.value)
+		if err != nil || n < 1 {
+			return nil, errorf(p.token.pos, "%s isn't a valid select item position", p.token)
+		}
+		item.ordinal = n
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+	} else {
+		value, err := p.value()
+		if err != nil {
+			return nil, err
+		}
+		item.value = value
+	}
+	if p.accept("desc") {
+		item.desc = true
+	} else {
+		p.accept("asc")
+	}
+	return item, nil
+}
+
+func (p *parser) or() (expr, error) {
+	left, err := p.and()
+	if err != nil {
+		return nil, err
+	}
+	for p.accept("or") {
+		right, err := p.and()
+		if err != nil {
+			return nil, err
+		}
+		left = &orExpr{left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) and() (expr, error) {
+	left, err := p.not()
+	if err != nil {
+		return nil, err
+	}
+	for p.accept("and") {
+		right, err := p.not()
+		if err != nil {
+			return nil, err
+		}
+		left = &andExpr{left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) not() (expr, error) {
+	if p.accept("not") {
+		operand, err := p.not()
+		if err != nil {
+			return nil, err
+		}
+		return &notExpr{operand: operand}, nil
+	}
+	return p.predicate()
+}
+
+// predicate parses a parenthesized condition, or a comparison, IN or
+// BETWEEN of a column with literals.
+func (p *parser) predicate() (expr, error) {
+	if p.acceptSymbol("(") {
+		e, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+		return e, p.expectSymbol(")")
+	}
+	pos := p.token.pos
+	column, err := p.column()
+	if err != nil {
+		return nil, err
+	}
+	negated := p.accept("not")
+	switch {
+	case p.accept("in"):
+		if err := p.expectSymbol("("); err != nil {
+			return nil, err
+		}
+		e := &inExpr{column: column, negated: negated, pos: pos}
+		for {
+			value, err := p.literal()
+			if err != nil {
+				return nil, err
+			}
+			e.values = append(e.values, value)
+			if !p.acceptSymbol(",") {
+				break
+			}
+		}
+		return e, p.expectSymbol(")")
+	case p.accept("between"):
+		e := &betweenExpr{column: column, negated: negated, pos: pos}
+		if e.low, err = p.literal(); err != nil {
+			return nil, err
+		}
+		if err := p.expect("and"); err != nil {
+			return nil, err
+		}
+		if e.high, err = p.literal(); err != nil {
+			return nil, err
+		}
+		return e, nil
+	case negated:
+		return nil, p.unexpected("IN or BETWEEN")
+	}
+	if p.token.kind != tokenSymbol || !slices.Contains([]string{"=", "!=", "<>", "<", "<=", ">", ">="}, p.token.value) {
+		return nil, p.unexpected("a comparison")
+	}
+	op := p.token.value
+	if op == "<>" {
+		op = "!="
+	}
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+	value, err := p.literal()
+	if err != nil {
+		return nil, err
+	}
+	return &comparison{column: column, op: op, value: value, pos: pos}, nil
+}
+
+func (p *parser) literal() (literal, error) {
+	if p.token.kind != tokenString && p.token.kind != tokenNumber {
+		return literal{}, p.unexpected("a string or a number")
+	}
+	value := literal{kind: p.token.kind, value: p.token.value, text: p.token.text, pos: p.token.pos}
+	return value, p.advance()
+}
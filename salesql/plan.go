@@ -0,0 +1,546 @@
+package salesql
+
+import (
+	"context"
+	"dataflow/models"
+	"dataflow/repo"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Plan is a statement prepared against a repository: how its sales are
+// fetched, which of them are kept, and how they become rows.
+type Plan struct {
+	repository repo.Repository
+	explain    bool
+	access     *access
+	residual   []expr
+	filter     predicate
+	items      []*boundItem
+	aggregated bool
+	groupBy    []*column
+	orderBy    []*orderKey
+	limit      int
+	offset     int
+	dates      *dateZone
+}
+
+// boundItem is a select item resolved to its column; function is empty for
+// a plain column and column is nil for COUNT(*).
+type boundItem struct {
+	name     string
+	function string
+	column   *column
+}
+
+// orderKey orders rows by the select item at index, or by column when index
+// is -1.
+type orderKey struct {
+	index  int
+	column *column
+	desc   bool
+	text   string
+}
+
+// Prepare parses statement and plans it against repository. A non-empty
+// scope restricts the query to the sales of those stores, and date literals
+// resolve in locations. The errors for invalid statements wrap
+// ErrInvalidQuery.
+func Prepare(statement string, repository repo.Repository, scope []string, locations Locations) (*Plan, error) {
+	stmt, err := parse(statement)
+	if err != nil {
+		return nil, err
+	}
+	plan := &Plan{repository: repository, explain: stmt.explain, limit: stmt.limit, offset: stmt.offset, dates: &dateZone{locations: locations}}
+	if err := plan.bindItems(stmt); err != nil {
+		return nil, err
+	}
+	if plan.access, plan.residual, err = pushDown(conjuncts(stmt.where), scope, plan.dates); err != nil {
+		return nil, err
+	}
+	if stmt.where != nil {
+		if _, err := compile(stmt.where, plan.dates); err != nil {
+			return nil, err
+		}
+	}
+	if len(plan.residual) > 0 {
+		if plan.filter, err = compile(conjunction(plan.residual), plan.dates); err != nil {
+			return nil, err
+		}
+	}
+	for _, item := range stmt.orderBy {
+		key, err := plan.bindOrderItem(item)
+		if err != nil {
+			return nil, err
+		}
+		plan.orderBy = append(plan.orderBy, key)
+	}
+	return plan, nil
+}
+
+func (p *Plan) bindItems(stmt *statement) error {
+	for _, value := range stmt.groupBy {
+		column, err := lookupColumn(value.column, value.pos)
+		if err != nil {
+			return err
+		}
+		p.groupBy = append(p.groupBy, column)
+	}
+	p.aggregated = len(p.groupBy) > 0
+	for _, item := range stmt.items {
+		p.aggregated = p.aggregated || item.value.function != ""
+	}
+
+	if len(stmt.items) == 0 {
+		if p.aggregated {
+			return fmt.Errorf("%w: SELECT * can't be used with GROUP BY", ErrInvalidQuery)
+		}
+		for _, column := range modelColumns {
+			p.items = append(p.items, &boundItem{name: column.name, column: column})
+		}
+		return nil
+	}
+	for _, item := range stmt.items {
+		value := item.value
+		bound := &boundItem{name: item.name(), function: value.function}
+		if value.column != "" {
+			column, err := lookupColumn(value.column, value.pos)
+			if err != nil {
+				return err
+			}
+			bound.column = column
+		}
+		switch {
+		case (value.function == "sum" || value.function == "avg") && bound.column.typ != typeInt && bound.column.typ != typeFloat:
+			return errorf(value.pos, "%s needs a numeric column, %s isn't one", strings.ToUpper(value.function), value.column)
+		case p.aggregated && value.function == "" && !slices.Contains(p.groupBy, bound.column):
+			return errorf(value.pos, "%s must be in GROUP BY or used in an aggregate function", value.column)
+		}
+		p.items = append(p.items, bound)
+	}
+	return nil
+}
+
+// bindOrderItem resolves an ORDER BY key to a select item, by position,
+// alias or expression, or else to a column. Aggregated rows can only be
+// ordered by their grouped columns and selected values.
+func (p *Plan) bindOrderItem(item *orderItem) (*orderKey, error) {
+	key := &orderKey{index: -1, desc: item.desc, text: item.String()}
+	if item.ordinal > 0 {
+		if item.ordinal > len(p.items) {
+			return nil, errorf(item.pos, "ORDER BY position %d is out of range, the query selects %d columns", item.ordinal, len(p.items))
+		}
+		key.index = item.ordinal - 1
+		return key, nil
+	}
+	for i, selected := range p.items {
+		if (item.value.function == "" && selected.name == item.value.column) || selected.name == item.value.String() {
+			key.index = i
+			return key, nil
+		}
+	}
+	if item.value.function != "" {
+		return nil, errorf(item.pos, "ORDER BY %s must be selected", item.value)
+	}
+	column, err := lookupColumn(item.value.column, item.pos)
+	if err != nil {
+		return nil, err
+	}
+	if p.aggregated && !slices.Contains(p.groupBy, column) {
+		return nil, errorf(item.pos, "ORDER BY %s must be in GROUP BY or selected", item.value)
+	}
+	key.column = column
+	return key, nil
+}
+
+// Explain reports whether the statement asked for its plan with EXPLAIN.
+func (p *Plan) Explain() bool {
+	return p.explain
+}
+
+// Columns returns the names of the result columns.
+func (p *Plan) Columns() []string {
+	columns := make([]string, len(p.items))
+	for i, item := range p.items {
+		columns[i] = item.name
+	}
+	return columns
+}
+
+// Steps describes the plan in execution order.
+func (p *Plan) Steps() []*models.PlanStep {
+	steps := []*models.PlanStep{p.access.step(p.repository)}
+	if len(p.residual) > 0 {
+		steps = append(steps, &models.PlanStep{Operation: "Filter", Detail: conjunction(p.residual).String()})
+	}
+	if p.aggregated {
+		var aggregates []string
+		for _, item := range p.items {
+			if item.function != "" {
+				aggregates = append(aggregates, (&valueExpr{function: item.function, column: columnName(item.column)}).String())
+			}
+		}
+		detail := strings.Join(aggregates, ", ")
+		if len(p.groupBy) > 0 {
+			groupBy := make([]string, len(p.groupBy))
+			for i, column := range p.groupBy {
+				groupBy[i] = column.name
+			}
+			detail = strings.TrimPrefix(detail+" grouped by "+strings.Join(groupBy, ", "), " ")
+		}
+		steps = append(steps, &models.PlanStep{Operation: "Aggregate", Detail: detail})
+	}
+	if len(p.orderBy) > 0 {
+		keys := make([]string, len(p.orderBy))
+		for i, key := range p.orderBy {
+			keys[i] = key.text
+		}
+		steps = append(steps, &models.PlanStep{Operation: "Sort", Detail: strings.Join(keys, ", ")})
+	}
+	if p.limit >= 0 {
+		detail := fmt.Sprint(p.limit)
+		if p.offset > 0 {
+			detail += fmt.Sprintf(" offset %d", p.offset)
+		}
+		steps = append(steps, &models.PlanStep{Operation: "Limit", Detail: detail})
+	}
+	return steps
+}
+
+func columnName(column *column) string {
+	if column == nil {
+		return ""
+	}
+	return column.name
+}
+
+type accessKind int
+
+const (
+	accessFullScan accessKind = iota
+	accessLookup
+	accessStores
+	accessNone
+)
+
+// access is how a plan fetches sales from the repository. conditions are
+// the conditions it pushes down, as shown by the plan.
+type access struct {
+	kind       accessKind
+	id         string
+	storeIds   []string
+	startDate  time.Time
+	endDate    time.Time
+	conditions []string
+}
+
+func (a *access) fetch(ctx context.Context, repository repo.Repository) ([]*models.Sale, error) {
+	switch a.kind {
+	case accessLookup:
+		sale, err := repository.GetSale(ctx, a.id)
+		if errors.Is(err, repo.ErrSaleNotFound) {
+			return nil, nil
+		} else if err != nil {
+			return nil, err
+		}
+		return []*models.Sale{sale}, nil
+	case accessStores:
+		return repo.GetSalesByStores(ctx, repository, a.startDate, a.endDate, a.storeIds)
+	case accessNone:
+		return nil, nil
+	default:
+		return repository.GetAllSales(ctx)
+	}
+}
+
+func (a *access) step(repository repo.Repository) *models.PlanStep {
+	conditions := strings.Join(a.conditions, " AND ")
+	switch a.kind {
+	case accessLookup:
+		return &models.PlanStep{Operation: "Lookup", Detail: "sale by " + conditions}
+	case accessStores:
+		if repo.HasStoreIndex(repository) {
+			return &models.PlanStep{Operation: "IndexScan", Detail: "sales by store index: " + conditions}
+		}
+		return &models.PlanStep{Operation: "RangeScan", Detail: "sales in range per store: " + conditions}
+	case accessNone:
+		return &models.PlanStep{Operation: "Empty", Detail: "no accessible store matches " + conditions}
+	default:
+		return &models.PlanStep{Operation: "FullScan", Detail: "all sales"}
+	}
+}
+
+// after and before narrow the date range of a store lookup to a condition on
+// sale_date. GetSalesInRange includes its start but not its end, so exclusive
+// starts and inclusive ends move the bound by a nanosecond, the resolution of
+// sale dates.
+func (a *access) after(date time.Time, inclusive bool) {
+	if !inclusive {
+		date = date.Add(time.Nanosecond)
+	}
+	if a.startDate.IsZero() || date.After(a.startDate) {
+		a.startDate = date
+	}
+}
+
+func (a *access) before(date time.Time, inclusive bool) {
+	if inclusive {
+		date = date.Add(time.Nanosecond)
+	}
+	if a.endDate.IsZero() || date.Before(a.endDate) {
+		a.endDate = date
+	}
+}
+
+// pushDown picks how to fetch the sales matching all conditions and returns
+// the conditions left to filter them with. An ID becomes a lookup, and
+// stores, from the conditions or the scope, a store lookup that also takes
+// the conditions on the sale date; anything else scans all sales.
+func pushDown(conditions []expr, scope []string, dates *dateZone) (*access, []expr, error) {
+	a := &access{}
+	var idCondition expr
+	var storeIds []string
+	var storeConditions, dateConditions []expr
+	restrict := func(condition expr, ids []string) {
+		if storeConditions == nil {
+			storeIds = nil
+			for _, id := range ids {
+				if !slices.Contains(storeIds, id) {
+					storeIds = append(storeIds, id)
+				}
+			}
+		} else {
+			storeIds = slices.DeleteFunc(storeIds, func(id string) bool { return !slices.Contains(ids, id) })
+		}
+		storeConditions = append(storeConditions, condition)
+	}
+	for _, condition := range conditions {
+		switch c := condition.(type) {
+		case *comparison:
+			switch {
+			case c.column == "id" && c.op == "=" && idCondition == nil:
+				idCondition = c
+				a.id = c.value.value
+			case c.column == "store_id" && c.op == "=":
+				restrict(c, []string{c.value.value})
+			case c.column == "sale_date" && c.op != "!=":
+				dateConditions = append(dateConditions, c)
+			}
+		case *inExpr:
+			if c.column == "store_id" && !c.negated {
+				ids := make([]string, len(c.values))
+				for i, value := range c.values {
+					ids[i] = value.value
+				}
+				restrict(c, ids)
+			}
+		case *betweenExpr:
+			if c.column == "sale_date" && !c.negated {
+				dateConditions = append(dateConditions, c)
+			}
+		}
+	}
+	var scopeCondition expr
+	if len(scope) > 0 {
+		scopeCondition = storeIn(scope)
+		restrict(scopeCondition, scope)
+	}
+
+	// Dates resolve in the time zone of the stores, so they are read once
+	// those are known.
+	dates.storeIds, dates.restricted = storeIds, storeConditions != nil
+	saleDate, _ := lookupColumn("sale_date", position{})
+	for _, condition := range dateConditions {
+		switch c := condition.(type) {
+		case *comparison:
+			value, err := saleDate.parse(c.value, dates)
+			if err != nil {
+				return nil, nil, err
+			}
+			date := value.(time.Time)
+			if c.op != "<" && c.op != "<=" {
+				a.after(date, c.op != ">")
+			}
+			if c.op != ">" && c.op != ">=" {
+				a.before(date, c.op != "<")
+			}
+		case *betweenExpr:
+			low, err := saleDate.parse(c.low, dates)
+			if err != nil {
+				return nil, nil, err
+			}
+			high, err := saleDate.parse(c.high, dates)
+			if err != nil {
+				return nil, nil, err
+			}
+			a.after(low.(time.Time), true)
+			a.before(high.(time.Time), true)
+		}
+	}
+
+	var pushed []expr
+	switch {
+	case idCondition != nil:
+		a.kind = accessLookup
+		pushed = []expr{idCondition}
+		a.conditions = []string{idCondition.String()}
+		if scopeCondition != nil {
+			conditions = append(conditions, scopeCondition)
+		}
+	case storeConditions != nil && len(storeIds) == 0:
+		a.kind = accessNone
+		for _, condition := range storeConditions {
+			a.conditions = append(a.conditions, condition.String())
+		}
+		return a, nil, nil
+	case storeConditions != nil:
+		a.kind = accessStores
+		a.storeIds = storeIds
+		pushed = append(storeConditions, dateConditions...)
+		a.conditions = []string{storeIn(storeIds).String()}
+		for _, condition := range dateConditions {
+			a.conditions = append(a.conditions, condition.String())
+		}
+	}
+	if a.kind != accessStores {
+		a.startDate, a.endDate = time.Time{}, time.Time{}
+	}
+	var residual []expr
+	for _, condition := range conditions {
+		if !slices.Contains(pushed, condition) {
+			residual = append(residual, condition)
+		}
+	}
+	return a, residual, nil
+}
+
+func storeIn(storeIds []string) *inExpr {
+	condition := &inExpr{column: "store_id"}
+	for _, id := range storeIds {
+		condition.values = append(condition.values, literal{kind: tokenString, value: id, text: "'" + strings.ReplaceAll(id, "'", "''") + "'"})
+	}
+	return condition
+}
+
+// conjuncts splits a condition into the conditions joined by its top-level
+// ANDs.
+func conjuncts(e expr) []expr {
+	switch e := e.(type) {
+	case nil:
+		return nil
+	case *andExpr:
+		return append(conjuncts(e.left), conjuncts(e.right)...)
+	default:
+		return []expr{e}
+	}
+}
+
+func conjunction(conditions []expr) expr {
+	e := conditions[0]
+	for _, condition := range conditions[1:] {
+		e = &andExpr{left: e, right: condition}
+	}
+	return e
+}
+
+// predicate reports whether a sale matches a condition.
+type predicate func(sale *models.Sale) bool
+
+func compile(e expr, dates *dateZone) (predicate, error) {
+	switch e := e.(type) {
+	case *andExpr:
+		left, right, err := compilePair(e.left, e.right, dates)
+		if err != nil {
+			return nil, err
+		}
+		return func(sale *models.Sale) bool { return left(sale) && right(sale) }, nil
+	case *orExpr:
+		left, right, err := compilePair(e.left, e.right, dates)
+		if err != nil {
+			return nil, err
+		}
+		return func(sale *models.Sale) bool { return left(sale) || right(sale) }, nil
+	case *notExpr:
+		operand, err := compile(e.operand, dates)
+		if err != nil {
+			return nil, err
+		}
+		return func(sale *models.Sale) bool { return !operand(sale) }, nil
+	case *comparison:
+		column, err := lookupColumn(e.column, e.pos)
+		if err != nil {
+			return nil, err
+		}
+		value, err := column.parse(e.value, dates)
+		if err != nil {
+			return nil, err
+		}
+		return func(sale *models.Sale) bool { return matches(compare(column.get(sale), value), e.op) }, nil
+	case *inExpr:
+		column, err := lookupColumn(e.column, e.pos)
+		if err != nil {
+			return nil, err
+		}
+		values := make([]interface{}, len(e.values))
+		for i, literal := range e.values {
+			if values[i], err = column.parse(literal, dates); err != nil {
+				return nil, err
+			}
+		}
+		return func(sale *models.Sale) bool {
+			value := column.get(sale)
+			return slices.ContainsFunc(values, func(v interface{}) bool { return compare(value, v) == 0 }) != e.negated
+		}, nil
+	case *betweenExpr:
+		column, err := lookupColumn(e.column, e.pos)
+		if err != nil {
+			return nil, err
+		}
+		low, err := column.parse(e.low, dates)
+		if err != nil {
+			return nil, err
+		}
+		high, err := column.parse(e.high, dates)
+		if err != nil {
+			return nil, err
+		}
+		return func(sale *models.Sale) bool {
+			value := column.get(sale)
+			return (compare(value, low) >= 0 && compare(value, high) <= 0) != e.negated
+		}, nil
+	default:
+		return nil, fmt.Errorf("%w: unsupported condition %s", ErrInvalidQuery, e)
+	}
+}
+
+func compilePair(left expr, right expr, dates *dateZone) (predicate, predicate, error) {
+	l, err := compile(left, dates)
+	if err != nil {
+		return nil, nil, err
+	}
+	r, err := compile(right, dates)
+	if err != nil {
+		return nil, nil, err
+	}
+	return l, r, nil
+}
+
+func matches(c int, op string) bool {
+	switch op {
+	case "=":
+		return c == 0
+	case "!=":
+		return c != 0
+	case "<":
+		return c < 0
+	case "<=":
+		return c <= 0
+	case ">":
+		return c > 0
+	default:
+		return c >= 0
+	}
+}
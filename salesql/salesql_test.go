@@ -0,0 +1,252 @@
+package salesql
+
+import (
+	"context"
+	"dataflow/models"
+	"dataflow/repo"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+// recordingRepository records the repository calls of a query.
+type recordingRepository struct {
+	*repo.InMemoryRepository
+	indexed bool
+	calls   []string
+}
+
+func (r *recordingRepository) GetSale(ctx context.Context, id string) (*models.Sale, error) {
+	r.calls = append(r.calls, "GetSale")
+	return r.InMemoryRepository.GetSale(ctx, id)
+}
+
+func (r *recordingRepository) GetAllSales(ctx context.Context) ([]*models.Sale, error) {
+	r.calls = append(r.calls, "GetAllSales")
+	return r.InMemoryRepository.GetAllSales(ctx)
+}
+
+func (r *recordingRepository) GetSalesInRange(ctx context.Context, startDate time.Time, endDate time.Time, storeId string) ([]*models.Sale, error) {
+	r.calls = append(r.calls, "GetSalesInRange")
+	return r.InMemoryRepository.GetSalesInRange(ctx, startDate, endDate, storeId)
+}
+
+func (r *recordingRepository) HasStoreIndex() bool {
+	return r.indexed
+}
+
+func (r *recordingRepository) GetSalesByStores(ctx context.Context, startDate time.Time, endDate time.Time, storeIds []string) ([]*models.Sale, error) {
+	r.calls = append(r.calls, "GetSalesByStores")
+	return r.InMemoryRepository.GetSalesByStores(ctx, startDate, endDate, storeIds)
+}
+
+func setupRepository(indexed bool) *recordingRepository {
+	repository := &recordingRepository{InMemoryRepository: repo.NewInMemoryRepository(), indexed: indexed}
+	for _, sale := range []*models.Sale{
+		{ID: "1", ProductId: "p1", StoreId: "s1", QuantitySold: 10, SalePrice: 19.99, SaleDate: time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)},
+		{ID: "2", ProductId: "p2", StoreId: "s1", QuantitySold: 1, SalePrice: 5, SaleDate: time.Date(2024, 6, 15, 9, 0, 0, 0, time.UTC)},
+		{ID: "3", ProductId: "p1", StoreId: "s2", QuantitySold: 3, SalePrice: 0.1, SaleDate: time.Date(2024, 6, 20, 9, 0, 0, 0, time.UTC)},
+		{ID: "4", ProductId: "p3", StoreId: "s3", QuantitySold: 2, SalePrice: 0.2, SaleDate: time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC)},
+	} {
+		repository.Restore(sale)
+	}
+	return repository
+}
+
+func query(t *testing.T, repository repo.Repository, statement string, scope ...string) ([]string, [][]interface{}) {
+	plan, err := Prepare(statement, repository, scope, Locations{})
+	if !assert.NoError(t, err, statement) {
+		return nil, nil
+	}
+	rows, err := plan.Execute(context.Background())
+	assert.NoError(t, err, statement)
+	return plan.Columns(), rows
+}
+
+func TestExecute(t *testing.T) {
+	repository := setupRepository(true)
+
+	columns, rows := query(t, repository, `SELECT id, amount FROM sales WHERE quantity_sold >= 2 ORDER BY amount DESC LIMIT 2`)
+	assert.Equal(t, []string{"id", "amount"}, columns)
+	assert.Equal(t, [][]interface{}{{"1", 199.9}, {"4", 0.4}}, rows)
+
+	columns, rows = query(t, repository, `
+		select store_id, count(*) sales, sum(amount) as revenue, avg(quantity_sold), min(sale_date), max(product_id)
+		from sales
+		where sale_date < '2024-07-01' -- July isn't closed yet
+		group by store_id
+		order by 3 desc`)
+	assert.Equal(t, []string{"store_id", "sales", "revenue", "avg(quantity_sold)", "min(sale_date)", "max(product_id)"}, columns)
+	assert.Equal(t, [][]interface{}{
+		{"s1", int64(2), 204.9, 5.5, time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC), "p2"},
+		{"s2", int64(1), 0.3, 3.0, time.Date(2024, 6, 20, 9, 0, 0, 0, time.UTC), "p1"},
+	}, rows)
+
+	// Aggregates without GROUP BY return one row, even without sales.
+	_, rows = query(t, repository, `SELECT COUNT(*), SUM(quantity_sold), MAX(sale_price) FROM sales WHERE product_id = 'none'`)
+	assert.Equal(t, [][]interface{}{{int64(0), nil, nil}}, rows)
+
+	columns, rows = query(t, repository, `SELECT * FROM sales WHERE NOT (store_id = 's1' OR sale_price > 0.15) ORDER BY sale_date LIMIT 5 OFFSET 0;`)
+	assert.Equal(t, []string{"id", "product_id", "store_id", "quantity_sold", "sale_price", "sale_date"}, columns)
+	assert.Equal(t, [][]interface{}{{"3", "p1", "s2", int64(3), 0.1, time.Date(2024, 6, 20, 9, 0, 0, 0, time.UTC)}}, rows)
+
+	_, rows = query(t, repository, `SELECT product_id FROM sales WHERE product_id NOT IN ('p2') AND quantity_sold BETWEEN 2 AND 3 ORDER BY product_id DESC`)
+	assert.Equal(t, [][]interface{}{{"p3"}, {"p1"}}, rows)
+}
+
+func TestExecute_PushDown(t *testing.T) {
+	for _, indexed := range []bool{true, false} {
+		for statement, calls := range map[string][]string{
+			`SELECT id FROM sales WHERE id = '2' AND store_id = 's1'`:                                                  {"GetSale"},
+			`SELECT id FROM sales WHERE store_id IN ('s1', 's2') AND sale_date >= '2024-06-01'`:                        {"GetSalesByStores"},
+			`SELECT id FROM sales WHERE store_id = 's1' AND sale_date BETWEEN '2024-06-01' AND '2024-06-15T09:00:00Z'`: {"GetSalesByStores"},
+			`SELECT id FROM sales WHERE store_id = 's1' OR sale_date >= '2024-06-01'`:                                  {"GetAllSales"},
+			`SELECT id FROM sales WHERE store_id = 's1' AND store_id = 's2'`:                                           nil,
+		} {
+			repository := setupRepository(indexed)
+			_, rows := query(t, repository, statement)
+			if !indexed && calls != nil && calls[0] == "GetSalesByStores" {
+				calls = []string{"GetSalesInRange"}
+			}
+			assert.Subset(t, calls, repository.calls, statement)
+			assert.Subset(t, repository.calls, calls, statement)
+
+			// Pushing conditions down doesn't change the result.
+			fullScan := setupRepository(indexed)
+			_, expected := query(t, fullScan, statement+" OR quantity_sold < 0")
+			assert.Equal(t, []string{"GetAllSales"}, fullScan.calls)
+			assert.Equal(t, expected, rows, statement)
+		}
+	}
+}
+
+func TestExecute_Scope(t *testing.T) {
+	repository := setupRepository(true)
+
+	_, rows := query(t, repository, `SELECT store_id, COUNT(*) FROM sales GROUP BY store_id`, "s2", "s3")
+	assert.Equal(t, [][]interface{}{{"s2", int64(1)}, {"s3", int64(1)}}, rows)
+	_, rows = query(t, repository, `SELECT id FROM sales WHERE id = '1'`, "s2")
+	assert.Empty(t, rows)
+	_, rows = query(t, repository, `SELECT id FROM sales WHERE store_id = 's1'`, "s2")
+	assert.Empty(t, rows)
+}
+
+func TestExecute_DateTimeZone(t *testing.T) {
+	newYork, _ := time.LoadLocation("America/New_York")
+	paris, _ := time.LoadLocation("Europe/Paris")
+	selectIds := func(statement string, locations Locations) ([]interface{}, error) {
+		plan, err := Prepare(statement, setupRepository(true), nil, locations)
+		if err != nil {
+			return nil, err
+		}
+		rows, err := plan.Execute(context.Background())
+		ids := []interface{}{}
+		for _, row := range rows {
+			ids = append(ids, row[0])
+		}
+		return ids, err
+	}
+
+	// Sale 1 is made at midnight UTC, the evening before in New York.
+	for _, test := range []struct {
+		statement string
+		locations Locations
+		ids       []interface{}
+	}{
+		{`SELECT id FROM sales WHERE sale_date < '2024-06-01'`, Locations{}, []interface{}{}},
+		{`SELECT id FROM sales WHERE store_id = 's1' AND sale_date < '2024-06-01'`, Locations{Stores: map[string]*time.Location{"s1": newYork}}, []interface{}{"1"}},
+		{`SELECT id FROM sales WHERE sale_date < '2024-06-01' OR quantity_sold > 100`, Locations{Stores: map[string]*time.Location{"s1": newYork, "s2": newYork}}, []interface{}{"1"}},
+		{`SELECT id FROM sales WHERE store_id = 's1' AND sale_date BETWEEN '2024-05-31' AND '2024-06-01'`, Locations{Stores: map[string]*time.Location{"s1": newYork, "s2": paris}}, []interface{}{"1"}},
+		{`SELECT id FROM sales WHERE sale_date < '2024-06-01'`, Locations{Location: time.UTC, Stores: map[string]*time.Location{"s1": newYork, "s2": paris}}, []interface{}{}},
+		// Times are absolute.
+		{`SELECT id FROM sales WHERE sale_date < '2024-06-01T00:00:00Z'`, Locations{Stores: map[string]*time.Location{"s1": newYork}}, []interface{}{}},
+	} {
+		ids, err := selectIds(test.statement, test.locations)
+		assert.NoError(t, err, test.statement)
+		assert.Equal(t, test.ids, ids, test.statement)
+	}
+
+	_, err := selectIds(`SELECT id FROM sales WHERE sale_date < '2024-06-01'`, Locations{Stores: map[string]*time.Location{"s1": newYork, "s2": paris}})
+	assert.True(t, errors.Is(err, ErrInvalidQuery))
+	assert.ErrorContains(t, err, "so dates need a timezone")
+}
+
+func TestPlan_Steps(t *testing.T) {
+	for _, test := range []struct {
+		statement string
+		indexed   bool
+		scope     []string
+		steps     []*models.PlanStep
+	}{
+		{
+			statement: `EXPLAIN SELECT id FROM sales WHERE id = '1' AND quantity_sold > 1`,
+			scope:     []string{"s1"},
+			steps: []*models.PlanStep{
+				{Operation: "Lookup", Detail: "sale by id = '1'"},
+				{Operation: "Filter", Detail: "quantity_sold > 1 AND store_id IN ('s1')"},
+			},
+		},
+		{
+			statement: `SELECT store_id, SUM(amount) FROM sales WHERE store_id IN ('s1', 's2') AND sale_date >= '2024-06-01' AND product_id <> 'p2' GROUP BY store_id ORDER BY sum(amount) DESC LIMIT 10 OFFSET 5`,
+			indexed:   true,
+			steps: []*models.PlanStep{
+				{Operation: "IndexScan", Detail: "sales by store index: store_id IN ('s1', 's2') AND sale_date >= '2024-06-01'"},
+				{Operation: "Filter", Detail: "product_id != 'p2'"},
+				{Operation: "Aggregate", Detail: "sum(amount) grouped by store_id"},
+				{Operation: "Sort", Detail: "sum(amount) DESC"},
+				{Operation: "Limit", Detail: "10 offset 5"},
+			},
+		},
+		{
+			statement: `SELECT COUNT(*) FROM sales`,
+			scope:     []string{"s1"},
+			steps: []*models.PlanStep{
+				{Operation: "RangeScan", Detail: "sales in range per store: store_id IN ('s1')"},
+				{Operation: "Aggregate", Detail: "count(*)"},
+			},
+		},
+		{
+			statement: `SELECT id FROM sales WHERE store_id = 's1' OR quantity_sold > 1 ORDER BY sale_date DESC`,
+			steps: []*models.PlanStep{
+				{Operation: "FullScan", Detail: "all sales"},
+				{Operation: "Filter", Detail: "(store_id = 's1' OR quantity_sold > 1)"},
+				{Operation: "Sort", Detail: "sale_date DESC"},
+			},
+		},
+		{
+			statement: `SELECT id FROM sales WHERE store_id = 's1'`,
+			scope:     []string{"s2"},
+			steps:     []*models.PlanStep{{Operation: "Empty", Detail: "no accessible store matches store_id = 's1' AND store_id IN ('s2')"}},
+		},
+	} {
+		plan, err := Prepare(test.statement, setupRepository(test.indexed), test.scope, Locations{})
+		if assert.NoError(t, err, test.statement) {
+			assert.Equal(t, test.steps, plan.Steps(), test.statement)
+		}
+	}
+}
+
+func TestPrepare_Invalid(t *testing.T) {
+	for statement, message := range map[string]string{
+		`SELECT id FROM sales WHERE`:                               "expected a column, found end of query (line 1, column 27)",
+		`SELECT id FROM orders`:                                    `unknown table "orders", the only table is sales (line 1, column 16)`,
+		`SELECT name FROM sales`:                                   "unknown column name (line 1, column 8)",
+		`SELECT median(amount) FROM sales`:                         "unknown function median, use one of SUM, COUNT, AVG, MIN and MAX (line 1, column 8)",
+		`SELECT SUM(store_id) FROM sales`:                          "SUM needs a numeric column, store_id isn't one (line 1, column 8)",
+		`SELECT store_id, COUNT(*) FROM sales`:                     "store_id must be in GROUP BY or used in an aggregate function (line 1, column 8)",
+		`SELECT * FROM sales GROUP BY store_id`:                    "SELECT * can't be used with GROUP BY",
+		`SELECT id FROM sales WHERE quantity_sold > 'many'`:        "quantity_sold is compared with a number, found 'many' (line 1, column 44)",
+		`SELECT id FROM sales WHERE sale_date > 'June'`:            "sale_date is compared with a date (2006-01-02) or an RFC 3339 time, found 'June' (line 1, column 40)",
+		`SELECT id FROM sales WHERE store_id = 'x`:                 "unterminated string (line 1, column 39)",
+		`SELECT id FROM sales WHERE store_id # 'x'`:                `unexpected character '#' (line 1, column 37)`,
+		`SELECT id FROM sales ORDER BY 2`:                          "ORDER BY position 2 is out of range, the query selects 1 columns (line 1, column 31)",
+		`SELECT store_id FROM sales GROUP BY store_id ORDER BY id`: "ORDER BY id must be in GROUP BY or selected (line 1, column 55)",
+		`SELECT id FROM sales LIMIT 10 OFFSET`:                     "expected a number, found end of query (line 1, column 37)",
+		`SELECT id FROM sales; DELETE FROM sales`:                  `expected end of query, found "DELETE" (line 1, column 23)`,
+	} {
+		_, err := Prepare(statement, setupRepository(true), nil, Locations{})
+		assert.True(t, errors.Is(err, ErrInvalidQuery), statement)
+		assert.EqualError(t, err, "invalid query: "+message, statement)
+	}
+}
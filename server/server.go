@@ -0,0 +1,500 @@
+// Package server wires the repositories, services and handlers into the HTTP
+// and gRPC APIs and runs them until it is told to shut down.
+package server
+
+import (
+	"context"
+	"dataflow/auth"
+	"dataflow/config"
+	"dataflow/graphql"
+	"dataflow/grpcapi"
+	"dataflow/handlers"
+	"dataflow/logging"
+	"dataflow/metrics"
+	"dataflow/openapi"
+	"dataflow/ratelimit"
+	"dataflow/repo"
+	"dataflow/requestid"
+	"dataflow/services"
+	"dataflow/tracing"
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"google.golang.org/grpc"
+	grpchealth "google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+	// Store time zones are resolved without relying on the host's zoneinfo, in
+	// every binary that links the server.
+	_ "time/tzdata"
+)
+
+// Main loads the configuration from args and the environment and runs the
+// server. It returns the process exit code.
+func Main(args []string) int {
+	cfg, options, err := config.Load(args, os.Getenv, os.Stderr)
+	if errors.Is(err, flag.ErrHelp) {
+		return 0
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	if options.PrintConfig {
+		if err := cfg.WriteYAML(os.Stdout); err != nil {
+			fmt.Fprintf(os.Stderr, "Could not print configuration: %v\n", err)
+			return 1
+		}
+		return 0
+	}
+
+	logger, err := logging.New(os.Stdout, logging.Config{Level: cfg.Log.Level, Format: cfg.Log.Format})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Could not configure logging: %v\n", err)
+		return 1
+	}
+	slog.SetDefault(logger)
+	if os.Getenv(gin.EnvGinMode) == "" {
+		gin.SetMode(gin.ReleaseMode)
+	}
+	slog.Info("Effective configuration", slog.Any("config", cfg.Redacted()))
+
+	if err := Run(cfg, logger); err != nil {
+		slog.Error("Server failed", slog.Any("error", err))
+		return 1
+	}
+	return 0
+}
+
+// Run serves until SIGINT or SIGTERM and then shuts down gracefully: readiness
+// fails, in-flight requests and jobs get cfg.Server.ShutdownTimeout to finish,
+// and the repositories are flushed and closed.
+func Run(cfg *config.Config, logger *slog.Logger) error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	shutdownTracing, err := tracing.Setup(context.Background(), tracing.Config{
+		ServiceName: "dataflow",
+		Exporter:    cfg.Tracing.Exporter,
+		Endpoint:    cfg.Tracing.Endpoint,
+		Headers:     cfg.Tracing.Headers,
+		File:        cfg.Tracing.File,
+	})
+	if err != nil {
+		return fmt.Errorf("couldn't configure tracing: %w", err)
+	}
+	defer shutdownTracing(context.Background())
+
+	m := metrics.New()
+	repositories, err := NewRepositories(cfg.Repository)
+	if err != nil {
+		return fmt.Errorf("couldn't open repository: %w", err)
+	}
+	salesRepository := repositories.Sales
+	defer closeResource("repository", salesRepository)
+	defer closeResource("quota repository", repositories.Quotas)
+	repository := tracing.NewRepository(logging.NewRepository(metrics.NewRepository(salesRepository, m)))
+	broker := services.NewSaleBroker(services.DefaultReplayBufferSize, services.DefaultSubscriberBufferSize)
+	auditRepository, err := NewAuditRepository(cfg.Audit)
+	if err != nil {
+		return fmt.Errorf("couldn't open audit log: %w", err)
+	}
+	defer closeResource("audit log", auditRepository)
+	auditLog := services.NewAuditLog(auditRepository)
+	catalog := services.NewCatalogService(repositories.Stores, repositories.Products, repository)
+	catalogHandler := handlers.NewCatalogHandler(catalog)
+	calendar, err := NewCalendar(cfg.Fiscal)
+	if err != nil {
+		return err
+	}
+	service := tracing.NewDataService(logging.NewDataService(services.NewAuthorizingDataService(NewDataService(services.NewPublishingDataService(metrics.NewDataService(services.NewDataService(repository), m), broker), catalog, calendar, auditLog, cfg.Catalog))))
+	handler := handlers.NewDataHandler(service, catalog, calendar)
+	streamHandler := handlers.NewStreamHandler(broker)
+	aggregator := services.NewLiveAggregator(broker, service, catalog)
+	liveHandler := handlers.NewLiveHandler(aggregator)
+	background, cancelBackground := context.WithCancel(context.Background())
+	defer cancelBackground()
+	var workers sync.WaitGroup
+	goWorker(&workers, func() { aggregator.Run(background) })
+	anomalyHandler := handlers.NewAnomalyHandler(services.NewAnomalyService(service, catalog, services.DefaultAnomalyOptions))
+
+	alertRepository := repo.NewInMemoryAlertRepository()
+	notifier := services.NewWebhookNotifier(alertRepository, services.DefaultWebhookOptions)
+	alertService := services.NewAlertService(alertRepository, service, catalog, broker, notifier)
+	alertHandler := handlers.NewAlertHandler(alertService)
+	if cfg.Alerts.Enabled {
+		goWorker(&workers, func() { alertService.Run(background, time.Duration(cfg.Alerts.EvaluationInterval)) })
+	}
+
+	jobService := services.NewJobService(repositories.Jobs, map[string]services.JobFunc{
+		handlers.JobTypeCalculate: handler.CalculateJob,
+		handlers.JobTypeExport:    handler.ExportJob,
+	}, services.JobOptions{
+		Workers:   cfg.Jobs.Workers,
+		QueueSize: cfg.Jobs.QueueSize,
+		TTL:       time.Duration(cfg.Jobs.TTL),
+	})
+	jobHandler := handlers.NewJobHandler(jobService)
+	goWorker(&workers, func() { jobService.Run(background) })
+
+	graphQLHandler := handlers.NewGraphQLHandler(service, graphql.Limits{
+		MaxDepth:      cfg.GraphQL.MaxDepth,
+		MaxComplexity: cfg.GraphQL.MaxComplexity,
+	})
+	auditHandler := handlers.NewAuditHandler(auditLog)
+	health := services.NewHealth(services.HealthCheck{
+		Name:  "repository",
+		Check: func(ctx context.Context) error { return repo.Ping(ctx, salesRepository) },
+	})
+	healthHandler := handlers.NewHealthHandler(health)
+
+	spec, err := openapi.Load()
+	if err != nil {
+		return err
+	}
+
+	router := gin.New()
+	router.Use(logging.Recovery(logger), requestid.Middleware(), tracing.Middleware(), logging.Middleware(logger), m.Middleware())
+	// Probes and the API documentation are registered before authentication
+	// and rate limiting.
+	router.GET("/healthz", healthHandler.Healthz)
+	router.GET("/readyz", healthHandler.Readyz)
+	router.GET("/openapi.json", openapi.Handler)
+	router.GET("/docs", openapi.DocsHandler)
+	var authenticator *auth.Authenticator
+	if cfg.Auth.Disabled {
+		slog.Warn("Authentication is disabled")
+		router.Use(auth.Anonymous())
+	} else {
+		authenticator, err = newAuthenticator(cfg.Auth)
+		if err != nil {
+			return fmt.Errorf("couldn't configure authentication: %w", err)
+		}
+		router.Use(authenticator.Middleware())
+	}
+	rateLimiter, err := newRateLimiter(cfg.RateLimit, repositories.Quotas)
+	if err != nil {
+		return fmt.Errorf("couldn't configure rate limiting: %w", err)
+	}
+	router.Use(rateLimiter.Middleware(), spec.Middleware(openapi.Options{ValidateResponses: cfg.Server.ValidateResponses}))
+	router.GET("/data", tracing.Handler("DataHandler.GetData", handler.GetData))
+	router.POST("/data", tracing.Handler("DataHandler.AddData", handler.AddData))
+	router.GET("/data/stream", streamHandler.StreamSales)
+	router.GET("/live/aggregates", liveHandler.Aggregates)
+	router.POST("/calculate", tracing.Handler("DataHandler.Calculate", handler.Calculate))
+	router.POST("/query", tracing.Handler("DataHandler.Query", handler.Query))
+	router.GET("/anomalies", anomalyHandler.GetAnomalies)
+	router.GET("/graphql", tracing.Handler("GraphQLHandler.Query", graphQLHandler.Query))
+	router.POST("/graphql", tracing.Handler("GraphQLHandler.Query", graphQLHandler.Query))
+	router.GET("/graphql/schema", graphQLHandler.Schema)
+	// Alert rules are evaluated across all stores, so managing them is
+	// reserved for admins.
+	alerts := router.Group("/alerts", auth.Require(auth.PermissionAdmin))
+	alerts.POST("", alertHandler.CreateRule)
+	alerts.GET("", alertHandler.GetRules)
+	alerts.GET("/:id", alertHandler.GetRule)
+	alerts.PUT("/:id", alertHandler.UpdateRule)
+	alerts.DELETE("/:id", alertHandler.DeleteRule)
+	alerts.GET("/:id/deliveries", alertHandler.GetDeliveries)
+	// Everyone who reads sales may look up the catalog, but only admins
+	// maintain it.
+	stores := router.Group("/stores")
+	stores.GET("", auth.Require(auth.PermissionReadSales), catalogHandler.GetStores)
+	stores.GET("/:id", auth.Require(auth.PermissionReadSales), catalogHandler.GetStore)
+	stores.POST("", auth.Require(auth.PermissionAdmin), catalogHandler.CreateStore)
+	stores.PUT("/:id", auth.Require(auth.PermissionAdmin), catalogHandler.UpdateStore)
+	stores.DELETE("/:id", auth.Require(auth.PermissionAdmin), catalogHandler.DeleteStore)
+	products := router.Group("/products")
+	products.GET("", auth.Require(auth.PermissionReadSales), catalogHandler.GetProducts)
+	products.GET("/:id", auth.Require(auth.PermissionReadSales), catalogHandler.GetProduct)
+	products.POST("", auth.Require(auth.PermissionAdmin), catalogHandler.CreateProduct)
+	products.PUT("/:id", auth.Require(auth.PermissionAdmin), catalogHandler.UpdateProduct)
+	products.DELETE("/:id", auth.Require(auth.PermissionAdmin), catalogHandler.DeleteProduct)
+	// Jobs read sales on behalf of their submitter, who is the only one
+	// besides admins to see them.
+	jobs := router.Group("/jobs", auth.Require(auth.PermissionReadSales))
+	jobs.POST("", jobHandler.Submit)
+	jobs.GET("/:id", jobHandler.GetJob)
+	jobs.GET("/:id/result", jobHandler.GetResult)
+	jobs.DELETE("/:id", jobHandler.Cancel)
+	router.GET("/metrics", auth.Require(auth.PermissionAdmin), gin.WrapH(m.Handler()))
+	audit := router.Group("/audit", auth.Require(auth.PermissionAdmin))
+	audit.GET("", auditHandler.GetEntries)
+	audit.GET("/verify", auditHandler.Verify)
+
+	server := &http.Server{
+		Addr:              cfg.Server.Addr,
+		Handler:           router,
+		ReadHeaderTimeout: time.Duration(cfg.Server.ReadHeaderTimeout),
+		ReadTimeout:       time.Duration(cfg.Server.ReadTimeout),
+		WriteTimeout:      time.Duration(cfg.Server.WriteTimeout),
+		IdleTimeout:       time.Duration(cfg.Server.IdleTimeout),
+	}
+	// Shutdown doesn't wait for streams, so end them when it starts; clients
+	// resume with Last-Event-ID after reconnecting to another instance.
+	server.RegisterOnShutdown(broker.Close)
+
+	// Room for both servers, so that neither blocks when the other failed first.
+	serveErr := make(chan error, 2)
+	go func() {
+		if err := server.ListenAndServe(); err != nil {
+			serveErr <- fmt.Errorf("couldn't listen on %s: %w", server.Addr, err)
+		}
+	}()
+	var grpcServer *grpc.Server
+	grpcHealth := grpchealth.NewServer()
+	if cfg.Server.GRPCAddr != "" {
+		listener, err := net.Listen("tcp", cfg.Server.GRPCAddr)
+		if err != nil {
+			server.Close()
+			return fmt.Errorf("couldn't listen on %s: %w", cfg.Server.GRPCAddr, err)
+		}
+		grpcServer = grpcapi.NewServer(service, grpcapi.Options{Logger: logger, Metrics: m, Authenticator: authenticator})
+		healthpb.RegisterHealthServer(grpcServer, grpcHealth)
+		go func() {
+			if err := grpcServer.Serve(listener); err != nil {
+				serveErr <- fmt.Errorf("couldn't serve gRPC on %s: %w", cfg.Server.GRPCAddr, err)
+			}
+		}()
+	}
+	health.SetReady()
+	grpcHealth.SetServingStatus("", healthpb.HealthCheckResponse_SERVING)
+	slog.Info("Server starting", slog.String("addr", server.Addr), slog.String("grpc_addr", cfg.Server.GRPCAddr))
+	select {
+	case err := <-serveErr:
+		server.Close()
+		if grpcServer != nil {
+			grpcServer.Stop()
+		}
+		return err
+	case <-ctx.Done():
+	}
+	// A second signal terminates immediately.
+	stop()
+
+	timeout := time.Duration(cfg.Server.ShutdownTimeout)
+	slog.Info("Shutting down", slog.Duration("timeout", timeout))
+	health.SetShuttingDown()
+	grpcHealth.Shutdown()
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	var stopped sync.WaitGroup
+	if grpcServer != nil {
+		goWorker(&stopped, func() { stopGRPC(shutdownCtx, grpcServer) })
+	}
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		slog.Warn("Requests didn't finish in time, closing connections", slog.Any("error", err))
+		server.Close()
+	}
+	stopped.Wait()
+	cancelBackground()
+	if !waitFor(shutdownCtx, &workers) {
+		slog.Warn("Background workers didn't stop in time")
+	}
+	slog.Info("Server stopped")
+	return nil
+}
+
+// stopGRPC lets in-flight calls finish until ctx expires and then cancels
+// them.
+func stopGRPC(ctx context.Context, server *grpc.Server) {
+	done := make(chan struct{})
+	go func() {
+		server.GracefulStop()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+		slog.Warn("gRPC calls didn't finish in time, cancelling them")
+		server.Stop()
+	}
+}
+
+func goWorker(wg *sync.WaitGroup, worker func()) {
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		worker()
+	}()
+}
+
+// waitFor reports whether wg finished before ctx expired.
+func waitFor(ctx context.Context, wg *sync.WaitGroup) bool {
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// closeResource flushes and closes resources that hold files or connections.
+func closeResource(name string, resource interface{}) {
+	closer, ok := resource.(io.Closer)
+	if !ok {
+		return
+	}
+	if err := closer.Close(); err != nil {
+		slog.Error("Could not close "+name, slog.Any("error", err))
+	}
+}
+
+func newRateLimiter(cfg config.RateLimitConfig, quotas repo.QuotaRepository) (*ratelimit.RateLimiter, error) {
+	ingest, err := ratelimit.ParseLimit(cfg.Ingest)
+	if err != nil {
+		return nil, err
+	}
+	query, err := ratelimit.ParseLimit(cfg.Query)
+	if err != nil {
+		return nil, err
+	}
+	return ratelimit.NewRateLimiter(quotas, ratelimit.Config{
+		Ingest:     ingest,
+		Query:      query,
+		DailyQuota: cfg.DailyQuota,
+	}), nil
+}
+
+// Repositories are the repositories of one backend.
+type Repositories struct {
+	Sales    repo.Repository
+	Jobs     repo.JobRepository
+	Stores   repo.StoreRepository
+	Products repo.ProductRepository
+	Quotas   repo.QuotaRepository
+}
+
+// NewRepositories opens the repositories of the configured backend. The file
+// backend keeps the others next to the sales file, e.g. sales.jobs and
+// sales.stores.json next to sales.ndjson.
+func NewRepositories(cfg config.RepositoryConfig) (*Repositories, error) {
+	switch cfg.Backend {
+	case config.RepositoryMemory:
+		return &Repositories{
+			Sales:    repo.NewInMemoryRepository(),
+			Jobs:     repo.NewInMemoryJobRepository(),
+			Stores:   repo.NewInMemoryStoreRepository(),
+			Products: repo.NewInMemoryProductRepository(),
+			Quotas:   repo.NewInMemoryQuotaRepository(),
+		}, nil
+	case config.RepositoryFile:
+		jobs, err := repo.NewFileJobRepository(besideSalesFile(cfg, ".jobs"))
+		if err != nil {
+			return nil, err
+		}
+		stores, products, err := NewCatalogRepositories(cfg)
+		if err != nil {
+			return nil, err
+		}
+		quotas, err := repo.NewFileQuotaRepository(besideSalesFile(cfg, ".quotas.ndjson"))
+		if err != nil {
+			return nil, err
+		}
+		sales, err := repo.NewFileRepository(cfg.File)
+		if err != nil {
+			quotas.Close()
+			return nil, err
+		}
+		return &Repositories{Sales: sales, Jobs: jobs, Stores: stores, Products: products, Quotas: quotas}, nil
+	default:
+		return nil, fmt.Errorf("unsupported repository backend %q", cfg.Backend)
+	}
+}
+
+// besideSalesFile returns the sales file's path with its extension replaced
+// by suffix.
+func besideSalesFile(cfg config.RepositoryConfig, suffix string) string {
+	return strings.TrimSuffix(cfg.File, filepath.Ext(cfg.File)) + suffix
+}
+
+// NewCatalogRepositories opens the store and product files of the file
+// backend, which are kept next to the sales file.
+func NewCatalogRepositories(cfg config.RepositoryConfig) (*repo.FileStoreRepository, *repo.FileProductRepository, error) {
+	stores, err := repo.NewFileStoreRepository(besideSalesFile(cfg, ".stores.json"))
+	if err != nil {
+		return nil, nil, err
+	}
+	products, err := repo.NewFileProductRepository(besideSalesFile(cfg, ".products.json"))
+	if err != nil {
+		return nil, nil, err
+	}
+	return stores, products, nil
+}
+
+// NewCalendar builds the configured fiscal calendar.
+func NewCalendar(cfg config.FiscalConfig) (*services.Calendar, error) {
+	calendar, err := services.NewCalendar(services.CalendarOptions{
+		Pattern:    cfg.Calendar,
+		StartMonth: time.Month(cfg.StartMonth),
+		WeekStart:  time.Weekday(cfg.WeekStart),
+		NameByEnd:  cfg.YearNamedBy == config.FiscalYearNamedByEnd,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("couldn't configure the fiscal calendar: %w", err)
+	}
+	return calendar, nil
+}
+
+// NewDataService adds catalog checks, store time zones, the fiscal calendar
+// and the audit log to inner. The server wraps the result in authorization,
+// logging and tracing; the offline commands of cmd/dataflow use it as it is.
+func NewDataService(inner services.DataService, catalog services.CatalogService, calendar *services.Calendar, auditLog *services.AuditLog, cfg config.CatalogConfig) services.DataService {
+	checked := services.NewCatalogCheckingDataService(inner, catalog, cfg.Validation == config.CatalogStrict)
+	return services.NewAuditingDataService(services.NewCalendarDataService(services.NewLocalTimeDataService(checked, catalog), calendar), auditLog)
+}
+
+// NewAuditRepository appends to the configured audit log file when there is
+// one and keeps the audit log in memory otherwise.
+func NewAuditRepository(cfg config.AuditConfig) (repo.AuditRepository, error) {
+	if cfg.File != "" {
+		return repo.NewFileAuditRepository(cfg.File)
+	}
+	return repo.NewInMemoryAuditRepository(), nil
+}
+
+func newAuthenticator(cfg config.AuthConfig) (*auth.Authenticator, error) {
+	var keys *auth.KeyStore
+	var verifier *auth.JWTVerifier
+	if cfg.APIKeysFile != "" {
+		store, err := auth.LoadAPIKeys(cfg.APIKeysFile)
+		if err != nil {
+			return nil, err
+		}
+		keys = store
+	}
+	if cfg.JWKSFile != "" {
+		jwks, err := auth.LoadJWKS(cfg.JWKSFile)
+		if err != nil {
+			return nil, err
+		}
+		verifier, err = auth.NewJWTVerifier(jwks, auth.JWTOptions{
+			Issuer:   cfg.JWTIssuer,
+			Audience: cfg.JWTAudience,
+			Leeway:   time.Minute,
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	if keys == nil && verifier == nil {
+		return nil, errors.New("configure an API key file and/or a JWKS, or disable authentication")
+	}
+	return auth.NewAuthenticator(keys, verifier), nil
+}
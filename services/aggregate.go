@@ -0,0 +1,110 @@
+package services
+
+import (
+	"context"
+	"dataflow/models"
+	"dataflow/repo"
+	"errors"
+	"fmt"
+	"math/big"
+	"slices"
+	"sort"
+	"strings"
+)
+
+var ErrUnsupportedDimension = errors.New("unsupported dimension")
+
+// Aggregate totals the sales matching query in one pass. Without GroupBy it
+// returns a single group, which is zero when no sales match; otherwise it
+// returns one group per combination of keys that has sales, ordered by the
+// keys.
+func (ds *dataService) Aggregate(ctx context.Context, query models.AggregateQuery) ([]*models.AggregateGroup, error) {
+	for _, dimension := range query.GroupBy {
+		switch dimension {
+		case models.DimensionStore, models.DimensionProduct, models.DimensionDay, models.DimensionWeek, models.DimensionMonth,
+			models.DimensionFiscalWeek, models.DimensionFiscalPeriod, models.DimensionFiscalQuarter, models.DimensionFiscalYear:
+		default:
+			return nil, fmt.Errorf("%w %q", ErrUnsupportedDimension, dimension)
+		}
+	}
+	if !query.StartDate.IsZero() && !query.EndDate.IsZero() && query.StartDate.After(query.EndDate) {
+		return nil, ErrWrongDate
+	}
+	sales, err := ds.repo.GetAllSales(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't aggregate sales: %w", err)
+	}
+	if query.Calendar == nil {
+		query.Calendar = DefaultCalendar
+	}
+
+	// Revenue is totalled exactly and only converted once all sales are in.
+	groups := make(map[string]*models.AggregateGroup)
+	revenues := make(map[string]*big.Rat)
+	if len(query.GroupBy) == 0 {
+		total := &models.AggregateGroup{}
+		groups[groupKey(total)] = total
+		revenues[groupKey(total)] = new(big.Rat)
+	}
+	for _, sale := range sales {
+		if !repo.InRange(sale.SaleDate, query.StartDate, query.EndDate) ||
+			(len(query.StoreIds) > 0 && !slices.Contains(query.StoreIds, sale.StoreId)) ||
+			(len(query.ProductIds) > 0 && !slices.Contains(query.ProductIds, sale.ProductId)) {
+			continue
+		}
+		group := groupOf(sale, &query)
+		key := groupKey(group)
+		if existing, ok := groups[key]; ok {
+			group = existing
+		} else {
+			groups[key] = group
+			revenues[key] = new(big.Rat)
+		}
+		revenues[key].Add(revenues[key], saleDecimal(sale))
+		group.Units += int64(sale.QuantitySold)
+		group.Count++
+	}
+
+	keys := make([]string, 0, len(groups))
+	for key := range groups {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	result := make([]*models.AggregateGroup, 0, len(keys))
+	for _, key := range keys {
+		groups[key].Revenue = decimalFloat(revenues[key])
+		result = append(result, groups[key])
+	}
+	return result, nil
+}
+
+// groupOf returns a group with the keys of sale for the grouped dimensions.
+// Periods are those of the sale's local time in the query's time zone for its
+// store.
+func groupOf(sale *models.Sale, query *models.AggregateQuery) *models.AggregateGroup {
+	group := &models.AggregateGroup{}
+	local := sale.SaleDate.In(query.LocationOf(sale.StoreId))
+	for _, dimension := range query.GroupBy {
+		switch dimension {
+		case models.DimensionStore:
+			group.StoreId = sale.StoreId
+		case models.DimensionProduct:
+			group.ProductId = sale.ProductId
+		case models.DimensionDay:
+			group.Period = local.Format("2006-01-02")
+		case models.DimensionWeek:
+			year, week := local.ISOWeek()
+			group.Period = fmt.Sprintf("%04d-W%02d", year, week)
+		case models.DimensionMonth:
+			group.Period = local.Format("2006-01")
+		case models.DimensionFiscalWeek, models.DimensionFiscalPeriod, models.DimensionFiscalQuarter, models.DimensionFiscalYear:
+			group.Period = query.Calendar.Period(local, dimension)
+		}
+	}
+	return group
+}
+
+// groupKey orders groups by store, product and period.
+func groupKey(group *models.AggregateGroup) string {
+	return strings.Join([]string{group.StoreId, group.ProductId, group.Period}, "\x00")
+}
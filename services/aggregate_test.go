@@ -0,0 +1,167 @@
+package services
+
+import (
+	"context"
+	"dataflow/auth"
+	"dataflow/models"
+	"dataflow/repo"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"math/big"
+	"testing"
+	"time"
+)
+
+func TestDataService_Aggregate(t *testing.T) {
+	mockRepo := new(repo.MockRepository)
+	service := NewDataService(mockRepo)
+	mockRepo.On("GetAllSales").Return(batchSales(), nil)
+
+	groups, err := service.Aggregate(context.Background(), models.AggregateQuery{
+		EndDate: time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC),
+		GroupBy: []string{models.DimensionStore, models.DimensionDay},
+	})
+
+	assert.Nil(t, err)
+	assert.Len(t, groups, 3)
+	assert.Equal(t, "6789", groups[0].StoreId)
+	assert.Equal(t, "2024-06-10", groups[0].Period)
+	assert.Equal(t, "", groups[0].ProductId)
+	assert.Equal(t, "10.00", groups[0].Revenue.Text('f', 2))
+	assert.Equal(t, int64(2), groups[0].Units)
+	assert.Equal(t, "2024-06-15", groups[1].Period)
+	assert.Equal(t, "9876", groups[2].StoreId)
+	assert.Equal(t, int64(1), groups[2].Count)
+}
+
+func TestDataService_Aggregate_LargeTotals(t *testing.T) {
+	sales := repo.NewInMemoryRepository()
+	start := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < 1000; i++ {
+		sales.AddSale(context.Background(), &models.Sale{ProductId: "12345", StoreId: "6789", QuantitySold: 1, SalePrice: 1234.56, SaleDate: start.Add(time.Duration(i) * time.Minute)})
+		sales.AddSale(context.Background(), &models.Sale{ProductId: "12345", StoreId: "9876", QuantitySold: 3, SalePrice: 19.99, SaleDate: start.Add(time.Duration(i) * time.Minute)})
+	}
+
+	groups, err := NewDataService(sales).Aggregate(context.Background(), models.AggregateQuery{GroupBy: []string{models.DimensionStore}})
+	assert.Nil(t, err)
+	if assert.Len(t, groups, 2) {
+		assert.Equal(t, "1234560", groups[0].Revenue.Text('f', -1))
+		assert.Equal(t, "1234.56", groups[0].AverageSale().Text('f', -1))
+		assert.Equal(t, "59970", groups[1].Revenue.Text('f', -1))
+	}
+}
+
+func TestDataService_Aggregate_Filters(t *testing.T) {
+	mockRepo := new(repo.MockRepository)
+	service := NewDataService(mockRepo)
+	mockRepo.On("GetAllSales").Return(batchSales(), nil)
+
+	groups, err := service.Aggregate(context.Background(), models.AggregateQuery{ProductIds: []string{"54321"}})
+	assert.Nil(t, err)
+	assert.Len(t, groups, 1)
+	assert.Equal(t, int64(8), groups[0].Units)
+	assert.Equal(t, int64(3), groups[0].Count)
+	assert.Equal(t, "53.3", groups[0].AverageSale().Text('f', 1))
+
+	groups, err = service.Aggregate(context.Background(), models.AggregateQuery{StoreIds: []string{"1111"}})
+	assert.Nil(t, err)
+	assert.Len(t, groups, 1)
+	assert.Equal(t, 0, groups[0].Revenue.Cmp(new(big.Float)))
+	assert.Nil(t, groups[0].AverageSale())
+
+	groups, err = service.Aggregate(context.Background(), models.AggregateQuery{StoreIds: []string{"1111"}, GroupBy: []string{models.DimensionMonth}})
+	assert.Nil(t, err)
+	assert.Empty(t, groups)
+}
+
+func TestDataService_Aggregate_LocalTime(t *testing.T) {
+	paris, _ := time.LoadLocation("Europe/Paris")
+	newYork, _ := time.LoadLocation("America/New_York")
+	mockRepo := new(repo.MockRepository)
+	service := NewDataService(mockRepo)
+	mockRepo.On("GetAllSales").Return([]*models.Sale{
+		// 00:30 on Sunday, March 31 in Paris, before the switch to CEST.
+		{StoreId: "paris", QuantitySold: 1, SalePrice: 1, SaleDate: time.Date(2024, 3, 30, 23, 30, 0, 0, time.UTC)},
+		// 00:30 on Monday, April 1 in Paris, after it.
+		{StoreId: "paris", QuantitySold: 1, SalePrice: 2, SaleDate: time.Date(2024, 3, 31, 22, 30, 0, 0, time.UTC)},
+		// 23:30 on Sunday, October 27 in Paris, the day back to CET.
+		{StoreId: "paris", QuantitySold: 1, SalePrice: 4, SaleDate: time.Date(2024, 10, 27, 22, 30, 0, 0, time.UTC)},
+		// 20:00 on Saturday, November 2 in New York, still on EDT.
+		{StoreId: "nyc", QuantitySold: 1, SalePrice: 8, SaleDate: time.Date(2024, 11, 3, 0, 0, 0, 0, time.UTC)},
+	}, nil)
+	periods := func(groups []*models.AggregateGroup) []string {
+		var periods []string
+		for _, group := range groups {
+			periods = append(periods, group.StoreId+" "+group.Period)
+		}
+		return periods
+	}
+	locations := map[string]*time.Location{"paris": paris, "nyc": newYork}
+
+	groups, err := service.Aggregate(context.Background(), models.AggregateQuery{GroupBy: []string{models.DimensionStore, models.DimensionDay}, StoreLocations: locations})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"nyc 2024-11-02", "paris 2024-03-31", "paris 2024-04-01", "paris 2024-10-27"}, periods(groups))
+
+	groups, err = service.Aggregate(context.Background(), models.AggregateQuery{GroupBy: []string{models.DimensionStore, models.DimensionWeek}, StoreLocations: locations})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"nyc 2024-W44", "paris 2024-W13", "paris 2024-W14", "paris 2024-W43"}, periods(groups))
+
+	// The query's time zone overrides the stores'.
+	groups, err = service.Aggregate(context.Background(), models.AggregateQuery{GroupBy: []string{models.DimensionStore, models.DimensionMonth}, StoreLocations: locations, Location: time.UTC})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"nyc 2024-11", "paris 2024-03", "paris 2024-10"}, periods(groups))
+}
+
+func TestLocalTimeDataService_Aggregate(t *testing.T) {
+	inner := new(MockService)
+	catalog := newTestCatalog(repo.NewInMemoryRepository())
+	service := NewLocalTimeDataService(inner, catalog)
+	inner.On("Aggregate", mock.Anything).Return([]*models.AggregateGroup{}, nil)
+
+	_, err := service.Aggregate(context.Background(), models.AggregateQuery{GroupBy: []string{models.DimensionDay}})
+	assert.Nil(t, err)
+	query := inner.Calls[0].Arguments.Get(0).(models.AggregateQuery)
+	assert.Equal(t, "Europe/Paris", query.LocationOf("6789").String())
+	assert.Equal(t, time.UTC, query.LocationOf("9876"))
+
+	_, err = service.Aggregate(context.Background(), models.AggregateQuery{Location: time.UTC})
+	assert.Nil(t, err)
+	inner.AssertCalled(t, "Aggregate", models.AggregateQuery{Location: time.UTC})
+}
+
+func TestDataService_Aggregate_InvalidQuery(t *testing.T) {
+	service := NewDataService(new(repo.MockRepository))
+
+	_, err := service.Aggregate(context.Background(), models.AggregateQuery{GroupBy: []string{"quarter"}})
+	assert.True(t, errors.Is(err, ErrUnsupportedDimension))
+
+	_, err = service.Aggregate(context.Background(), models.AggregateQuery{
+		StartDate: time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC),
+		EndDate:   time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC),
+	})
+	assert.True(t, errors.Is(err, ErrWrongDate))
+}
+
+func TestAuthorizingDataService_Aggregate(t *testing.T) {
+	inner := new(MockService)
+	service := NewAuthorizingDataService(inner)
+	inner.On("Aggregate", mock.Anything).Return([]*models.AggregateGroup{}, nil)
+
+	_, err := service.Aggregate(withRoles([]string{auth.RoleStoreManager}, "6789"), models.AggregateQuery{GroupBy: []string{models.DimensionDay}})
+	assert.Nil(t, err)
+	inner.AssertCalled(t, "Aggregate", models.AggregateQuery{StoreIds: []string{"6789"}, GroupBy: []string{models.DimensionDay}})
+
+	_, err = service.Aggregate(withRoles([]string{auth.RoleStoreManager}, "6789"), models.AggregateQuery{StoreIds: []string{"6789", "9876"}})
+	assert.True(t, errors.Is(err, ErrForbidden))
+
+	_, err = service.Aggregate(withRoles([]string{auth.RoleStoreManager}), models.AggregateQuery{})
+	assert.True(t, errors.Is(err, ErrForbidden))
+
+	_, err = service.Aggregate(withRoles([]string{auth.RoleIntegration}), models.AggregateQuery{})
+	assert.True(t, errors.Is(err, ErrForbidden))
+
+	_, err = service.Aggregate(withRoles([]string{auth.RoleAnalyst}), models.AggregateQuery{})
+	assert.Nil(t, err)
+	inner.AssertCalled(t, "Aggregate", models.AggregateQuery{})
+}
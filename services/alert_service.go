@@ -0,0 +1,481 @@
+package services
+
+import (
+	"context"
+	"dataflow/auth"
+	"dataflow/models"
+	"dataflow/repo"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"sort"
+	"sync"
+	"time"
+)
+
+var ErrInvalidAlertRule = errors.New("invalid alert rule")
+
+// defaultThresholdWindow is the revenue window of threshold rules that don't
+// set one, i.e. "daily revenue" means revenue over the trailing 24 hours.
+const defaultThresholdWindow = 24 * time.Hour
+
+type AlertService interface {
+	CreateRule(rule *models.AlertRule) error
+	GetRule(id string) (*models.AlertRule, error)
+	GetAllRules() ([]*models.AlertRule, error)
+	UpdateRule(rule *models.AlertRule) error
+	DeleteRule(id string) error
+	GetDeliveries(ruleId string) ([]*models.WebhookDelivery, error)
+	Evaluate(ctx context.Context, now time.Time) error
+	Run(ctx context.Context, interval time.Duration)
+}
+
+type alertService struct {
+	rules    repo.AlertRepository
+	data     DataService
+	catalog  CatalogService
+	broker   *SaleBroker
+	notifier *WebhookNotifier
+
+	mu sync.Mutex
+	// states holds the state of every rule per store, under "" for rules
+	// of a single store or of all stores.
+	states map[string]map[string]string
+	// stores are the stores seen with sales, which no_sales rules for all
+	// stores check besides the catalog's. They are found by scanning all
+	// sales once and then from the sales published to the broker.
+	stores  map[string]bool
+	scanned bool
+}
+
+// NewAlertService checks no_sales rules without a store for each active store
+// of catalog and each store seen with sales. catalog and broker may be nil;
+// without a broker, stores that first sell after the first evaluation aren't
+// seen.
+func NewAlertService(rules repo.AlertRepository, data DataService, catalog CatalogService, broker *SaleBroker, notifier *WebhookNotifier) AlertService {
+	return &alertService{
+		rules:    rules,
+		data:     data,
+		catalog:  catalog,
+		broker:   broker,
+		notifier: notifier,
+		states:   make(map[string]map[string]string),
+		stores:   make(map[string]bool),
+	}
+}
+
+// CreateRule adds rule, with a generated webhook secret if it has none.
+func (as *alertService) CreateRule(rule *models.AlertRule) error {
+	if err := validateRule(rule); err != nil {
+		return err
+	}
+	if rule.WebhookSecret == "" {
+		secret, err := newWebhookSecret()
+		if err != nil {
+			return fmt.Errorf("couldn't generate webhook secret: %w", err)
+		}
+		rule.WebhookSecret = secret
+	}
+	rule.State = models.AlertStateOk
+	if err := as.rules.AddRule(rule); err != nil {
+		return fmt.Errorf("couldn't add alert rule: %w", err)
+	}
+	return nil
+}
+
+func (as *alertService) GetRule(id string) (*models.AlertRule, error) {
+	rule, err := as.rules.GetRule(id)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't get alert rule: %w", err)
+	}
+	rule.State = as.state(rule.ID)
+	return rule, nil
+}
+
+func (as *alertService) GetAllRules() ([]*models.AlertRule, error) {
+	rules, err := as.rules.GetAllRules()
+	if err != nil {
+		return nil, fmt.Errorf("couldn't get alert rules: %w", err)
+	}
+	for _, rule := range rules {
+		rule.State = as.state(rule.ID)
+	}
+	return rules, nil
+}
+
+func (as *alertService) UpdateRule(rule *models.AlertRule) error {
+	if err := validateRule(rule); err != nil {
+		return err
+	}
+	existing, err := as.rules.GetRule(rule.ID)
+	if err != nil {
+		return fmt.Errorf("couldn't update alert rule: %w", err)
+	}
+	if rule.WebhookSecret == "" {
+		rule.WebhookSecret = existing.WebhookSecret
+	}
+	if err := as.rules.UpdateRule(rule); err != nil {
+		return fmt.Errorf("couldn't update alert rule: %w", err)
+	}
+	rule.State = as.state(rule.ID)
+	return nil
+}
+
+func (as *alertService) DeleteRule(id string) error {
+	if err := as.rules.DeleteRule(id); err != nil {
+		return fmt.Errorf("couldn't delete alert rule: %w", err)
+	}
+	as.mu.Lock()
+	delete(as.states, id)
+	as.mu.Unlock()
+	return nil
+}
+
+func (as *alertService) GetDeliveries(ruleId string) ([]*models.WebhookDelivery, error) {
+	deliveries, err := as.rules.GetDeliveries(ruleId)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't get webhook deliveries: %w", err)
+	}
+	return deliveries, nil
+}
+
+// Evaluate checks every rule at the given instant and notifies the rule's
+// webhook when it starts firing or resolves, once per store for no_sales rules
+// without a store. Deliveries run concurrently and Evaluate returns once all
+// of them have finished. Rules are checked as the system principal so that
+// they see every store.
+func (as *alertService) Evaluate(ctx context.Context, now time.Time) error {
+	ctx = auth.WithPrincipal(ctx, auth.SystemPrincipal)
+	rules, err := as.rules.GetAllRules()
+	if err != nil {
+		return fmt.Errorf("couldn't evaluate alert rules: %w", err)
+	}
+
+	var wg sync.WaitGroup
+	var errs []error
+	for _, rule := range rules {
+		checks, err := as.check(ctx, rule, now)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("couldn't evaluate alert rule %s: %w", rule.ID, err))
+			continue
+		}
+
+		for _, check := range checks {
+			status := as.transition(rule.ID, check.storeId, check.firing)
+			if status == "" {
+				continue
+			}
+			storeId := rule.StoreId
+			if check.storeId != "" {
+				storeId = check.storeId
+			}
+			notification := &models.AlertNotification{
+				RuleId:    rule.ID,
+				RuleName:  rule.Name,
+				Status:    status,
+				StoreId:   storeId,
+				Value:     check.value,
+				Threshold: rule.Threshold,
+				Message:   describe(rule, storeId, status, check.value),
+				Timestamp: now.UTC(),
+			}
+			wg.Add(1)
+			go func(rule *models.AlertRule) {
+				defer wg.Done()
+				if err := as.notifier.Notify(ctx, rule, notification); err != nil {
+					slog.ErrorContext(ctx, "couldn't notify webhook", slog.String("rule_id", rule.ID), slog.Any("error", err))
+				}
+			}(rule)
+		}
+	}
+	wg.Wait()
+	return errors.Join(errs...)
+}
+
+// Run evaluates the rules every interval until ctx is done, and records the
+// stores of the sales published to the broker meanwhile.
+func (as *alertService) Run(ctx context.Context, interval time.Duration) {
+	if as.broker != nil {
+		var wg sync.WaitGroup
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			as.watchStores(ctx)
+		}()
+		defer wg.Wait()
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			if err := as.Evaluate(ctx, now); err != nil {
+				slog.ErrorContext(ctx, "couldn't evaluate alert rules", slog.Any("error", err))
+			}
+		}
+	}
+}
+
+// ruleCheck is the outcome of a rule for a store, or for the rule's own store
+// or all stores when storeId is empty.
+type ruleCheck struct {
+	storeId string
+	firing  bool
+	value   float64
+}
+
+func (as *alertService) check(ctx context.Context, rule *models.AlertRule, now time.Time) ([]ruleCheck, error) {
+	window, err := ruleWindow(rule)
+	if err != nil {
+		return nil, err
+	}
+	start := now.Add(-window)
+
+	switch rule.Type {
+	case models.AlertTypeThreshold:
+		total, err := as.data.CalculateSales(ctx, start, now, rule.StoreId)
+		if err != nil {
+			return nil, err
+		}
+		value, _ := total.Float64()
+		return []ruleCheck{{firing: compare(value, rule.Comparator, rule.Threshold), value: roundTo(value, 2)}}, nil
+	case models.AlertTypeNoSales:
+		if rule.StoreId == "" {
+			return as.checkStores(ctx, start, now)
+		}
+		sales, err := as.data.GetSalesInRange(ctx, start, now, rule.StoreId)
+		if err != nil {
+			return nil, err
+		}
+		return []ruleCheck{{firing: len(sales) == 0, value: float64(len(sales))}}, nil
+	}
+	return nil, fmt.Errorf("%w: unknown type %q", ErrInvalidAlertRule, rule.Type)
+}
+
+// watchStores records the store of every sale published to the broker until
+// ctx is done, resubscribing when it falls behind.
+func (as *alertService) watchStores(ctx context.Context) {
+	var lastID uint64
+	for {
+		sub, replay := as.broker.Subscribe(lastID)
+		for _, event := range replay {
+			lastID = event.ID
+			as.seeStore(event.Sale.StoreId)
+		}
+	consume:
+		for {
+			select {
+			case <-ctx.Done():
+				sub.Close()
+				return
+			case event, ok := <-sub.Events():
+				if !ok {
+					break consume
+				}
+				lastID = event.ID
+				as.seeStore(event.Sale.StoreId)
+			}
+		}
+		if as.broker.Closed() {
+			return
+		}
+	}
+}
+
+func (as *alertService) seeStore(storeId string) {
+	as.mu.Lock()
+	defer as.mu.Unlock()
+	as.stores[storeId] = true
+}
+
+// checkStores counts the sales of every known store between start and now
+// with a lookup per store, and checks for each that it made some. The first
+// check scans all sales to find the stores that sold before. Without any
+// store, the check is for all stores.
+func (as *alertService) checkStores(ctx context.Context, start time.Time, now time.Time) ([]ruleCheck, error) {
+	as.mu.Lock()
+	scanned := as.scanned
+	as.mu.Unlock()
+	if !scanned {
+		sales, err := as.data.GetAllSales(ctx)
+		if err != nil {
+			return nil, err
+		}
+		as.mu.Lock()
+		for _, sale := range sales {
+			as.stores[sale.StoreId] = true
+		}
+		as.scanned = true
+		as.mu.Unlock()
+	}
+
+	storeIds, err := as.knownStores(ctx)
+	if err != nil {
+		return nil, err
+	}
+	counts := make(map[string]int)
+	for _, storeId := range storeIds {
+		sales, err := as.data.GetSalesInRange(ctx, start, now, storeId)
+		if err != nil {
+			return nil, err
+		}
+		counts[storeId] = len(sales)
+	}
+
+	var checks []ruleCheck
+	total := 0
+	for storeId, count := range counts {
+		checks = append(checks, ruleCheck{storeId: storeId, firing: count == 0, value: float64(count)})
+		total += count
+	}
+	checks = append(checks, ruleCheck{firing: len(counts) == 0, value: float64(total)})
+	sort.Slice(checks, func(i, j int) bool { return checks[i].storeId < checks[j].storeId })
+	return checks, nil
+}
+
+// knownStores returns the active stores of the catalog and the stores seen
+// with sales that the catalog doesn't list as inactive, ordered by ID.
+func (as *alertService) knownStores(ctx context.Context) ([]string, error) {
+	known := make(map[string]bool)
+	inactive := make(map[string]bool)
+	if as.catalog != nil {
+		stores, err := as.catalog.GetAllStores(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, store := range stores {
+			if store.Active {
+				known[store.ID] = true
+			} else {
+				inactive[store.ID] = true
+			}
+		}
+	}
+	as.mu.Lock()
+	for storeId := range as.stores {
+		if !inactive[storeId] {
+			known[storeId] = true
+		}
+	}
+	as.mu.Unlock()
+
+	storeIds := make([]string, 0, len(known))
+	for storeId := range known {
+		storeIds = append(storeIds, storeId)
+	}
+	sort.Strings(storeIds)
+	return storeIds, nil
+}
+
+// transition records the new state of the rule for a store and returns the
+// notification status to send, or an empty string when nothing changed.
+func (as *alertService) transition(ruleId string, storeId string, firing bool) string {
+	as.mu.Lock()
+	defer as.mu.Unlock()
+	if as.states[ruleId] == nil {
+		as.states[ruleId] = make(map[string]string)
+	}
+	previous := as.states[ruleId][storeId]
+	switch {
+	case firing && previous != models.AlertStateFiring:
+		as.states[ruleId][storeId] = models.AlertStateFiring
+		return models.AlertStateFiring
+	case !firing && previous == models.AlertStateFiring:
+		as.states[ruleId][storeId] = models.AlertStateOk
+		return models.AlertStateResolved
+	}
+	return ""
+}
+
+// state returns firing while the rule fires for any store.
+func (as *alertService) state(ruleId string) string {
+	as.mu.Lock()
+	defer as.mu.Unlock()
+	for _, state := range as.states[ruleId] {
+		if state == models.AlertStateFiring {
+			return state
+		}
+	}
+	return models.AlertStateOk
+}
+
+func validateRule(rule *models.AlertRule) error {
+	if rule.Name == "" {
+		return fmt.Errorf("%w: name is required", ErrInvalidAlertRule)
+	}
+	switch rule.Type {
+	case models.AlertTypeThreshold:
+		if rule.StoreId == "" {
+			return fmt.Errorf("%w: threshold rules require a store_id", ErrInvalidAlertRule)
+		}
+		if !validComparator(rule.Comparator) {
+			return fmt.Errorf("%w: unsupported comparator %q", ErrInvalidAlertRule, rule.Comparator)
+		}
+	case models.AlertTypeNoSales:
+		if rule.Window == "" {
+			return fmt.Errorf("%w: no_sales rules require a window", ErrInvalidAlertRule)
+		}
+	default:
+		return fmt.Errorf("%w: unsupported type %q", ErrInvalidAlertRule, rule.Type)
+	}
+	if _, err := ruleWindow(rule); err != nil {
+		return err
+	}
+	u, err := url.Parse(rule.WebhookURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("%w: webhook_url must be an absolute http(s) URL", ErrInvalidAlertRule)
+	}
+	return nil
+}
+
+func ruleWindow(rule *models.AlertRule) (time.Duration, error) {
+	if rule.Window == "" {
+		return defaultThresholdWindow, nil
+	}
+	window, err := time.ParseDuration(rule.Window)
+	if err != nil || window <= 0 {
+		return 0, fmt.Errorf("%w: window must be a positive duration", ErrInvalidAlertRule)
+	}
+	return window, nil
+}
+
+func validComparator(comparator string) bool {
+	switch comparator {
+	case "<", "<=", ">", ">=":
+		return true
+	}
+	return false
+}
+
+func compare(value float64, comparator string, threshold float64) bool {
+	switch comparator {
+	case "<":
+		return value < threshold
+	case "<=":
+		return value <= threshold
+	case ">":
+		return value > threshold
+	case ">=":
+		return value >= threshold
+	}
+	return false
+}
+
+func describe(rule *models.AlertRule, storeId string, status string, value float64) string {
+	subject := "all stores"
+	if storeId != "" {
+		subject = "store " + storeId
+	}
+	window, _ := ruleWindow(rule)
+	if rule.Type == models.AlertTypeNoSales {
+		if status == models.AlertStateFiring {
+			return fmt.Sprintf("no sales for %s in the last %s", subject, window)
+		}
+		return fmt.Sprintf("%s recorded %.0f sales in the last %s", subject, value, window)
+	}
+	return fmt.Sprintf("revenue for %s over the last %s is %.2f (rule: %s %.2f)", subject, window, value, rule.Comparator, rule.Threshold)
+}
@@ -0,0 +1,299 @@
+package services
+
+import (
+	"context"
+	"dataflow/models"
+	"dataflow/repo"
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+)
+
+type webhookReceiver struct {
+	*httptest.Server
+	mu            sync.Mutex
+	notifications []models.AlertNotification
+}
+
+func newWebhookReceiver() *webhookReceiver {
+	receiver := &webhookReceiver{}
+	receiver.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var notification models.AlertNotification
+		json.NewDecoder(r.Body).Decode(&notification)
+		receiver.mu.Lock()
+		receiver.notifications = append(receiver.notifications, notification)
+		receiver.mu.Unlock()
+	}))
+	return receiver
+}
+
+func (r *webhookReceiver) received() []models.AlertNotification {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]models.AlertNotification(nil), r.notifications...)
+}
+
+func newTestAlertService(data DataService) AlertService {
+	alertRepo := repo.NewInMemoryAlertRepository()
+	return NewAlertService(alertRepo, data, nil, nil, NewWebhookNotifier(alertRepo, testWebhookOptions))
+}
+
+func TestAlertService_CreateRule_Invalid(t *testing.T) {
+	service := newTestAlertService(new(MockService))
+
+	rules := []*models.AlertRule{
+		{Name: "", Type: models.AlertTypeThreshold, StoreId: "6789", Comparator: "<", WebhookURL: "http://localhost"},
+		{Name: "x", Type: "unknown", WebhookURL: "http://localhost"},
+		{Name: "x", Type: models.AlertTypeThreshold, Comparator: "<", WebhookURL: "http://localhost"},
+		{Name: "x", Type: models.AlertTypeThreshold, StoreId: "6789", Comparator: "!=", WebhookURL: "http://localhost"},
+		{Name: "x", Type: models.AlertTypeNoSales, WebhookURL: "http://localhost"},
+		{Name: "x", Type: models.AlertTypeNoSales, Window: "soon", WebhookURL: "http://localhost"},
+		{Name: "x", Type: models.AlertTypeNoSales, Window: "2h", WebhookURL: "localhost"},
+	}
+	for _, rule := range rules {
+		assert.ErrorIs(t, service.CreateRule(rule), ErrInvalidAlertRule)
+	}
+}
+
+func TestAlertService_UpdateRule_KeepsSecret(t *testing.T) {
+	service := newTestAlertService(new(MockService))
+
+	rule := &models.AlertRule{Name: "quiet", Type: models.AlertTypeNoSales, Window: "2h", WebhookURL: "http://localhost", WebhookSecret: "s3cret"}
+	assert.Nil(t, service.CreateRule(rule))
+
+	update := &models.AlertRule{ID: rule.ID, Name: "quiet", Type: models.AlertTypeNoSales, Window: "3h", WebhookURL: "http://localhost"}
+	assert.Nil(t, service.UpdateRule(update))
+
+	stored, err := service.GetRule(rule.ID)
+	assert.Nil(t, err)
+	assert.Equal(t, "3h", stored.Window)
+	assert.Equal(t, "s3cret", stored.WebhookSecret)
+}
+
+func TestAlertService_CreateRule_GeneratesSecret(t *testing.T) {
+	var mu sync.Mutex
+	var signature, timestamp string
+	var body []byte
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		signature, timestamp = r.Header.Get(SignatureHeader), r.Header.Get(TimestampHeader)
+		body, _ = io.ReadAll(r.Body)
+	}))
+	defer receiver.Close()
+
+	mockService := new(MockService)
+	service := newTestAlertService(mockService)
+
+	rule := &models.AlertRule{Name: "quiet", Type: models.AlertTypeNoSales, StoreId: "6789", Window: "2h", WebhookURL: receiver.URL}
+	assert.Nil(t, service.CreateRule(rule))
+	assert.Len(t, rule.WebhookSecret, 64)
+
+	other := &models.AlertRule{Name: "quiet", Type: models.AlertTypeNoSales, StoreId: "6789", Window: "2h", WebhookURL: receiver.URL}
+	assert.Nil(t, service.CreateRule(other))
+	assert.NotEqual(t, rule.WebhookSecret, other.WebhookSecret)
+	assert.Nil(t, service.DeleteRule(other.ID))
+
+	mockService.On("GetSalesInRange", mock.Anything, mock.Anything, "6789").Return([]*models.Sale{}, nil)
+	assert.Nil(t, service.Evaluate(context.Background(), time.Now()))
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, SignPayload(rule.WebhookSecret, timestamp, body), signature)
+}
+
+func TestAlertService_Evaluate_ThresholdFiresAndResolves(t *testing.T) {
+	receiver := newWebhookReceiver()
+	defer receiver.Close()
+
+	mockService := new(MockService)
+	service := newTestAlertService(mockService)
+
+	rule := &models.AlertRule{Name: "low revenue", Type: models.AlertTypeThreshold, StoreId: "6789", Comparator: "<", Threshold: 500, WebhookURL: receiver.URL}
+	assert.Nil(t, service.CreateRule(rule))
+
+	now := time.Date(2024, 6, 15, 14, 30, 0, 0, time.UTC)
+	calculate := mockService.On("CalculateSales", now.Add(-24*time.Hour), now, "6789").Return(new(big.Float).SetFloat64(120), nil)
+
+	assert.Nil(t, service.Evaluate(context.Background(), now))
+	assert.Nil(t, service.Evaluate(context.Background(), now))
+
+	stored, _ := service.GetRule(rule.ID)
+	assert.Equal(t, models.AlertStateFiring, stored.State)
+
+	calculate.Unset()
+	mockService.On("CalculateSales", now.Add(-24*time.Hour), now, "6789").Return(new(big.Float).SetFloat64(800), nil)
+	assert.Nil(t, service.Evaluate(context.Background(), now))
+
+	notifications := receiver.received()
+	assert.Len(t, notifications, 2)
+	assert.Equal(t, models.AlertStateFiring, notifications[0].Status)
+	assert.Equal(t, 120.0, notifications[0].Value)
+	assert.Equal(t, models.AlertStateResolved, notifications[1].Status)
+	assert.Equal(t, 800.0, notifications[1].Value)
+}
+
+func TestAlertService_Evaluate_NoSalesForAnyStore(t *testing.T) {
+	receiver := newWebhookReceiver()
+	defer receiver.Close()
+
+	mockService := new(MockService)
+	service := newTestAlertService(mockService)
+
+	rule := &models.AlertRule{Name: "quiet", Type: models.AlertTypeNoSales, Window: "2h", WebhookURL: receiver.URL}
+	assert.Nil(t, service.CreateRule(rule))
+
+	now := time.Date(2024, 6, 15, 14, 30, 0, 0, time.UTC)
+	mockService.On("GetAllSales").Return([]*models.Sale{}, nil)
+
+	assert.Nil(t, service.Evaluate(context.Background(), now))
+
+	notifications := receiver.received()
+	assert.Len(t, notifications, 1)
+	assert.Equal(t, models.AlertStateFiring, notifications[0].Status)
+	assert.Empty(t, notifications[0].StoreId)
+	assert.Contains(t, notifications[0].Message, "all stores")
+}
+
+func TestAlertService_Evaluate_NoSalesPerStore(t *testing.T) {
+	receiver := newWebhookReceiver()
+	defer receiver.Close()
+
+	mockService := new(MockService)
+	service := newTestAlertService(mockService)
+
+	rule := &models.AlertRule{Name: "quiet", Type: models.AlertTypeNoSales, Window: "2h", WebhookURL: receiver.URL}
+	assert.Nil(t, service.CreateRule(rule))
+
+	// The stores are found in all sales once, then looked up one by one.
+	now := time.Date(2024, 6, 15, 14, 30, 0, 0, time.UTC)
+	mockService.On("GetAllSales").Return([]*models.Sale{
+		{StoreId: "6789", QuantitySold: 1, SalePrice: 10, SaleDate: now.Add(-3 * time.Hour)},
+		{StoreId: "9876", QuantitySold: 1, SalePrice: 10, SaleDate: now.Add(-time.Hour)},
+	}, nil).Once()
+	mockService.On("GetSalesInRange", now.Add(-2*time.Hour), now, "6789").Return([]*models.Sale{}, nil)
+	mockService.On("GetSalesInRange", now.Add(-2*time.Hour), now, "9876").Return([]*models.Sale{{StoreId: "9876", SaleDate: now.Add(-time.Hour)}}, nil)
+	assert.Nil(t, service.Evaluate(context.Background(), now))
+
+	notifications := receiver.received()
+	if assert.Len(t, notifications, 1) {
+		assert.Equal(t, models.AlertStateFiring, notifications[0].Status)
+		assert.Equal(t, "6789", notifications[0].StoreId)
+		assert.Equal(t, "no sales for store 6789 in the last 2h0m0s", notifications[0].Message)
+	}
+	rule, _ = service.GetRule(rule.ID)
+	assert.Equal(t, models.AlertStateFiring, rule.State)
+
+	later := now.Add(time.Hour)
+	mockService.On("GetSalesInRange", later.Add(-2*time.Hour), later, "6789").Return([]*models.Sale{{StoreId: "6789", SaleDate: later}}, nil)
+	mockService.On("GetSalesInRange", later.Add(-2*time.Hour), later, "9876").Return([]*models.Sale{}, nil)
+	assert.Nil(t, service.Evaluate(context.Background(), later))
+
+	notifications = receiver.received()[1:]
+	if assert.Len(t, notifications, 2) {
+		sort.Slice(notifications, func(i, j int) bool { return notifications[i].StoreId < notifications[j].StoreId })
+		assert.Equal(t, models.AlertStateResolved, notifications[0].Status)
+		assert.Equal(t, "6789", notifications[0].StoreId)
+		assert.Equal(t, models.AlertStateFiring, notifications[1].Status)
+		assert.Equal(t, "9876", notifications[1].StoreId)
+	}
+	mockService.AssertNumberOfCalls(t, "GetAllSales", 1)
+}
+
+func TestAlertService_Evaluate_NoSalesNewStore(t *testing.T) {
+	receiver := newWebhookReceiver()
+	defer receiver.Close()
+
+	mockService := new(MockService)
+	alertRepo := repo.NewInMemoryAlertRepository()
+	broker := NewSaleBroker(10, 10)
+	service := NewAlertService(alertRepo, mockService, nil, broker, NewWebhookNotifier(alertRepo, testWebhookOptions))
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		service.Run(ctx, time.Hour)
+	}()
+	defer func() {
+		cancel()
+		<-stopped
+	}()
+	assert.Eventually(t, func() bool { return broker.Subscribers() == 1 }, time.Second, time.Millisecond)
+
+	rule := &models.AlertRule{Name: "quiet", Type: models.AlertTypeNoSales, Window: "2h", WebhookURL: receiver.URL}
+	assert.Nil(t, service.CreateRule(rule))
+
+	now := time.Date(2024, 6, 15, 14, 30, 0, 0, time.UTC)
+	sale := &models.Sale{StoreId: "6789", QuantitySold: 1, SalePrice: 10, SaleDate: now}
+	mockService.On("GetAllSales").Return([]*models.Sale{sale}, nil).Once()
+	mockService.On("GetSalesInRange", mock.Anything, mock.Anything, "6789").Return([]*models.Sale{sale}, nil)
+	assert.Nil(t, service.Evaluate(context.Background(), now))
+	assert.Empty(t, receiver.received())
+
+	// Store 9876 first sells after the first evaluation, then goes quiet.
+	broker.Publish(&models.Sale{StoreId: "9876", QuantitySold: 1, SalePrice: 10, SaleDate: now.Add(time.Minute)})
+	assert.Eventually(t, func() bool {
+		stores, _ := service.(*alertService).knownStores(context.Background())
+		return len(stores) == 2
+	}, time.Second, time.Millisecond)
+
+	later := now.Add(3 * time.Hour)
+	mockService.On("GetSalesInRange", later.Add(-2*time.Hour), later, "9876").Return([]*models.Sale{}, nil)
+	assert.Nil(t, service.Evaluate(context.Background(), later))
+
+	notifications := receiver.received()
+	if assert.Len(t, notifications, 1) {
+		assert.Equal(t, models.AlertStateFiring, notifications[0].Status)
+		assert.Equal(t, "9876", notifications[0].StoreId)
+	}
+	mockService.AssertNumberOfCalls(t, "GetAllSales", 1)
+}
+
+func TestAlertService_Evaluate_NoSalesCatalogStores(t *testing.T) {
+	receiver := newWebhookReceiver()
+	defer receiver.Close()
+
+	mockService := new(MockService)
+	alertRepo := repo.NewInMemoryAlertRepository()
+	catalog := newTestCatalog(repo.NewInMemoryRepository())
+	catalog.CreateStore(context.Background(), &models.Store{ID: "1234", Name: "Uptown", Active: true})
+	service := NewAlertService(alertRepo, mockService, catalog, nil, NewWebhookNotifier(alertRepo, testWebhookOptions))
+
+	rule := &models.AlertRule{Name: "quiet", Type: models.AlertTypeNoSales, Window: "2h", WebhookURL: receiver.URL}
+	assert.Nil(t, service.CreateRule(rule))
+
+	// Store 9876 is closed, so it isn't expected to sell, and store 5555
+	// sold without being in the catalog.
+	now := time.Date(2024, 6, 15, 14, 30, 0, 0, time.UTC)
+	mockService.On("GetAllSales").Return([]*models.Sale{{StoreId: "9876"}, {StoreId: "5555"}}, nil)
+	mockService.On("GetSalesInRange", now.Add(-2*time.Hour), now, "5555").Return([]*models.Sale{{StoreId: "5555", SaleDate: now}}, nil)
+	mockService.On("GetSalesInRange", now.Add(-2*time.Hour), now, "1234").Return([]*models.Sale{{StoreId: "1234", SaleDate: now}}, nil)
+	mockService.On("GetSalesInRange", now.Add(-2*time.Hour), now, "6789").Return([]*models.Sale{}, nil)
+	assert.Nil(t, service.Evaluate(context.Background(), now))
+
+	notifications := receiver.received()
+	if assert.Len(t, notifications, 1) {
+		assert.Equal(t, models.AlertStateFiring, notifications[0].Status)
+		assert.Equal(t, "6789", notifications[0].StoreId)
+	}
+}
+
+func TestAlertService_Evaluate_ReportsErrors(t *testing.T) {
+	mockService := new(MockService)
+	service := newTestAlertService(mockService)
+
+	rule := &models.AlertRule{Name: "low revenue", Type: models.AlertTypeThreshold, StoreId: "6789", Comparator: "<", Threshold: 500, WebhookURL: "http://localhost"}
+	assert.Nil(t, service.CreateRule(rule))
+
+	mockService.On("CalculateSales", mock.Anything, mock.Anything, "6789").Return((*big.Float)(nil), assert.AnError)
+
+	err := service.Evaluate(context.Background(), time.Now())
+	assert.ErrorIs(t, err, assert.AnError)
+}
@@ -0,0 +1,255 @@
+package services
+
+import (
+	"context"
+	"dataflow/models"
+	"dataflow/repo"
+	"fmt"
+	"math"
+	"math/big"
+	"sort"
+	"time"
+)
+
+const (
+	DirectionAbove = "above"
+	DirectionBelow = "below"
+)
+
+// madScale converts a median absolute deviation into an estimate of the
+// standard deviation for normally distributed data.
+const madScale = 1.4826
+
+type AnomalyOptions struct {
+	// Window is the number of preceding days that form the baseline of a day.
+	Window int
+	// MinHistory is the number of baseline days required before a day is scored.
+	MinHistory int
+	// Threshold is the robust z-score above which a day is flagged.
+	Threshold float64
+}
+
+var DefaultAnomalyOptions = AnomalyOptions{
+	Window:     28,
+	MinHistory: 7,
+	Threshold:  3.5,
+}
+
+type AnomalyService interface {
+	DetectAnomalies(ctx context.Context, from time.Time, to time.Time, storeId string) ([]*models.Anomaly, error)
+}
+
+type anomalyService struct {
+	data    DataService
+	catalog CatalogService
+	opts    AnomalyOptions
+}
+
+// NewAnomalyService takes the stores' time zones from catalog, which may be
+// nil to score UTC days for every store.
+func NewAnomalyService(data DataService, catalog CatalogService, opts AnomalyOptions) AnomalyService {
+	if opts.Window <= 0 {
+		opts.Window = DefaultAnomalyOptions.Window
+	}
+	if opts.MinHistory <= 0 || opts.MinHistory > opts.Window {
+		opts.MinHistory = min(DefaultAnomalyOptions.MinHistory, opts.Window)
+	}
+	if opts.Threshold <= 0 {
+		opts.Threshold = DefaultAnomalyOptions.Threshold
+	}
+	return &anomalyService{data: data, catalog: catalog, opts: opts}
+}
+
+// DetectAnomalies scores the daily revenue of every store between the dates of
+// from and to (inclusive) against the median and MAD of the preceding window.
+// Days are those of the store's time zone, or UTC days for stores without one.
+// Days without sales count as zero revenue once a store has made its first
+// sale, so outages show up as drops. Zero bounds default to the first and
+// last sale found.
+func (as *anomalyService) DetectAnomalies(ctx context.Context, from time.Time, to time.Time, storeId string) ([]*models.Anomaly, error) {
+	from = truncateDay(from)
+	to = truncateDay(to)
+	if !from.IsZero() && !to.IsZero() && from.After(to) {
+		return nil, ErrWrongDate
+	}
+
+	var historyStart, end time.Time
+	if !from.IsZero() {
+		historyStart = from.AddDate(0, 0, -as.opts.Window)
+	}
+	if !to.IsZero() {
+		end = to.AddDate(0, 0, 1)
+	}
+	var locations map[string]*time.Location
+	if as.catalog != nil {
+		var err error
+		if locations, err = as.catalog.StoreLocations(ctx); err != nil {
+			return nil, fmt.Errorf("couldn't detect anomalies: %w", err)
+		}
+	}
+	sales, err := as.fetchSales(ctx, historyStart, end, storeId, locations)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't detect anomalies: %w", err)
+	}
+
+	revenue, firstDay, lastDay := dailyRevenue(sales, locations)
+	if !to.IsZero() {
+		lastDay = to
+	}
+
+	anomalies := make([]*models.Anomaly, 0)
+	for store, days := range revenue {
+		start := firstDay[store]
+		if !from.IsZero() && from.After(start) {
+			start = from
+		}
+		for day := start; !day.After(lastDay); day = day.AddDate(0, 0, 1) {
+			baseline := make([]float64, 0, as.opts.Window)
+			for d := day.AddDate(0, 0, -as.opts.Window); d.Before(day); d = d.AddDate(0, 0, 1) {
+				if !d.Before(firstDay[store]) {
+					baseline = append(baseline, days[d])
+				}
+			}
+			if len(baseline) < as.opts.MinHistory {
+				continue
+			}
+			if anomaly := as.score(store, day, days[day], baseline); anomaly != nil {
+				anomalies = append(anomalies, anomaly)
+			}
+		}
+	}
+
+	sort.Slice(anomalies, func(i, j int) bool {
+		if anomalies[i].Date != anomalies[j].Date {
+			return anomalies[i].Date < anomalies[j].Date
+		}
+		return anomalies[i].StoreId < anomalies[j].StoreId
+	})
+	return anomalies, nil
+}
+
+// fetchSales returns the sales made from the day start until before the day
+// end, in the time zones of their stores. Zero days leave their side open.
+func (as *anomalyService) fetchSales(ctx context.Context, start time.Time, end time.Time, storeId string, locations map[string]*time.Location) ([]*models.Sale, error) {
+	if storeId != "" {
+		location := locationOf(locations, storeId)
+		return as.data.GetSalesInRange(ctx, repo.StartOfDay(start, location), repo.StartOfDay(end, location), storeId)
+	}
+	all, err := as.data.GetAllSales(ctx)
+	if err != nil {
+		return nil, err
+	}
+	sales := make([]*models.Sale, 0, len(all))
+	for _, sale := range all {
+		day := localDay(sale.SaleDate, locationOf(locations, sale.StoreId))
+		if (start.IsZero() || !day.Before(start)) && (end.IsZero() || day.Before(end)) {
+			sales = append(sales, sale)
+		}
+	}
+	return sales, nil
+}
+
+func (as *anomalyService) score(storeId string, day time.Time, value float64, baseline []float64) *models.Anomaly {
+	median := medianOf(baseline)
+	deviations := make([]float64, len(baseline))
+	for i, v := range baseline {
+		deviations[i] = math.Abs(v - median)
+	}
+	scale := madScale * medianOf(deviations)
+	if scale == 0 {
+		// A flat baseline has no spread, so fall back to one percent of the
+		// median (or one cent) to avoid flagging rounding noise.
+		scale = math.Max(math.Abs(median)*0.01, 0.01)
+	}
+
+	z := (value - median) / scale
+	if math.Abs(z) <= as.opts.Threshold {
+		return nil
+	}
+	direction := DirectionAbove
+	if z < 0 {
+		direction = DirectionBelow
+	}
+	return &models.Anomaly{
+		StoreId:  storeId,
+		Date:     day.Format(time.DateOnly),
+		Revenue:  roundTo(value, 2),
+		Baseline: roundTo(median, 2),
+		ExpectedRange: models.ExpectedRange{
+			Low:  roundTo(math.Max(0, median-as.opts.Threshold*scale), 2),
+			High: roundTo(median+as.opts.Threshold*scale, 2),
+		},
+		Severity:  roundTo(math.Abs(z), 2),
+		Direction: direction,
+	}
+}
+
+func dailyRevenue(sales []*models.Sale, locations map[string]*time.Location) (map[string]map[time.Time]float64, map[string]time.Time, time.Time) {
+	totals := make(map[string]map[time.Time]*big.Float)
+	firstDay := make(map[string]time.Time)
+	var lastDay time.Time
+	for _, sale := range sales {
+		day := localDay(sale.SaleDate, locationOf(locations, sale.StoreId))
+		if totals[sale.StoreId] == nil {
+			totals[sale.StoreId] = make(map[time.Time]*big.Float)
+		}
+		if totals[sale.StoreId][day] == nil {
+			totals[sale.StoreId][day] = new(big.Float).SetPrec(64)
+		}
+		totals[sale.StoreId][day].Add(totals[sale.StoreId][day], saleAmount(sale))
+		if first, ok := firstDay[sale.StoreId]; !ok || day.Before(first) {
+			firstDay[sale.StoreId] = day
+		}
+		if day.After(lastDay) {
+			lastDay = day
+		}
+	}
+
+	revenue := make(map[string]map[time.Time]float64, len(totals))
+	for store, days := range totals {
+		revenue[store] = make(map[time.Time]float64, len(days))
+		for day, total := range days {
+			revenue[store][day], _ = total.Float64()
+		}
+	}
+	return revenue, firstDay, lastDay
+}
+
+func truncateDay(t time.Time) time.Time {
+	if t.IsZero() {
+		return t
+	}
+	year, month, day := t.UTC().Date()
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+// localDay returns the date of t in location as a UTC midnight, so that days
+// of different time zones compare equal.
+func localDay(t time.Time, location *time.Location) time.Time {
+	year, month, day := t.In(location).Date()
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+// locationOf returns the time zone of a store in locations, UTC for stores
+// without one.
+func locationOf(locations map[string]*time.Location, storeId string) *time.Location {
+	if location, ok := locations[storeId]; ok {
+		return location
+	}
+	return time.UTC
+}
+
+func medianOf(values []float64) float64 {
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+	mid := len(sorted) / 2
+	if len(sorted)%2 == 0 {
+		return (sorted[mid-1] + sorted[mid]) / 2
+	}
+	return sorted[mid]
+}
+
+func roundTo(value float64, places int) float64 {
+	factor := math.Pow(10, float64(places))
+	return math.Round(value*factor) / factor
+}
@@ -0,0 +1,136 @@
+package services
+
+import (
+	"context"
+	"dataflow/models"
+	"dataflow/repo"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func dailySales(storeId string, start time.Time, revenues ...float64) []*models.Sale {
+	sales := make([]*models.Sale, 0, len(revenues))
+	for i, revenue := range revenues {
+		sales = append(sales, &models.Sale{
+			ProductId:    "12345",
+			StoreId:      storeId,
+			QuantitySold: 1,
+			SalePrice:    revenue,
+			SaleDate:     start.AddDate(0, 0, i).Add(12 * time.Hour),
+		})
+	}
+	return sales
+}
+
+func TestAnomalyService_DetectAnomalies_Drop(t *testing.T) {
+	mockService := new(MockService)
+	service := NewAnomalyService(mockService, nil, DefaultAnomalyOptions)
+
+	start := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	sales := dailySales("6789", start, 1000, 1020, 980, 1010, 990, 1005, 995, 1000, 1015, 985, 20)
+
+	mockService.On("GetAllSales").Return(sales, nil)
+
+	anomalies, err := service.DetectAnomalies(context.Background(), time.Time{}, time.Time{}, "")
+	assert.Nil(t, err)
+	assert.Len(t, anomalies, 1)
+	assert.Equal(t, "6789", anomalies[0].StoreId)
+	assert.Equal(t, "2024-06-11", anomalies[0].Date)
+	assert.Equal(t, 20.0, anomalies[0].Revenue)
+	assert.Equal(t, DirectionBelow, anomalies[0].Direction)
+	assert.Greater(t, anomalies[0].Severity, DefaultAnomalyOptions.Threshold)
+	assert.Less(t, anomalies[0].ExpectedRange.Low, 1000.0)
+	assert.Greater(t, anomalies[0].ExpectedRange.High, 1000.0)
+}
+
+func TestAnomalyService_DetectAnomalies_MissingDaysCountAsZero(t *testing.T) {
+	mockService := new(MockService)
+	service := NewAnomalyService(mockService, nil, DefaultAnomalyOptions)
+
+	start := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	sales := dailySales("6789", start, 500, 510, 490, 505, 495, 500, 500, 510)
+	to := time.Date(2024, 6, 9, 0, 0, 0, 0, time.UTC)
+
+	mockService.On("GetSalesInRange", time.Time{}, to.AddDate(0, 0, 1), "6789").Return(sales, nil)
+
+	anomalies, err := service.DetectAnomalies(context.Background(), time.Time{}, to, "6789")
+	assert.Nil(t, err)
+	assert.Len(t, anomalies, 1)
+	assert.Equal(t, "2024-06-09", anomalies[0].Date)
+	assert.Equal(t, 0.0, anomalies[0].Revenue)
+}
+
+func TestAnomalyService_DetectAnomalies_Spike(t *testing.T) {
+	mockService := new(MockService)
+	service := NewAnomalyService(mockService, nil, DefaultAnomalyOptions)
+
+	start := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	sales := append(
+		dailySales("6789", start, 100, 110, 90, 105, 95, 100, 100, 5000),
+		dailySales("9876", start, 300, 310, 290, 305, 295, 300, 300, 300)...,
+	)
+
+	mockService.On("GetAllSales").Return(sales, nil)
+
+	anomalies, err := service.DetectAnomalies(context.Background(), time.Time{}, time.Time{}, "")
+	assert.Nil(t, err)
+	assert.Len(t, anomalies, 1)
+	assert.Equal(t, "6789", anomalies[0].StoreId)
+	assert.Equal(t, DirectionAbove, anomalies[0].Direction)
+}
+
+func TestAnomalyService_DetectAnomalies_NotEnoughHistory(t *testing.T) {
+	mockService := new(MockService)
+	service := NewAnomalyService(mockService, nil, DefaultAnomalyOptions)
+
+	start := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	mockService.On("GetAllSales").Return(dailySales("6789", start, 100, 100, 5000), nil)
+
+	anomalies, err := service.DetectAnomalies(context.Background(), time.Time{}, time.Time{}, "")
+	assert.Nil(t, err)
+	assert.Empty(t, anomalies)
+}
+
+func TestAnomalyService_DetectAnomalies_WrongDate(t *testing.T) {
+	mockService := new(MockService)
+	service := NewAnomalyService(mockService, nil, DefaultAnomalyOptions)
+
+	from := time.Date(2024, 6, 20, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2024, 6, 10, 0, 0, 0, 0, time.UTC)
+
+	_, err := service.DetectAnomalies(context.Background(), from, to, "")
+	assert.ErrorIs(t, err, ErrWrongDate)
+}
+
+func TestAnomalyService_DetectAnomalies_StoreTimeZone(t *testing.T) {
+	mockService := new(MockService)
+	service := NewAnomalyService(mockService, newTestCatalog(repo.NewInMemoryRepository()), DefaultAnomalyOptions)
+
+	// Paris moves to summer time on March 31st, so the spike at 22:30 UTC is
+	// made at 00:30 on April 1st.
+	start := time.Date(2024, 3, 22, 0, 0, 0, 0, time.UTC)
+	sales := append(
+		dailySales("6789", start, 100, 100, 100, 100, 100, 100, 100, 100, 100, 100, 100),
+		&models.Sale{ProductId: "12345", StoreId: "6789", QuantitySold: 1, SalePrice: 5000, SaleDate: time.Date(2024, 3, 31, 22, 30, 0, 0, time.UTC)},
+	)
+	mockService.On("GetAllSales").Return(sales, nil)
+
+	anomalies, err := service.DetectAnomalies(context.Background(), time.Time{}, time.Time{}, "")
+	assert.Nil(t, err)
+	if assert.Len(t, anomalies, 1) {
+		assert.Equal(t, "2024-04-01", anomalies[0].Date)
+		assert.Equal(t, 5100.0, anomalies[0].Revenue)
+	}
+
+	// A single store's days are fetched from its local midnights.
+	paris, _ := time.LoadLocation("Europe/Paris")
+	day := time.Date(2024, 4, 1, 0, 0, 0, 0, time.UTC)
+	mockService.On("GetSalesInRange", time.Date(2024, 3, 4, 0, 0, 0, 0, paris), time.Date(2024, 4, 2, 0, 0, 0, 0, paris), "6789").Return(sales, nil)
+
+	anomalies, err = service.DetectAnomalies(context.Background(), day, day, "6789")
+	assert.Nil(t, err)
+	if assert.Len(t, anomalies, 1) {
+		assert.Equal(t, "2024-04-01", anomalies[0].Date)
+	}
+}
@@ -0,0 +1,156 @@
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"dataflow/auth"
+	"dataflow/models"
+	"dataflow/repo"
+	"dataflow/requestid"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+var ErrAuditChainBroken = errors.New("audit chain is broken")
+
+// AuditLog appends hash-chained entries to an AuditRepository. Every entry
+// stores the hash of its predecessor, so altering, inserting or removing an
+// entry is detected by VerifyAuditChain.
+type AuditLog struct {
+	entries repo.AuditRepository
+	now     func() time.Time
+
+	mu sync.Mutex
+}
+
+func NewAuditLog(entries repo.AuditRepository) *AuditLog {
+	return &AuditLog{entries: entries, now: time.Now}
+}
+
+// Record appends an entry for the principal and request ID in ctx. before and
+// after are snapshots of the changed object and may be nil.
+func (al *AuditLog) Record(ctx context.Context, action string, storeId string, before interface{}, after interface{}) (*models.AuditEntry, error) {
+	principal, _ := auth.PrincipalFromContext(ctx)
+	entry := &models.AuditEntry{
+		Principal: principal,
+		Action:    action,
+		StoreId:   storeId,
+		RequestId: requestid.FromContext(ctx),
+	}
+	var err error
+	if entry.Before, err = snapshot(before); err != nil {
+		return nil, fmt.Errorf("couldn't record audit entry: %w", err)
+	}
+	if entry.After, err = snapshot(after); err != nil {
+		return nil, fmt.Errorf("couldn't record audit entry: %w", err)
+	}
+
+	al.mu.Lock()
+	defer al.mu.Unlock()
+	last, err := al.entries.GetLast()
+	if err != nil {
+		return nil, fmt.Errorf("couldn't record audit entry: %w", err)
+	}
+	entry.Sequence = 1
+	if last != nil {
+		entry.Sequence = last.Sequence + 1
+		entry.PrevHash = last.Hash
+	}
+	entry.Timestamp = al.now().UTC()
+	entry.Hash = HashAuditEntry(entry)
+	if err := al.entries.Append(entry); err != nil {
+		return nil, fmt.Errorf("couldn't record audit entry: %w", err)
+	}
+	return entry, nil
+}
+
+func (al *AuditLog) GetEntries(filter repo.AuditFilter) ([]*models.AuditEntry, error) {
+	entries, err := al.entries.GetEntries(filter)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't get audit entries: %w", err)
+	}
+	return entries, nil
+}
+
+// Verify checks the whole chain.
+func (al *AuditLog) Verify() (*models.AuditVerification, error) {
+	entries, err := al.entries.GetEntries(repo.AuditFilter{})
+	if err != nil {
+		return nil, fmt.Errorf("couldn't verify audit log: %w", err)
+	}
+	return VerifyAuditChain(entries), nil
+}
+
+// HashAuditEntry returns the hex SHA-256 of the entry's JSON encoding with
+// an empty hash field.
+func HashAuditEntry(entry *models.AuditEntry) string {
+	unhashed := *entry
+	unhashed.Hash = ""
+	data, _ := json.Marshal(&unhashed)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// VerifyAuditChain checks that sequence numbers are contiguous from 1, that
+// every entry links to its predecessor's hash and that every hash matches the
+// entry's content.
+func VerifyAuditChain(entries []*models.AuditEntry) *models.AuditVerification {
+	verification := &models.AuditVerification{Valid: true, Entries: len(entries)}
+	prevHash := ""
+	for i, entry := range entries {
+		var problem string
+		switch {
+		case entry.Sequence != uint64(i+1):
+			problem = fmt.Sprintf("expected sequence %d", i+1)
+		case entry.PrevHash != prevHash:
+			problem = "previous hash mismatch"
+		case entry.Hash != HashAuditEntry(entry):
+			problem = "hash mismatch"
+		}
+		if problem != "" {
+			verification.Valid = false
+			verification.Sequence = entry.Sequence
+			verification.Error = fmt.Sprintf("%v at sequence %d: %s", ErrAuditChainBroken, entry.Sequence, problem)
+			return verification
+		}
+		prevHash = entry.Hash
+	}
+	return verification
+}
+
+func snapshot(v interface{}) (json.RawMessage, error) {
+	if v == nil {
+		return nil, nil
+	}
+	return json.Marshal(v)
+}
+
+// auditingDataService records every mutation in the audit log before it is
+// committed, so a stored sale always has an entry. A commit that fails after
+// its entry was written is followed by a failure entry.
+type auditingDataService struct {
+	DataService
+	audit *AuditLog
+}
+
+func NewAuditingDataService(inner DataService, audit *AuditLog) DataService {
+	return &auditingDataService{DataService: inner, audit: audit}
+}
+
+func (as *auditingDataService) AddSale(ctx context.Context, sale *models.Sale) error {
+	if _, err := as.audit.Record(ctx, models.AuditActionAddSale, sale.StoreId, nil, sale); err != nil {
+		return fmt.Errorf("couldn't add sale: %w", err)
+	}
+	if err := as.DataService.AddSale(ctx, sale); err != nil {
+		if _, auditErr := as.audit.Record(ctx, models.AuditActionAddSaleFailed, sale.StoreId, sale, nil); auditErr != nil {
+			slog.ErrorContext(ctx, "couldn't record failed sale in the audit log", slog.String("store_id", sale.StoreId), slog.Any("error", auditErr))
+		}
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,111 @@
+package services
+
+import (
+	"context"
+	"dataflow/auth"
+	"dataflow/models"
+	"dataflow/repo"
+	"dataflow/requestid"
+	"encoding/json"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"testing"
+)
+
+func auditContext() context.Context {
+	ctx := auth.WithPrincipal(context.Background(), &models.Principal{Subject: "pos", Method: auth.MethodAPIKey, Roles: []string{auth.RoleIntegration}})
+	return requestid.NewContext(ctx, "req-1")
+}
+
+func TestAuditLog_Record(t *testing.T) {
+	audit := NewAuditLog(repo.NewInMemoryAuditRepository())
+
+	first, err := audit.Record(auditContext(), models.AuditActionAddSale, "6789", nil, &models.Sale{ID: "a", StoreId: "6789"})
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(1), first.Sequence)
+	assert.Empty(t, first.PrevHash)
+	assert.Equal(t, "pos", first.Principal.Subject)
+	assert.Equal(t, "req-1", first.RequestId)
+	assert.Nil(t, first.Before)
+	assert.Contains(t, string(first.After), `"id":"a"`)
+
+	second, err := audit.Record(auditContext(), models.AuditActionAddSale, "6789", nil, &models.Sale{ID: "b", StoreId: "6789"})
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(2), second.Sequence)
+	assert.Equal(t, first.Hash, second.PrevHash)
+
+	verification, err := audit.Verify()
+	assert.Nil(t, err)
+	assert.True(t, verification.Valid)
+	assert.Equal(t, 2, verification.Entries)
+}
+
+func TestVerifyAuditChain_Tampering(t *testing.T) {
+	chain := func() []*models.AuditEntry {
+		audit := NewAuditLog(repo.NewInMemoryAuditRepository())
+		for _, id := range []string{"a", "b", "c"} {
+			audit.Record(auditContext(), models.AuditActionAddSale, "6789", nil, &models.Sale{ID: id, StoreId: "6789"})
+		}
+		entries, _ := audit.GetEntries(repo.AuditFilter{})
+		return entries
+	}
+
+	edited := chain()
+	edited[1].After = json.RawMessage(`{"id":"b","store_id":"9876"}`)
+	removed := chain()
+	removed = append(removed[:1], removed[2:]...)
+	rehashed := chain()
+	rehashed[1].StoreId = "9876"
+	rehashed[1].Hash = HashAuditEntry(rehashed[1])
+
+	for name, entries := range map[string][]*models.AuditEntry{"edited": edited, "removed": removed, "rehashed": rehashed} {
+		verification := VerifyAuditChain(entries)
+		assert.False(t, verification.Valid, name)
+		assert.NotZero(t, verification.Sequence, name)
+		assert.Contains(t, verification.Error, ErrAuditChainBroken.Error(), name)
+	}
+	assert.True(t, VerifyAuditChain(chain()).Valid)
+}
+
+func TestAuditingDataService_AddSale(t *testing.T) {
+	mockRepo := new(repo.MockRepository)
+	audit := NewAuditLog(repo.NewInMemoryAuditRepository())
+	service := NewAuditingDataService(NewDataService(mockRepo), audit)
+
+	sale := &models.Sale{ID: "a", StoreId: "6789"}
+	mockRepo.On("AddSale", sale).Return(nil).Once()
+	assert.Nil(t, service.AddSale(auditContext(), sale))
+
+	failed := &models.Sale{ID: "b", StoreId: "6789"}
+	mockRepo.On("AddSale", failed).Return(errors.New("boom")).Once()
+	assert.NotNil(t, service.AddSale(auditContext(), failed))
+
+	entries, _ := audit.GetEntries(repo.AuditFilter{})
+	if assert.Len(t, entries, 3) {
+		assert.Equal(t, models.AuditActionAddSale, entries[0].Action)
+		assert.Equal(t, "6789", entries[0].StoreId)
+		assert.Equal(t, models.AuditActionAddSale, entries[1].Action)
+		assert.Equal(t, models.AuditActionAddSaleFailed, entries[2].Action)
+		assert.NotEmpty(t, entries[2].Before)
+	}
+	assert.True(t, VerifyAuditChain(entries).Valid)
+}
+
+type failingAuditRepository struct {
+	repo.AuditRepository
+}
+
+func (failingAuditRepository) Append(*models.AuditEntry) error {
+	return errors.New("disk full")
+}
+
+func TestAuditingDataService_AddSale_AuditFailure(t *testing.T) {
+	mockRepo := new(repo.MockRepository)
+	audit := NewAuditLog(failingAuditRepository{repo.NewInMemoryAuditRepository()})
+	service := NewAuditingDataService(NewDataService(mockRepo), audit)
+
+	err := service.AddSale(auditContext(), &models.Sale{StoreId: "6789"})
+	assert.ErrorContains(t, err, "disk full")
+	mockRepo.AssertNotCalled(t, "AddSale", mock.Anything)
+}
@@ -0,0 +1,181 @@
+package services
+
+import (
+	"context"
+	"dataflow/auth"
+	"dataflow/models"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+)
+
+var ErrForbidden = errors.New("forbidden")
+
+// authorizingDataService enforces the permissions and store scope of the
+// principal in the request context before delegating to the wrapped service.
+type authorizingDataService struct {
+	DataService
+}
+
+func NewAuthorizingDataService(inner DataService) DataService {
+	return &authorizingDataService{DataService: inner}
+}
+
+// GetSale reports a sale of a store outside the caller's scope as forbidden.
+func (as *authorizingDataService) GetSale(ctx context.Context, id string) (*models.Sale, error) {
+	if _, err := authorize(ctx, auth.PermissionReadSales); err != nil {
+		return nil, err
+	}
+	sale, err := as.DataService.GetSale(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := authorizeStore(ctx, auth.PermissionReadSales, sale.StoreId); err != nil {
+		return nil, err
+	}
+	return sale, nil
+}
+
+// GetAllSales returns only the sales of stores the caller may access.
+func (as *authorizingDataService) GetAllSales(ctx context.Context) ([]*models.Sale, error) {
+	principal, err := authorize(ctx, auth.PermissionReadSales)
+	if err != nil {
+		return nil, err
+	}
+	sales, err := as.DataService.GetAllSales(ctx)
+	if err != nil || auth.Unscoped(principal) {
+		return sales, err
+	}
+	permitted := make([]*models.Sale, 0, len(sales))
+	for _, sale := range sales {
+		if auth.CanAccessStore(principal, sale.StoreId) {
+			permitted = append(permitted, sale)
+		}
+	}
+	return permitted, nil
+}
+
+func (as *authorizingDataService) GetSalesInRange(ctx context.Context, startDate time.Time, endDate time.Time, storeId string) ([]*models.Sale, error) {
+	if err := authorizeStore(ctx, auth.PermissionReadSales, storeId); err != nil {
+		return nil, err
+	}
+	return as.DataService.GetSalesInRange(ctx, startDate, endDate, storeId)
+}
+
+func (as *authorizingDataService) AddSale(ctx context.Context, sale *models.Sale) error {
+	if err := authorizeStore(ctx, auth.PermissionWriteSales, sale.StoreId); err != nil {
+		return err
+	}
+	return as.DataService.AddSale(ctx, sale)
+}
+
+func (as *authorizingDataService) CalculateSales(ctx context.Context, startDate time.Time, endDate time.Time, storeId string) (*big.Float, error) {
+	if err := authorizeStore(ctx, auth.PermissionCalculate, storeId); err != nil {
+		return nil, err
+	}
+	return as.DataService.CalculateSales(ctx, startDate, endDate, storeId)
+}
+
+// CalculateBatch expands "all" to the caller's permitted stores only and
+// reports explicitly requested stores outside the scope as forbidden cells.
+func (as *authorizingDataService) CalculateBatch(ctx context.Context, startDate time.Time, endDate time.Time, storeIds []string, operations []string) (*models.BatchResult, error) {
+	principal, err := authorize(ctx, auth.PermissionCalculate)
+	if err != nil {
+		return nil, err
+	}
+	if auth.Unscoped(principal) {
+		return as.DataService.CalculateBatch(ctx, startDate, endDate, storeIds, operations)
+	}
+
+	result, err := as.DataService.CalculateBatch(ctx, startDate, endDate, storeIds, operations)
+	if err != nil {
+		return nil, err
+	}
+	requested := make(map[string]bool, len(storeIds))
+	for _, storeId := range storeIds {
+		requested[storeId] = true
+	}
+	permitted := make([]string, 0, len(result.StoreIds))
+	for _, storeId := range result.StoreIds {
+		switch {
+		case auth.CanAccessStore(principal, storeId):
+			permitted = append(permitted, storeId)
+		case requested[storeId]:
+			cells := make(map[string]models.CalculationCell, len(operations))
+			for _, operation := range operations {
+				cells[operation] = models.CalculationCell{Error: ErrForbidden.Error()}
+			}
+			result.Cells[storeId] = cells
+			permitted = append(permitted, storeId)
+		default:
+			delete(result.Cells, storeId)
+		}
+	}
+	result.StoreIds = permitted
+	return result, nil
+}
+
+// Aggregate limits scoped callers to their stores: explicitly requested
+// stores outside the scope are forbidden, and a query for all stores only
+// covers the permitted ones.
+func (as *authorizingDataService) Aggregate(ctx context.Context, query models.AggregateQuery) ([]*models.AggregateGroup, error) {
+	principal, err := authorize(ctx, auth.PermissionCalculate)
+	if err != nil {
+		return nil, err
+	}
+	for _, storeId := range query.StoreIds {
+		if !auth.CanAccessStore(principal, storeId) {
+			return nil, fmt.Errorf("%w: no access to store %q", ErrForbidden, storeId)
+		}
+	}
+	if len(query.StoreIds) == 0 && !auth.Unscoped(principal) {
+		if len(principal.Stores) == 0 {
+			return nil, fmt.Errorf("%w: no access to any store", ErrForbidden)
+		}
+		query.StoreIds = principal.Stores
+	}
+	return as.DataService.Aggregate(ctx, query)
+}
+
+// Query limits scoped callers to their stores, like Aggregate.
+func (as *authorizingDataService) Query(ctx context.Context, query models.SalesQuery) (*models.QueryResult, error) {
+	principal, err := authorize(ctx, auth.PermissionReadSales)
+	if err != nil {
+		return nil, err
+	}
+	for _, storeId := range query.StoreIds {
+		if !auth.CanAccessStore(principal, storeId) {
+			return nil, fmt.Errorf("%w: no access to store %q", ErrForbidden, storeId)
+		}
+	}
+	if len(query.StoreIds) == 0 && !auth.Unscoped(principal) {
+		if len(principal.Stores) == 0 {
+			return nil, fmt.Errorf("%w: no access to any store", ErrForbidden)
+		}
+		query.StoreIds = principal.Stores
+	}
+	return as.DataService.Query(ctx, query)
+}
+
+func authorize(ctx context.Context, permission string) (*models.Principal, error) {
+	principal, ok := auth.PrincipalFromContext(ctx)
+	if !ok {
+		return nil, fmt.Errorf("%w: no principal", ErrForbidden)
+	}
+	if !auth.HasPermission(principal, permission) {
+		return nil, fmt.Errorf("%w: missing permission %s", ErrForbidden, permission)
+	}
+	return principal, nil
+}
+
+func authorizeStore(ctx context.Context, permission string, storeId string) error {
+	principal, err := authorize(ctx, permission)
+	if err != nil {
+		return err
+	}
+	if !auth.CanAccessStore(principal, storeId) {
+		return fmt.Errorf("%w: no access to store %q", ErrForbidden, storeId)
+	}
+	return nil
+}
@@ -0,0 +1,121 @@
+package services
+
+import (
+	"context"
+	"dataflow/auth"
+	"dataflow/models"
+	"dataflow/repo"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"testing"
+	"time"
+)
+
+func withRoles(roles []string, stores ...string) context.Context {
+	return auth.WithPrincipal(context.Background(), &models.Principal{Subject: "test", Roles: roles, Stores: stores})
+}
+
+func TestAuthorizingDataService_GetAllSales(t *testing.T) {
+	mockRepo := new(repo.MockRepository)
+	service := NewAuthorizingDataService(NewDataService(mockRepo))
+	mockRepo.On("GetAllSales").Return(batchSales(), nil)
+
+	sales, err := service.GetAllSales(withRoles([]string{auth.RoleStoreManager}, "6789"))
+	assert.Nil(t, err)
+	assert.Len(t, sales, 2)
+	for _, sale := range sales {
+		assert.Equal(t, "6789", sale.StoreId)
+	}
+
+	sales, err = service.GetAllSales(withRoles([]string{auth.RoleAnalyst}))
+	assert.Nil(t, err)
+	assert.Len(t, sales, 4)
+
+	_, err = service.GetAllSales(withRoles([]string{auth.RoleIntegration}))
+	assert.True(t, errors.Is(err, ErrForbidden))
+
+	_, err = service.GetAllSales(context.Background())
+	assert.True(t, errors.Is(err, ErrForbidden))
+}
+
+func TestAuthorizingDataService_GetSale(t *testing.T) {
+	mockRepo := new(repo.MockRepository)
+	service := NewAuthorizingDataService(NewDataService(mockRepo))
+	sales := batchSales()
+	mockRepo.On("GetSale", "1").Return(sales[0], nil)
+	mockRepo.On("GetSale", "3").Return(sales[2], nil)
+	mockRepo.On("GetSale", "missing").Return(nil, repo.ErrSaleNotFound)
+	ctx := withRoles([]string{auth.RoleStoreManager}, "6789")
+
+	sale, err := service.GetSale(ctx, "1")
+	assert.Nil(t, err)
+	assert.Equal(t, sales[0], sale)
+
+	_, err = service.GetSale(ctx, "3")
+	assert.True(t, errors.Is(err, ErrForbidden))
+
+	_, err = service.GetSale(ctx, "missing")
+	assert.True(t, errors.Is(err, repo.ErrSaleNotFound))
+
+	_, err = service.GetSale(withRoles([]string{auth.RoleIntegration}), "1")
+	assert.True(t, errors.Is(err, ErrForbidden))
+}
+
+func TestAuthorizingDataService_StoreScope(t *testing.T) {
+	mockRepo := new(repo.MockRepository)
+	service := NewAuthorizingDataService(NewDataService(mockRepo))
+	ctx := withRoles([]string{auth.RoleStoreManager}, "6789")
+	startDate := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	endDate := time.Date(2024, 6, 30, 0, 0, 0, 0, time.UTC)
+
+	mockRepo.On("GetSalesInRange", startDate, endDate, "6789").Return(batchSales()[:2], nil)
+
+	sales, err := service.GetSalesInRange(ctx, startDate, endDate, "6789")
+	assert.Nil(t, err)
+	assert.Len(t, sales, 2)
+
+	_, err = service.GetSalesInRange(ctx, startDate, endDate, "9876")
+	assert.True(t, errors.Is(err, ErrForbidden))
+
+	_, err = service.CalculateSales(ctx, startDate, endDate, "9876")
+	assert.True(t, errors.Is(err, ErrForbidden))
+
+	err = service.AddSale(ctx, &models.Sale{StoreId: "6789"})
+	assert.True(t, errors.Is(err, ErrForbidden))
+
+	mockRepo.AssertNotCalled(t, "GetSalesInRange", startDate, endDate, "9876")
+	mockRepo.AssertNotCalled(t, "AddSale", mock.Anything)
+}
+
+func TestAuthorizingDataService_AddSale(t *testing.T) {
+	mockRepo := new(repo.MockRepository)
+	service := NewAuthorizingDataService(NewDataService(mockRepo))
+	sale := &models.Sale{StoreId: "6789"}
+	mockRepo.On("AddSale", sale).Return(nil)
+
+	err := service.AddSale(withRoles([]string{auth.RoleIntegration}), sale)
+	assert.Nil(t, err)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestAuthorizingDataService_CalculateBatch(t *testing.T) {
+	mockRepo := new(repo.MockRepository)
+	service := NewAuthorizingDataService(NewDataService(mockRepo))
+	mockRepo.On("GetAllSales").Return(batchSales(), nil)
+	mockRepo.On("GetSalesInRange", time.Time{}, time.Time{}, "6789").Return(storeBatchSales("6789"), nil)
+	mockRepo.On("GetSalesInRange", time.Time{}, time.Time{}, "9876").Return(storeBatchSales("9876"), nil)
+	ctx := withRoles([]string{auth.RoleStoreManager}, "6789")
+
+	result, err := service.CalculateBatch(ctx, time.Time{}, time.Time{}, []string{AllStores}, []string{MetricSaleCount})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"6789"}, result.StoreIds)
+	assert.NotContains(t, result.Cells, "9876")
+
+	result, err = service.CalculateBatch(ctx, time.Time{}, time.Time{}, []string{"6789", "9876"}, []string{MetricSaleCount})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"6789", "9876"}, result.StoreIds)
+	assert.NotNil(t, result.Cells["6789"][MetricSaleCount].Value)
+	assert.Nil(t, result.Cells["9876"][MetricSaleCount].Value)
+	assert.Equal(t, "forbidden", result.Cells["9876"][MetricSaleCount].Error)
+}
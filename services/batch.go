@@ -0,0 +1,131 @@
+package services
+
+import (
+	"context"
+	"dataflow/models"
+	"dataflow/repo"
+	"fmt"
+	"math/big"
+	"sort"
+	"time"
+)
+
+const MetricAverageSale = "average_sale"
+
+// AllStores in a batch's store list expands to every store with sales in the
+// requested range.
+const AllStores = "all"
+
+// batchTotals keeps revenue exact, so that totals and averages don't depend
+// on how many sales they add up.
+type batchTotals struct {
+	revenue *big.Rat
+	units   int64
+	count   int64
+}
+
+// CalculateBatch computes every operation for every store in one pass over the
+// sales in range. Unsupported operations are reported in their cells instead
+// of failing the batch.
+func (ds *dataService) CalculateBatch(ctx context.Context, startDate time.Time, endDate time.Time, storeIds []string, operations []string) (*models.BatchResult, error) {
+	if !startDate.IsZero() && !endDate.IsZero() && startDate.After(endDate) {
+		return nil, ErrWrongDate
+	}
+	sales, err := ds.batchSales(ctx, startDate, endDate, storeIds)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't calculate sales: %w", err)
+	}
+
+	totals := make(map[string]*batchTotals)
+	for _, sale := range sales {
+		if !repo.InRange(sale.SaleDate, startDate, endDate) {
+			continue
+		}
+		t, ok := totals[sale.StoreId]
+		if !ok {
+			t = &batchTotals{revenue: new(big.Rat)}
+			totals[sale.StoreId] = t
+		}
+		t.revenue.Add(t.revenue, saleDecimal(sale))
+		t.units += int64(sale.QuantitySold)
+		t.count++
+	}
+
+	result := &models.BatchResult{
+		StoreIds:   expandStores(storeIds, totals),
+		Operations: operations,
+		Cells:      make(map[string]map[string]models.CalculationCell),
+	}
+	for _, storeId := range result.StoreIds {
+		t, ok := totals[storeId]
+		if !ok {
+			t = &batchTotals{revenue: new(big.Rat)}
+		}
+		cells := make(map[string]models.CalculationCell, len(operations))
+		for _, operation := range operations {
+			cells[operation] = t.cell(operation)
+		}
+		result.Cells[storeId] = cells
+	}
+	return result, nil
+}
+
+// batchSales looks up the sales of the listed stores, or scans all sales
+// when the batch asks for all stores.
+func (ds *dataService) batchSales(ctx context.Context, startDate time.Time, endDate time.Time, storeIds []string) ([]*models.Sale, error) {
+	seen := make(map[string]bool, len(storeIds))
+	lookup := make([]string, 0, len(storeIds))
+	for _, storeId := range storeIds {
+		if storeId == AllStores {
+			return ds.repo.GetAllSales(ctx)
+		}
+		if !seen[storeId] {
+			seen[storeId] = true
+			lookup = append(lookup, storeId)
+		}
+	}
+	return repo.GetSalesByStores(ctx, ds.repo, startDate, endDate, lookup)
+}
+
+func (t *batchTotals) cell(operation string) models.CalculationCell {
+	switch operation {
+	case MetricTotalSales:
+		return models.CalculationCell{Value: decimalFloat(t.revenue)}
+	case MetricUnitsSold:
+		return models.CalculationCell{Value: new(big.Float).SetInt64(t.units)}
+	case MetricSaleCount:
+		return models.CalculationCell{Value: new(big.Float).SetInt64(t.count)}
+	case MetricAverageSale:
+		if t.count == 0 {
+			return models.CalculationCell{Error: "no sales in range"}
+		}
+		return models.CalculationCell{Value: decimalFloat(new(big.Rat).Quo(t.revenue, new(big.Rat).SetInt64(t.count)))}
+	}
+	return models.CalculationCell{Error: "unsupported operation"}
+}
+
+func expandStores(storeIds []string, totals map[string]*batchTotals) []string {
+	seen := make(map[string]bool)
+	expanded := make([]string, 0, len(storeIds))
+	for _, storeId := range storeIds {
+		if storeId != AllStores {
+			if !seen[storeId] {
+				seen[storeId] = true
+				expanded = append(expanded, storeId)
+			}
+			continue
+		}
+		all := make([]string, 0, len(totals))
+		for id := range totals {
+			if !seen[id] {
+				all = append(all, id)
+			}
+		}
+		sort.Strings(all)
+		for _, id := range all {
+			seen[id] = true
+		}
+		expanded = append(expanded, all...)
+	}
+	return expanded
+}
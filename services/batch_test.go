@@ -0,0 +1,110 @@
+package services
+
+import (
+	"context"
+	"dataflow/models"
+	"dataflow/repo"
+	"github.com/stretchr/testify/assert"
+	"math/big"
+	"testing"
+	"time"
+)
+
+func batchSales() []*models.Sale {
+	return []*models.Sale{
+		{ProductId: "12345", StoreId: "6789", QuantitySold: 10, SalePrice: 19.99, SaleDate: time.Date(2024, 6, 15, 14, 30, 0, 0, time.UTC)},
+		{ProductId: "54321", StoreId: "6789", QuantitySold: 2, SalePrice: 5, SaleDate: time.Date(2024, 6, 10, 9, 0, 0, 0, time.UTC)},
+		{ProductId: "54321", StoreId: "9876", QuantitySold: 5, SalePrice: 9.99, SaleDate: time.Date(2024, 6, 16, 10, 0, 0, 0, time.UTC)},
+		{ProductId: "54321", StoreId: "9876", QuantitySold: 1, SalePrice: 100, SaleDate: time.Date(2024, 7, 1, 10, 0, 0, 0, time.UTC)},
+	}
+}
+
+// storeBatchSales returns the sales of batchSales for storeId, as the mock
+// repository's GetSalesInRange does.
+func storeBatchSales(storeId string) []*models.Sale {
+	var sales []*models.Sale
+	for _, sale := range batchSales() {
+		if sale.StoreId == storeId {
+			sales = append(sales, sale)
+		}
+	}
+	return sales
+}
+
+func TestDataService_CalculateBatch(t *testing.T) {
+	mockRepo := new(repo.MockRepository)
+	service := NewDataService(mockRepo)
+
+	startDate := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	endDate := time.Date(2024, 6, 30, 0, 0, 0, 0, time.UTC)
+	mockRepo.On("GetSalesInRange", startDate, endDate, "6789").Return(storeBatchSales("6789"), nil).Once()
+	mockRepo.On("GetSalesInRange", startDate, endDate, "9876").Return(storeBatchSales("9876"), nil).Once()
+
+	result, err := service.CalculateBatch(context.Background(), startDate, endDate, []string{"6789", "9876", "6789"}, []string{MetricTotalSales, MetricUnitsSold, MetricSaleCount})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"6789", "9876"}, result.StoreIds)
+
+	assert.Equal(t, "209.9", result.Cells["6789"][MetricTotalSales].Value.Text('f', -1))
+	assert.Equal(t, new(big.Float).SetInt64(12), result.Cells["6789"][MetricUnitsSold].Value)
+	assert.Equal(t, new(big.Float).SetInt64(2), result.Cells["6789"][MetricSaleCount].Value)
+	assert.Equal(t, new(big.Float).SetInt64(1), result.Cells["9876"][MetricSaleCount].Value)
+	mockRepo.AssertExpectations(t)
+	mockRepo.AssertNotCalled(t, "GetAllSales")
+}
+
+func TestDataService_CalculateBatch_LargeTotals(t *testing.T) {
+	var sales []*models.Sale
+	for i := 0; i < 1000; i++ {
+		sales = append(sales,
+			&models.Sale{StoreId: "6789", QuantitySold: 1, SalePrice: 1234.56, SaleDate: time.Date(2024, 6, 15, 12, 0, 0, 0, time.UTC)},
+			&models.Sale{StoreId: "6789", QuantitySold: 3, SalePrice: 19.99, SaleDate: time.Date(2024, 6, 16, 12, 0, 0, 0, time.UTC)})
+	}
+	mockRepo := new(repo.MockRepository)
+	service := NewDataService(mockRepo)
+	mockRepo.On("GetSalesInRange", time.Time{}, time.Time{}, "6789").Return(sales, nil)
+
+	result, err := service.CalculateBatch(context.Background(), time.Time{}, time.Time{}, []string{"6789"}, []string{MetricTotalSales, MetricAverageSale})
+	assert.Nil(t, err)
+	assert.Equal(t, "1294530", result.Cells["6789"][MetricTotalSales].Value.Text('f', -1))
+	assert.Equal(t, "647.265", result.Cells["6789"][MetricAverageSale].Value.Text('f', -1))
+}
+
+func TestDataService_CalculateBatch_AllStores(t *testing.T) {
+	mockRepo := new(repo.MockRepository)
+	service := NewDataService(mockRepo)
+
+	mockRepo.On("GetAllSales").Return(batchSales(), nil)
+
+	result, err := service.CalculateBatch(context.Background(), time.Time{}, time.Time{}, []string{"1111", AllStores}, []string{MetricSaleCount})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"1111", "6789", "9876"}, result.StoreIds)
+	assert.Equal(t, new(big.Float).SetInt64(0), result.Cells["1111"][MetricSaleCount].Value)
+	assert.Equal(t, new(big.Float).SetInt64(2), result.Cells["9876"][MetricSaleCount].Value)
+}
+
+func TestDataService_CalculateBatch_PartialFailure(t *testing.T) {
+	mockRepo := new(repo.MockRepository)
+	service := NewDataService(mockRepo)
+
+	mockRepo.On("GetSalesInRange", time.Time{}, time.Time{}, "6789").Return(storeBatchSales("6789"), nil)
+	mockRepo.On("GetSalesInRange", time.Time{}, time.Time{}, "1111").Return([]*models.Sale{}, nil)
+
+	result, err := service.CalculateBatch(context.Background(), time.Time{}, time.Time{}, []string{"6789", "1111"}, []string{MetricAverageSale, "profit"})
+	assert.Nil(t, err)
+	assert.Equal(t, "unsupported operation", result.Cells["6789"]["profit"].Error)
+	assert.Nil(t, result.Cells["6789"]["profit"].Value)
+	assert.NotNil(t, result.Cells["6789"][MetricAverageSale].Value)
+	assert.Equal(t, "no sales in range", result.Cells["1111"][MetricAverageSale].Error)
+}
+
+func TestDataService_CalculateBatch_WrongDate(t *testing.T) {
+	mockRepo := new(repo.MockRepository)
+	service := NewDataService(mockRepo)
+
+	startDate := time.Date(2024, 6, 20, 0, 0, 0, 0, time.UTC)
+	endDate := time.Date(2024, 6, 10, 0, 0, 0, 0, time.UTC)
+
+	_, err := service.CalculateBatch(context.Background(), startDate, endDate, []string{AllStores}, []string{MetricTotalSales})
+	assert.ErrorIs(t, err, ErrWrongDate)
+	mockRepo.AssertNotCalled(t, "GetAllSales")
+}
@@ -0,0 +1,163 @@
+package services
+
+import (
+	"context"
+	"dataflow/models"
+	"sync"
+)
+
+const (
+	DefaultReplayBufferSize     = 1024
+	DefaultSubscriberBufferSize = 64
+)
+
+type SaleEvent struct {
+	ID   uint64
+	Sale *models.Sale
+}
+
+// SaleBroker fans out committed sales to subscribers and keeps the most recent
+// events in a bounded replay buffer so that clients can resume after a
+// disconnect. Publishing never blocks: a subscriber whose buffer is full is
+// dropped and has to resubscribe from the last event it saw.
+type SaleBroker struct {
+	mu          sync.Mutex
+	lastID      uint64
+	replay      []SaleEvent
+	next        int
+	subscribers map[*Subscription]struct{}
+	bufferSize  int
+	closed      bool
+}
+
+type Subscription struct {
+	broker *SaleBroker
+	events chan SaleEvent
+	once   sync.Once
+}
+
+func NewSaleBroker(replaySize int, subscriberBufferSize int) *SaleBroker {
+	if replaySize <= 0 {
+		replaySize = DefaultReplayBufferSize
+	}
+	if subscriberBufferSize <= 0 {
+		subscriberBufferSize = DefaultSubscriberBufferSize
+	}
+	return &SaleBroker{
+		replay:      make([]SaleEvent, 0, replaySize),
+		subscribers: make(map[*Subscription]struct{}),
+		bufferSize:  subscriberBufferSize,
+	}
+}
+
+func (b *SaleBroker) Publish(sale *models.Sale) SaleEvent {
+	copied := *sale
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.lastID++
+	event := SaleEvent{ID: b.lastID, Sale: &copied}
+	if len(b.replay) < cap(b.replay) {
+		b.replay = append(b.replay, event)
+	} else {
+		b.replay[b.next] = event
+		b.next = (b.next + 1) % len(b.replay)
+	}
+
+	for sub := range b.subscribers {
+		select {
+		case sub.events <- event:
+		default:
+			delete(b.subscribers, sub)
+			sub.close()
+		}
+	}
+	return event
+}
+
+// Subscribe registers a subscriber and returns the buffered events published
+// after lastEventID. Pass 0 to receive only new events. If the first replayed
+// event's ID is greater than lastEventID+1, older events have already been
+// evicted from the buffer.
+func (b *SaleBroker) Subscribe(lastEventID uint64) (*Subscription, []SaleEvent) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	sub := &Subscription{broker: b, events: make(chan SaleEvent, b.bufferSize)}
+	if b.closed {
+		sub.close()
+		return sub, nil
+	}
+	b.subscribers[sub] = struct{}{}
+
+	var replay []SaleEvent
+	if lastEventID > 0 && lastEventID < b.lastID {
+		for i := 0; i < len(b.replay); i++ {
+			event := b.replay[(b.next+i)%len(b.replay)]
+			if event.ID > lastEventID {
+				replay = append(replay, event)
+			}
+		}
+	}
+	return sub, replay
+}
+
+func (b *SaleBroker) Subscribers() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return len(b.subscribers)
+}
+
+func (b *SaleBroker) Closed() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.closed
+}
+
+// Close disconnects every subscriber; later subscriptions are closed
+// immediately.
+func (b *SaleBroker) Close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.closed = true
+	for sub := range b.subscribers {
+		delete(b.subscribers, sub)
+		sub.close()
+	}
+}
+
+// Events is closed when the subscription is closed, the subscriber fell behind
+// or the broker shut down.
+func (s *Subscription) Events() <-chan SaleEvent {
+	return s.events
+}
+
+func (s *Subscription) Close() {
+	s.broker.mu.Lock()
+	defer s.broker.mu.Unlock()
+	delete(s.broker.subscribers, s)
+	s.close()
+}
+
+func (s *Subscription) close() {
+	s.once.Do(func() { close(s.events) })
+}
+
+type publishingDataService struct {
+	DataService
+	broker *SaleBroker
+}
+
+// NewPublishingDataService publishes every sale added through the wrapped
+// service to the broker once it has been stored.
+func NewPublishingDataService(service DataService, broker *SaleBroker) DataService {
+	return &publishingDataService{DataService: service, broker: broker}
+}
+
+func (ps *publishingDataService) AddSale(ctx context.Context, sale *models.Sale) error {
+	if err := ps.DataService.AddSale(ctx, sale); err != nil {
+		return err
+	}
+	ps.broker.Publish(sale)
+	return nil
+}
@@ -0,0 +1,112 @@
+package services
+
+import (
+	"context"
+	"dataflow/models"
+	"dataflow/repo"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestSaleBroker_Publish(t *testing.T) {
+	broker := NewSaleBroker(10, 10)
+	sub, replay := broker.Subscribe(0)
+	defer sub.Close()
+
+	event := broker.Publish(&models.Sale{ID: "1", StoreId: "6789"})
+
+	assert.Empty(t, replay)
+	assert.Equal(t, uint64(1), event.ID)
+	received := <-sub.Events()
+	assert.Equal(t, event, received)
+}
+
+func TestSaleBroker_Subscribe_Replay(t *testing.T) {
+	broker := NewSaleBroker(3, 10)
+	for i := 0; i < 5; i++ {
+		broker.Publish(&models.Sale{StoreId: "6789"})
+	}
+
+	sub, replay := broker.Subscribe(3)
+	defer sub.Close()
+	assert.Len(t, replay, 2)
+	assert.Equal(t, uint64(4), replay[0].ID)
+	assert.Equal(t, uint64(5), replay[1].ID)
+
+	evicted, replay := broker.Subscribe(1)
+	defer evicted.Close()
+	assert.Len(t, replay, 3)
+	assert.Equal(t, uint64(3), replay[0].ID)
+}
+
+func TestSaleBroker_SlowSubscriberDropped(t *testing.T) {
+	broker := NewSaleBroker(10, 1)
+	slow, _ := broker.Subscribe(0)
+
+	done := make(chan struct{})
+	go func() {
+		for i := 0; i < 5; i++ {
+			broker.Publish(&models.Sale{StoreId: "6789"})
+		}
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("publish blocked on a slow subscriber")
+	}
+
+	first, ok := <-slow.Events()
+	assert.True(t, ok)
+	assert.Equal(t, uint64(1), first.ID)
+	_, ok = <-slow.Events()
+	assert.False(t, ok)
+	assert.Equal(t, 0, broker.Subscribers())
+}
+
+func TestSaleBroker_Close(t *testing.T) {
+	broker := NewSaleBroker(10, 10)
+	sub, _ := broker.Subscribe(0)
+
+	broker.Close()
+
+	_, ok := <-sub.Events()
+	assert.False(t, ok)
+	late, _ := broker.Subscribe(0)
+	_, ok = <-late.Events()
+	assert.False(t, ok)
+}
+
+func TestPublishingDataService_AddSale(t *testing.T) {
+	mockRepo := new(repo.MockRepository)
+	broker := NewSaleBroker(10, 10)
+	service := NewPublishingDataService(NewDataService(mockRepo), broker)
+
+	sale := &models.Sale{ProductId: "12345", StoreId: "6789", QuantitySold: 1, SalePrice: 9.99}
+	mockRepo.On("AddSale", sale).Return(nil)
+
+	sub, _ := broker.Subscribe(0)
+	defer sub.Close()
+
+	err := service.AddSale(context.Background(), sale)
+	assert.Nil(t, err)
+	event := <-sub.Events()
+	assert.Equal(t, *sale, *event.Sale)
+}
+
+func TestPublishingDataService_AddSale_Error(t *testing.T) {
+	mockRepo := new(repo.MockRepository)
+	broker := NewSaleBroker(10, 10)
+	service := NewPublishingDataService(NewDataService(mockRepo), broker)
+
+	sale := &models.Sale{StoreId: "6789"}
+	mockRepo.On("AddSale", sale).Return(repo.ErrSaleAlreadyExists)
+
+	err := service.AddSale(context.Background(), sale)
+	assert.ErrorIs(t, err, repo.ErrSaleAlreadyExists)
+	sub, replay := broker.Subscribe(0)
+	defer sub.Close()
+	assert.Empty(t, replay)
+}
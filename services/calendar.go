@@ -0,0 +1,206 @@
+package services
+
+import (
+	"context"
+	"dataflow/models"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"time"
+)
+
+// Calendar patterns. Retail patterns give the weeks of the three periods of
+// every quarter.
+const (
+	CalendarGregorian = "gregorian"
+	Calendar445       = "4-4-5"
+	Calendar454       = "4-5-4"
+	Calendar544       = "5-4-4"
+)
+
+var ErrInvalidFiscalPeriod = errors.New("invalid fiscal period")
+
+type CalendarOptions struct {
+	Pattern string
+	// StartMonth is the month fiscal years start in. Gregorian fiscal years
+	// start on its first day, retail ones on the WeekStart nearest to it.
+	StartMonth time.Month
+	WeekStart  time.Weekday
+	// NameByEnd names fiscal years after the calendar year they end in
+	// rather than the one they start in.
+	NameByEnd bool
+}
+
+var DefaultCalendarOptions = CalendarOptions{
+	Pattern:    CalendarGregorian,
+	StartMonth: time.January,
+	WeekStart:  time.Sunday,
+}
+
+// Calendar divides fiscal years into 12 periods, 4 quarters and weeks. The
+// periods of a Gregorian calendar are months and its weeks are counted from
+// the start of the year, so the last one is short. Retail calendars have 52
+// whole weeks, or 53 when the year's start drifts far enough, in which case
+// the last period has a week more.
+type Calendar struct {
+	options CalendarOptions
+	// weeks of every period of a retail calendar, nil for a Gregorian one.
+	weeks []int
+}
+
+// DefaultCalendar is the Gregorian calendar with years starting in January.
+var DefaultCalendar, _ = NewCalendar(DefaultCalendarOptions)
+
+func NewCalendar(options CalendarOptions) (*Calendar, error) {
+	if options.StartMonth < time.January || options.StartMonth > time.December {
+		return nil, fmt.Errorf("fiscal years can't start in month %d", options.StartMonth)
+	}
+	if options.WeekStart < time.Sunday || options.WeekStart > time.Saturday {
+		return nil, fmt.Errorf("weeks can't start on day %d", options.WeekStart)
+	}
+	calendar := &Calendar{options: options}
+	switch options.Pattern {
+	case CalendarGregorian:
+	case Calendar445, Calendar454, Calendar544:
+		for i := 0; i < 12; i++ {
+			calendar.weeks = append(calendar.weeks, int(options.Pattern[2*(i%3)]-'0'))
+		}
+	default:
+		return nil, fmt.Errorf("unknown calendar %q", options.Pattern)
+	}
+	return calendar, nil
+}
+
+// Period returns the label of the fiscal year (FY2024), quarter (FY2024-Q1),
+// period (FY2024-P01) or week (FY2024-W01) that the local date of t falls in,
+// for the matching fiscal dimension.
+func (c *Calendar) Period(t time.Time, dimension string) string {
+	date := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	year := date.Year()
+	if date.Before(c.yearStart(year)) {
+		year--
+	} else if !date.Before(c.yearStart(year + 1)) {
+		year++
+	}
+	day := int(date.Sub(c.yearStart(year)).Hours()) / 24
+	switch dimension {
+	case models.DimensionFiscalYear:
+		return fmt.Sprintf("FY%04d", c.name(year))
+	case models.DimensionFiscalQuarter:
+		return fmt.Sprintf("FY%04d-Q%d", c.name(year), (c.period(year, date)-1)/3+1)
+	case models.DimensionFiscalPeriod:
+		return fmt.Sprintf("FY%04d-P%02d", c.name(year), c.period(year, date))
+	default:
+		return fmt.Sprintf("FY%04d-W%02d", c.name(year), day/7+1)
+	}
+}
+
+var periodLabel = regexp.MustCompile(`^FY(\d{4})(?:-(?:Q([1-4])|P(\d\d)|W(\d\d)))?$`)
+
+// Range returns the first day of the fiscal year, quarter, period or week
+// with a label such as Period returns, and the first day after it, as UTC
+// midnights of those dates.
+func (c *Calendar) Range(label string) (time.Time, time.Time, error) {
+	match := periodLabel.FindStringSubmatch(label)
+	if match == nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("%w %q, expected FY2024, FY2024-Q1, FY2024-P01 or FY2024-W01", ErrInvalidFiscalPeriod, label)
+	}
+	year, _ := strconv.Atoi(match[1])
+	if c.options.NameByEnd && c.options.StartMonth != time.January {
+		year--
+	}
+	start, next := c.yearStart(year), c.yearStart(year+1)
+	switch {
+	case match[2] != "":
+		quarter, _ := strconv.Atoi(match[2])
+		start, _ = c.periodRange(year, 3*quarter-2)
+		_, next = c.periodRange(year, 3*quarter)
+	case match[3] != "":
+		period, _ := strconv.Atoi(match[3])
+		if period < 1 || period > 12 {
+			return time.Time{}, time.Time{}, fmt.Errorf("%w %q, periods are 01 to 12", ErrInvalidFiscalPeriod, label)
+		}
+		start, next = c.periodRange(year, period)
+	case match[4] != "":
+		week, _ := strconv.Atoi(match[4])
+		weekStart := start.AddDate(0, 0, 7*(week-1))
+		if week < 1 || !weekStart.Before(next) {
+			return time.Time{}, time.Time{}, fmt.Errorf("%w %q, the year has no such week", ErrInvalidFiscalPeriod, label)
+		}
+		start = weekStart
+		if weekEnd := start.AddDate(0, 0, 7); weekEnd.Before(next) {
+			next = weekEnd
+		}
+	}
+	return start, next, nil
+}
+
+// yearStart returns the first day of the fiscal year that starts in or
+// around the start month of year.
+func (c *Calendar) yearStart(year int) time.Time {
+	first := time.Date(year, c.options.StartMonth, 1, 0, 0, 0, 0, time.UTC)
+	if c.weeks == nil {
+		return first
+	}
+	offset := (int(c.options.WeekStart) - int(first.Weekday()) + 7) % 7
+	if offset > 3 {
+		offset -= 7
+	}
+	return first.AddDate(0, 0, offset)
+}
+
+// periodRange returns the first day of a period of year and the first day
+// after it.
+func (c *Calendar) periodRange(year int, period int) (time.Time, time.Time) {
+	if c.weeks == nil {
+		start := c.yearStart(year).AddDate(0, period-1, 0)
+		return start, start.AddDate(0, 1, 0)
+	}
+	start := c.yearStart(year)
+	for _, weeks := range c.weeks[:period-1] {
+		start = start.AddDate(0, 0, 7*weeks)
+	}
+	if period == 12 {
+		return start, c.yearStart(year + 1)
+	}
+	return start, start.AddDate(0, 0, 7*c.weeks[period-1])
+}
+
+// period returns the period of year that date falls in.
+func (c *Calendar) period(year int, date time.Time) int {
+	period := 1
+	for period < 12 {
+		if _, next := c.periodRange(year, period); date.Before(next) {
+			break
+		}
+		period++
+	}
+	return period
+}
+
+// name returns the number that the fiscal year starting in year is named by.
+func (c *Calendar) name(year int) int {
+	if c.options.NameByEnd && c.options.StartMonth != time.January {
+		return year + 1
+	}
+	return year
+}
+
+type calendarDataService struct {
+	DataService
+	calendar *Calendar
+}
+
+// NewCalendarDataService sets calendar on aggregate queries that don't set a
+// calendar themselves, so that fiscal periods are those of the business.
+func NewCalendarDataService(inner DataService, calendar *Calendar) DataService {
+	return &calendarDataService{DataService: inner, calendar: calendar}
+}
+
+func (cs *calendarDataService) Aggregate(ctx context.Context, query models.AggregateQuery) ([]*models.AggregateGroup, error) {
+	if query.Calendar == nil {
+		query.Calendar = cs.calendar
+	}
+	return cs.DataService.Aggregate(ctx, query)
+}
@@ -0,0 +1,118 @@
+package services
+
+import (
+	"context"
+	"dataflow/models"
+	"dataflow/repo"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func date(year int, month time.Month, day int) time.Time {
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+// The NRF retail calendar: 4-5-4 with years starting on the Sunday nearest
+// to February 1. Fiscal 2023 had 53 weeks.
+var nrf, _ = NewCalendar(CalendarOptions{Pattern: Calendar454, StartMonth: time.February, WeekStart: time.Sunday})
+
+func TestCalendar_Period_Retail(t *testing.T) {
+	for _, test := range []struct {
+		date      time.Time
+		dimension string
+		period    string
+	}{
+		{date(2023, 1, 29), models.DimensionFiscalWeek, "FY2023-W01"},
+		{date(2023, 1, 28), models.DimensionFiscalWeek, "FY2022-W52"},
+		{date(2024, 2, 3), models.DimensionFiscalWeek, "FY2023-W53"},
+		{date(2024, 2, 3), models.DimensionFiscalPeriod, "FY2023-P12"},
+		{date(2024, 2, 4), models.DimensionFiscalYear, "FY2024"},
+		{date(2024, 5, 4), models.DimensionFiscalPeriod, "FY2024-P03"},
+		{date(2024, 5, 4), models.DimensionFiscalQuarter, "FY2024-Q1"},
+		{date(2024, 5, 5), models.DimensionFiscalPeriod, "FY2024-P04"},
+		{date(2024, 5, 5), models.DimensionFiscalQuarter, "FY2024-Q2"},
+		// Only the local date matters.
+		{time.Date(2024, 5, 4, 23, 30, 0, 0, time.FixedZone("", -5*60*60)), models.DimensionFiscalPeriod, "FY2024-P03"},
+	} {
+		assert.Equal(t, test.period, nrf.Period(test.date, test.dimension), test.date)
+	}
+}
+
+func TestCalendar_Range(t *testing.T) {
+	gregorian, err := NewCalendar(CalendarOptions{Pattern: CalendarGregorian, StartMonth: time.July, NameByEnd: true})
+	assert.NoError(t, err)
+	calendar445, err := NewCalendar(CalendarOptions{Pattern: Calendar445, StartMonth: time.February, WeekStart: time.Sunday})
+	assert.NoError(t, err)
+
+	for _, test := range []struct {
+		calendar    *Calendar
+		label       string
+		start, next time.Time
+	}{
+		{nrf, "FY2023", date(2023, 1, 29), date(2024, 2, 4)},
+		{nrf, "FY2023-P12", date(2023, 12, 31), date(2024, 2, 4)},
+		{nrf, "FY2023-W53", date(2024, 1, 28), date(2024, 2, 4)},
+		{nrf, "FY2024-P02", date(2024, 3, 3), date(2024, 4, 7)},
+		{nrf, "FY2024-Q2", date(2024, 5, 5), date(2024, 8, 4)},
+		{calendar445, "FY2024-P03", date(2024, 3, 31), date(2024, 5, 5)},
+		{gregorian, "FY2025", date(2024, 7, 1), date(2025, 7, 1)},
+		{gregorian, "FY2025-Q2", date(2024, 10, 1), date(2025, 1, 1)},
+		{gregorian, "FY2025-P07", date(2025, 1, 1), date(2025, 2, 1)},
+		// The last week of a Gregorian year is cut short.
+		{gregorian, "FY2024-W53", date(2024, 6, 29), date(2024, 7, 1)},
+	} {
+		start, next, err := test.calendar.Range(test.label)
+		assert.NoError(t, err, test.label)
+		assert.Equal(t, test.start, start, test.label)
+		assert.Equal(t, test.next, next, test.label)
+	}
+
+	for _, label := range []string{"FY2024-W53", "FY2024-P13", "FY2024-W00", "2024-P01", "FY24"} {
+		_, _, err := nrf.Range(label)
+		assert.ErrorIs(t, err, ErrInvalidFiscalPeriod, label)
+	}
+}
+
+func TestCalendar_RangeMatchesPeriod(t *testing.T) {
+	for day := date(2023, 1, 1); day.Before(date(2025, 12, 31)); day = day.AddDate(0, 0, 1) {
+		for _, dimension := range []string{models.DimensionFiscalWeek, models.DimensionFiscalPeriod, models.DimensionFiscalQuarter, models.DimensionFiscalYear} {
+			label := nrf.Period(day, dimension)
+			start, next, err := nrf.Range(label)
+			if !assert.NoError(t, err, label) || !assert.True(t, !day.Before(start) && day.Before(next), "%s isn't in %s", day, label) {
+				return
+			}
+		}
+	}
+}
+
+func TestNewCalendar_Invalid(t *testing.T) {
+	_, err := NewCalendar(CalendarOptions{Pattern: "lunar", StartMonth: time.January})
+	assert.Error(t, err)
+	_, err = NewCalendar(CalendarOptions{Pattern: CalendarGregorian})
+	assert.Error(t, err)
+}
+
+func TestCalendarDataService_Aggregate(t *testing.T) {
+	sales := repo.NewInMemoryRepository()
+	for i, day := range []time.Time{date(2024, 2, 3), date(2024, 2, 4), date(2024, 2, 11)} {
+		sales.AddSale(context.Background(), &models.Sale{ProductId: "12345", StoreId: "6789", QuantitySold: i + 1, SalePrice: 10, SaleDate: day.Add(12 * time.Hour)})
+	}
+	service := NewCalendarDataService(NewDataService(sales), nrf)
+
+	groups, err := service.Aggregate(context.Background(), models.AggregateQuery{GroupBy: []string{models.DimensionFiscalWeek}})
+	assert.NoError(t, err)
+	if assert.Len(t, groups, 3) {
+		assert.Equal(t, "FY2023-W53", groups[0].Period)
+		assert.Equal(t, "FY2024-W01", groups[1].Period)
+		assert.Equal(t, "FY2024-W02", groups[2].Period)
+	}
+
+	// Without a calendar, fiscal years are calendar years.
+	groups, err = NewDataService(sales).Aggregate(context.Background(), models.AggregateQuery{GroupBy: []string{models.DimensionFiscalPeriod}})
+	assert.NoError(t, err)
+	if assert.Len(t, groups, 1) {
+		assert.Equal(t, "FY2024-P02", groups[0].Period)
+		assert.Equal(t, int64(6), groups[0].Units)
+	}
+}
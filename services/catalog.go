@@ -0,0 +1,293 @@
+package services
+
+import (
+	"context"
+	"dataflow/models"
+	"dataflow/repo"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sort"
+	"time"
+)
+
+var (
+	ErrInvalidCatalogEntry = errors.New("invalid catalog entry")
+	ErrCatalogEntryInUse   = errors.New("catalog entry is referenced by sales")
+	ErrUnknownReference    = errors.New("unknown reference")
+)
+
+type CatalogService interface {
+	CreateStore(ctx context.Context, store *models.Store) error
+	GetStore(ctx context.Context, id string) (*models.Store, error)
+	GetAllStores(ctx context.Context) ([]*models.Store, error)
+	UpdateStore(ctx context.Context, store *models.Store) error
+	DeleteStore(ctx context.Context, id string) error
+	CreateProduct(ctx context.Context, product *models.Product) error
+	GetProduct(ctx context.Context, id string) (*models.Product, error)
+	GetAllProducts(ctx context.Context) ([]*models.Product, error)
+	UpdateProduct(ctx context.Context, product *models.Product) error
+	DeleteProduct(ctx context.Context, id string) error
+	// StoreLocations returns the time zones of the stores that have one.
+	StoreLocations(ctx context.Context) (map[string]*time.Location, error)
+	// CheckSale reports the store and product of sale that are missing from
+	// the catalog or inactive, with errors wrapping ErrUnknownReference.
+	CheckSale(ctx context.Context, sale *models.Sale) error
+}
+
+type catalogService struct {
+	stores   repo.StoreRepository
+	products repo.ProductRepository
+	sales    repo.Repository
+}
+
+// NewCatalogService manages stores and products. sales is used to refuse
+// deleting entries that sales still reference; such entries can be
+// deactivated instead.
+func NewCatalogService(stores repo.StoreRepository, products repo.ProductRepository, sales repo.Repository) CatalogService {
+	return &catalogService{stores: stores, products: products, sales: sales}
+}
+
+func (cs *catalogService) CreateStore(ctx context.Context, store *models.Store) error {
+	if err := validateStore(store); err != nil {
+		return err
+	}
+	if err := cs.stores.AddStore(store); err != nil {
+		return fmt.Errorf("couldn't add store: %w", err)
+	}
+	return nil
+}
+
+func (cs *catalogService) GetStore(ctx context.Context, id string) (*models.Store, error) {
+	store, err := cs.stores.GetStore(id)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't get store: %w", err)
+	}
+	return store, nil
+}
+
+// GetAllStores returns the stores ordered by ID.
+func (cs *catalogService) GetAllStores(ctx context.Context) ([]*models.Store, error) {
+	stores, err := cs.stores.GetAllStores()
+	if err != nil {
+		return nil, fmt.Errorf("couldn't get stores: %w", err)
+	}
+	sort.Slice(stores, func(i, j int) bool { return stores[i].ID < stores[j].ID })
+	return stores, nil
+}
+
+func (cs *catalogService) UpdateStore(ctx context.Context, store *models.Store) error {
+	if err := validateStore(store); err != nil {
+		return err
+	}
+	if err := cs.stores.UpdateStore(store); err != nil {
+		return fmt.Errorf("couldn't update store: %w", err)
+	}
+	return nil
+}
+
+func (cs *catalogService) DeleteStore(ctx context.Context, id string) error {
+	if _, err := cs.stores.GetStore(id); err != nil {
+		return fmt.Errorf("couldn't delete store: %w", err)
+	}
+	sales, err := cs.sales.GetSalesInRange(ctx, time.Time{}, time.Time{}, id)
+	if err != nil {
+		return fmt.Errorf("couldn't delete store: %w", err)
+	}
+	if len(sales) > 0 {
+		return fmt.Errorf("%w: store %q has %d sales, deactivate it instead", ErrCatalogEntryInUse, id, len(sales))
+	}
+	if err := cs.stores.DeleteStore(id); err != nil {
+		return fmt.Errorf("couldn't delete store: %w", err)
+	}
+	return nil
+}
+
+func (cs *catalogService) CreateProduct(ctx context.Context, product *models.Product) error {
+	if err := validateProduct(product); err != nil {
+		return err
+	}
+	if err := cs.products.AddProduct(product); err != nil {
+		return fmt.Errorf("couldn't add product: %w", err)
+	}
+	return nil
+}
+
+func (cs *catalogService) GetProduct(ctx context.Context, id string) (*models.Product, error) {
+	product, err := cs.products.GetProduct(id)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't get product: %w", err)
+	}
+	return product, nil
+}
+
+// GetAllProducts returns the products ordered by ID.
+func (cs *catalogService) GetAllProducts(ctx context.Context) ([]*models.Product, error) {
+	products, err := cs.products.GetAllProducts()
+	if err != nil {
+		return nil, fmt.Errorf("couldn't get products: %w", err)
+	}
+	sort.Slice(products, func(i, j int) bool { return products[i].ID < products[j].ID })
+	return products, nil
+}
+
+func (cs *catalogService) UpdateProduct(ctx context.Context, product *models.Product) error {
+	if err := validateProduct(product); err != nil {
+		return err
+	}
+	if err := cs.products.UpdateProduct(product); err != nil {
+		return fmt.Errorf("couldn't update product: %w", err)
+	}
+	return nil
+}
+
+func (cs *catalogService) DeleteProduct(ctx context.Context, id string) error {
+	if _, err := cs.products.GetProduct(id); err != nil {
+		return fmt.Errorf("couldn't delete product: %w", err)
+	}
+	sales, err := cs.sales.GetAllSales(ctx)
+	if err != nil {
+		return fmt.Errorf("couldn't delete product: %w", err)
+	}
+	var count int
+	for _, sale := range sales {
+		if sale.ProductId == id {
+			count++
+		}
+	}
+	if count > 0 {
+		return fmt.Errorf("%w: product %q has %d sales, deactivate it instead", ErrCatalogEntryInUse, id, count)
+	}
+	if err := cs.products.DeleteProduct(id); err != nil {
+		return fmt.Errorf("couldn't delete product: %w", err)
+	}
+	return nil
+}
+
+func (cs *catalogService) StoreLocations(ctx context.Context) (map[string]*time.Location, error) {
+	stores, err := cs.stores.GetAllStores()
+	if err != nil {
+		return nil, fmt.Errorf("couldn't get stores: %w", err)
+	}
+	locations := make(map[string]*time.Location)
+	for _, store := range stores {
+		if store.Timezone == "" {
+			continue
+		}
+		location, err := time.LoadLocation(store.Timezone)
+		if err != nil {
+			return nil, fmt.Errorf("couldn't load the time zone of store %q: %w", store.ID, err)
+		}
+		locations[store.ID] = location
+	}
+	return locations, nil
+}
+
+func (cs *catalogService) CheckSale(ctx context.Context, sale *models.Sale) error {
+	var errs []error
+	store, err := cs.stores.GetStore(sale.StoreId)
+	switch {
+	case errors.Is(err, repo.ErrStoreNotFound):
+		errs = append(errs, fmt.Errorf("%w: store %q isn't in the catalog", ErrUnknownReference, sale.StoreId))
+	case err != nil:
+		return fmt.Errorf("couldn't check store: %w", err)
+	case !store.Active:
+		errs = append(errs, fmt.Errorf("%w: store %q is inactive", ErrUnknownReference, sale.StoreId))
+	}
+	product, err := cs.products.GetProduct(sale.ProductId)
+	switch {
+	case errors.Is(err, repo.ErrProductNotFound):
+		errs = append(errs, fmt.Errorf("%w: product %q isn't in the catalog", ErrUnknownReference, sale.ProductId))
+	case err != nil:
+		return fmt.Errorf("couldn't check product: %w", err)
+	case !product.Active:
+		errs = append(errs, fmt.Errorf("%w: product %q is inactive", ErrUnknownReference, sale.ProductId))
+	}
+	return errors.Join(errs...)
+}
+
+func validateStore(store *models.Store) error {
+	if store.ID == "" {
+		return fmt.Errorf("%w: id is required", ErrInvalidCatalogEntry)
+	}
+	if store.Name == "" {
+		return fmt.Errorf("%w: name is required", ErrInvalidCatalogEntry)
+	}
+	if store.Timezone != "" {
+		if _, err := time.LoadLocation(store.Timezone); err != nil {
+			return fmt.Errorf("%w: unknown timezone %q", ErrInvalidCatalogEntry, store.Timezone)
+		}
+	}
+	return nil
+}
+
+func validateProduct(product *models.Product) error {
+	if product.ID == "" {
+		return fmt.Errorf("%w: id is required", ErrInvalidCatalogEntry)
+	}
+	if product.Name == "" {
+		return fmt.Errorf("%w: name is required", ErrInvalidCatalogEntry)
+	}
+	return nil
+}
+
+// catalogCheckingDataService checks the references of added sales against
+// the catalog.
+type catalogCheckingDataService struct {
+	DataService
+	catalog CatalogService
+	strict  bool
+}
+
+// NewCatalogCheckingDataService rejects sales whose store or product is
+// missing from the catalog or inactive when strict is set, and otherwise
+// adds them with a warning.
+func NewCatalogCheckingDataService(inner DataService, catalog CatalogService, strict bool) DataService {
+	return &catalogCheckingDataService{DataService: inner, catalog: catalog, strict: strict}
+}
+
+func (cs *catalogCheckingDataService) AddSale(ctx context.Context, sale *models.Sale) error {
+	if err := cs.catalog.CheckSale(ctx, sale); err != nil {
+		if cs.strict || !errors.Is(err, ErrUnknownReference) {
+			return err
+		}
+		slog.WarnContext(ctx, "sale references entries missing from the catalog", slog.String("store_id", sale.StoreId), slog.String("product_id", sale.ProductId), slog.Any("error", err))
+	}
+	return cs.DataService.AddSale(ctx, sale)
+}
+
+// localTimeDataService buckets aggregates and resolves the dates of queries
+// in the time zones of the stores.
+type localTimeDataService struct {
+	DataService
+	catalog CatalogService
+}
+
+// NewLocalTimeDataService sets the store time zones of the catalog on
+// aggregate and sales queries that don't set a time zone themselves, so that
+// days, weeks and months are those of the store's local time.
+func NewLocalTimeDataService(inner DataService, catalog CatalogService) DataService {
+	return &localTimeDataService{DataService: inner, catalog: catalog}
+}
+
+func (ls *localTimeDataService) Aggregate(ctx context.Context, query models.AggregateQuery) ([]*models.AggregateGroup, error) {
+	if query.Location == nil && query.StoreLocations == nil {
+		locations, err := ls.catalog.StoreLocations(ctx)
+		if err != nil {
+			return nil, err
+		}
+		query.StoreLocations = locations
+	}
+	return ls.DataService.Aggregate(ctx, query)
+}
+
+func (ls *localTimeDataService) Query(ctx context.Context, query models.SalesQuery) (*models.QueryResult, error) {
+	if query.Location == nil && query.StoreLocations == nil {
+		locations, err := ls.catalog.StoreLocations(ctx)
+		if err != nil {
+			return nil, err
+		}
+		query.StoreLocations = locations
+	}
+	return ls.DataService.Query(ctx, query)
+}
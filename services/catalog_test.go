@@ -0,0 +1,88 @@
+package services
+
+import (
+	"context"
+	"dataflow/models"
+	"dataflow/repo"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func newTestCatalog(sales repo.Repository) CatalogService {
+	catalog := NewCatalogService(repo.NewInMemoryStoreRepository(), repo.NewInMemoryProductRepository(), sales)
+	catalog.CreateStore(context.Background(), &models.Store{ID: "6789", Name: "Downtown", Timezone: "Europe/Paris", Active: true})
+	catalog.CreateStore(context.Background(), &models.Store{ID: "9876", Name: "Closed", Active: false})
+	catalog.CreateProduct(context.Background(), &models.Product{ID: "12345", Name: "Espresso", Active: true})
+	return catalog
+}
+
+func TestCatalogService_Invalid(t *testing.T) {
+	catalog := newTestCatalog(repo.NewInMemoryRepository())
+	ctx := context.Background()
+
+	assert.ErrorIs(t, catalog.CreateStore(ctx, &models.Store{Name: "No ID"}), ErrInvalidCatalogEntry)
+	assert.ErrorIs(t, catalog.CreateStore(ctx, &models.Store{ID: "1"}), ErrInvalidCatalogEntry)
+	assert.ErrorIs(t, catalog.CreateStore(ctx, &models.Store{ID: "1", Name: "x", Timezone: "Mars/Olympus"}), ErrInvalidCatalogEntry)
+	assert.ErrorIs(t, catalog.UpdateStore(ctx, &models.Store{ID: "6789"}), ErrInvalidCatalogEntry)
+	assert.ErrorIs(t, catalog.CreateProduct(ctx, &models.Product{ID: "1"}), ErrInvalidCatalogEntry)
+	assert.ErrorIs(t, catalog.CreateStore(ctx, &models.Store{ID: "6789", Name: "Again"}), repo.ErrStoreAlreadyExists)
+	assert.ErrorIs(t, catalog.UpdateProduct(ctx, &models.Product{ID: "missing", Name: "x"}), repo.ErrProductNotFound)
+}
+
+func TestCatalogService_GetAllStores(t *testing.T) {
+	catalog := newTestCatalog(repo.NewInMemoryRepository())
+
+	stores, err := catalog.GetAllStores(context.Background())
+	assert.Nil(t, err)
+	if assert.Len(t, stores, 2) {
+		assert.Equal(t, "6789", stores[0].ID)
+		assert.Equal(t, "9876", stores[1].ID)
+	}
+}
+
+func TestCatalogService_Delete_InUse(t *testing.T) {
+	sales := repo.NewInMemoryRepository()
+	sales.AddSale(context.Background(), &models.Sale{ProductId: "12345", StoreId: "6789", QuantitySold: 1, SalePrice: 2, SaleDate: time.Now()})
+	catalog := newTestCatalog(sales)
+	ctx := context.Background()
+
+	assert.ErrorIs(t, catalog.DeleteStore(ctx, "6789"), ErrCatalogEntryInUse)
+	assert.ErrorIs(t, catalog.DeleteProduct(ctx, "12345"), ErrCatalogEntryInUse)
+	assert.ErrorIs(t, catalog.DeleteStore(ctx, "missing"), repo.ErrStoreNotFound)
+
+	assert.Nil(t, catalog.DeleteStore(ctx, "9876"))
+	_, err := catalog.GetStore(ctx, "9876")
+	assert.ErrorIs(t, err, repo.ErrStoreNotFound)
+}
+
+func TestCatalogService_CheckSale(t *testing.T) {
+	catalog := newTestCatalog(repo.NewInMemoryRepository())
+	ctx := context.Background()
+
+	assert.Nil(t, catalog.CheckSale(ctx, &models.Sale{StoreId: "6789", ProductId: "12345"}))
+
+	err := catalog.CheckSale(ctx, &models.Sale{StoreId: "9876", ProductId: "54321"})
+	assert.ErrorIs(t, err, ErrUnknownReference)
+	assert.ErrorContains(t, err, `store "9876" is inactive`)
+	assert.ErrorContains(t, err, `product "54321" isn't in the catalog`)
+}
+
+func TestCatalogCheckingDataService_AddSale(t *testing.T) {
+	catalog := newTestCatalog(repo.NewInMemoryRepository())
+	known := &models.Sale{StoreId: "6789", ProductId: "12345"}
+	phantom := &models.Sale{StoreId: "678", ProductId: "12345"}
+
+	mockService := new(MockService)
+	mockService.On("AddSale", known).Return(nil)
+	mockService.On("AddSale", phantom).Return(nil)
+
+	strict := NewCatalogCheckingDataService(mockService, catalog, true)
+	assert.Nil(t, strict.AddSale(context.Background(), known))
+	assert.ErrorIs(t, strict.AddSale(context.Background(), phantom), ErrUnknownReference)
+	mockService.AssertNumberOfCalls(t, "AddSale", 1)
+
+	lenient := NewCatalogCheckingDataService(mockService, catalog, false)
+	assert.Nil(t, lenient.AddSale(context.Background(), phantom))
+	mockService.AssertCalled(t, "AddSale", phantom)
+}
@@ -0,0 +1,59 @@
+package services
+
+import (
+	"context"
+	"dataflow/models"
+	"dataflow/repo"
+	"dataflow/repo/repotest"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+// TestRangeConformance checks that every query path of every backend covers
+// the same sales for a range.
+func TestRangeConformance(t *testing.T) {
+	ctx := context.Background()
+	for name, repository := range repotest.Backends(t) {
+		t.Run(name, func(t *testing.T) {
+			repotest.AddRangeSales(t, repository)
+			service := NewDataService(repository)
+
+			for bounds, condition := range map[string]string{
+				repo.RangeHalfOpen: "sale_date >= '%s' AND sale_date < '%s'",
+				repo.RangeClosed:   "sale_date BETWEEN '%s' AND '%s'",
+			} {
+				expected := repotest.InRange(bounds)
+				end := repo.EndOf(repotest.RangeEnd, bounds)
+
+				sales, err := service.GetSalesInRange(ctx, repotest.RangeStart, end, repotest.RangeStore)
+				assert.NoError(t, err)
+				assert.Equal(t, expected, repotest.ProductIds(sales), "GetSalesInRange, %s", bounds)
+
+				total, err := service.CalculateSales(ctx, repotest.RangeStart, end, repotest.RangeStore)
+				assert.NoError(t, err)
+				assert.Equal(t, fmt.Sprint(len(expected)), total.Text('g', -1), "CalculateSales, %s", bounds)
+
+				batch, err := service.CalculateBatch(ctx, repotest.RangeStart, end, []string{repotest.RangeStore}, []string{MetricSaleCount})
+				assert.NoError(t, err)
+				assert.Equal(t, fmt.Sprint(len(expected)), batch.Cells[repotest.RangeStore][MetricSaleCount].Value.Text('g', -1), "CalculateBatch, %s", bounds)
+
+				groups, err := service.Aggregate(ctx, models.AggregateQuery{StartDate: repotest.RangeStart, EndDate: end})
+				assert.NoError(t, err)
+				assert.Equal(t, int64(len(expected)), groups[0].Count, "Aggregate, %s", bounds)
+
+				statement := fmt.Sprintf("SELECT product_id FROM sales WHERE store_id = '%s' AND "+condition+" ORDER BY sale_date",
+					repotest.RangeStore, repotest.RangeStart.Format(time.RFC3339Nano), repotest.RangeEnd.Format(time.RFC3339Nano))
+				result, err := service.Query(ctx, models.SalesQuery{Statement: statement})
+				if assert.NoError(t, err) {
+					var products []string
+					for _, row := range result.Rows {
+						products = append(products, row[0].(string))
+					}
+					assert.Equal(t, expected, products, "Query, %s", bounds)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,93 @@
+package services
+
+import (
+	"context"
+	"dataflow/models"
+	"errors"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+const defaultHealthCheckTimeout = 2 * time.Second
+
+var (
+	ErrStarting     = errors.New("starting")
+	ErrShuttingDown = errors.New("shutting down")
+)
+
+// HealthCheck reports whether a dependency, e.g. the repository, can serve
+// requests.
+type HealthCheck struct {
+	Name  string
+	Check func(ctx context.Context) error
+}
+
+// Health tracks whether the server should receive traffic. It isn't ready
+// until startup, including repository recovery, has completed, and stops
+// being ready as soon as shutdown begins.
+type Health struct {
+	mu      sync.Mutex
+	state   error
+	checks  []HealthCheck
+	timeout time.Duration
+}
+
+func NewHealth(checks ...HealthCheck) *Health {
+	return &Health{state: ErrStarting, checks: checks, timeout: defaultHealthCheckTimeout}
+}
+
+// SetReady marks startup as complete.
+func (h *Health) SetReady() {
+	h.setState(nil)
+}
+
+// SetShuttingDown fails readiness from now on so that load balancers stop
+// routing new requests here while in-flight ones drain.
+func (h *Health) SetShuttingDown() {
+	h.setState(ErrShuttingDown)
+}
+
+func (h *Health) setState(state error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.state = state
+}
+
+// Readiness runs every check concurrently, each bounded by a timeout.
+func (h *Health) Readiness(ctx context.Context) *models.Readiness {
+	readiness := &models.Readiness{Status: models.HealthStatusOK, Checks: make(map[string]string, len(h.checks)+1)}
+	h.mu.Lock()
+	err := h.state
+	h.mu.Unlock()
+	if err != nil {
+		readiness.Status = models.HealthStatusUnavailable
+		readiness.Checks["server"] = err.Error()
+		return readiness
+	}
+	readiness.Checks["server"] = models.HealthStatusOK
+
+	ctx, cancel := context.WithTimeout(ctx, h.timeout)
+	defer cancel()
+	results := make([]error, len(h.checks))
+	var wg sync.WaitGroup
+	for i, check := range h.checks {
+		wg.Add(1)
+		go func(i int, check HealthCheck) {
+			defer wg.Done()
+			results[i] = check.Check(ctx)
+		}(i, check)
+	}
+	wg.Wait()
+
+	for i, check := range h.checks {
+		if results[i] != nil {
+			slog.WarnContext(ctx, "readiness check failed", slog.String("check", check.Name), slog.Any("error", results[i]))
+			readiness.Status = models.HealthStatusUnavailable
+			readiness.Checks[check.Name] = results[i].Error()
+			continue
+		}
+		readiness.Checks[check.Name] = models.HealthStatusOK
+	}
+	return readiness
+}
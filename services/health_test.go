@@ -0,0 +1,23 @@
+package services
+
+import (
+	"context"
+	"dataflow/models"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestHealth_Readiness_CheckTimeout(t *testing.T) {
+	health := NewHealth(HealthCheck{Name: "repository", Check: func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}})
+	health.timeout = 10 * time.Millisecond
+	health.SetReady()
+
+	readiness := health.Readiness(context.Background())
+
+	assert.Equal(t, models.HealthStatusUnavailable, readiness.Status)
+	assert.Equal(t, context.DeadlineExceeded.Error(), readiness.Checks["repository"])
+}
@@ -0,0 +1,312 @@
+package services
+
+import (
+	"context"
+	"dataflow/auth"
+	"dataflow/models"
+	"dataflow/repo"
+	"dataflow/requestid"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+var (
+	ErrUnsupportedJobType = errors.New("unsupported job type")
+	ErrJobQueueFull       = errors.New("job queue is full")
+	ErrJobFinished        = errors.New("job has already finished")
+	ErrJobNotFinished     = errors.New("job has not finished")
+)
+
+// JobFunc executes a job request and returns the result body and its content
+// type. Implementations should report progress in percent and stop when ctx
+// is cancelled.
+type JobFunc func(ctx context.Context, request json.RawMessage, progress func(percent int)) ([]byte, string, error)
+
+type JobOptions struct {
+	Workers   int
+	QueueSize int
+	// TTL is how long a finished job and its result are kept.
+	TTL time.Duration
+}
+
+var DefaultJobOptions = JobOptions{
+	Workers:   4,
+	QueueSize: 100,
+	TTL:       time.Hour,
+}
+
+type JobService interface {
+	Submit(ctx context.Context, jobType string, request json.RawMessage) (*models.Job, error)
+	GetJob(ctx context.Context, id string) (*models.Job, error)
+	Cancel(ctx context.Context, id string) (*models.Job, error)
+	Run(ctx context.Context)
+}
+
+type jobService struct {
+	jobs  repo.JobRepository
+	funcs map[string]JobFunc
+	opts  JobOptions
+	now   func() time.Time
+	queue chan string
+
+	mu      sync.Mutex
+	cancels map[string]context.CancelFunc
+}
+
+func NewJobService(jobs repo.JobRepository, funcs map[string]JobFunc, opts JobOptions) JobService {
+	if opts.Workers <= 0 {
+		opts.Workers = DefaultJobOptions.Workers
+	}
+	if opts.QueueSize <= 0 {
+		opts.QueueSize = DefaultJobOptions.QueueSize
+	}
+	if opts.TTL <= 0 {
+		opts.TTL = DefaultJobOptions.TTL
+	}
+	return &jobService{
+		jobs:    jobs,
+		funcs:   funcs,
+		opts:    opts,
+		now:     time.Now,
+		queue:   make(chan string, opts.QueueSize),
+		cancels: make(map[string]context.CancelFunc),
+	}
+}
+
+// Submit queues a job that runs on behalf of the principal in ctx.
+func (js *jobService) Submit(ctx context.Context, jobType string, request json.RawMessage) (*models.Job, error) {
+	if _, ok := js.funcs[jobType]; !ok {
+		return nil, fmt.Errorf("%w: %q", ErrUnsupportedJobType, jobType)
+	}
+	principal, _ := auth.PrincipalFromContext(ctx)
+	job := &models.Job{
+		Type:      jobType,
+		Status:    models.JobStatusQueued,
+		Request:   request,
+		Principal: principal,
+		RequestId: requestid.FromContext(ctx),
+		CreatedAt: js.now().UTC(),
+	}
+	if err := js.jobs.AddJob(job); err != nil {
+		return nil, fmt.Errorf("couldn't add job: %w", err)
+	}
+
+	select {
+	case js.queue <- job.ID:
+		return job, nil
+	default:
+		if err := js.jobs.DeleteJob(job.ID); err != nil {
+			slog.ErrorContext(ctx, "couldn't delete rejected job", slog.String("job_id", job.ID), slog.Any("error", err))
+		}
+		return nil, ErrJobQueueFull
+	}
+}
+
+// GetJob returns a job that the principal in ctx submitted. Other principals'
+// jobs are reported as not found, unless the principal is an admin.
+func (js *jobService) GetJob(ctx context.Context, id string) (*models.Job, error) {
+	job, err := js.jobs.GetJob(id)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't get job: %w", err)
+	}
+	if js.expired(job) || !canAccessJob(ctx, job) {
+		return nil, fmt.Errorf("couldn't get job: %w", repo.ErrJobNotFound)
+	}
+	return job, nil
+}
+
+// Cancel cancels a queued job immediately and signals a running job to stop;
+// the running job is marked cancelled once its function returns. Only the
+// principal that submitted the job and admins may cancel it.
+func (js *jobService) Cancel(ctx context.Context, id string) (*models.Job, error) {
+	js.mu.Lock()
+	defer js.mu.Unlock()
+
+	job, err := js.jobs.GetJob(id)
+	if err != nil || js.expired(job) || !canAccessJob(ctx, job) {
+		return nil, fmt.Errorf("couldn't cancel job: %w", repo.ErrJobNotFound)
+	}
+	switch job.Status {
+	case models.JobStatusQueued:
+		js.finish(job, models.JobStatusCancelled, context.Canceled.Error())
+		if err := js.jobs.UpdateJob(job); err != nil {
+			return nil, fmt.Errorf("couldn't cancel job: %w", err)
+		}
+	case models.JobStatusRunning:
+		if cancel, ok := js.cancels[id]; ok {
+			cancel()
+		}
+	default:
+		return nil, ErrJobFinished
+	}
+	return job, nil
+}
+
+// Run starts the worker pool and the expiry janitor and blocks until ctx is
+// cancelled. Jobs left queued or running by a previous process are requeued.
+func (js *jobService) Run(ctx context.Context) {
+	js.recover()
+
+	var wg sync.WaitGroup
+	for i := 0; i < js.opts.Workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			js.work(ctx)
+		}()
+	}
+
+	janitor := time.NewTicker(js.janitorInterval())
+	defer janitor.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			wg.Wait()
+			return
+		case <-janitor.C:
+			js.removeExpired()
+		}
+	}
+}
+
+func (js *jobService) work(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case id := <-js.queue:
+			js.execute(ctx, id)
+		}
+	}
+}
+
+func (js *jobService) execute(ctx context.Context, id string) {
+	jobCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	js.mu.Lock()
+	job, err := js.jobs.GetJob(id)
+	if err != nil || job.Status != models.JobStatusQueued {
+		js.mu.Unlock()
+		return
+	}
+	started := js.now().UTC()
+	job.Status = models.JobStatusRunning
+	job.StartedAt = &started
+	if err := js.jobs.UpdateJob(job); err != nil {
+		js.mu.Unlock()
+		slog.ErrorContext(requestid.NewContext(ctx, job.RequestId), "couldn't start job", slog.String("job_id", id), slog.Any("error", err))
+		return
+	}
+	js.cancels[id] = cancel
+	js.mu.Unlock()
+
+	// The job runs as the principal that submitted it, and its logs carry the
+	// ID of the submitting request.
+	runCtx := requestid.NewContext(auth.WithPrincipal(jobCtx, job.Principal), job.RequestId)
+	progress := func(percent int) {
+		js.mu.Lock()
+		defer js.mu.Unlock()
+		job.Progress = min(max(percent, 0), 100)
+		if err := js.jobs.UpdateJob(job); err != nil {
+			slog.ErrorContext(runCtx, "couldn't update job progress", slog.String("job_id", id), slog.Any("error", err))
+		}
+	}
+	result, contentType, runErr := js.funcs[job.Type](runCtx, job.Request, progress)
+
+	js.mu.Lock()
+	defer js.mu.Unlock()
+	delete(js.cancels, id)
+	switch {
+	case jobCtx.Err() != nil && ctx.Err() == nil:
+		js.finish(job, models.JobStatusCancelled, context.Canceled.Error())
+	case ctx.Err() != nil:
+		// The service is shutting down; leave the job running so that it is
+		// requeued on the next start if the repository is durable.
+		return
+	case runErr != nil:
+		js.finish(job, models.JobStatusFailed, runErr.Error())
+	default:
+		job.Progress = 100
+		job.Result = result
+		job.ContentType = contentType
+		js.finish(job, models.JobStatusSucceeded, "")
+	}
+	if err := js.jobs.UpdateJob(job); err != nil {
+		slog.ErrorContext(runCtx, "couldn't finish job", slog.String("job_id", id), slog.Any("error", err))
+	}
+}
+
+func (js *jobService) finish(job *models.Job, status string, message string) {
+	finished := js.now().UTC()
+	expires := finished.Add(js.opts.TTL)
+	job.Status = status
+	job.Error = message
+	job.FinishedAt = &finished
+	job.ExpiresAt = &expires
+}
+
+func (js *jobService) recover() {
+	jobs, err := js.jobs.GetAllJobs()
+	if err != nil {
+		slog.Error("couldn't recover jobs", slog.Any("error", err))
+		return
+	}
+	for _, job := range jobs {
+		if job.Finished() {
+			continue
+		}
+		job.Status = models.JobStatusQueued
+		job.Progress = 0
+		job.StartedAt = nil
+		if err := js.jobs.UpdateJob(job); err != nil {
+			slog.Error("couldn't requeue job", slog.String("job_id", job.ID), slog.Any("error", err))
+			continue
+		}
+		select {
+		case js.queue <- job.ID:
+		default:
+			slog.Error("couldn't requeue job", slog.String("job_id", job.ID), slog.Any("error", ErrJobQueueFull))
+		}
+	}
+}
+
+func (js *jobService) removeExpired() {
+	jobs, err := js.jobs.GetAllJobs()
+	if err != nil {
+		slog.Error("couldn't remove expired jobs", slog.Any("error", err))
+		return
+	}
+	for _, job := range jobs {
+		if js.expired(job) {
+			if err := js.jobs.DeleteJob(job.ID); err != nil && !errors.Is(err, repo.ErrJobNotFound) {
+				slog.Error("couldn't remove expired job", slog.String("job_id", job.ID), slog.Any("error", err))
+			}
+		}
+	}
+}
+
+// canAccessJob reports whether the principal in ctx submitted the job or is an
+// admin.
+func canAccessJob(ctx context.Context, job *models.Job) bool {
+	principal, ok := auth.PrincipalFromContext(ctx)
+	if !ok {
+		return false
+	}
+	if auth.HasPermission(principal, auth.PermissionAdmin) {
+		return true
+	}
+	return job.Principal != nil && job.Principal.Subject == principal.Subject && job.Principal.Method == principal.Method
+}
+
+func (js *jobService) expired(job *models.Job) bool {
+	return job.ExpiresAt != nil && !js.now().Before(*job.ExpiresAt)
+}
+
+func (js *jobService) janitorInterval() time.Duration {
+	return min(js.opts.TTL, time.Minute)
+}
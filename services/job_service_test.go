@@ -0,0 +1,233 @@
+package services
+
+import (
+	"context"
+	"dataflow/auth"
+	"dataflow/models"
+	"dataflow/repo"
+	"encoding/json"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func echoJob(ctx context.Context, request json.RawMessage, progress func(int)) ([]byte, string, error) {
+	progress(50)
+	return request, "application/json", nil
+}
+
+func failingJob(ctx context.Context, request json.RawMessage, progress func(int)) ([]byte, string, error) {
+	return nil, "", errors.New("boom")
+}
+
+func blockingJob(started chan<- struct{}) JobFunc {
+	return func(ctx context.Context, request json.RawMessage, progress func(int)) ([]byte, string, error) {
+		close(started)
+		<-ctx.Done()
+		return nil, "", ctx.Err()
+	}
+}
+
+func startJobService(t *testing.T, jobs repo.JobRepository, funcs map[string]JobFunc, opts JobOptions) JobService {
+	service := NewJobService(jobs, funcs, opts)
+	ctx, cancel := context.WithCancel(context.Background())
+	go service.Run(ctx)
+	t.Cleanup(cancel)
+	return service
+}
+
+// adminContext carries an admin principal, who may see and cancel every job.
+var adminContext = auth.WithPrincipal(context.Background(), auth.SystemPrincipal)
+
+func waitForJob(t *testing.T, service JobService, id string) *models.Job {
+	var job *models.Job
+	assert.Eventually(t, func() bool {
+		job, _ = service.GetJob(adminContext, id)
+		return job != nil && job.Finished()
+	}, time.Second, time.Millisecond)
+	return job
+}
+
+func TestJobService_Submit_Principal(t *testing.T) {
+	principal := &models.Principal{Subject: "manager-1", Roles: []string{auth.RoleStoreManager}, Stores: []string{"6789"}}
+	whoami := func(ctx context.Context, request json.RawMessage, progress func(int)) ([]byte, string, error) {
+		p, _ := auth.PrincipalFromContext(ctx)
+		return []byte(p.Subject), "text/plain", nil
+	}
+	service := startJobService(t, repo.NewInMemoryJobRepository(), map[string]JobFunc{"whoami": whoami}, DefaultJobOptions)
+
+	job, err := service.Submit(auth.WithPrincipal(context.Background(), principal), "whoami", json.RawMessage(`{}`))
+	assert.Nil(t, err)
+	assert.Equal(t, principal, job.Principal)
+
+	finished := waitForJob(t, service, job.ID)
+	assert.Equal(t, []byte("manager-1"), finished.Result)
+}
+
+func TestJobService_OtherPrincipalsJobs(t *testing.T) {
+	managerA := &models.Principal{Subject: "manager-a", Method: auth.MethodAPIKey, Roles: []string{auth.RoleStoreManager}, Stores: []string{"6789"}}
+	managerB := &models.Principal{Subject: "manager-b", Method: auth.MethodAPIKey, Roles: []string{auth.RoleStoreManager}, Stores: []string{"9876"}}
+	service := NewJobService(repo.NewInMemoryJobRepository(), map[string]JobFunc{"echo": echoJob}, DefaultJobOptions)
+
+	job, err := service.Submit(auth.WithPrincipal(context.Background(), managerB), "echo", json.RawMessage(`{"store_id":"9876"}`))
+	assert.Nil(t, err)
+
+	ctxA := auth.WithPrincipal(context.Background(), managerA)
+	_, err = service.GetJob(ctxA, job.ID)
+	assert.ErrorIs(t, err, repo.ErrJobNotFound)
+	_, err = service.Cancel(ctxA, job.ID)
+	assert.ErrorIs(t, err, repo.ErrJobNotFound)
+	_, err = service.GetJob(context.Background(), job.ID)
+	assert.ErrorIs(t, err, repo.ErrJobNotFound)
+
+	own, err := service.GetJob(auth.WithPrincipal(context.Background(), managerB), job.ID)
+	assert.Nil(t, err)
+	assert.Equal(t, models.JobStatusQueued, own.Status)
+	_, err = service.GetJob(adminContext, job.ID)
+	assert.Nil(t, err)
+}
+
+func TestJobService_Submit(t *testing.T) {
+	service := startJobService(t, repo.NewInMemoryJobRepository(), map[string]JobFunc{"echo": echoJob}, DefaultJobOptions)
+
+	job, err := service.Submit(context.Background(), "echo", json.RawMessage(`{"a":1}`))
+	assert.Nil(t, err)
+	assert.Equal(t, models.JobStatusQueued, job.Status)
+
+	finished := waitForJob(t, service, job.ID)
+	assert.Equal(t, models.JobStatusSucceeded, finished.Status)
+	assert.Equal(t, 100, finished.Progress)
+	assert.Equal(t, []byte(`{"a":1}`), finished.Result)
+	assert.NotNil(t, finished.ExpiresAt)
+}
+
+func TestJobService_Submit_UnsupportedType(t *testing.T) {
+	service := NewJobService(repo.NewInMemoryJobRepository(), map[string]JobFunc{}, DefaultJobOptions)
+
+	_, err := service.Submit(context.Background(), "echo", nil)
+	assert.ErrorIs(t, err, ErrUnsupportedJobType)
+}
+
+func TestJobService_Submit_QueueFull(t *testing.T) {
+	jobs := repo.NewInMemoryJobRepository()
+	service := NewJobService(jobs, map[string]JobFunc{"echo": echoJob}, JobOptions{QueueSize: 1})
+
+	_, err := service.Submit(context.Background(), "echo", nil)
+	assert.Nil(t, err)
+	_, err = service.Submit(context.Background(), "echo", nil)
+	assert.ErrorIs(t, err, ErrJobQueueFull)
+
+	all, _ := jobs.GetAllJobs()
+	assert.Len(t, all, 1)
+}
+
+func TestJobService_Failed(t *testing.T) {
+	service := startJobService(t, repo.NewInMemoryJobRepository(), map[string]JobFunc{"fail": failingJob}, DefaultJobOptions)
+
+	job, _ := service.Submit(context.Background(), "fail", nil)
+
+	finished := waitForJob(t, service, job.ID)
+	assert.Equal(t, models.JobStatusFailed, finished.Status)
+	assert.Equal(t, "boom", finished.Error)
+}
+
+func TestJobService_Cancel_Running(t *testing.T) {
+	started := make(chan struct{})
+	service := startJobService(t, repo.NewInMemoryJobRepository(), map[string]JobFunc{"block": blockingJob(started)}, DefaultJobOptions)
+
+	job, _ := service.Submit(context.Background(), "block", nil)
+	<-started
+
+	_, err := service.Cancel(adminContext, job.ID)
+	assert.Nil(t, err)
+
+	finished := waitForJob(t, service, job.ID)
+	assert.Equal(t, models.JobStatusCancelled, finished.Status)
+
+	_, err = service.Cancel(adminContext, job.ID)
+	assert.ErrorIs(t, err, ErrJobFinished)
+}
+
+func TestJobService_Cancel_Queued(t *testing.T) {
+	service := NewJobService(repo.NewInMemoryJobRepository(), map[string]JobFunc{"echo": echoJob}, DefaultJobOptions)
+
+	job, _ := service.Submit(context.Background(), "echo", nil)
+	cancelled, err := service.Cancel(adminContext, job.ID)
+	assert.Nil(t, err)
+	assert.Equal(t, models.JobStatusCancelled, cancelled.Status)
+}
+
+func TestJobService_Expiry(t *testing.T) {
+	jobs := repo.NewInMemoryJobRepository()
+	service := NewJobService(jobs, map[string]JobFunc{"echo": echoJob}, JobOptions{TTL: time.Minute}).(*jobService)
+	now := time.Date(2024, 6, 15, 14, 30, 0, 0, time.UTC)
+	service.now = func() time.Time { return now }
+
+	job, _ := service.Submit(context.Background(), "echo", nil)
+	service.execute(context.Background(), <-service.queue)
+
+	_, err := service.GetJob(adminContext, job.ID)
+	assert.Nil(t, err)
+
+	now = now.Add(time.Minute)
+	_, err = service.GetJob(adminContext, job.ID)
+	assert.ErrorIs(t, err, repo.ErrJobNotFound)
+
+	service.removeExpired()
+	_, err = jobs.GetJob(job.ID)
+	assert.ErrorIs(t, err, repo.ErrJobNotFound)
+}
+
+func TestJobService_Run_RequeuesUnfinishedJobs(t *testing.T) {
+	jobs := repo.NewInMemoryJobRepository()
+	interrupted := &models.Job{Type: "echo", Status: models.JobStatusRunning, Request: json.RawMessage(`{}`)}
+	jobs.AddJob(interrupted)
+
+	service := startJobService(t, jobs, map[string]JobFunc{"echo": echoJob}, DefaultJobOptions)
+
+	finished := waitForJob(t, service, interrupted.ID)
+	assert.Equal(t, models.JobStatusSucceeded, finished.Status)
+}
+
+func TestJobService_Run_ResumesJobsAfterRestart(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "jobs")
+	jobs, err := repo.NewFileJobRepository(dir)
+	assert.Nil(t, err)
+	// The worker may pick up the queued job while stopping, so the job that
+	// blocks can start more than once.
+	started := make(chan struct{}, 1)
+	block := func(ctx context.Context, request json.RawMessage, progress func(int)) ([]byte, string, error) {
+		select {
+		case started <- struct{}{}:
+		default:
+		}
+		<-ctx.Done()
+		return nil, "", ctx.Err()
+	}
+	service := NewJobService(jobs, map[string]JobFunc{"echo": block}, JobOptions{Workers: 1})
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan struct{})
+	go func() {
+		service.Run(ctx)
+		close(stopped)
+	}()
+
+	running, _ := service.Submit(context.Background(), "echo", json.RawMessage(`{"a":1}`))
+	<-started
+	queued, _ := service.Submit(context.Background(), "echo", json.RawMessage(`{"a":2}`))
+	cancel()
+	<-stopped
+
+	jobs, err = repo.NewFileJobRepository(dir)
+	assert.Nil(t, err)
+	restarted := startJobService(t, jobs, map[string]JobFunc{"echo": echoJob}, DefaultJobOptions)
+
+	finished := waitForJob(t, restarted, running.ID)
+	assert.Equal(t, models.JobStatusSucceeded, finished.Status)
+	assert.Equal(t, []byte(`{"a":1}`), finished.Result)
+	finished = waitForJob(t, restarted, queued.ID)
+	assert.Equal(t, models.JobStatusSucceeded, finished.Status)
+	assert.Equal(t, []byte(`{"a":2}`), finished.Result)
+}
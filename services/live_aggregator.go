@@ -0,0 +1,408 @@
+package services
+
+import (
+	"context"
+	"dataflow/auth"
+	"dataflow/models"
+	"dataflow/repo"
+	"errors"
+	"log/slog"
+	"math/big"
+	"sync"
+	"time"
+)
+
+const (
+	MetricTotalSales = "total_sales"
+	MetricUnitsSold  = "units_sold"
+	MetricSaleCount  = "sale_count"
+)
+
+var ErrUnsupportedMetric = errors.New("unsupported metric")
+
+// rolloverCheckInterval is how often the aggregator checks whether the day of a
+// store changed while no sales arrived, and reloads the stores' time zones.
+const rolloverCheckInterval = time.Minute
+
+type aggregateKey struct {
+	storeId string
+	metric  string
+}
+
+type storeTotals struct {
+	revenue *big.Float
+	units   int64
+	count   int64
+}
+
+// LiveAggregator keeps running totals of today's sales per store, where today
+// is the day in the store's time zone, or the UTC day for stores without one.
+// The totals of the catalog's stores are seeded from the repository when it
+// starts; after that it is fed exclusively by the sale broker, so watchers
+// never cause repository reads.
+type LiveAggregator struct {
+	broker  *SaleBroker
+	data    DataService
+	catalog CatalogService
+	now     func() time.Time
+
+	mu        sync.Mutex
+	locations map[string]*time.Location
+	// days holds the day of every store's totals, as a UTC midnight.
+	days   map[string]time.Time
+	lastID uint64
+	totals map[string]*storeTotals
+	// seeded holds the IDs of the sales counted by seed until the first
+	// rollover check, so that their events aren't counted again.
+	seeded   map[string]struct{}
+	watchers map[*AggregateWatch]struct{}
+	stopped  bool
+}
+
+// AggregateWatch collects updates for a set of store/metric subscriptions.
+// Only the latest value per subscription is kept, so a slow reader sees fewer
+// intermediate values instead of holding up the aggregator.
+type AggregateWatch struct {
+	aggregator *LiveAggregator
+
+	mu      sync.Mutex
+	keys    map[aggregateKey]struct{}
+	pending map[aggregateKey]models.LiveAggregate
+	order   []aggregateKey
+	ready   chan struct{}
+	done    chan struct{}
+	once    sync.Once
+}
+
+// NewLiveAggregator seeds today's totals of the stores of catalog from data
+// and takes the stores' time zones from catalog. Either may be nil; without
+// both, totals start at zero, and without catalog every store counts UTC days.
+func NewLiveAggregator(broker *SaleBroker, data DataService, catalog CatalogService) *LiveAggregator {
+	return &LiveAggregator{
+		broker:   broker,
+		data:     data,
+		catalog:  catalog,
+		now:      time.Now,
+		days:     make(map[string]time.Time),
+		totals:   make(map[string]*storeTotals),
+		watchers: make(map[*AggregateWatch]struct{}),
+	}
+}
+
+// Run seeds the totals, then consumes sale events until ctx is cancelled or
+// the broker is closed and then closes every watch. If the aggregator falls
+// behind, it resumes from the broker's replay buffer. The repository is read
+// as the system principal so that every store is seeded.
+func (a *LiveAggregator) Run(ctx context.Context) {
+	defer a.stop()
+
+	a.loadLocations(ctx)
+	// Subscribing first leaves no gap between the seeded sales and the
+	// events.
+	sub, _ := a.broker.Subscribe(0)
+	a.seed(auth.WithPrincipal(ctx, auth.SystemPrincipal))
+	rollover := time.NewTicker(rolloverCheckInterval)
+	defer rollover.Stop()
+
+	for {
+		if !a.consume(ctx, sub, rollover.C) {
+			return
+		}
+		if a.broker.Closed() {
+			return
+		}
+
+		a.mu.Lock()
+		lastID := a.lastID
+		a.mu.Unlock()
+
+		var replay []SaleEvent
+		sub, replay = a.broker.Subscribe(lastID)
+		if len(replay) > 0 && replay[0].ID > lastID+1 {
+			slog.WarnContext(ctx, "live aggregates missed sale events", slog.Uint64("missed", replay[0].ID-lastID-1))
+		}
+		for _, event := range replay {
+			a.apply(event)
+		}
+	}
+}
+
+func (a *LiveAggregator) consume(ctx context.Context, sub *Subscription, rollover <-chan time.Time) bool {
+	defer sub.Close()
+	for {
+		select {
+		case <-ctx.Done():
+			return false
+		case event, ok := <-sub.Events():
+			if !ok {
+				return true
+			}
+			a.apply(event)
+		case <-rollover:
+			a.loadLocations(ctx)
+			a.mu.Lock()
+			// Events of seeded sales are published right after the sales
+			// are stored, so any still to come have arrived by now.
+			a.seeded = nil
+			for storeId := range a.days {
+				if a.rollover(storeId) {
+					a.publish(storeId)
+				}
+			}
+			a.mu.Unlock()
+		}
+	}
+}
+
+func (a *LiveAggregator) Watch() *AggregateWatch {
+	w := &AggregateWatch{
+		aggregator: a,
+		keys:       make(map[aggregateKey]struct{}),
+		pending:    make(map[aggregateKey]models.LiveAggregate),
+		ready:      make(chan struct{}, 1),
+		done:       make(chan struct{}),
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.stopped {
+		w.close()
+		return w
+	}
+	a.watchers[w] = struct{}{}
+	return w
+}
+
+func (a *LiveAggregator) apply(event SaleEvent) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if event.ID <= a.lastID {
+		return
+	}
+	a.lastID = event.ID
+
+	sale := event.Sale
+	if _, ok := a.seeded[sale.ID]; ok {
+		delete(a.seeded, sale.ID)
+		return
+	}
+	rolledOver := a.rollover(sale.StoreId)
+	if !localDay(sale.SaleDate, locationOf(a.locations, sale.StoreId)).Equal(a.days[sale.StoreId]) {
+		if rolledOver {
+			a.publish(sale.StoreId)
+		}
+		return
+	}
+	totals := a.storeTotals(sale.StoreId)
+	totals.revenue.Add(totals.revenue, saleAmount(sale))
+	totals.units += int64(sale.QuantitySold)
+	totals.count++
+	a.publish(sale.StoreId)
+}
+
+// seed adds the sales of the current day of every store of the catalog to
+// its totals. A store that can't be read starts at zero.
+func (a *LiveAggregator) seed(ctx context.Context) {
+	if a.data == nil || a.catalog == nil {
+		return
+	}
+	stores, err := a.catalog.GetAllStores(ctx)
+	if err != nil {
+		slog.ErrorContext(ctx, "couldn't seed live aggregates", slog.Any("error", err))
+		return
+	}
+	for _, store := range stores {
+		a.mu.Lock()
+		location := locationOf(a.locations, store.ID)
+		a.mu.Unlock()
+		day := localDay(a.now(), location)
+		sales, err := a.data.GetSalesInRange(ctx, repo.StartOfDay(day, location), repo.StartOfDay(day.AddDate(0, 0, 1), location), store.ID)
+		if err != nil {
+			slog.ErrorContext(ctx, "couldn't seed live aggregates", slog.String("store_id", store.ID), slog.Any("error", err))
+			continue
+		}
+
+		a.mu.Lock()
+		a.rollover(store.ID)
+		if a.days[store.ID].Equal(day) && len(sales) > 0 {
+			if a.seeded == nil {
+				a.seeded = make(map[string]struct{})
+			}
+			totals := a.storeTotals(store.ID)
+			for _, sale := range sales {
+				a.seeded[sale.ID] = struct{}{}
+				totals.revenue.Add(totals.revenue, saleAmount(sale))
+				totals.units += int64(sale.QuantitySold)
+				totals.count++
+			}
+			a.publish(store.ID)
+		}
+		a.mu.Unlock()
+	}
+}
+
+// loadLocations reloads the time zones of the stores from the catalog. A
+// failure keeps the previous ones.
+func (a *LiveAggregator) loadLocations(ctx context.Context) {
+	if a.catalog == nil {
+		return
+	}
+	locations, err := a.catalog.StoreLocations(ctx)
+	if err != nil {
+		slog.ErrorContext(ctx, "couldn't load store time zones for live aggregates", slog.Any("error", err))
+		return
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.locations = locations
+}
+
+// rollover resets the totals of a store when its day has changed and reports
+// whether it did.
+func (a *LiveAggregator) rollover(storeId string) bool {
+	today := localDay(a.now(), locationOf(a.locations, storeId))
+	if day, ok := a.days[storeId]; ok && day.Equal(today) {
+		return false
+	}
+	a.days[storeId] = today
+	delete(a.totals, storeId)
+	return true
+}
+
+// publish pushes the values of a store to the watchers subscribed to it.
+func (a *LiveAggregator) publish(storeId string) {
+	for w := range a.watchers {
+		for _, metric := range []string{MetricTotalSales, MetricUnitsSold, MetricSaleCount} {
+			w.push(aggregateKey{storeId: storeId, metric: metric}, a.value)
+		}
+	}
+}
+
+func (a *LiveAggregator) storeTotals(storeId string) *storeTotals {
+	totals, ok := a.totals[storeId]
+	if !ok {
+		totals = &storeTotals{revenue: new(big.Float).SetPrec(64)}
+		a.totals[storeId] = totals
+	}
+	return totals
+}
+
+func (a *LiveAggregator) value(key aggregateKey) models.LiveAggregate {
+	aggregate := models.LiveAggregate{
+		StoreId: key.storeId,
+		Metric:  key.metric,
+		Date:    a.days[key.storeId].Format(time.DateOnly),
+		EventId: a.lastID,
+	}
+	totals, ok := a.totals[key.storeId]
+	if !ok {
+		return aggregate
+	}
+	switch key.metric {
+	case MetricTotalSales:
+		revenue, _ := totals.revenue.Float64()
+		aggregate.Value = roundTo(revenue, 2)
+	case MetricUnitsSold:
+		aggregate.Value = float64(totals.units)
+	case MetricSaleCount:
+		aggregate.Value = float64(totals.count)
+	}
+	return aggregate
+}
+
+func (a *LiveAggregator) stop() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.stopped = true
+	for w := range a.watchers {
+		delete(a.watchers, w)
+		w.close()
+	}
+}
+
+// Subscribe adds a store/metric subscription and queues its current value.
+func (w *AggregateWatch) Subscribe(storeId string, metric string) error {
+	switch metric {
+	case MetricTotalSales, MetricUnitsSold, MetricSaleCount:
+	default:
+		return ErrUnsupportedMetric
+	}
+	key := aggregateKey{storeId: storeId, metric: metric}
+
+	a := w.aggregator
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.rollover(storeId) {
+		a.publish(storeId)
+	}
+	w.mu.Lock()
+	w.keys[key] = struct{}{}
+	w.mu.Unlock()
+	w.push(key, a.value)
+	return nil
+}
+
+func (w *AggregateWatch) Unsubscribe(storeId string, metric string) {
+	key := aggregateKey{storeId: storeId, metric: metric}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	delete(w.keys, key)
+	delete(w.pending, key)
+}
+
+// Ready receives a value whenever Drain has new updates.
+func (w *AggregateWatch) Ready() <-chan struct{} {
+	return w.ready
+}
+
+// Done is closed when the watch is closed or the aggregator stops.
+func (w *AggregateWatch) Done() <-chan struct{} {
+	return w.done
+}
+
+// Drain returns the pending updates in the order they were first queued.
+func (w *AggregateWatch) Drain() []models.LiveAggregate {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	updates := make([]models.LiveAggregate, 0, len(w.order))
+	for _, key := range w.order {
+		if update, ok := w.pending[key]; ok {
+			updates = append(updates, update)
+		}
+	}
+	w.pending = make(map[aggregateKey]models.LiveAggregate)
+	w.order = w.order[:0]
+	return updates
+}
+
+func (w *AggregateWatch) Close() {
+	a := w.aggregator
+	a.mu.Lock()
+	delete(a.watchers, w)
+	a.mu.Unlock()
+	w.close()
+}
+
+// push must be called with the aggregator lock held.
+func (w *AggregateWatch) push(key aggregateKey, value func(aggregateKey) models.LiveAggregate) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if _, ok := w.keys[key]; !ok {
+		return
+	}
+	w.queue(key, value(key))
+}
+
+func (w *AggregateWatch) queue(key aggregateKey, update models.LiveAggregate) {
+	if _, ok := w.pending[key]; !ok {
+		w.order = append(w.order, key)
+	}
+	w.pending[key] = update
+	select {
+	case w.ready <- struct{}{}:
+	default:
+	}
+}
+
+func (w *AggregateWatch) close() {
+	w.once.Do(func() { close(w.done) })
+}
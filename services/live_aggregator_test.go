@@ -0,0 +1,171 @@
+package services
+
+import (
+	"context"
+	"dataflow/models"
+	"dataflow/repo"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func startAggregator(t *testing.T, now time.Time) (*SaleBroker, *LiveAggregator, context.CancelFunc) {
+	broker := NewSaleBroker(10, 10)
+	aggregator := NewLiveAggregator(broker, nil, nil)
+	aggregator.now = func() time.Time { return now }
+	ctx, cancel := context.WithCancel(context.Background())
+	go aggregator.Run(ctx)
+	assert.Eventually(t, func() bool { return broker.Subscribers() == 1 }, time.Second, time.Millisecond)
+	return broker, aggregator, cancel
+}
+
+func waitForUpdates(t *testing.T, watch *AggregateWatch) []models.LiveAggregate {
+	select {
+	case <-watch.Ready():
+		return watch.Drain()
+	case <-time.After(time.Second):
+		t.Fatal("no aggregate update received")
+		return nil
+	}
+}
+
+func TestLiveAggregator_Subscribe_CurrentValue(t *testing.T) {
+	now := time.Date(2024, 6, 15, 14, 30, 0, 0, time.UTC)
+	_, aggregator, cancel := startAggregator(t, now)
+	defer cancel()
+
+	watch := aggregator.Watch()
+	defer watch.Close()
+	assert.Nil(t, watch.Subscribe("6789", MetricTotalSales))
+
+	updates := waitForUpdates(t, watch)
+	assert.Equal(t, []models.LiveAggregate{{StoreId: "6789", Metric: MetricTotalSales, Date: "2024-06-15"}}, updates)
+}
+
+func TestLiveAggregator_RunningTotals(t *testing.T) {
+	now := time.Date(2024, 6, 15, 14, 30, 0, 0, time.UTC)
+	broker, aggregator, cancel := startAggregator(t, now)
+	defer cancel()
+
+	watch := aggregator.Watch()
+	defer watch.Close()
+	watch.Subscribe("6789", MetricTotalSales)
+	watch.Subscribe("6789", MetricSaleCount)
+	waitForUpdates(t, watch)
+
+	broker.Publish(&models.Sale{StoreId: "6789", QuantitySold: 10, SalePrice: 19.99, SaleDate: now})
+	broker.Publish(&models.Sale{StoreId: "9876", QuantitySold: 1, SalePrice: 5, SaleDate: now})
+	broker.Publish(&models.Sale{StoreId: "6789", QuantitySold: 1, SalePrice: 100, SaleDate: now.AddDate(0, 0, -1)})
+	broker.Publish(&models.Sale{StoreId: "6789", QuantitySold: 2, SalePrice: 0.05, SaleDate: now})
+
+	var latest map[string]models.LiveAggregate
+	assert.Eventually(t, func() bool {
+		latest = make(map[string]models.LiveAggregate)
+		for _, update := range watch.Drain() {
+			latest[update.Metric] = update
+		}
+		return latest[MetricTotalSales].EventId == 4
+	}, time.Second, time.Millisecond)
+	assert.Equal(t, 200.0, latest[MetricTotalSales].Value)
+	assert.Equal(t, 2.0, latest[MetricSaleCount].Value)
+}
+
+func TestLiveAggregator_Subscribe_UnsupportedMetric(t *testing.T) {
+	aggregator := NewLiveAggregator(NewSaleBroker(10, 10), nil, nil)
+	watch := aggregator.Watch()
+
+	err := watch.Subscribe("6789", "profit")
+	assert.ErrorIs(t, err, ErrUnsupportedMetric)
+}
+
+func TestLiveAggregator_Rollover(t *testing.T) {
+	now := time.Date(2024, 6, 15, 23, 59, 0, 0, time.UTC)
+	aggregator := NewLiveAggregator(NewSaleBroker(10, 10), nil, nil)
+	aggregator.now = func() time.Time { return now }
+
+	aggregator.apply(SaleEvent{ID: 1, Sale: &models.Sale{StoreId: "6789", QuantitySold: 1, SalePrice: 10, SaleDate: now}})
+	now = now.Add(2 * time.Minute)
+
+	watch := aggregator.Watch()
+	watch.Subscribe("6789", MetricTotalSales)
+	updates := watch.Drain()
+	assert.Len(t, updates, 1)
+	assert.Equal(t, "2024-06-16", updates[0].Date)
+	assert.Equal(t, 0.0, updates[0].Value)
+}
+
+func TestLiveAggregator_StopClosesWatches(t *testing.T) {
+	_, aggregator, cancel := startAggregator(t, time.Now())
+	watch := aggregator.Watch()
+
+	cancel()
+
+	select {
+	case <-watch.Done():
+	case <-time.After(time.Second):
+		t.Fatal("watch not closed on shutdown")
+	}
+}
+
+func TestLiveAggregator_StoreTimeZone(t *testing.T) {
+	// 00:30 in Paris, where the clocks move forward at the end of the day.
+	now := time.Date(2024, 3, 30, 23, 30, 0, 0, time.UTC)
+	aggregator := NewLiveAggregator(NewSaleBroker(10, 10), nil, newTestCatalog(repo.NewInMemoryRepository()))
+	aggregator.now = func() time.Time { return now }
+	aggregator.loadLocations(context.Background())
+
+	aggregator.apply(SaleEvent{ID: 1, Sale: &models.Sale{StoreId: "6789", QuantitySold: 1, SalePrice: 10, SaleDate: now.Add(-time.Hour)}})
+	aggregator.apply(SaleEvent{ID: 2, Sale: &models.Sale{StoreId: "6789", QuantitySold: 1, SalePrice: 20, SaleDate: now.Add(-10 * time.Minute)}})
+	aggregator.apply(SaleEvent{ID: 3, Sale: &models.Sale{StoreId: "9876", QuantitySold: 1, SalePrice: 40, SaleDate: now.Add(-time.Hour)}})
+
+	watch := aggregator.Watch()
+	watch.Subscribe("6789", MetricTotalSales)
+	watch.Subscribe("9876", MetricTotalSales)
+	assert.Equal(t, []models.LiveAggregate{
+		{StoreId: "6789", Metric: MetricTotalSales, Date: "2024-03-31", Value: 20, EventId: 3},
+		{StoreId: "9876", Metric: MetricTotalSales, Date: "2024-03-30", Value: 40, EventId: 3},
+	}, watch.Drain())
+
+	// 00:30 in summer time, an hour earlier in UTC than the day before.
+	now = time.Date(2024, 3, 31, 22, 30, 0, 0, time.UTC)
+	aggregator.apply(SaleEvent{ID: 4, Sale: &models.Sale{StoreId: "6789", QuantitySold: 1, SalePrice: 5, SaleDate: now}})
+	assert.Equal(t, []models.LiveAggregate{{StoreId: "6789", Metric: MetricTotalSales, Date: "2024-04-01", Value: 5, EventId: 4}}, watch.Drain())
+}
+
+func TestLiveAggregator_Seed(t *testing.T) {
+	now := time.Date(2024, 6, 15, 12, 0, 0, 0, time.UTC)
+	ctx := context.Background()
+	repository := repo.NewInMemoryRepository()
+	// 01:00 on the 15th and 23:00 on the 14th in Paris.
+	today := &models.Sale{StoreId: "6789", QuantitySold: 2, SalePrice: 10, SaleDate: time.Date(2024, 6, 14, 23, 0, 0, 0, time.UTC)}
+	repository.AddSale(ctx, today)
+	repository.AddSale(ctx, &models.Sale{StoreId: "6789", QuantitySold: 1, SalePrice: 100, SaleDate: time.Date(2024, 6, 14, 21, 0, 0, 0, time.UTC)})
+	repository.AddSale(ctx, &models.Sale{StoreId: "9876", QuantitySold: 1, SalePrice: 7, SaleDate: now.Add(-time.Hour)})
+
+	broker := NewSaleBroker(10, 10)
+	aggregator := NewLiveAggregator(broker, NewDataService(repository), newTestCatalog(repository))
+	aggregator.now = func() time.Time { return now }
+	runCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go aggregator.Run(runCtx)
+	assert.Eventually(t, func() bool { return broker.Subscribers() == 1 }, time.Second, time.Millisecond)
+
+	// The event of a seeded sale that was stored just before the aggregator
+	// started doesn't count it again.
+	broker.Publish(today)
+	broker.Publish(&models.Sale{ID: "new", StoreId: "6789", QuantitySold: 1, SalePrice: 5, SaleDate: now})
+	assert.Eventually(t, func() bool {
+		aggregator.mu.Lock()
+		defer aggregator.mu.Unlock()
+		return aggregator.lastID == 2
+	}, time.Second, time.Millisecond)
+
+	watch := aggregator.Watch()
+	defer watch.Close()
+	watch.Subscribe("6789", MetricTotalSales)
+	watch.Subscribe("9876", MetricSaleCount)
+	assert.Equal(t, []models.LiveAggregate{
+		{StoreId: "6789", Metric: MetricTotalSales, Date: "2024-06-15", Value: 25, EventId: 2},
+		{StoreId: "9876", Metric: MetricSaleCount, Date: "2024-06-15", Value: 1, EventId: 2},
+	}, watch.Drain())
+}
@@ -1,7 +1,9 @@
 package services
 
 import (
+	"context"
 	"dataflow/models"
+	"encoding/json"
 	"github.com/stretchr/testify/mock"
 	"math/big"
 	"time"
@@ -11,17 +13,125 @@ type MockService struct {
 	mock.Mock
 }
 
-func (m *MockService) GetAllSales() ([]*models.Sale, error) {
+func (m *MockService) GetSale(ctx context.Context, id string) (*models.Sale, error) {
+	args := m.Called(id)
+	sale, _ := args.Get(0).(*models.Sale)
+	return sale, args.Error(1)
+}
+
+func (m *MockService) GetAllSales(ctx context.Context) ([]*models.Sale, error) {
 	args := m.Called()
 	return args.Get(0).([]*models.Sale), args.Error(1)
 }
 
-func (m *MockService) AddSale(sale *models.Sale) error {
+func (m *MockService) GetSalesInRange(ctx context.Context, startDate time.Time, endDate time.Time, storeId string) ([]*models.Sale, error) {
+	args := m.Called(startDate, endDate, storeId)
+	return args.Get(0).([]*models.Sale), args.Error(1)
+}
+
+func (m *MockService) AddSale(ctx context.Context, sale *models.Sale) error {
 	args := m.Called(sale)
 	return args.Error(0)
 }
 
-func (m *MockService) CalculateSales(startDate time.Time, endDate time.Time, storeId string) (*big.Float, error) {
+func (m *MockService) CalculateSales(ctx context.Context, startDate time.Time, endDate time.Time, storeId string) (*big.Float, error) {
 	args := m.Called(startDate, endDate, storeId)
 	return args.Get(0).(*big.Float), args.Error(1)
 }
+
+func (m *MockService) CalculateBatch(ctx context.Context, startDate time.Time, endDate time.Time, storeIds []string, operations []string) (*models.BatchResult, error) {
+	args := m.Called(startDate, endDate, storeIds, operations)
+	result, _ := args.Get(0).(*models.BatchResult)
+	return result, args.Error(1)
+}
+
+func (m *MockService) Aggregate(ctx context.Context, query models.AggregateQuery) ([]*models.AggregateGroup, error) {
+	args := m.Called(query)
+	groups, _ := args.Get(0).([]*models.AggregateGroup)
+	return groups, args.Error(1)
+}
+
+func (m *MockService) Query(ctx context.Context, query models.SalesQuery) (*models.QueryResult, error) {
+	args := m.Called(query)
+	result, _ := args.Get(0).(*models.QueryResult)
+	return result, args.Error(1)
+}
+
+type MockAnomalyService struct {
+	mock.Mock
+}
+
+func (m *MockAnomalyService) DetectAnomalies(ctx context.Context, from time.Time, to time.Time, storeId string) ([]*models.Anomaly, error) {
+	args := m.Called(from, to, storeId)
+	return args.Get(0).([]*models.Anomaly), args.Error(1)
+}
+
+type MockAlertService struct {
+	mock.Mock
+}
+
+func (m *MockAlertService) CreateRule(rule *models.AlertRule) error {
+	args := m.Called(rule)
+	return args.Error(0)
+}
+
+func (m *MockAlertService) GetRule(id string) (*models.AlertRule, error) {
+	args := m.Called(id)
+	rule, _ := args.Get(0).(*models.AlertRule)
+	return rule, args.Error(1)
+}
+
+func (m *MockAlertService) GetAllRules() ([]*models.AlertRule, error) {
+	args := m.Called()
+	return args.Get(0).([]*models.AlertRule), args.Error(1)
+}
+
+func (m *MockAlertService) UpdateRule(rule *models.AlertRule) error {
+	args := m.Called(rule)
+	return args.Error(0)
+}
+
+func (m *MockAlertService) DeleteRule(id string) error {
+	args := m.Called(id)
+	return args.Error(0)
+}
+
+func (m *MockAlertService) GetDeliveries(ruleId string) ([]*models.WebhookDelivery, error) {
+	args := m.Called(ruleId)
+	return args.Get(0).([]*models.WebhookDelivery), args.Error(1)
+}
+
+func (m *MockAlertService) Evaluate(ctx context.Context, now time.Time) error {
+	args := m.Called(ctx, now)
+	return args.Error(0)
+}
+
+func (m *MockAlertService) Run(ctx context.Context, interval time.Duration) {
+	m.Called(ctx, interval)
+}
+
+type MockJobService struct {
+	mock.Mock
+}
+
+func (m *MockJobService) Submit(ctx context.Context, jobType string, request json.RawMessage) (*models.Job, error) {
+	args := m.Called(jobType, request)
+	job, _ := args.Get(0).(*models.Job)
+	return job, args.Error(1)
+}
+
+func (m *MockJobService) GetJob(ctx context.Context, id string) (*models.Job, error) {
+	args := m.Called(id)
+	job, _ := args.Get(0).(*models.Job)
+	return job, args.Error(1)
+}
+
+func (m *MockJobService) Cancel(ctx context.Context, id string) (*models.Job, error) {
+	args := m.Called(id)
+	job, _ := args.Get(0).(*models.Job)
+	return job, args.Error(1)
+}
+
+func (m *MockJobService) Run(ctx context.Context) {
+	m.Called(ctx)
+}
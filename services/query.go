@@ -0,0 +1,28 @@
+package services
+
+import (
+	"context"
+	"dataflow/models"
+	"dataflow/salesql"
+	"fmt"
+)
+
+var ErrInvalidQuery = salesql.ErrInvalidQuery
+
+// Query runs a statement of the SQL dialect of package salesql against the
+// repository, or only plans it when it's explained.
+func (ds *dataService) Query(ctx context.Context, query models.SalesQuery) (*models.QueryResult, error) {
+	plan, err := salesql.Prepare(query.Statement, ds.repo, query.StoreIds, salesql.Locations{Location: query.Location, Stores: query.StoreLocations})
+	if err != nil {
+		return nil, err
+	}
+	result := &models.QueryResult{Columns: plan.Columns()}
+	if query.Explain || plan.Explain() {
+		result.Plan = plan.Steps()
+		return result, nil
+	}
+	if result.Rows, err = plan.Execute(ctx); err != nil {
+		return nil, fmt.Errorf("couldn't run query: %w", err)
+	}
+	return result, nil
+}
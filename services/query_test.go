@@ -0,0 +1,58 @@
+package services
+
+import (
+	"context"
+	"dataflow/auth"
+	"dataflow/models"
+	"dataflow/repo"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestDataService_Query(t *testing.T) {
+	mockRepo := new(repo.MockRepository)
+	service := NewDataService(mockRepo)
+	mockRepo.On("GetAllSales").Return(batchSales(), nil)
+
+	result, err := service.Query(context.Background(), models.SalesQuery{
+		Statement: "SELECT store_id, SUM(quantity_sold) AS units FROM sales GROUP BY store_id ORDER BY units DESC",
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"store_id", "units"}, result.Columns)
+	assert.Equal(t, [][]interface{}{{"6789", int64(12)}, {"9876", int64(6)}}, result.Rows)
+	assert.Nil(t, result.Plan)
+
+	result, err = service.Query(context.Background(), models.SalesQuery{Statement: "SELECT id FROM sales", Explain: true})
+	assert.Nil(t, err)
+	assert.Nil(t, result.Rows)
+	assert.Equal(t, []*models.PlanStep{{Operation: "FullScan", Detail: "all sales"}}, result.Plan)
+	mockRepo.AssertNumberOfCalls(t, "GetAllSales", 1)
+
+	_, err = service.Query(context.Background(), models.SalesQuery{Statement: "DELETE FROM sales"})
+	assert.True(t, errors.Is(err, ErrInvalidQuery))
+}
+
+func TestAuthorizingDataService_Query(t *testing.T) {
+	mockRepo := new(repo.MockRepository)
+	service := NewAuthorizingDataService(NewDataService(mockRepo))
+	mockRepo.On("GetSalesInRange", time.Time{}, time.Time{}, "6789").Return(batchSales()[:2], nil)
+	query := models.SalesQuery{Statement: "SELECT COUNT(*) FROM sales"}
+
+	// Scoped callers only query their stores, through the repository's
+	// range lookup since it has no store index.
+	result, err := service.Query(withRoles([]string{auth.RoleStoreManager}, "6789"), query)
+	assert.Nil(t, err)
+	assert.Equal(t, [][]interface{}{{int64(2)}}, result.Rows)
+	mockRepo.AssertNotCalled(t, "GetAllSales")
+
+	_, err = service.Query(withRoles([]string{auth.RoleStoreManager}, "6789"), models.SalesQuery{Statement: query.Statement, StoreIds: []string{"9876"}})
+	assert.True(t, errors.Is(err, ErrForbidden))
+
+	_, err = service.Query(withRoles([]string{auth.RoleStoreManager}), query)
+	assert.True(t, errors.Is(err, ErrForbidden))
+
+	_, err = service.Query(withRoles([]string{auth.RoleIntegration}), query)
+	assert.True(t, errors.Is(err, ErrForbidden))
+}
@@ -0,0 +1,160 @@
+package services
+
+import (
+	"context"
+	"dataflow/models"
+	"fmt"
+	"math/big"
+	"slices"
+	"sort"
+)
+
+// RollupService totals sales along the store and product hierarchies of the
+// catalog.
+type RollupService interface {
+	// Rollup returns a root node with the totals of all sales in range,
+	// whose children are the groups at the query's level.
+	Rollup(ctx context.Context, query models.RollupQuery) (*models.RollupNode, error)
+}
+
+type rollupService struct {
+	data    DataService
+	catalog CatalogService
+}
+
+// NewRollupService totals the per store or per product aggregates of data,
+// so rollups are subject to the same authorization and store scope.
+func NewRollupService(data DataService, catalog CatalogService) RollupService {
+	return &rollupService{data: data, catalog: catalog}
+}
+
+// rollupTotals accumulates a node's revenue as an exact decimal, so parents
+// sum their children without rounding.
+type rollupTotals struct {
+	node     *models.RollupNode
+	revenue  *big.Rat
+	children map[string]*rollupTotals
+}
+
+func (rs *rollupService) Rollup(ctx context.Context, query models.RollupQuery) (*models.RollupNode, error) {
+	hierarchy, dimension := models.StoreHierarchy, models.DimensionStore
+	if slices.Contains(models.ProductHierarchy, query.Level) {
+		hierarchy, dimension = models.ProductHierarchy, models.DimensionProduct
+	}
+	depth := slices.Index(hierarchy, query.Level)
+	if depth < 0 {
+		return nil, fmt.Errorf("%w %q", ErrUnsupportedDimension, query.Level)
+	}
+	levels := slices.Clone(hierarchy[:depth+1])
+	slices.Reverse(levels)
+	if !query.DrillDown {
+		levels = levels[:1]
+	}
+
+	groups, err := rs.data.Aggregate(ctx, models.AggregateQuery{
+		StartDate: query.StartDate,
+		EndDate:   query.EndDate,
+		GroupBy:   []string{dimension},
+	})
+	if err != nil {
+		return nil, err
+	}
+	path, err := rs.paths(ctx, dimension)
+	if err != nil {
+		return nil, err
+	}
+
+	root := newRollupTotals("", "")
+	for _, group := range groups {
+		id := group.StoreId
+		if dimension == models.DimensionProduct {
+			id = group.ProductId
+		}
+		// Leaves are totalled from the decimal that clients see for the
+		// group's revenue elsewhere, so the rollup adds up to it. Aggregates
+		// total revenue exactly, so that decimal is the exact sum.
+		revenue, ok := new(big.Rat).SetString(group.Revenue.Text('g', -1))
+		if !ok {
+			return nil, fmt.Errorf("couldn't total revenue %s", group.Revenue.Text('g', -1))
+		}
+		keys, name := path(id)
+		node := root
+		node.add(revenue, group)
+		for _, level := range levels {
+			key := keys[level]
+			child, ok := node.children[key]
+			if !ok {
+				child = newRollupTotals(level, key)
+				node.children[key] = child
+			}
+			if level == hierarchy[0] {
+				child.node.Name = name
+			}
+			child.add(revenue, group)
+			node = child
+		}
+	}
+	return root.finish(), nil
+}
+
+// paths returns the keys of a store or product at each level of its
+// hierarchy, and its catalog name.
+func (rs *rollupService) paths(ctx context.Context, dimension string) (func(id string) (map[string]string, string), error) {
+	keys := make(map[string]map[string]string)
+	names := make(map[string]string)
+	if dimension == models.DimensionStore {
+		stores, err := rs.catalog.GetAllStores(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, store := range stores {
+			keys[store.ID] = map[string]string{models.LevelDistrict: store.District, models.LevelRegion: store.Region, models.LevelCountry: store.Country}
+			names[store.ID] = store.Name
+		}
+	} else {
+		products, err := rs.catalog.GetAllProducts(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, product := range products {
+			keys[product.ID] = map[string]string{models.LevelSubcategory: product.Subcategory, models.LevelCategory: product.Category}
+			names[product.ID] = product.Name
+		}
+	}
+	return func(id string) (map[string]string, string) {
+		path := map[string]string{models.LevelStore: id, models.LevelProduct: id}
+		for level, key := range keys[id] {
+			path[level] = key
+		}
+		return path, names[id]
+	}, nil
+}
+
+func newRollupTotals(level string, key string) *rollupTotals {
+	return &rollupTotals{
+		node:     &models.RollupNode{Level: level, Key: key},
+		revenue:  new(big.Rat),
+		children: make(map[string]*rollupTotals),
+	}
+}
+
+func (t *rollupTotals) add(revenue *big.Rat, group *models.AggregateGroup) {
+	t.revenue.Add(t.revenue, revenue)
+	t.node.Units += group.Units
+	t.node.Count += group.Count
+}
+
+// finish converts the revenue to a big.Float whose shortest representation
+// is the exact decimal, and orders the children by key.
+func (t *rollupTotals) finish() *models.RollupNode {
+	t.node.Revenue = decimalFloat(t.revenue)
+	keys := make([]string, 0, len(t.children))
+	for key := range t.children {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		t.node.Children = append(t.node.Children, t.children[key].finish())
+	}
+	return t.node
+}
@@ -0,0 +1,146 @@
+package services
+
+import (
+	"context"
+	"dataflow/models"
+	"dataflow/repo"
+	"github.com/stretchr/testify/assert"
+	"math/big"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func setupRollup() RollupService {
+	sales := repo.NewInMemoryRepository()
+	for _, sale := range []*models.Sale{
+		{ProductId: "espresso", StoreId: "paris-1", QuantitySold: 3, SalePrice: 0.1},
+		{ProductId: "latte", StoreId: "paris-2", QuantitySold: 1, SalePrice: 0.2},
+		{ProductId: "espresso", StoreId: "lyon-1", QuantitySold: 7, SalePrice: 19.99},
+		{ProductId: "mug", StoreId: "berlin-1", QuantitySold: 2, SalePrice: 5.55},
+		{ProductId: "mug", StoreId: "phantom", QuantitySold: 1, SalePrice: 1},
+	} {
+		sale.SaleDate = time.Date(2024, 6, 15, 12, 0, 0, 0, time.UTC)
+		sales.AddSale(context.Background(), sale)
+	}
+	catalog := NewCatalogService(repo.NewInMemoryStoreRepository(), repo.NewInMemoryProductRepository(), sales)
+	for _, store := range []*models.Store{
+		{ID: "paris-1", Name: "Opéra", District: "paris", Region: "idf", Country: "FR"},
+		{ID: "paris-2", Name: "Bastille", District: "paris", Region: "idf", Country: "FR"},
+		{ID: "lyon-1", Name: "Bellecour", District: "lyon", Region: "ara", Country: "FR"},
+		{ID: "berlin-1", Name: "Mitte", District: "berlin", Region: "be", Country: "DE"},
+	} {
+		catalog.CreateStore(context.Background(), store)
+	}
+	for _, product := range []*models.Product{
+		{ID: "espresso", Name: "Espresso", Subcategory: "coffee", Category: "drinks"},
+		{ID: "latte", Name: "Latte", Subcategory: "coffee", Category: "drinks"},
+		{ID: "mug", Name: "Mug", Subcategory: "tableware", Category: "merchandise"},
+	} {
+		catalog.CreateProduct(context.Background(), product)
+	}
+	return NewRollupService(NewDataService(sales), catalog)
+}
+
+// assertSums checks that every node's totals are the exact sums of its
+// children's, as they are written in JSON.
+func assertSums(t *testing.T, node *models.RollupNode) {
+	if len(node.Children) == 0 {
+		return
+	}
+	revenue := new(big.Rat)
+	var units, count int64
+	for _, child := range node.Children {
+		r, _ := new(big.Rat).SetString(child.Revenue.Text('g', -1))
+		revenue.Add(revenue, r)
+		units += child.Units
+		count += child.Count
+		assertSums(t, child)
+	}
+	total, _ := new(big.Rat).SetString(node.Revenue.Text('g', -1))
+	assert.Equal(t, revenue.String(), total.String(), node.Key)
+	assert.Equal(t, units, node.Units, node.Key)
+	assert.Equal(t, count, node.Count, node.Key)
+}
+
+func TestRollupService_Rollup(t *testing.T) {
+	service := setupRollup()
+
+	root, err := service.Rollup(context.Background(), models.RollupQuery{Level: models.LevelCountry})
+	assert.Nil(t, err)
+	assert.Equal(t, "152.53", root.Revenue.Text('g', -1))
+	if assert.Len(t, root.Children, 3) {
+		assert.Equal(t, "", root.Children[0].Key)
+		assert.Equal(t, "1", root.Children[0].Revenue.Text('g', -1))
+		assert.Equal(t, "DE", root.Children[1].Key)
+		assert.Equal(t, "FR", root.Children[2].Key)
+		assert.Equal(t, "140.43", root.Children[2].Revenue.Text('g', -1))
+		assert.Empty(t, root.Children[2].Children)
+	}
+	assertSums(t, root)
+}
+
+func TestRollupService_Rollup_DrillDown(t *testing.T) {
+	service := setupRollup()
+
+	root, err := service.Rollup(context.Background(), models.RollupQuery{Level: models.LevelRegion, DrillDown: true})
+	assert.Nil(t, err)
+	assertSums(t, root)
+	if !assert.Len(t, root.Children, 4) {
+		return
+	}
+	idf := root.Children[3]
+	assert.Equal(t, "idf", idf.Key)
+	assert.Equal(t, "0.5", idf.Revenue.Text('g', -1))
+	if assert.Len(t, idf.Children, 1) && assert.Len(t, idf.Children[0].Children, 2) {
+		assert.Equal(t, models.LevelDistrict, idf.Children[0].Level)
+		store := idf.Children[0].Children[0]
+		assert.Equal(t, models.LevelStore, store.Level)
+		assert.Equal(t, "paris-1", store.Key)
+		assert.Equal(t, "Opéra", store.Name)
+		assert.Equal(t, "0.3", store.Revenue.Text('g', -1))
+	}
+
+	root, err = service.Rollup(context.Background(), models.RollupQuery{Level: models.LevelCategory, DrillDown: true})
+	assert.Nil(t, err)
+	assertSums(t, root)
+	if assert.Len(t, root.Children, 2) {
+		assert.Equal(t, "drinks", root.Children[0].Key)
+		assert.Equal(t, int64(11), root.Children[0].Units)
+		assert.Equal(t, "coffee", root.Children[0].Children[0].Key)
+		assert.Len(t, root.Children[0].Children[0].Children, 2)
+	}
+}
+
+func TestRollupService_Rollup_UnsupportedLevel(t *testing.T) {
+	service := setupRollup()
+
+	_, err := service.Rollup(context.Background(), models.RollupQuery{Level: "continent"})
+	assert.ErrorIs(t, err, ErrUnsupportedDimension)
+}
+
+func TestRollupService_Rollup_LargeTotals(t *testing.T) {
+	sales := repo.NewInMemoryRepository()
+	catalog := NewCatalogService(repo.NewInMemoryStoreRepository(), repo.NewInMemoryProductRepository(), sales)
+	exact := new(big.Rat)
+	for i, store := range []*models.Store{
+		{ID: "paris-1", Name: "Opéra", District: "paris", Region: "idf", Country: "FR"},
+		{ID: "paris-2", Name: "Bastille", District: "paris", Region: "idf", Country: "FR"},
+		{ID: "lyon-1", Name: "Bellecour", District: "lyon", Region: "ara", Country: "FR"},
+	} {
+		catalog.CreateStore(context.Background(), store)
+		price := []float64{1234.56, 19.99, 0.07}[i]
+		for j := 0; j < 2000; j++ {
+			sales.AddSale(context.Background(), &models.Sale{ProductId: "espresso", StoreId: store.ID, QuantitySold: 3, SalePrice: price, SaleDate: time.Date(2024, 6, 15, 12, 0, j, 0, time.UTC)})
+			amount, _ := new(big.Rat).SetString(strconv.FormatFloat(price, 'f', -1, 64))
+			exact.Add(exact, amount.Mul(amount, big.NewRat(3, 1)))
+		}
+	}
+
+	root, err := NewRollupService(NewDataService(sales), catalog).Rollup(context.Background(), models.RollupQuery{Level: models.LevelDistrict, DrillDown: true})
+	assert.Nil(t, err)
+	assert.Equal(t, "7527720", exact.FloatString(0))
+	assert.Equal(t, exact.FloatString(2), root.Revenue.Text('f', 2))
+	assert.Equal(t, "7527720", root.Revenue.Text('f', -1))
+	assertSums(t, root)
+}
@@ -1,20 +1,27 @@
 package services
 
 import (
+	"context"
 	"dataflow/models"
 	"dataflow/repo"
 	"errors"
 	"fmt"
 	"math/big"
+	"strconv"
 	"time"
 )
 
 var ErrWrongDate = errors.New("start date must be before end date")
 
 type DataService interface {
-	GetAllSales() ([]*models.Sale, error)
-	AddSale(sale *models.Sale) error
-	CalculateSales(startDate time.Time, endDate time.Time, storeId string) (*big.Float, error)
+	GetSale(ctx context.Context, id string) (*models.Sale, error)
+	GetAllSales(ctx context.Context) ([]*models.Sale, error)
+	GetSalesInRange(ctx context.Context, startDate time.Time, endDate time.Time, storeId string) ([]*models.Sale, error)
+	AddSale(ctx context.Context, sale *models.Sale) error
+	CalculateSales(ctx context.Context, startDate time.Time, endDate time.Time, storeId string) (*big.Float, error)
+	CalculateBatch(ctx context.Context, startDate time.Time, endDate time.Time, storeIds []string, operations []string) (*models.BatchResult, error)
+	Aggregate(ctx context.Context, query models.AggregateQuery) ([]*models.AggregateGroup, error)
+	Query(ctx context.Context, query models.SalesQuery) (*models.QueryResult, error)
 }
 
 type dataService struct {
@@ -25,39 +32,77 @@ func NewDataService(repo repo.Repository) DataService {
 	return &dataService{repo}
 }
 
-func (ds *dataService) GetAllSales() ([]*models.Sale, error) {
-	sales, err := ds.repo.GetAllSales()
+func (ds *dataService) GetSale(ctx context.Context, id string) (*models.Sale, error) {
+	sale, err := ds.repo.GetSale(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't get sale: %w", err)
+	}
+	return sale, nil
+}
+
+func (ds *dataService) GetAllSales(ctx context.Context) ([]*models.Sale, error) {
+	sales, err := ds.repo.GetAllSales(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't get sales: %w", err)
+	}
+	return sales, nil
+}
+
+func (ds *dataService) GetSalesInRange(ctx context.Context, startDate time.Time, endDate time.Time, storeId string) ([]*models.Sale, error) {
+	if !startDate.IsZero() && !endDate.IsZero() && startDate.After(endDate) {
+		return nil, ErrWrongDate
+	}
+	sales, err := ds.repo.GetSalesInRange(ctx, startDate, endDate, storeId)
 	if err != nil {
 		return nil, fmt.Errorf("couldn't get sales: %w", err)
 	}
 	return sales, nil
 }
 
-func (ds *dataService) AddSale(sale *models.Sale) error {
-	err := ds.repo.AddSale(sale)
+func (ds *dataService) AddSale(ctx context.Context, sale *models.Sale) error {
+	err := ds.repo.AddSale(ctx, sale)
 	if err != nil {
 		return fmt.Errorf("couldn't add sale: %w", err)
 	}
 	return nil
 }
 
-func (ds *dataService) CalculateSales(startDate time.Time, endDate time.Time, storeId string) (*big.Float, error) {
-	if endDate.IsZero() {
-		endDate = time.Time{}
-	} else if !startDate.IsZero() && startDate.After(endDate) {
+func (ds *dataService) CalculateSales(ctx context.Context, startDate time.Time, endDate time.Time, storeId string) (*big.Float, error) {
+	if !startDate.IsZero() && !endDate.IsZero() && startDate.After(endDate) {
 		return new(big.Float).SetFloat64(0.0), ErrWrongDate
 	}
-	sales, err := ds.repo.GetSalesInRange(startDate, endDate, storeId)
+	sales, err := ds.repo.GetSalesInRange(ctx, startDate, endDate, storeId)
 	if err != nil {
 		return nil, fmt.Errorf("couldn't calculate sales: %w", err)
 	}
 
 	totalSales := new(big.Float).SetPrec(20).SetFloat64(0.0)
 	for _, sale := range sales {
-		quantityBigFloat := new(big.Float).SetPrec(64).SetInt64(int64(sale.QuantitySold))
-		priceBigFloat := new(big.Float).SetPrec(64).SetFloat64(sale.SalePrice)
-		saleAmount := new(big.Float).Mul(quantityBigFloat, priceBigFloat)
-		totalSales.Add(totalSales, saleAmount)
+		totalSales.Add(totalSales, saleAmount(sale))
 	}
 	return totalSales, err
 }
+
+func saleAmount(sale *models.Sale) *big.Float {
+	quantityBigFloat := new(big.Float).SetPrec(64).SetInt64(int64(sale.QuantitySold))
+	priceBigFloat := new(big.Float).SetPrec(64).SetFloat64(sale.SalePrice)
+	return new(big.Float).Mul(quantityBigFloat, priceBigFloat)
+}
+
+// saleDecimal returns the amount of a sale as an exact decimal, taking the
+// price as the shortest decimal that its float64 stands for, such as 19.99.
+// Totals of these don't round however many sales they add up.
+func saleDecimal(sale *models.Sale) *big.Rat {
+	price, ok := new(big.Rat).SetString(strconv.FormatFloat(sale.SalePrice, 'f', -1, 64))
+	if !ok {
+		// NaN and infinite prices have no decimal and add nothing.
+		return new(big.Rat)
+	}
+	return price.Mul(price, new(big.Rat).SetInt64(int64(sale.QuantitySold)))
+}
+
+// decimalFloat converts an exact decimal total to a big.Float whose shortest
+// representation is that decimal.
+func decimalFloat(total *big.Rat) *big.Float {
+	return new(big.Float).SetPrec(64).SetRat(total)
+}
@@ -1,6 +1,7 @@
 package services
 
 import (
+	"context"
 	"dataflow/models"
 	"dataflow/repo"
 	"github.com/stretchr/testify/assert"
@@ -30,7 +31,7 @@ func TestDataService_GetAllSales(t *testing.T) {
 
 	mockRepo.On("GetAllSales").Return([]*models.Sale{sale1, sale2}, nil)
 
-	sales, err := service.GetAllSales()
+	sales, err := service.GetAllSales(context.Background())
 
 	assert.Nil(t, err)
 	assert.Equal(t, 2, len(sales))
@@ -52,7 +53,7 @@ func TestDataService_AddSale(t *testing.T) {
 
 	mockRepo.On("AddSale", sale).Return(nil)
 
-	err := service.AddSale(sale)
+	err := service.AddSale(context.Background(), sale)
 	assert.Nil(t, err)
 }
 
@@ -76,7 +77,7 @@ func TestDataService_CalculateSales(t *testing.T) {
 
 	expectedTotal := new(big.Float).SetPrec(20).SetFloat64(199.90)
 
-	totalSales, err := service.CalculateSales(startDate, endDate, sale1.StoreId)
+	totalSales, err := service.CalculateSales(context.Background(), startDate, endDate, sale1.StoreId)
 	assert.Nil(t, err)
 	assert.Equal(t, expectedTotal, totalSales)
 }
@@ -87,7 +88,7 @@ func TestDataService_GetAllSales_Empty(t *testing.T) {
 
 	mockRepo.On("GetAllSales").Return([]*models.Sale{}, nil)
 
-	sales, err := service.GetAllSales()
+	sales, err := service.GetAllSales(context.Background())
 
 	assert.Nil(t, err)
 	assert.Equal(t, 0, len(sales))
@@ -113,7 +114,7 @@ func TestDataService_CalculateSales_NoSalesInDateRange(t *testing.T) {
 
 	expectedTotal := new(big.Float).SetPrec(20).SetFloat64(0.0)
 
-	totalSales, err := service.CalculateSales(startDate, endDate, sale1.StoreId)
+	totalSales, err := service.CalculateSales(context.Background(), startDate, endDate, sale1.StoreId)
 	assert.Nil(t, err)
 	assert.Equal(t, expectedTotal, totalSales)
 }
@@ -130,7 +131,7 @@ func TestDataService_CalculateSales_NoSalesForStore(t *testing.T) {
 
 	expectedTotal := new(big.Float).SetPrec(20).SetFloat64(0.0)
 
-	totalSales, err := service.CalculateSales(startDate, endDate, storeId)
+	totalSales, err := service.CalculateSales(context.Background(), startDate, endDate, storeId)
 	assert.Nil(t, err)
 	assert.Equal(t, expectedTotal, totalSales)
 }
@@ -146,7 +147,7 @@ func TestDataService_CalculateSales_ZeroStartDate(t *testing.T) {
 
 	expectedTotal := new(big.Float).SetPrec(20).SetFloat64(0.0)
 
-	totalSales, err := service.CalculateSales(time.Time{}, endDate, storeId)
+	totalSales, err := service.CalculateSales(context.Background(), time.Time{}, endDate, storeId)
 	assert.Nil(t, err)
 	assert.Equal(t, expectedTotal, totalSales)
 }
@@ -162,7 +163,7 @@ func TestDataService_CalculateSales_ZeroEndDate(t *testing.T) {
 
 	expectedTotal := new(big.Float).SetPrec(20).SetFloat64(0.0)
 
-	totalSales, err := service.CalculateSales(startDate, time.Time{}, storeId)
+	totalSales, err := service.CalculateSales(context.Background(), startDate, time.Time{}, storeId)
 	assert.Nil(t, err)
 	assert.Equal(t, expectedTotal, totalSales)
 }
@@ -177,7 +178,40 @@ func TestDataService_CalculateSales_ZeroStartDateAndEndDate(t *testing.T) {
 
 	expectedTotal := new(big.Float).SetPrec(20).SetFloat64(0.0)
 
-	totalSales, err := service.CalculateSales(time.Time{}, time.Time{}, storeId)
+	totalSales, err := service.CalculateSales(context.Background(), time.Time{}, time.Time{}, storeId)
 	assert.Nil(t, err)
 	assert.Equal(t, expectedTotal, totalSales)
 }
+
+func TestDataService_GetSalesInRange(t *testing.T) {
+	mockRepo := new(repo.MockRepository)
+	service := NewDataService(mockRepo)
+
+	sale1 := &models.Sale{
+		ProductId:    "12345",
+		StoreId:      "6789",
+		QuantitySold: 10,
+		SalePrice:    19.99,
+		SaleDate:     time.Date(2024, 6, 15, 14, 30, 0, 0, time.UTC),
+	}
+
+	startDate := time.Date(2024, 6, 1, 14, 30, 0, 0, time.UTC)
+	endDate := time.Date(2024, 6, 16, 14, 30, 0, 0, time.UTC)
+
+	mockRepo.On("GetSalesInRange", startDate, endDate, "6789").Return([]*models.Sale{sale1}, nil)
+
+	sales, err := service.GetSalesInRange(context.Background(), startDate, endDate, "6789")
+	assert.Nil(t, err)
+	assert.Equal(t, []*models.Sale{sale1}, sales)
+}
+
+func TestDataService_GetSalesInRange_WrongDate(t *testing.T) {
+	mockRepo := new(repo.MockRepository)
+	service := NewDataService(mockRepo)
+
+	startDate := time.Date(2024, 6, 20, 14, 30, 0, 0, time.UTC)
+	endDate := time.Date(2024, 6, 16, 14, 30, 0, 0, time.UTC)
+
+	_, err := service.GetSalesInRange(context.Background(), startDate, endDate, "6789")
+	assert.ErrorIs(t, err, ErrWrongDate)
+}
@@ -0,0 +1,147 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"dataflow/models"
+	"dataflow/repo"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	SignatureHeader = "X-Dataflow-Signature"
+	TimestampHeader = "X-Dataflow-Timestamp"
+)
+
+type WebhookOptions struct {
+	MaxAttempts    int
+	InitialBackoff time.Duration
+	Timeout        time.Duration
+}
+
+var DefaultWebhookOptions = WebhookOptions{
+	MaxAttempts:    5,
+	InitialBackoff: time.Second,
+	Timeout:        10 * time.Second,
+}
+
+type WebhookNotifier struct {
+	client     *http.Client
+	deliveries repo.AlertRepository
+	opts       WebhookOptions
+}
+
+func NewWebhookNotifier(deliveries repo.AlertRepository, opts WebhookOptions) *WebhookNotifier {
+	if opts.MaxAttempts <= 0 {
+		opts.MaxAttempts = DefaultWebhookOptions.MaxAttempts
+	}
+	if opts.InitialBackoff <= 0 {
+		opts.InitialBackoff = DefaultWebhookOptions.InitialBackoff
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = DefaultWebhookOptions.Timeout
+	}
+	return &WebhookNotifier{
+		client:     &http.Client{Timeout: opts.Timeout},
+		deliveries: deliveries,
+		opts:       opts,
+	}
+}
+
+// SignPayload returns the signature sent in SignatureHeader: the hex encoded
+// HMAC-SHA256 of "<timestamp>.<body>" keyed with the rule's webhook secret.
+func SignPayload(secret string, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// newWebhookSecret returns a random secret for rules created without one, so
+// that every notification is signed.
+func newWebhookSecret() (string, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(secret), nil
+}
+
+// Notify posts the notification to the rule's webhook, retrying network
+// errors, 429 and 5xx responses with exponential backoff. Every attempt is
+// recorded in the delivery log.
+func (n *WebhookNotifier) Notify(ctx context.Context, rule *models.AlertRule, notification *models.AlertNotification) error {
+	body, err := json.Marshal(notification)
+	if err != nil {
+		return fmt.Errorf("couldn't encode notification: %w", err)
+	}
+
+	backoff := n.opts.InitialBackoff
+	for attempt := 1; ; attempt++ {
+		statusCode, err := n.send(ctx, rule, body)
+		delivered := err == nil && statusCode < 300
+		delivery := &models.WebhookDelivery{
+			RuleId:     rule.ID,
+			Status:     notification.Status,
+			URL:        rule.WebhookURL,
+			Attempt:    attempt,
+			StatusCode: statusCode,
+			Delivered:  delivered,
+			Timestamp:  time.Now().UTC(),
+		}
+		if err != nil {
+			delivery.Error = err.Error()
+		}
+		if logErr := n.deliveries.AddDelivery(delivery); logErr != nil {
+			slog.ErrorContext(ctx, "couldn't record webhook delivery", slog.String("rule_id", rule.ID), slog.Any("error", logErr))
+		}
+
+		if delivered {
+			return nil
+		}
+		if err == nil && statusCode != http.StatusTooManyRequests && statusCode < 500 {
+			return fmt.Errorf("webhook rejected notification with status %d", statusCode)
+		}
+		if attempt >= n.opts.MaxAttempts {
+			if err != nil {
+				return fmt.Errorf("couldn't deliver notification after %d attempts: %w", attempt, err)
+			}
+			return fmt.Errorf("couldn't deliver notification after %d attempts: status %d", attempt, statusCode)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
+
+func (n *WebhookNotifier) send(ctx context.Context, rule *models.AlertRule, body []byte) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, rule.WebhookURL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(TimestampHeader, timestamp)
+	req.Header.Set(SignatureHeader, SignPayload(rule.WebhookSecret, timestamp, body))
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	return resp.StatusCode, nil
+}
@@ -0,0 +1,102 @@
+package services
+
+import (
+	"context"
+	"dataflow/models"
+	"dataflow/repo"
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+var testWebhookOptions = WebhookOptions{MaxAttempts: 3, InitialBackoff: time.Millisecond, Timeout: time.Second}
+
+func TestWebhookNotifier_Notify_Signed(t *testing.T) {
+	var received models.AlertNotification
+	var signatureValid bool
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		expected := SignPayload("s3cret", r.Header.Get(TimestampHeader), body)
+		signatureValid = r.Header.Get(SignatureHeader) == expected
+		json.Unmarshal(body, &received)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer receiver.Close()
+
+	alertRepo := repo.NewInMemoryAlertRepository()
+	notifier := NewWebhookNotifier(alertRepo, testWebhookOptions)
+	rule := &models.AlertRule{ID: "1", WebhookURL: receiver.URL, WebhookSecret: "s3cret"}
+
+	err := notifier.Notify(context.Background(), rule, &models.AlertNotification{RuleId: "1", Status: models.AlertStateFiring})
+	assert.Nil(t, err)
+	assert.True(t, signatureValid)
+	assert.Equal(t, models.AlertStateFiring, received.Status)
+
+	deliveries, _ := alertRepo.GetDeliveries("1")
+	assert.Len(t, deliveries, 1)
+	assert.True(t, deliveries[0].Delivered)
+	assert.Equal(t, http.StatusNoContent, deliveries[0].StatusCode)
+}
+
+func TestWebhookNotifier_Notify_RetriesServerErrors(t *testing.T) {
+	var calls atomic.Int32
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer receiver.Close()
+
+	alertRepo := repo.NewInMemoryAlertRepository()
+	notifier := NewWebhookNotifier(alertRepo, testWebhookOptions)
+	rule := &models.AlertRule{ID: "1", WebhookURL: receiver.URL}
+
+	err := notifier.Notify(context.Background(), rule, &models.AlertNotification{RuleId: "1"})
+	assert.Nil(t, err)
+	assert.Equal(t, int32(3), calls.Load())
+
+	deliveries, _ := alertRepo.GetDeliveries("1")
+	assert.Len(t, deliveries, 3)
+	assert.False(t, deliveries[0].Delivered)
+	assert.True(t, deliveries[2].Delivered)
+}
+
+func TestWebhookNotifier_Notify_GivesUp(t *testing.T) {
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer receiver.Close()
+
+	alertRepo := repo.NewInMemoryAlertRepository()
+	notifier := NewWebhookNotifier(alertRepo, testWebhookOptions)
+	rule := &models.AlertRule{ID: "1", WebhookURL: receiver.URL}
+
+	err := notifier.Notify(context.Background(), rule, &models.AlertNotification{RuleId: "1"})
+	assert.Error(t, err)
+
+	deliveries, _ := alertRepo.GetDeliveries("1")
+	assert.Len(t, deliveries, testWebhookOptions.MaxAttempts)
+}
+
+func TestWebhookNotifier_Notify_ClientErrorNotRetried(t *testing.T) {
+	var calls atomic.Int32
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer receiver.Close()
+
+	notifier := NewWebhookNotifier(repo.NewInMemoryAlertRepository(), testWebhookOptions)
+	rule := &models.AlertRule{ID: "1", WebhookURL: receiver.URL}
+
+	err := notifier.Notify(context.Background(), rule, &models.AlertNotification{RuleId: "1"})
+	assert.Error(t, err)
+	assert.Equal(t, int32(1), calls.Load())
+}
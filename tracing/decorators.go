@@ -0,0 +1,177 @@
+package tracing
+
+import (
+	"context"
+	"dataflow/models"
+	"dataflow/repo"
+	"dataflow/services"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"math/big"
+	"time"
+)
+
+const (
+	attrSaleId     = attribute.Key("dataflow.sale_id")
+	attrStoreId    = attribute.Key("dataflow.store_id")
+	attrStoreIds   = attribute.Key("dataflow.store_ids")
+	attrOperations = attribute.Key("dataflow.operations")
+	attrGroupBy    = attribute.Key("dataflow.group_by")
+	attrStatement  = attribute.Key("dataflow.statement")
+	attrStartDate  = attribute.Key("dataflow.start_date")
+	attrEndDate    = attribute.Key("dataflow.end_date")
+	attrResultSize = attribute.Key("dataflow.result.size")
+	attrTotal      = attribute.Key("dataflow.result.total")
+)
+
+type tracedRepository struct {
+	inner repo.Repository
+}
+
+func NewRepository(inner repo.Repository) repo.Repository {
+	return &tracedRepository{inner: inner}
+}
+
+func (r *tracedRepository) AddSale(ctx context.Context, sale *models.Sale) error {
+	ctx, span := tracer().Start(ctx, "Repository.AddSale", trace.WithAttributes(attrStoreId.String(sale.StoreId)))
+	defer span.End()
+	err := r.inner.AddSale(ctx, sale)
+	return end(span, err)
+}
+
+func (r *tracedRepository) GetSale(ctx context.Context, id string) (*models.Sale, error) {
+	ctx, span := tracer().Start(ctx, "Repository.GetSale", trace.WithAttributes(attrSaleId.String(id)))
+	defer span.End()
+	sale, err := r.inner.GetSale(ctx, id)
+	return sale, end(span, err)
+}
+
+func (r *tracedRepository) GetAllSales(ctx context.Context) ([]*models.Sale, error) {
+	ctx, span := tracer().Start(ctx, "Repository.GetAllSales")
+	defer span.End()
+	sales, err := r.inner.GetAllSales(ctx)
+	span.SetAttributes(attrResultSize.Int(len(sales)))
+	return sales, end(span, err)
+}
+
+func (r *tracedRepository) GetSalesInRange(ctx context.Context, startDate time.Time, endDate time.Time, storeId string) ([]*models.Sale, error) {
+	ctx, span := tracer().Start(ctx, "Repository.GetSalesInRange", trace.WithAttributes(rangeAttributes(startDate, endDate, storeId)...))
+	defer span.End()
+	sales, err := r.inner.GetSalesInRange(ctx, startDate, endDate, storeId)
+	span.SetAttributes(attrResultSize.Int(len(sales)))
+	return sales, end(span, err)
+}
+
+func (r *tracedRepository) HasStoreIndex() bool {
+	return repo.HasStoreIndex(r.inner)
+}
+
+func (r *tracedRepository) GetSalesByStores(ctx context.Context, startDate time.Time, endDate time.Time, storeIds []string) ([]*models.Sale, error) {
+	attributes := append(rangeAttributes(startDate, endDate, ""), attrStoreIds.StringSlice(storeIds))
+	ctx, span := tracer().Start(ctx, "Repository.GetSalesByStores", trace.WithAttributes(attributes...))
+	defer span.End()
+	sales, err := repo.GetSalesByStores(ctx, r.inner, startDate, endDate, storeIds)
+	span.SetAttributes(attrResultSize.Int(len(sales)))
+	return sales, end(span, err)
+}
+
+type tracedDataService struct {
+	inner services.DataService
+}
+
+func NewDataService(inner services.DataService) services.DataService {
+	return &tracedDataService{inner: inner}
+}
+
+func (ds *tracedDataService) GetSale(ctx context.Context, id string) (*models.Sale, error) {
+	ctx, span := tracer().Start(ctx, "DataService.GetSale", trace.WithAttributes(attrSaleId.String(id)))
+	defer span.End()
+	sale, err := ds.inner.GetSale(ctx, id)
+	return sale, end(span, err)
+}
+
+func (ds *tracedDataService) GetAllSales(ctx context.Context) ([]*models.Sale, error) {
+	ctx, span := tracer().Start(ctx, "DataService.GetAllSales")
+	defer span.End()
+	sales, err := ds.inner.GetAllSales(ctx)
+	span.SetAttributes(attrResultSize.Int(len(sales)))
+	return sales, end(span, err)
+}
+
+func (ds *tracedDataService) GetSalesInRange(ctx context.Context, startDate time.Time, endDate time.Time, storeId string) ([]*models.Sale, error) {
+	ctx, span := tracer().Start(ctx, "DataService.GetSalesInRange", trace.WithAttributes(rangeAttributes(startDate, endDate, storeId)...))
+	defer span.End()
+	sales, err := ds.inner.GetSalesInRange(ctx, startDate, endDate, storeId)
+	span.SetAttributes(attrResultSize.Int(len(sales)))
+	return sales, end(span, err)
+}
+
+func (ds *tracedDataService) AddSale(ctx context.Context, sale *models.Sale) error {
+	ctx, span := tracer().Start(ctx, "DataService.AddSale", trace.WithAttributes(attrStoreId.String(sale.StoreId)))
+	defer span.End()
+	err := ds.inner.AddSale(ctx, sale)
+	return end(span, err)
+}
+
+func (ds *tracedDataService) CalculateSales(ctx context.Context, startDate time.Time, endDate time.Time, storeId string) (*big.Float, error) {
+	ctx, span := tracer().Start(ctx, "DataService.CalculateSales", trace.WithAttributes(rangeAttributes(startDate, endDate, storeId)...))
+	defer span.End()
+	total, err := ds.inner.CalculateSales(ctx, startDate, endDate, storeId)
+	if total != nil {
+		span.SetAttributes(attrTotal.String(total.String()))
+	}
+	return total, end(span, err)
+}
+
+func (ds *tracedDataService) CalculateBatch(ctx context.Context, startDate time.Time, endDate time.Time, storeIds []string, operations []string) (*models.BatchResult, error) {
+	attributes := append(rangeAttributes(startDate, endDate, ""), attrStoreIds.StringSlice(storeIds), attrOperations.StringSlice(operations))
+	ctx, span := tracer().Start(ctx, "DataService.CalculateBatch", trace.WithAttributes(attributes...))
+	defer span.End()
+	result, err := ds.inner.CalculateBatch(ctx, startDate, endDate, storeIds, operations)
+	if result != nil {
+		span.SetAttributes(attrResultSize.Int(len(result.StoreIds) * len(result.Operations)))
+	}
+	return result, end(span, err)
+}
+
+func (ds *tracedDataService) Aggregate(ctx context.Context, query models.AggregateQuery) ([]*models.AggregateGroup, error) {
+	attributes := append(rangeAttributes(query.StartDate, query.EndDate, ""), attrStoreIds.StringSlice(query.StoreIds), attrGroupBy.StringSlice(query.GroupBy))
+	ctx, span := tracer().Start(ctx, "DataService.Aggregate", trace.WithAttributes(attributes...))
+	defer span.End()
+	groups, err := ds.inner.Aggregate(ctx, query)
+	span.SetAttributes(attrResultSize.Int(len(groups)))
+	return groups, end(span, err)
+}
+
+func (ds *tracedDataService) Query(ctx context.Context, query models.SalesQuery) (*models.QueryResult, error) {
+	ctx, span := tracer().Start(ctx, "DataService.Query", trace.WithAttributes(attrStatement.String(query.Statement), attrStoreIds.StringSlice(query.StoreIds)))
+	defer span.End()
+	result, err := ds.inner.Query(ctx, query)
+	if result != nil {
+		span.SetAttributes(attrResultSize.Int(len(result.Rows)))
+	}
+	return result, end(span, err)
+}
+
+func rangeAttributes(startDate time.Time, endDate time.Time, storeId string) []attribute.KeyValue {
+	var attributes []attribute.KeyValue
+	if storeId != "" {
+		attributes = append(attributes, attrStoreId.String(storeId))
+	}
+	if !startDate.IsZero() {
+		attributes = append(attributes, attrStartDate.String(startDate.Format(time.RFC3339)))
+	}
+	if !endDate.IsZero() {
+		attributes = append(attributes, attrEndDate.String(endDate.Format(time.RFC3339)))
+	}
+	return attributes
+}
+
+func end(span trace.Span, err error) error {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	return err
+}
@@ -0,0 +1,125 @@
+package tracing
+
+import (
+	"context"
+	"dataflow/models"
+	"dataflow/repo"
+	"dataflow/services"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func recordSpans(t *testing.T) *tracetest.SpanRecorder {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+	return recorder
+}
+
+func spanNamed(spans []sdktrace.ReadOnlySpan, name string) sdktrace.ReadOnlySpan {
+	for _, span := range spans {
+		if span.Name() == name {
+			return span
+		}
+	}
+	return nil
+}
+
+func hasAttribute(span sdktrace.ReadOnlySpan, kv attribute.KeyValue) bool {
+	for _, attr := range span.Attributes() {
+		if attr == kv {
+			return true
+		}
+	}
+	return false
+}
+
+func TestTracing_Layers(t *testing.T) {
+	recorder := recordSpans(t)
+
+	repository := repo.NewInMemoryRepository()
+	repository.AddSale(context.Background(), &models.Sale{StoreId: "6789", QuantitySold: 2, SalePrice: 5, SaleDate: time.Date(2024, 6, 15, 0, 0, 0, 0, time.UTC)})
+	service := NewDataService(services.NewDataService(NewRepository(repository)))
+
+	router := gin.New()
+	router.Use(Middleware())
+	router.POST("/calculate", Handler("DataHandler.Calculate", func(c *gin.Context) {
+		total, _ := service.CalculateSales(c.Request.Context(), time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC), time.Time{}, "6789")
+		c.JSON(http.StatusOK, total)
+	}))
+
+	traceparent := "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+	req := httptest.NewRequest("POST", "/calculate", nil)
+	req.Header.Set("traceparent", traceparent)
+	router.ServeHTTP(httptest.NewRecorder(), req)
+
+	spans := recorder.Ended()
+	assert.Len(t, spans, 4)
+	server := spanNamed(spans, "POST /calculate")
+	handler := spanNamed(spans, "DataHandler.Calculate")
+	calculate := spanNamed(spans, "DataService.CalculateSales")
+	scan := spanNamed(spans, "Repository.GetSalesInRange")
+
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", server.SpanContext().TraceID().String())
+	assert.Equal(t, "00f067aa0ba902b7", server.Parent().SpanID().String())
+	assert.True(t, hasAttribute(server, attribute.Int("http.response.status_code", http.StatusOK)))
+	assert.Equal(t, server.SpanContext().SpanID(), handler.Parent().SpanID())
+	assert.Equal(t, handler.SpanContext().SpanID(), calculate.Parent().SpanID())
+	assert.Equal(t, calculate.SpanContext().SpanID(), scan.Parent().SpanID())
+
+	assert.True(t, hasAttribute(calculate, attrStoreId.String("6789")))
+	assert.True(t, hasAttribute(calculate, attrStartDate.String("2024-06-01T00:00:00Z")))
+	assert.True(t, hasAttribute(calculate, attrTotal.String("10")))
+	assert.True(t, hasAttribute(scan, attrResultSize.Int(1)))
+}
+
+func TestTracing_Error(t *testing.T) {
+	recorder := recordSpans(t)
+	service := NewDataService(services.NewDataService(NewRepository(repo.NewInMemoryRepository())))
+
+	_, err := service.GetSalesInRange(context.Background(), time.Date(2024, 6, 2, 0, 0, 0, 0, time.UTC), time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC), "6789")
+	assert.ErrorIs(t, err, services.ErrWrongDate)
+
+	spans := recorder.Ended()
+	assert.Len(t, spans, 1)
+	assert.Equal(t, "Error", spans[0].Status().Code.String())
+	assert.Len(t, spans[0].Events(), 1)
+}
+
+func TestSetup_FileExporter(t *testing.T) {
+	previous := otel.GetTracerProvider()
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+	path := filepath.Join(t.TempDir(), "traces.json")
+
+	shutdown, err := Setup(context.Background(), Config{ServiceName: "dataflow-test", Exporter: ExporterFile, File: path})
+	assert.Nil(t, err)
+	_, span := tracer().Start(context.Background(), "test-span")
+	span.End()
+	assert.Nil(t, shutdown(context.Background()))
+
+	data, err := os.ReadFile(path)
+	assert.Nil(t, err)
+	assert.Contains(t, string(data), `"Name":"test-span"`)
+	assert.Contains(t, string(data), "dataflow-test")
+}
+
+func TestSetup_InvalidExporter(t *testing.T) {
+	_, err := Setup(context.Background(), Config{Exporter: "zipkin"})
+	assert.NotNil(t, err)
+	_, err = Setup(context.Background(), Config{Exporter: ExporterFile})
+	assert.NotNil(t, err)
+}